@@ -21,6 +21,18 @@ type ClusterImportStatus struct {
 	State       State               `json:"state"`
 	Conditions  *[]metav1.Condition `json:"conditions,omitempty"`
 	Description string              `json:"description,omitempty"`
+	// Devices records the last lifecycle transition observed for each imported device.
+	Devices []DeviceLifecycle `json:"devices,omitempty"`
+}
+
+// DeviceLifecycle records the NetBox status of a device and the action taken for it.
+type DeviceLifecycle struct {
+	Name               string             `json:"name"`
+	NetboxStatus       string             `json:"netboxStatus"`
+	Action             DeviceStatusAction `json:"action"`
+	LastTransitionTime metav1.Time        `json:"lastTransitionTime"`
+	// Message explains why a device was skipped, e.g. because its name does not match the name pattern.
+	Message string `json:"message,omitempty"`
 }
 
 // +kubebuilder:object:root=true
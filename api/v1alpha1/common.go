@@ -21,23 +21,237 @@ type ClusterSelector struct {
 	// Used by the ironcore controller; ignored by others.
 	// +kubebuilder:validation:Optional
 	BMCCredentialsRef *corev1.LocalObjectReference `json:"bmcCredentialsRef,omitempty"`
+	// StatusMapping optionally overrides how NetBox device statuses are mapped to lifecycle actions.
+	// Statuses not listed fall back to the built-in defaults (active is imported, offline and failed
+	// put the server into maintenance, decommissioning removes the imported resources, anything else is skipped).
+	// Used by the ironcore and metal3 controllers; ignored by others.
+	// +kubebuilder:validation:Optional
+	StatusMapping []DeviceStatusMapping `json:"statusMapping,omitempty"`
+	// LabelTemplate optionally customizes the labels set on imported objects.
+	// Used by the ironcore and metal3 controllers; ignored by others.
+	// +kubebuilder:validation:Optional
+	LabelTemplate *LabelTemplate `json:"labelTemplate,omitempty"`
+	// NamePattern is a regular expression with named capture groups used to parse device names,
+	// e.g. ^(?P<nodename>[^-]+)-(?P<bb>[^-]+)$. Every named group becomes a label.
+	// Overrides the globally configured pattern; devices not matching the pattern are skipped.
+	// Used by the ironcore and metal3 controllers; ignored by others.
+	// +kubebuilder:validation:Optional
+	NamePattern string `json:"namePattern,omitempty"`
+	// ConfigContext optionally materializes the NetBox config context of imported devices. If unset, config context
+	// previously exported is removed.
+	// Used by the ironcore and metal3 controllers; ignored by others.
+	// +kubebuilder:validation:Optional
+	ConfigContext *ConfigContextExport `json:"configContext,omitempty"`
+	// GatewayRoles are the NetBox IP address roles, e.g. anycast or vip, of the IP address used as gateway in the
+	// network data, if the prefix has neither a gateway custom field nor an IP address tagged gateway.
+	// If empty, IP addresses are not considered a gateway by their role.
+	// Used by the metal3 controller; ignored by others.
+	// +kubebuilder:validation:Optional
+	GatewayRoles []string `json:"gatewayRoles,omitempty"`
 }
 
+// ConfigContextTarget defines where the config context of a device is materialized.
+// +kubebuilder:validation:Enum=ConfigMap;Annotations
+type ConfigContextTarget string
+
+const (
+	// ConfigContextTargetConfigMap writes the config context into a ConfigMap per device.
+	ConfigContextTargetConfigMap ConfigContextTarget = "ConfigMap"
+	// ConfigContextTargetAnnotations writes the config context into annotations of the BMC or BareMetalHost.
+	ConfigContextTargetAnnotations ConfigContextTarget = "Annotations"
+)
+
+// ConfigContextExport selects the parts of the NetBox config context exported for a device.
+type ConfigContextExport struct {
+	// Target is either ConfigMap, creating a ConfigMap named config-context-<device> next to the imported objects,
+	// or Annotations, annotating the BMC or BareMetalHost with config-context.argora.cloud.sap/<key>.
+	// The config context exported to the target not selected is removed.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=ConfigMap
+	Target ConfigContextTarget `json:"target,omitempty"`
+	// Keys maps exported keys to JSONPath expressions evaluated against the config context, e.g. "{.kernel.args}".
+	// String values are exported as is, other values JSON encoded; keys without a result are omitted.
+	// If empty, every top-level key of the config context is exported.
+	// +kubebuilder:validation:Optional
+	Keys map[string]string `json:"keys,omitempty"`
+}
+
+// LabelTemplate customizes the labels set on imported objects.
+type LabelTemplate struct {
+	// Labels maps label keys to Go templates rendered with the device topology, e.g. "{{ .Rack }}".
+	// Available fields: Region, Zone, SiteGroup, Location, Rack, RackPosition, Tenant, Manufacturer, Serial,
+	// Cluster, ClusterType, Name, NodeName, BB, Type, Role and Platform.
+	// Rendered labels are merged into the default labels, a label rendered to an empty value is removed.
+	// Labels no longer rendered are removed from the objects they were set on.
+	// +kubebuilder:validation:Optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// Sanitize converts label values into valid Kubernetes label values by replacing invalid characters
+	// and truncating them to 63 characters. If disabled, invalid label values fail the import.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=true
+	Sanitize *bool `json:"sanitize,omitempty"`
+}
+
+// DeviceStatusAction is the lifecycle action taken for a device in a given NetBox status.
+// +kubebuilder:validation:Enum=Import;Maintenance;Decommission;Skip
+type DeviceStatusAction string
+
+const (
+	// DeviceStatusActionImport imports the device and clears any maintenance previously requested for it.
+	DeviceStatusActionImport DeviceStatusAction = "Import"
+	// DeviceStatusActionMaintenance puts the servers of the device into maintenance.
+	DeviceStatusActionMaintenance DeviceStatusAction = "Maintenance"
+	// DeviceStatusActionDecommission removes the resources imported for the device.
+	DeviceStatusActionDecommission DeviceStatusAction = "Decommission"
+	// DeviceStatusActionSkip leaves the device and its resources untouched.
+	DeviceStatusActionSkip DeviceStatusAction = "Skip"
+)
+
+// DeviceStatusMapping maps a NetBox device status to a lifecycle action.
+type DeviceStatusMapping struct {
+	// Status is the NetBox device status value, e.g. offline.
+	// +kubebuilder:validation:Required
+	Status string `json:"status"`
+	// +kubebuilder:validation:Required
+	Action DeviceStatusAction `json:"action"`
+}
+
+// IPPoolKind is the kind of the IP pools generated for the selected prefixes.
+// +kubebuilder:validation:Enum=GlobalInClusterIPPool;InClusterIPPool
+type IPPoolKind string
+
+const (
+	// IPPoolKindGlobalInClusterIPPool generates cluster-wide GlobalInClusterIPPools.
+	IPPoolKindGlobalInClusterIPPool IPPoolKind = "GlobalInClusterIPPool"
+	// IPPoolKindInClusterIPPool generates InClusterIPPools, which only serve IP address claims of their namespace.
+	IPPoolKindInClusterIPPool IPPoolKind = "InClusterIPPool"
+)
+
 // IPPoolSelector defines the selection criteria for an IP pool to be imported.
-// +kubebuilder:validation:XValidation:rule="has(self.namePrefix) != has(self.nameOverride)", message="either namePrefix or nameOverride must be set, but not both"
+// +kubebuilder:validation:XValidation:rule="[has(self.namePrefix), has(self.nameOverride), has(self.nameTemplate)].filter(x, x).size() == 1", message="exactly one of namePrefix, nameOverride or nameTemplate must be set"
+// +kubebuilder:validation:XValidation:rule="!has(self.targetNamespace) || self.poolKind == 'InClusterIPPool'", message="targetNamespace requires poolKind InClusterIPPool"
+// +kubebuilder:validation:XValidation:rule="[has(self.region), has(self.role), has(self.site), has(self.tenant), has(self.vrf), has(self.tag), has(self.status), has(self.vlanGroup), has(self.family), has(self.maskLength)].exists(x, x)", message="the prefix filter must set at least one field"
 type IPPoolSelector struct {
 	// +kubebuilder:validation:Optional
 	NamePrefix string `json:"namePrefix,omitempty"`
+	// NameOverride is the name of the IP pool. It is only suitable for selectors matching a single prefix,
+	// further prefixes are reported as name collisions.
 	// +kubebuilder:validation:Optional
 	NameOverride string `json:"nameOverride,omitempty"`
+	// NameTemplate is a Go template rendering the name of the IP pool of each selected prefix, e.g.
+	// {{ .Role }}-{{ .Site }}{{ if eq .Family 6 }}-v6{{ end }}. The template has access to the fields
+	// Prefix, Network, Mask, Family, ID, Site, Region, Vlan, VlanID, Vrf, Tenant and Role of the prefix
+	// and to the helper functions lower, upper, replace, trimPrefix, trimSuffix, submatch, atoi, add, sub and dnsLabel.
 	// +kubebuilder:validation:Optional
-	Region string `json:"region,omitempty"`
+	NameTemplate string `json:"nameTemplate,omitempty"`
+	// PoolKind is the kind of the generated IP pools, either GlobalInClusterIPPool or InClusterIPPool.
 	// +kubebuilder:validation:Optional
-	Role string `json:"role,omitempty"`
+	// +kubebuilder:default=GlobalInClusterIPPool
+	PoolKind IPPoolKind `json:"poolKind,omitempty"`
+	// TargetNamespace is a Go template rendering the namespace of the InClusterIPPool of each selected prefix,
+	// e.g. {{ .Tenant }} or tenant-{{ .Site }}. It has access to the same fields and helper functions as NameTemplate.
+	// If empty, the IP pools are generated in the namespace of the IPPoolImport.
+	// +kubebuilder:validation:Optional
+	TargetNamespace string `json:"targetNamespace,omitempty"`
+	// PrefixFilter selects the prefixes to import IP pools for.
+	PrefixFilter `json:",inline"`
+	// Exclude drops the prefixes matching any of the filters from the selection.
+	// +kubebuilder:validation:Optional
+	Exclude []PrefixFilter `json:"exclude,omitempty"`
 	// +kubebuilder:validation:Optional
 	ExcludeMask *int `json:"excludeMask,omitempty"`
 	// +kubebuilder:validation:Optional
 	ExcludedAddresses []string `json:"excludedAddresses,omitempty"`
 	// +kubebuilder:validation:Optional
 	ExcludeLastNAddresses *int `json:"excludeLastNAddresses,omitempty"`
+	// Claim switches the selector from importing the selected prefixes to carving a child prefix out of them:
+	// the prefix filter selects a NetBox container prefix, from which a child prefix of the requested length
+	// is allocated in NetBox and imported as IP pool.
+	// +kubebuilder:validation:Optional
+	Claim *PrefixClaim `json:"claim,omitempty"`
+	// ExcludeNetboxAddresses excludes the IP addresses and IP ranges documented in NetBox inside each prefix,
+	// e.g. gateways, VIPs or DHCP ranges, so they are not handed out twice.
+	// IP addresses already allocated from the IP pool are not excluded.
+	// +kubebuilder:validation:Optional
+	ExcludeNetboxAddresses *NetboxAddressExclusion `json:"excludeNetboxAddresses,omitempty"`
+	// GatewayRoles are the NetBox IP address roles, e.g. anycast or vip, of the IP address used as gateway of each
+	// prefix, if the prefix has neither a gateway custom field nor an IP address tagged gateway.
+	// If empty, IP addresses are not considered a gateway by their role.
+	// +kubebuilder:validation:Optional
+	GatewayRoles []string `json:"gatewayRoles,omitempty"`
+}
+
+// PrefixClaim requests a child prefix from a NetBox container prefix. The child prefix is allocated once and
+// released when the IPPoolImport is deleted. Removing the claim from the IPPoolImport keeps the child prefix in NetBox
+// until the IPPoolImport is deleted. The child prefix is identified by the IPPoolImport and the name of the claim, so the
+// IP pool selector may be renamed or moved.
+type PrefixClaim struct {
+	// Name identifies the claim in the IPPoolImport and must be unique in it. It must not be changed once the child
+	// prefix is allocated, as a renamed claim allocates a new child prefix.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// PrefixLength is the mask length of the child prefix.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=128
+	PrefixLength int `json:"prefixLength"`
+	// Tag is the name of the NetBox tag set on the child prefix, e.g. the name of the cluster. The tag must exist in NetBox.
+	// +kubebuilder:validation:Required
+	Tag string `json:"tag"`
+}
+
+// PrefixFilter selects NetBox prefixes. A prefix matches if it matches all fields which are set, at least one field
+// must be set.
+// +kubebuilder:validation:MinProperties=1
+type PrefixFilter struct {
+	// Region is the slug of the region of the prefixes.
+	// +kubebuilder:validation:Optional
+	Region string `json:"region,omitempty"`
+	// Role is the slug of the role of the prefixes.
+	// +kubebuilder:validation:Optional
+	Role string `json:"role,omitempty"`
+	// Site is the slug of the site of the prefixes.
+	// +kubebuilder:validation:Optional
+	Site string `json:"site,omitempty"`
+	// Tenant is the slug of the tenant of the prefixes.
+	// +kubebuilder:validation:Optional
+	Tenant string `json:"tenant,omitempty"`
+	// Vrf is the name of the VRF of the prefixes.
+	// +kubebuilder:validation:Optional
+	Vrf string `json:"vrf,omitempty"`
+	// Tag is the slug of a tag of the prefixes.
+	// +kubebuilder:validation:Optional
+	Tag string `json:"tag,omitempty"`
+	// Status is the NetBox status of the prefixes, e.g. active.
+	// +kubebuilder:validation:Optional
+	Status string `json:"status,omitempty"`
+	// VlanGroup is the slug of the VLAN group of the VLANs assigned to the prefixes.
+	// +kubebuilder:validation:Optional
+	VlanGroup string `json:"vlanGroup,omitempty"`
+	// Family is the address family of the prefixes.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=4;6
+	Family int `json:"family,omitempty"`
+	// MaskLength is the mask length of the prefixes.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=128
+	MaskLength int `json:"maskLength,omitempty"`
+}
+
+// NetboxAddressExclusion selects the NetBox IP addresses and IP ranges excluded from an IP pool.
+type NetboxAddressExclusion struct {
+	// Statuses restricts the excluded IP addresses and IP ranges to the given NetBox statuses, e.g. reserved.
+	// If empty, all statuses are excluded.
+	// +kubebuilder:validation:Optional
+	Statuses []string `json:"statuses,omitempty"`
+	// Roles restricts the excluded IP addresses to the given NetBox roles, e.g. anycast or vip.
+	// If empty, IP addresses of all roles, including those without role, are excluded. IP ranges are not filtered by role.
+	// +kubebuilder:validation:Optional
+	Roles []string `json:"roles,omitempty"`
+	// IPRanges enables the exclusion of NetBox IP ranges.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=true
+	IPRanges *bool `json:"ipRanges,omitempty"`
 }
@@ -14,6 +14,8 @@ const (
 	Error State = "Error"
 
 	ConditionTypeReady ConditionType = "Ready"
+	// ConditionTypePrefixesMatched is only set while IPPool selectors of an IPPoolImport match no prefixes.
+	ConditionTypePrefixesMatched ConditionType = "PrefixesMatched"
 
 	ConditionReasonUpdateSucceeded        ConditionReason = "UpdateSucceeded"
 	ConditionReasonUpdateSucceededMessage                 = "Update succeeded"
@@ -25,10 +27,16 @@ const (
 	ConditionReasonClusterImportFailed           ConditionReason = "ClusterImportFailed"
 	ConditionReasonClusterImportFailedMessage                    = "ClusterImport failed"
 
-	ConditionReasonIPPoolImportSucceeded        ConditionReason = "IPPoolImportSucceeded"
-	ConditionReasonIPPoolImportSucceededMessage                 = "IPPoolImport succeeded"
-	ConditionReasonIPPoolImportFailed           ConditionReason = "IPPoolImportFailed"
-	ConditionReasonIPPoolImportFailedMessage                    = "IPPoolImport failed"
+	ConditionReasonIPPoolImportSucceeded                ConditionReason = "IPPoolImportSucceeded"
+	ConditionReasonIPPoolImportSucceededMessage                         = "IPPoolImport succeeded"
+	ConditionReasonIPPoolImportFailed                   ConditionReason = "IPPoolImportFailed"
+	ConditionReasonIPPoolImportFailedMessage                            = "IPPoolImport failed"
+	ConditionReasonIPPoolImportUnsafeChange             ConditionReason = "IPPoolImportUnsafeChange"
+	ConditionReasonIPPoolImportUnsafeChangeMessage                      = "IPPoolImport refused an unsafe IPPool change"
+	ConditionReasonIPPoolImportNameCollision            ConditionReason = "IPPoolImportNameCollision"
+	ConditionReasonIPPoolImportNameCollisionMessage                     = "IPPoolImport found IPPool name collisions"
+	ConditionReasonIPPoolImportNoPrefixesMatched        ConditionReason = "IPPoolImportNoPrefixesMatched"
+	ConditionReasonIPPoolImportNoPrefixesMatchedMessage                 = "IPPoolImport selectors matched no prefixes"
 )
 
 var conditionReasons = map[ConditionReason]conditionMeta{
@@ -38,8 +46,11 @@ var conditionReasons = map[ConditionReason]conditionMeta{
 	ConditionReasonClusterImportSucceeded: {Type: ConditionTypeReady, Status: metav1.ConditionTrue, Message: ConditionReasonClusterImportSucceededMessage},
 	ConditionReasonClusterImportFailed:    {Type: ConditionTypeReady, Status: metav1.ConditionFalse, Message: ConditionReasonClusterImportFailedMessage},
 
-	ConditionReasonIPPoolImportSucceeded: {Type: ConditionTypeReady, Status: metav1.ConditionTrue, Message: ConditionReasonIPPoolImportSucceededMessage},
-	ConditionReasonIPPoolImportFailed:    {Type: ConditionTypeReady, Status: metav1.ConditionFalse, Message: ConditionReasonIPPoolImportFailedMessage},
+	ConditionReasonIPPoolImportSucceeded:         {Type: ConditionTypeReady, Status: metav1.ConditionTrue, Message: ConditionReasonIPPoolImportSucceededMessage},
+	ConditionReasonIPPoolImportFailed:            {Type: ConditionTypeReady, Status: metav1.ConditionFalse, Message: ConditionReasonIPPoolImportFailedMessage},
+	ConditionReasonIPPoolImportUnsafeChange:      {Type: ConditionTypeReady, Status: metav1.ConditionFalse, Message: ConditionReasonIPPoolImportUnsafeChangeMessage},
+	ConditionReasonIPPoolImportNameCollision:     {Type: ConditionTypeReady, Status: metav1.ConditionFalse, Message: ConditionReasonIPPoolImportNameCollisionMessage},
+	ConditionReasonIPPoolImportNoPrefixesMatched: {Type: ConditionTypePrefixesMatched, Status: metav1.ConditionFalse, Message: ConditionReasonIPPoolImportNoPrefixesMatchedMessage},
 }
 
 type ReasonWithMessage struct {
@@ -9,9 +9,25 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// IPPoolDeletionPolicy defines what happens to IPPools whose NetBox prefix is no longer selected.
+// +kubebuilder:validation:Enum=Retain;Delete
+type IPPoolDeletionPolicy string
+
+const (
+	// IPPoolDeletionPolicyRetain keeps orphaned IPPools and reports them in the status.
+	IPPoolDeletionPolicyRetain IPPoolDeletionPolicy = "Retain"
+	// IPPoolDeletionPolicyDelete deletes orphaned IPPools without allocated addresses.
+	IPPoolDeletionPolicyDelete IPPoolDeletionPolicy = "Delete"
+)
+
 // IPPoolImportSpec defines the desired state of IPPoolImport
 type IPPoolImportSpec struct {
 	IPPools []*IPPoolSelector `json:"ippools,omitempty"`
+	// DeletionPolicy defines what happens to IPPools generated by this IPPoolImport whose prefix was deleted in NetBox
+	// or is no longer selected. Delete prunes them unless addresses are still allocated from them, Retain keeps them.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=Retain
+	DeletionPolicy IPPoolDeletionPolicy `json:"deletionPolicy,omitempty"`
 }
 
 // IPPoolImportStatus defines the observed state of IPPoolImport.
@@ -21,6 +37,30 @@ type IPPoolImportStatus struct {
 	State       State               `json:"state"`
 	Conditions  *[]metav1.Condition `json:"conditions,omitempty"`
 	Description string              `json:"description,omitempty"`
+	// OrphanedPools lists the IPPools generated by this IPPoolImport whose prefix is no longer selected
+	// and which were not pruned.
+	OrphanedPools []OrphanedIPPool `json:"orphanedPools,omitempty"`
+	// ClaimedPrefixes lists the child prefixes allocated in NetBox for the prefix claims of this IPPoolImport.
+	ClaimedPrefixes []ClaimedPrefix `json:"claimedPrefixes,omitempty"`
+}
+
+// ClaimedPrefix is a child prefix allocated in NetBox for a prefix claim.
+type ClaimedPrefix struct {
+	// ID is the ID of the NetBox prefix.
+	ID int `json:"id"`
+	// Prefix is the prefix in CIDR notation.
+	Prefix string `json:"prefix"`
+}
+
+// OrphanedIPPool is an IPPool whose NetBox prefix is no longer selected.
+type OrphanedIPPool struct {
+	Name string `json:"name"`
+	// Namespace is the namespace of the IPPool, empty for GlobalInClusterIPPools.
+	Namespace string `json:"namespace,omitempty"`
+	// PrefixID is the ID of the NetBox prefix the IPPool was generated from.
+	PrefixID string `json:"prefixID,omitempty"`
+	// Message explains why the IPPool was not pruned, e.g. because addresses are still allocated from it.
+	Message string `json:"message"`
 }
 
 // +kubebuilder:object:root=true
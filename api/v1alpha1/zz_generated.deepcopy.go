@@ -9,6 +9,21 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClaimedPrefix) DeepCopyInto(out *ClaimedPrefix) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClaimedPrefix.
+func (in *ClaimedPrefix) DeepCopy() *ClaimedPrefix {
+	if in == nil {
+		return nil
+	}
+	out := new(ClaimedPrefix)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterImport) DeepCopyInto(out *ClusterImport) {
 	*out = *in
@@ -108,6 +123,13 @@ func (in *ClusterImportStatus) DeepCopyInto(out *ClusterImportStatus) {
 			}
 		}
 	}
+	if in.Devices != nil {
+		in, out := &in.Devices, &out.Devices
+		*out = make([]DeviceLifecycle, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterImportStatus.
@@ -128,6 +150,26 @@ func (in *ClusterSelector) DeepCopyInto(out *ClusterSelector) {
 		*out = new(corev1.LocalObjectReference)
 		**out = **in
 	}
+	if in.StatusMapping != nil {
+		in, out := &in.StatusMapping, &out.StatusMapping
+		*out = make([]DeviceStatusMapping, len(*in))
+		copy(*out, *in)
+	}
+	if in.LabelTemplate != nil {
+		in, out := &in.LabelTemplate, &out.LabelTemplate
+		*out = new(LabelTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ConfigContext != nil {
+		in, out := &in.ConfigContext, &out.ConfigContext
+		*out = new(ConfigContextExport)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GatewayRoles != nil {
+		in, out := &in.GatewayRoles, &out.GatewayRoles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterSelector.
@@ -140,6 +182,59 @@ func (in *ClusterSelector) DeepCopy() *ClusterSelector {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigContextExport) DeepCopyInto(out *ConfigContextExport) {
+	*out = *in
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigContextExport.
+func (in *ConfigContextExport) DeepCopy() *ConfigContextExport {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigContextExport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeviceLifecycle) DeepCopyInto(out *DeviceLifecycle) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceLifecycle.
+func (in *DeviceLifecycle) DeepCopy() *DeviceLifecycle {
+	if in == nil {
+		return nil
+	}
+	out := new(DeviceLifecycle)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeviceStatusMapping) DeepCopyInto(out *DeviceStatusMapping) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceStatusMapping.
+func (in *DeviceStatusMapping) DeepCopy() *DeviceStatusMapping {
+	if in == nil {
+		return nil
+	}
+	out := new(DeviceStatusMapping)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IPPoolImport) DeepCopyInto(out *IPPoolImport) {
 	*out = *in
@@ -239,6 +334,16 @@ func (in *IPPoolImportStatus) DeepCopyInto(out *IPPoolImportStatus) {
 			}
 		}
 	}
+	if in.OrphanedPools != nil {
+		in, out := &in.OrphanedPools, &out.OrphanedPools
+		*out = make([]OrphanedIPPool, len(*in))
+		copy(*out, *in)
+	}
+	if in.ClaimedPrefixes != nil {
+		in, out := &in.ClaimedPrefixes, &out.ClaimedPrefixes
+		*out = make([]ClaimedPrefix, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPPoolImportStatus.
@@ -254,11 +359,42 @@ func (in *IPPoolImportStatus) DeepCopy() *IPPoolImportStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IPPoolSelector) DeepCopyInto(out *IPPoolSelector) {
 	*out = *in
+	out.PrefixFilter = in.PrefixFilter
+	if in.Exclude != nil {
+		in, out := &in.Exclude, &out.Exclude
+		*out = make([]PrefixFilter, len(*in))
+		copy(*out, *in)
+	}
 	if in.ExcludeMask != nil {
 		in, out := &in.ExcludeMask, &out.ExcludeMask
 		*out = new(int)
 		**out = **in
 	}
+	if in.ExcludedAddresses != nil {
+		in, out := &in.ExcludedAddresses, &out.ExcludedAddresses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeLastNAddresses != nil {
+		in, out := &in.ExcludeLastNAddresses, &out.ExcludeLastNAddresses
+		*out = new(int)
+		**out = **in
+	}
+	if in.Claim != nil {
+		in, out := &in.Claim, &out.Claim
+		*out = new(PrefixClaim)
+		**out = **in
+	}
+	if in.ExcludeNetboxAddresses != nil {
+		in, out := &in.ExcludeNetboxAddresses, &out.ExcludeNetboxAddresses
+		*out = new(NetboxAddressExclusion)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GatewayRoles != nil {
+		in, out := &in.GatewayRoles, &out.GatewayRoles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPPoolSelector.
@@ -271,6 +407,108 @@ func (in *IPPoolSelector) DeepCopy() *IPPoolSelector {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LabelTemplate) DeepCopyInto(out *LabelTemplate) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Sanitize != nil {
+		in, out := &in.Sanitize, &out.Sanitize
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LabelTemplate.
+func (in *LabelTemplate) DeepCopy() *LabelTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(LabelTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetboxAddressExclusion) DeepCopyInto(out *NetboxAddressExclusion) {
+	*out = *in
+	if in.Statuses != nil {
+		in, out := &in.Statuses, &out.Statuses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IPRanges != nil {
+		in, out := &in.IPRanges, &out.IPRanges
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetboxAddressExclusion.
+func (in *NetboxAddressExclusion) DeepCopy() *NetboxAddressExclusion {
+	if in == nil {
+		return nil
+	}
+	out := new(NetboxAddressExclusion)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OrphanedIPPool) DeepCopyInto(out *OrphanedIPPool) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OrphanedIPPool.
+func (in *OrphanedIPPool) DeepCopy() *OrphanedIPPool {
+	if in == nil {
+		return nil
+	}
+	out := new(OrphanedIPPool)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrefixClaim) DeepCopyInto(out *PrefixClaim) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrefixClaim.
+func (in *PrefixClaim) DeepCopy() *PrefixClaim {
+	if in == nil {
+		return nil
+	}
+	out := new(PrefixClaim)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrefixFilter) DeepCopyInto(out *PrefixFilter) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrefixFilter.
+func (in *PrefixFilter) DeepCopy() *PrefixFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(PrefixFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ReasonWithMessage) DeepCopyInto(out *ReasonWithMessage) {
 	*out = *in
@@ -48,11 +48,15 @@ import (
 )
 
 const (
-	rateLimiterBurstDefault     = 200
-	rateLimiterFrequencyDefault = 30
-	failureBaseDelayDefault     = 1 * time.Second
-	failureMaxDelayDefault      = 1000 * time.Second
-	reconcileIntervalDefault    = 5 * time.Minute
+	rateLimiterBurstDefault      = 200
+	rateLimiterFrequencyDefault  = 30
+	failureBaseDelayDefault      = 1 * time.Second
+	failureMaxDelayDefault       = 1000 * time.Second
+	reconcileIntervalDefault     = 5 * time.Minute
+	ipBackfillBatchSizeDefault   = 100
+	ipBackfillConcurrencyDefault = 2
+	ipBackfillRateDefault        = 5
+	ipUpdateConcurrencyDefault   = 1
 )
 
 var (
@@ -65,6 +69,22 @@ type FlagVariables struct {
 	probeAddr               string
 	leaderElectionNamespace string
 	netboxURL               string
+	statusSyncRulesFile     string
+	deviceNamePattern       string
+	ipConflictPolicy        string
+	interfaceRulesFile      string
+	ipAddressTemplateFile   string
+	ipDriftPolicy           string
+	ipDeletionPolicy        string
+	ipResyncInterval        time.Duration
+	ipBackfill              bool
+	ipBackfillNamespace     string
+	ipBackfillBatchSize     int
+	ipBackfillConcurrency   int
+	ipBackfillRate          float64
+	ipUpdateConcurrency     int
+	ipUpdateNamespaces      string
+	ipUpdatePools           string
 
 	enableLeaderElection bool
 	secureMetrics        bool
@@ -164,8 +184,48 @@ func main() {
 	creds := credentials.NewDefaultCredentials(&credentials.Reader{})
 	setupLog.Info("argora", "version", bininfo.Version())
 
+	deviceNamePattern, err := controller.CompileDeviceNamePattern(flagVar.deviceNamePattern)
+	if err != nil {
+		setupLog.Error(err, "invalid device name pattern")
+		os.Exit(1)
+	}
+
+	ipConflictPolicy, err := controller.ParseIPConflictPolicy(flagVar.ipConflictPolicy)
+	if err != nil {
+		setupLog.Error(err, "invalid ip conflict policy")
+		os.Exit(1)
+	}
+
+	interfaceRules := controller.DefaultInterfaceRules()
+	if flagVar.interfaceRulesFile != "" {
+		if interfaceRules, err = controller.LoadInterfaceRules(&credentials.Reader{}, flagVar.interfaceRulesFile); err != nil {
+			setupLog.Error(err, "unable to load interface rules")
+			os.Exit(1)
+		}
+	}
+
+	ipDriftPolicy, err := controller.ParseIPDriftPolicy(flagVar.ipDriftPolicy)
+	if err != nil {
+		setupLog.Error(err, "invalid ip drift policy")
+		os.Exit(1)
+	}
+
+	ipDeletionPolicy, err := controller.ParseIPDeletionPolicy(flagVar.ipDeletionPolicy)
+	if err != nil {
+		setupLog.Error(err, "invalid ip deletion policy")
+		os.Exit(1)
+	}
+
+	var ipAddressTemplate *controller.IPAddressTemplate
+	if flagVar.ipAddressTemplateFile != "" {
+		if ipAddressTemplate, err = controller.LoadIPAddressTemplate(&credentials.Reader{}, flagVar.ipAddressTemplateFile); err != nil {
+			setupLog.Error(err, "unable to load ip address template")
+			os.Exit(1)
+		}
+	}
+
 	if flagVar.enableIronCore {
-		if err = controller.NewIronCoreReconciler(mgr, creds, status.NewClusterImportStatusHandler(mgr.GetClient()), netbox.NewNetbox(flagVar.netboxURL), flagVar.reconcileInterval).SetupWithManager(mgr, rateLimiter); err != nil {
+		if err = controller.NewIronCoreReconciler(mgr, creds, status.NewClusterImportStatusHandler(mgr.GetClient()), netbox.NewNetbox(flagVar.netboxURL), flagVar.reconcileInterval, deviceNamePattern).SetupWithManager(mgr, rateLimiter); err != nil {
 			setupLog.Error(err, "unable to create controller", "controller", "ironcore")
 			os.Exit(1)
 		}
@@ -181,7 +241,7 @@ func main() {
 			os.Exit(1)
 		}
 
-		if err = controller.NewMetal3Reconciler(mgr.GetClient(), mgr.GetScheme(), creds, netbox.NewNetbox(flagVar.netboxURL), flagVar.reconcileInterval).SetupWithManager(mgr, rateLimiter); err != nil {
+		if err = controller.NewMetal3Reconciler(mgr.GetClient(), mgr.GetAPIReader(), mgr.GetScheme(), mgr.GetEventRecorder("metal3"), creds, netbox.NewNetbox(flagVar.netboxURL), flagVar.reconcileInterval, deviceNamePattern).SetupWithManager(mgr, rateLimiter); err != nil {
 			setupLog.Error(err, "unable to create controller", "controller", "metal3")
 			os.Exit(1)
 		}
@@ -197,10 +257,43 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err = controller.NewIPUpdateReconciler(mgr, creds, netbox.NewNetbox(flagVar.netboxURL)).SetupWithManager(mgr); err != nil {
+	if flagVar.ipUpdateConcurrency < 1 {
+		setupLog.Error(errors.New("must be at least 1"), "invalid ip update max concurrent reconciles", "value", flagVar.ipUpdateConcurrency)
+		os.Exit(1)
+	}
+
+	// the IPUpdate controller reconciles concurrently and reloads its own credentials, not shared with the other
+	// controllers reloading them in parallel
+	ipUpdateCreds := credentials.NewDefaultCredentials(&credentials.Reader{})
+	ipUpdateReconciler := controller.NewIPUpdateReconciler(mgr, ipUpdateCreds, netbox.NewNetbox(flagVar.netboxURL), ipConflictPolicy, interfaceRules, ipAddressTemplate, flagVar.ipResyncInterval, ipDriftPolicy, ipDeletionPolicy)
+	if flagVar.ipBackfill {
+		ipUpdateReconciler.EnableBackfill(mgr, controller.IPBackfillOptions{
+			Namespace:   flagVar.ipBackfillNamespace,
+			BatchSize:   flagVar.ipBackfillBatchSize,
+			Concurrency: flagVar.ipBackfillConcurrency,
+			Rate:        flagVar.ipBackfillRate,
+		})
+	}
+	if err = ipUpdateReconciler.SetupWithManager(mgr, rateLimiter, flagVar.ipUpdateConcurrency,
+		controller.NewIPUpdateFilter(flagVar.ipUpdateNamespaces, flagVar.ipUpdatePools)); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ipupdate")
 		os.Exit(1)
 	}
+
+	if flagVar.statusSyncRulesFile != "" {
+		statusSyncRules, err := controller.LoadStatusSyncRules(&credentials.Reader{}, flagVar.statusSyncRulesFile)
+		if err != nil {
+			setupLog.Error(err, "unable to load status sync rules")
+			os.Exit(1)
+		}
+
+		// the StatusSync controller reconciles independently of the import controllers and reloads its own credentials
+		statusSyncCreds := credentials.NewDefaultCredentials(&credentials.Reader{})
+		if err = controller.NewStatusSyncReconciler(mgr, statusSyncCreds, netbox.NewNetbox(flagVar.netboxURL), statusSyncRules, flagVar.enableIronCore).SetupWithManager(mgr, rateLimiter); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "statussync")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -226,6 +319,22 @@ func getFlagVariables() *FlagVariables {
 	flag.StringVar(&flagVariables.probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.StringVar(&flagVariables.leaderElectionNamespace, "leader-elect-ns", "kube-system", "The namespace in which the leader election resource will be created. This is only used if --leader-elect is set to true. Defaults to kube-system.")
 	flag.StringVar(&flagVariables.netboxURL, "netbox-url", "https://netbox-url", "The URL of the NetBox instance to connect to. If not set, the default value will be used.")
+	flag.StringVar(&flagVariables.statusSyncRulesFile, "status-sync-rules", "", "Path to a JSON file with rules for reflecting BMC/Server or BareMetalHost state into NetBox. If not set, the status sync controller is disabled.")
+	flag.StringVar(&flagVariables.deviceNamePattern, "device-name-pattern", controller.DefaultDeviceNamePattern, "Regular expression with named capture groups used to parse device names. Every named group becomes a label, devices not matching the pattern are skipped. Can be overridden per cluster selector.")
+	flag.StringVar(&flagVariables.interfaceRulesFile, "interface-rules", "", "Path to a JSON file with named rules selecting the NetBox interface of IP addresses, referenced by the netbox.argora.cloud.sap/interface-rule annotation of IPAddressClaims or label of IP pools. If not set, the LAG interface with the highest number is selected.")
+	flag.StringVar(&flagVariables.ipAddressTemplateFile, "ip-address-template", "", "Path to a JSON file with templates for the DNS name, status, role, description, tags and custom fields of NetBox IP addresses managed by the IPUpdate controller. If not set, only address, tenant, VRF and interface are managed.")
+	flag.StringVar(&flagVariables.ipConflictPolicy, "ip-conflict-policy", string(controller.IPConflictPolicyReport), "Policy for IP addresses assigned to another interface or device in NetBox: Report, Takeover or TakeoverIfStale. Can be overridden per IPAddress or IP pool with the netbox.argora.cloud.sap/conflict-policy annotation.")
+	flag.StringVar(&flagVariables.ipDriftPolicy, "ip-drift-policy", string(controller.IPDriftPolicyReport), "Policy for IP addresses changed or deleted in NetBox after they were synced by the IPUpdate controller: Repair or Report.")
+	flag.StringVar(&flagVariables.ipDeletionPolicy, "ip-deletion-policy", string(controller.IPDeletionPolicyDelete), "Policy for the NetBox IP address of a deleted IPAddress: Delete, MarkDeprecated or Unassign. Can be overridden per IPAddress or IP pool with the netbox.argora.cloud.sap/deletion-policy annotation.")
+	flag.BoolVar(&flagVariables.ipBackfill, "ip-backfill", false, "Sync IP addresses not yet synced by the IPUpdate controller in rate limited batches, instead of all at once. The progress is kept in the "+controller.IPBackfillConfigMapName+" ConfigMap to resume after a restart, delete it to run the backfill again.")
+	flag.StringVar(&flagVariables.ipBackfillNamespace, "ip-backfill-ns", "kube-system", "The namespace of the ConfigMap holding the progress of the IP address backfill.")
+	flag.IntVar(&flagVariables.ipBackfillBatchSize, "ip-backfill-batch-size", ipBackfillBatchSizeDefault, "Number of IP addresses processed by the backfill before its progress is saved.")
+	flag.IntVar(&flagVariables.ipBackfillConcurrency, "ip-backfill-concurrency", ipBackfillConcurrencyDefault, "Number of IP addresses processed by the backfill in parallel.")
+	flag.Float64Var(&flagVariables.ipBackfillRate, "ip-backfill-rate", ipBackfillRateDefault, "Number of IP addresses processed by the backfill per second.")
+	flag.IntVar(&flagVariables.ipUpdateConcurrency, "ip-update-max-concurrent-reconciles", ipUpdateConcurrencyDefault, "Maximum number of IP addresses reconciled by the IPUpdate controller in parallel, reloads of the credentials and NetBox clients are serialized.")
+	flag.StringVar(&flagVariables.ipUpdateNamespaces, "ip-update-namespaces", "", "Comma separated list of namespaces of the IP addresses reconciled by the IPUpdate controller. If not set, IP addresses of all namespaces are reconciled.")
+	flag.StringVar(&flagVariables.ipUpdatePools, "ip-update-pools", "", "Comma separated list of names of the IP pools the IP addresses reconciled by the IPUpdate controller are allocated from. If not set, IP addresses of all pools are reconciled.")
+	flag.DurationVar(&flagVariables.ipResyncInterval, "ip-resync-interval", 0, "Interval to resync IP addresses synced by the IPUpdate controller to detect drift in NetBox. If not set, the resync is disabled.")
 
 	flag.BoolVar(&flagVariables.enableLeaderElection, "leader-elect", false, "Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
 	flag.BoolVar(&flagVariables.secureMetrics, "metrics-secure", true, "If true (default), the metrics endpoint is served securely via HTTPS. Use --metrics-secure=false to use HTTP instead.")
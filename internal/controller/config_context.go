@@ -0,0 +1,193 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/jsonpath"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	argorav1alpha1 "github.com/sapcc/argora/api/v1alpha1"
+)
+
+const (
+	configContextAnnotationPrefix = "config-context.argora.cloud.sap/"
+	configContextConfigMapPrefix  = "config-context-"
+)
+
+// renderConfigContext selects the exported keys of the config context. String values are exported as is,
+// all other values are JSON encoded.
+func renderConfigContext(configContext map[string]any, export *argorav1alpha1.ConfigContextExport) (map[string]string, error) {
+	data := make(map[string]string)
+
+	if len(export.Keys) == 0 {
+		for key, value := range configContext {
+			encoded, err := encodeConfigContextValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("unable to encode config context key %s: %w", key, err)
+			}
+			data[key] = encoded
+		}
+		return data, nil
+	}
+
+	for key, path := range export.Keys {
+		j := jsonpath.New(key).AllowMissingKeys(true)
+		if err := j.Parse(path); err != nil {
+			return nil, fmt.Errorf("unable to parse JSONPath %s for config context key %s: %w", path, key, err)
+		}
+
+		results, err := j.FindResults(configContext)
+		if err != nil {
+			return nil, fmt.Errorf("unable to evaluate JSONPath %s for config context key %s: %w", path, key, err)
+		}
+
+		var values []any
+		for _, result := range results {
+			for _, value := range result {
+				values = append(values, value.Interface())
+			}
+		}
+
+		if len(values) == 0 {
+			continue
+		}
+
+		var value any = values
+		if len(values) == 1 {
+			value = values[0]
+		}
+
+		encoded, err := encodeConfigContextValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("unable to encode config context key %s: %w", key, err)
+		}
+		data[key] = encoded
+	}
+
+	return data, nil
+}
+
+func encodeConfigContextValue(value any) (string, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// renderConfigContextAnnotations converts the rendered config context into annotations.
+func renderConfigContextAnnotations(data map[string]string) (map[string]string, error) {
+	annotations := make(map[string]string, len(data))
+	for key, value := range data {
+		annotation := configContextAnnotationPrefix + key
+		if errs := validation.IsQualifiedName(annotation); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid config context annotation %s: %s", annotation, strings.Join(errs, ", "))
+		}
+		annotations[annotation] = value
+	}
+	return annotations, nil
+}
+
+// setConfigContextAnnotations replaces the config context annotations of the object. Nil annotations, i.e. if the
+// config context is not exported to annotations, remove all config context annotations of the object.
+func setConfigContextAnnotations(obj client.Object, configContextAnnotations map[string]string) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		if len(configContextAnnotations) == 0 {
+			return
+		}
+		annotations = make(map[string]string)
+	}
+	maps.DeleteFunc(annotations, func(key, _ string) bool {
+		return strings.HasPrefix(key, configContextAnnotationPrefix)
+	})
+	maps.Copy(annotations, configContextAnnotations)
+	obj.SetAnnotations(annotations)
+}
+
+// reconcileConfigContextConfigMap creates or updates the config context ConfigMap of a device, owned by the given object.
+func reconcileConfigContextConfigMap(ctx context.Context, k8sClient client.Client, apiReader client.Reader, scheme *runtime.Scheme, owner client.Object, namespace, deviceName string, labels, data map[string]string) error {
+	logger := log.FromContext(ctx)
+
+	for _, key := range slices.Sorted(maps.Keys(data)) {
+		if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+			return fmt.Errorf("invalid config context key %s: %s", key, strings.Join(errs, ", "))
+		}
+	}
+
+	// the ConfigMap is read through the API reader, to not cache all ConfigMaps of the cluster
+	key := client.ObjectKey{Name: configContextConfigMapPrefix + deviceName, Namespace: namespace}
+	configMap := &corev1.ConfigMap{}
+	err := apiReader.Get(ctx, key, configMap)
+	if client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("unable to get config context config map %s: %w", key.Name, err)
+	}
+	create := apierrors.IsNotFound(err)
+	if create {
+		configMap = &corev1.ConfigMap{ObjectMeta: ctrl.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}
+	}
+
+	base := configMap.DeepCopy()
+	configMap.Labels = labels
+	configMap.Data = data
+	if err := controllerutil.SetControllerReference(owner, configMap, scheme); err != nil {
+		return err
+	}
+
+	switch {
+	case create:
+		if err := k8sClient.Create(ctx, configMap); err != nil {
+			return fmt.Errorf("unable to create config context config map %s: %w", configMap.Name, err)
+		}
+		logger.Info("created config context ConfigMap", "name", configMap.Name)
+	case !equality.Semantic.DeepEqual(base, configMap):
+		if err := k8sClient.Update(ctx, configMap); err != nil {
+			return fmt.Errorf("unable to update config context config map %s: %w", configMap.Name, err)
+		}
+		logger.Info("config context ConfigMap updated", "name", configMap.Name)
+	}
+
+	return nil
+}
+
+// deleteConfigContextConfigMap deletes the config context ConfigMap of a device owned by the given object, if the
+// config context is no longer exported into a ConfigMap.
+func deleteConfigContextConfigMap(ctx context.Context, k8sClient client.Client, apiReader client.Reader, owner client.Object, namespace, deviceName string) error {
+	logger := log.FromContext(ctx)
+
+	configMap := &corev1.ConfigMap{}
+	if err := apiReader.Get(ctx, client.ObjectKey{Name: configContextConfigMapPrefix + deviceName, Namespace: namespace}, configMap); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !metav1.IsControlledBy(configMap, owner) {
+		return nil
+	}
+
+	if err := k8sClient.Delete(ctx, configMap); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("unable to delete config context config map %s: %w", configMap.Name, err)
+	}
+
+	logger.Info("deleted config context ConfigMap", "name", configMap.Name)
+	return nil
+}
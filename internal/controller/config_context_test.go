@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	argorav1alpha1 "github.com/sapcc/argora/api/v1alpha1"
+)
+
+var _ = Describe("Config Context", func() {
+	ctx := context.Background()
+
+	configContext := map[string]any{
+		"kernel": map[string]any{
+			"args": "quiet splash",
+		},
+		"bios": map[string]any{
+			"settings": []any{"SriovGlobalEnable", "ProcVirtualization"},
+		},
+		"ntp": "ntp.example.com",
+	}
+
+	It("should export every top-level key if no keys are selected", func() {
+		data, err := renderConfigContext(configContext, &argorav1alpha1.ConfigContextExport{})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(map[string]string{
+			"kernel": `{"args":"quiet splash"}`,
+			"bios":   `{"settings":["SriovGlobalEnable","ProcVirtualization"]}`,
+			"ntp":    "ntp.example.com",
+		}))
+	})
+
+	It("should export the keys selected by JSONPath", func() {
+		data, err := renderConfigContext(configContext, &argorav1alpha1.ConfigContextExport{
+			Keys: map[string]string{
+				"kernel-args":   "{.kernel.args}",
+				"bios-settings": "{.bios.settings}",
+				"first-setting": "{.bios.settings[0]}",
+				"missing":       "{.grub.timeout}",
+			},
+		})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(map[string]string{
+			"kernel-args":   "quiet splash",
+			"bios-settings": `["SriovGlobalEnable","ProcVirtualization"]`,
+			"first-setting": "SriovGlobalEnable",
+		}))
+	})
+
+	It("should fail on an invalid JSONPath", func() {
+		_, err := renderConfigContext(configContext, &argorav1alpha1.ConfigContextExport{
+			Keys: map[string]string{"kernel-args": "{.kernel.args"},
+		})
+
+		Expect(err).To(MatchError(ContainSubstring("unable to parse JSONPath {.kernel.args for config context key kernel-args")))
+	})
+
+	It("should render annotations", func() {
+		annotations, err := renderConfigContextAnnotations(map[string]string{"kernel-args": "quiet splash"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(annotations).To(Equal(map[string]string{"config-context.argora.cloud.sap/kernel-args": "quiet splash"}))
+
+		_, err = renderConfigContextAnnotations(map[string]string{"kernel args": "quiet splash"})
+		Expect(err).To(MatchError(ContainSubstring("invalid config context annotation config-context.argora.cloud.sap/kernel args")))
+	})
+
+	It("should replace config context annotations and keep all others", func() {
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					"config-context.argora.cloud.sap/stale": "value",
+					"example.com/other":                     "value",
+				},
+			},
+		}
+
+		setConfigContextAnnotations(configMap, map[string]string{"config-context.argora.cloud.sap/kernel-args": "quiet"})
+		Expect(configMap.Annotations).To(Equal(map[string]string{
+			"config-context.argora.cloud.sap/kernel-args": "quiet",
+			"example.com/other":                           "value",
+		}))
+
+		setConfigContextAnnotations(configMap, nil)
+		Expect(configMap.Annotations).To(Equal(map[string]string{"example.com/other": "value"}))
+	})
+
+	It("should only delete the config context ConfigMap of the owner", func() {
+		owner := &argorav1alpha1.ClusterImport{ObjectMeta: metav1.ObjectMeta{Name: "import", Namespace: "default", UID: "owner-uid"}}
+		other := &argorav1alpha1.ClusterImport{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default", UID: "other-uid"}}
+		k8sClient := createFakeClient(owner)
+		Expect(reconcileConfigContextConfigMap(ctx, k8sClient, k8sClient, k8sClient.Scheme(), owner, "default", "device1", nil, map[string]string{"ntp": "ntp.example.com"})).To(Succeed())
+
+		Expect(deleteConfigContextConfigMap(ctx, k8sClient, k8sClient, other, "default", "device1")).To(Succeed())
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Name: "config-context-device1", Namespace: "default"}, &corev1.ConfigMap{})).To(Succeed())
+
+		Expect(deleteConfigContextConfigMap(ctx, k8sClient, k8sClient, owner, "default", "device1")).To(Succeed())
+		err := k8sClient.Get(ctx, client.ObjectKey{Name: "config-context-device1", Namespace: "default"}, &corev1.ConfigMap{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		Expect(deleteConfigContextConfigMap(ctx, k8sClient, k8sClient, owner, "default", "device1")).To(Succeed())
+	})
+
+	It("should read the config context ConfigMap through the API reader", func() {
+		owner := &argorav1alpha1.ClusterImport{ObjectMeta: metav1.ObjectMeta{Name: "import", Namespace: "default", UID: "owner-uid"}}
+		apiReader := createFakeClient(owner)
+		k8sClient := interceptor.NewClient(apiReader.(client.WithWatch), interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				if _, ok := obj.(*corev1.ConfigMap); ok {
+					return errors.New("config maps are not cached")
+				}
+				return c.Get(ctx, key, obj, opts...)
+			},
+		})
+
+		data := map[string]string{"ntp": "ntp.example.com"}
+		Expect(reconcileConfigContextConfigMap(ctx, k8sClient, apiReader, apiReader.Scheme(), owner, "default", "device1", nil, data)).To(Succeed())
+		data["ntp"] = "ntp2.example.com"
+		Expect(reconcileConfigContextConfigMap(ctx, k8sClient, apiReader, apiReader.Scheme(), owner, "default", "device1", nil, data)).To(Succeed())
+
+		configMap := &corev1.ConfigMap{}
+		Expect(apiReader.Get(ctx, client.ObjectKey{Name: "config-context-device1", Namespace: "default"}, configMap)).To(Succeed())
+		Expect(configMap.Data).To(Equal(map[string]string{"ntp": "ntp2.example.com"}))
+
+		Expect(deleteConfigContextConfigMap(ctx, k8sClient, apiReader, owner, "default", "device1")).To(Succeed())
+		err := apiReader.Get(ctx, client.ObjectKey{Name: "config-context-device1", Namespace: "default"}, configMap)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
@@ -7,6 +7,10 @@ const (
 	deviceStatusActive = "active"
 	deviceStatusStaged = "staged"
 
+	deviceStatusOffline         = "offline"
+	deviceStatusFailed          = "failed"
+	deviceStatusDecommissioning = "decommissioning"
+
 	interfaceTypeLag = "lag"
 
 	rootHintBOSS = "BOSS"
@@ -14,4 +18,10 @@ const (
 	remoteboardInterfaceName = "remoteboard"
 
 	annotationValueTrue = "true"
+
+	labelDeviceKey     = "argora.cloud.sap/device"
+	labelDeviceNameKey = "kubernetes.metal.cloud.sap/name"
+
+	// serverBMCRefField is the field index of the Servers by the name of their BMC.
+	serverBMCRefField = "spec.bmcRef.name"
 )
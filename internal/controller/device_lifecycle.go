@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"slices"
+
+	"github.com/sapcc/go-netbox-go/models"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	argorav1alpha1 "github.com/sapcc/argora/api/v1alpha1"
+)
+
+var defaultDeviceStatusMapping = map[string]argorav1alpha1.DeviceStatusAction{
+	deviceStatusActive:          argorav1alpha1.DeviceStatusActionImport,
+	deviceStatusOffline:         argorav1alpha1.DeviceStatusActionMaintenance,
+	deviceStatusFailed:          argorav1alpha1.DeviceStatusActionMaintenance,
+	deviceStatusDecommissioning: argorav1alpha1.DeviceStatusActionDecommission,
+}
+
+// deviceStatusAction resolves the lifecycle action for a NetBox device status. Mappings configured
+// on the cluster selector take precedence over the defaults; unknown statuses are skipped.
+func deviceStatusAction(clusterSelector *argorav1alpha1.ClusterSelector, deviceStatus string) argorav1alpha1.DeviceStatusAction {
+	for _, mapping := range clusterSelector.StatusMapping {
+		if mapping.Status == deviceStatus {
+			return mapping.Action
+		}
+	}
+
+	if action, ok := defaultDeviceStatusMapping[deviceStatus]; ok {
+		return action
+	}
+
+	return argorav1alpha1.DeviceStatusActionSkip
+}
+
+// recordDeviceLifecycle stores the NetBox status, action and message of the device in the ClusterImport status.
+// It returns true if this is a transition, i.e. the device was not recorded before or its status, action or message changed.
+func recordDeviceLifecycle(clusterImportCR *argorav1alpha1.ClusterImport, device *models.Device, action argorav1alpha1.DeviceStatusAction, message string) bool {
+	for i := range clusterImportCR.Status.Devices {
+		lifecycle := &clusterImportCR.Status.Devices[i]
+		if lifecycle.Name != device.Name {
+			continue
+		}
+
+		if lifecycle.NetboxStatus == device.Status.Value && lifecycle.Action == action && lifecycle.Message == message {
+			return false
+		}
+
+		lifecycle.NetboxStatus = device.Status.Value
+		lifecycle.Action = action
+		lifecycle.Message = message
+		lifecycle.LastTransitionTime = metav1.Now()
+		return true
+	}
+
+	clusterImportCR.Status.Devices = append(clusterImportCR.Status.Devices, argorav1alpha1.DeviceLifecycle{
+		Name:               device.Name,
+		NetboxStatus:       device.Status.Value,
+		Action:             action,
+		LastTransitionTime: metav1.Now(),
+		Message:            message,
+	})
+	return true
+}
+
+// pruneDeviceLifecycles removes the devices no longer selected from the ClusterImport status.
+func pruneDeviceLifecycles(clusterImportCR *argorav1alpha1.ClusterImport, selected map[string]bool) {
+	clusterImportCR.Status.Devices = slices.DeleteFunc(clusterImportCR.Status.Devices, func(lifecycle argorav1alpha1.DeviceLifecycle) bool {
+		return !selected[lifecycle.Name]
+	})
+}
@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// DefaultDeviceNamePattern splits device names like node001-bb123 into node name and building block.
+	DefaultDeviceNamePattern = `^(?P<nodename>[^-]+)-(?P<bb>[^-]+)$`
+
+	deviceNameGroupNodeName = "nodename"
+	deviceNameGroupBB       = "bb"
+
+	deviceNameLabelPrefix = "kubernetes.metal.cloud.sap/"
+)
+
+var defaultDeviceNamePattern = regexp.MustCompile(DefaultDeviceNamePattern)
+
+// reservedDeviceNameGroups are the capture group names whose labels are set from the NetBox topology of the device.
+// The nodename and bb groups are not reserved, their labels are set from the groups.
+var reservedDeviceNameGroups = []string{"location", "rack", "tenant", "manufacturer", "serial", "cluster", "name", "type", "role", "platform"}
+
+// errDeviceNameMismatch is returned if a device name does not match the name pattern.
+var errDeviceNameMismatch = errors.New("device name does not match name pattern")
+
+// compiledDeviceNamePatterns caches the compiled override patterns of the cluster selectors, which are resolved for
+// every device.
+var compiledDeviceNamePatterns sync.Map
+
+// CompileDeviceNamePattern compiles a device name pattern. The pattern must contain at least one named
+// capture group, every group name must form a valid label key and must not collide with a topology label.
+func CompileDeviceNamePattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile device name pattern: %w", err)
+	}
+
+	named := false
+	for _, name := range re.SubexpNames() {
+		if name == "" {
+			continue
+		}
+		if errs := validation.IsQualifiedName(deviceNameLabelPrefix + name); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid capture group name %s in device name pattern: %s", name, strings.Join(errs, ", "))
+		}
+		if slices.Contains(reservedDeviceNameGroups, name) {
+			return nil, fmt.Errorf("capture group name %s in device name pattern collides with the topology label %s", name, deviceNameLabelPrefix+name)
+		}
+		named = true
+	}
+
+	if !named {
+		return nil, fmt.Errorf("device name pattern %s has no named capture groups", pattern)
+	}
+
+	return re, nil
+}
+
+// resolveDeviceNamePattern returns the compiled override pattern if set, otherwise the global pattern
+// or the default pattern if no global pattern is configured.
+func resolveDeviceNamePattern(global *regexp.Regexp, override string) (*regexp.Regexp, error) {
+	if override != "" {
+		if pattern, ok := compiledDeviceNamePatterns.Load(override); ok {
+			return pattern.(*regexp.Regexp), nil
+		}
+
+		pattern, err := CompileDeviceNamePattern(override)
+		if err != nil {
+			return nil, err
+		}
+		compiledDeviceNamePatterns.Store(override, pattern)
+		return pattern, nil
+	}
+	if global != nil {
+		return global, nil
+	}
+	return defaultDeviceNamePattern, nil
+}
+
+// parseDeviceName matches the device name against the pattern and returns the values of the named capture groups.
+func parseDeviceName(pattern *regexp.Regexp, deviceName string) (map[string]string, error) {
+	match := pattern.FindStringSubmatch(deviceName)
+	if match == nil {
+		return nil, fmt.Errorf("%w %s: %s", errDeviceNameMismatch, pattern, deviceName)
+	}
+
+	nameParts := make(map[string]string)
+	for i, name := range pattern.SubexpNames() {
+		if name != "" {
+			nameParts[name] = match[i]
+		}
+	}
+
+	return nameParts, nil
+}
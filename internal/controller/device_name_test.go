@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Device Name", func() {
+	It("should parse device names with the default pattern", func() {
+		nameParts, err := parseDeviceName(defaultDeviceNamePattern, "node001-bb123")
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nameParts).To(Equal(map[string]string{"nodename": "node001", "bb": "bb123"}))
+	})
+
+	It("should parse device names with a custom pattern", func() {
+		pattern, err := CompileDeviceNamePattern(`^(?P<nodename>[^-]+)-(?P<bb>[^-]+)-(?P<row>r[0-9]+)$`)
+		Expect(err).ToNot(HaveOccurred())
+
+		nameParts, err := parseDeviceName(pattern, "node001-bb123-r2")
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nameParts).To(Equal(map[string]string{"nodename": "node001", "bb": "bb123", "row": "r2"}))
+	})
+
+	It("should fail if the device name does not match", func() {
+		_, err := parseDeviceName(defaultDeviceNamePattern, "node001-bb123-r2")
+
+		Expect(err).To(MatchError(errDeviceNameMismatch))
+	})
+
+	It("should refuse invalid patterns", func() {
+		_, err := CompileDeviceNamePattern(`^(?P<nodename>[^-]+`)
+		Expect(err).To(MatchError(ContainSubstring("unable to compile device name pattern")))
+
+		_, err = CompileDeviceNamePattern(`^([^-]+)-([^-]+)$`)
+		Expect(err).To(MatchError("device name pattern ^([^-]+)-([^-]+)$ has no named capture groups"))
+
+		_, err = CompileDeviceNamePattern(`^(?P<_node>[^-]+)$`)
+		Expect(err).To(MatchError(ContainSubstring("invalid capture group name _node in device name pattern")))
+
+		_, err = CompileDeviceNamePattern(`^(?P<nodename>[^-]+)-(?P<rack>[^-]+)$`)
+		Expect(err).To(MatchError("capture group name rack in device name pattern collides with the topology label kubernetes.metal.cloud.sap/rack"))
+	})
+
+	It("should prefer the override pattern", func() {
+		pattern, err := resolveDeviceNamePattern(nil, "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pattern).To(BeIdenticalTo(defaultDeviceNamePattern))
+
+		pattern, err = resolveDeviceNamePattern(defaultDeviceNamePattern, `^(?P<host>.+)$`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pattern.String()).To(Equal(`^(?P<host>.+)$`))
+	})
+
+	It("should compile an override pattern once", func() {
+		pattern, err := resolveDeviceNamePattern(nil, `^(?P<node>.+)$`)
+		Expect(err).ToNot(HaveOccurred())
+
+		cached, err := resolveDeviceNamePattern(nil, `^(?P<node>.+)$`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cached).To(BeIdenticalTo(pattern))
+
+		_, err = resolveDeviceNamePattern(nil, `^(?P<node>.+$`)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,166 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/sapcc/go-netbox-go/models"
+
+	"github.com/sapcc/argora/internal/netbox/ipam"
+)
+
+const (
+	// gatewayCustomField is the prefix custom field holding the gateway address.
+	gatewayCustomField = "gateway"
+	// dnsServersCustomField is the prefix custom field holding the DNS server addresses.
+	dnsServersCustomField = "dns_servers"
+	// gatewayTag marks the gateway IP address inside a prefix.
+	gatewayTag = "gateway"
+	// dnsTag marks the DNS server IP addresses inside a prefix.
+	dnsTag = "dns"
+)
+
+// resolveGateway resolves the gateway of the prefix from NetBox. In order of precedence the gateway is the address
+// in the gateway custom field of the prefix, the IP address in the prefix tagged gateway, or, if roles are given,
+// the first IP address in the prefix with one of the roles, e.g. anycast. NetBox is only queried for the IP addresses
+// in question if the custom field is not set. The returned address is invalid if NetBox documents no gateway for the prefix.
+func resolveGateway(ctx context.Context, ipamClient ipam.IPAM, prefix *models.Prefix, roles []string) (netip.Addr, error) {
+	prefixParsed, err := netip.ParsePrefix(prefix.Prefix)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	var gateway netip.Addr
+	if value, ok := customFieldValue(prefix.CustomFields, gatewayCustomField); ok {
+		gateway, err = parseNetboxAddressValue(value)
+		if err != nil {
+			return netip.Addr{}, fmt.Errorf("invalid %s custom field of prefix %s: %w", gatewayCustomField, prefix.Prefix, err)
+		}
+	} else {
+		gateway, err = findNetboxAddress(ctx, ipamClient, prefix, ipam.IPAddressesWithTag(gatewayTag))
+		if err == nil && !gateway.IsValid() && len(roles) > 0 {
+			gateway, err = findNetboxAddress(ctx, ipamClient, prefix, ipam.IPAddressesWithRole(roles...))
+		}
+		if err != nil {
+			return netip.Addr{}, err
+		}
+	}
+
+	if gateway.IsValid() && !prefixParsed.Contains(gateway) {
+		return netip.Addr{}, fmt.Errorf("gateway %s is not in prefix %s", gateway, prefix.Prefix)
+	}
+
+	return gateway, nil
+}
+
+// resolveDNSServers resolves the DNS servers of the prefix from NetBox, i.e. the addresses in the dns_servers
+// custom field of the prefix or, if not set, the IP addresses in the prefix tagged dns.
+func resolveDNSServers(ctx context.Context, ipamClient ipam.IPAM, prefix *models.Prefix) ([]netip.Addr, error) {
+	var servers []netip.Addr
+
+	if value, ok := customFieldValue(prefix.CustomFields, dnsServersCustomField); ok {
+		var values []any
+		switch v := value.(type) {
+		case []any:
+			values = v
+		case string:
+			for s := range strings.SplitSeq(v, ",") {
+				values = append(values, strings.TrimSpace(s))
+			}
+		default:
+			values = []any{v}
+		}
+
+		for _, v := range values {
+			server, err := parseNetboxAddressValue(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s custom field of prefix %s: %w", dnsServersCustomField, prefix.Prefix, err)
+			}
+			servers = append(servers, server)
+		}
+		return servers, nil
+	}
+
+	addresses, err := netboxAddressesInPrefix(ctx, ipamClient, prefix, ipam.IPAddressesWithTag(dnsTag))
+	if err != nil {
+		return nil, err
+	}
+	for _, address := range addresses {
+		server, err := parseNetboxAddress(address.Address)
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, server)
+	}
+
+	return servers, nil
+}
+
+// findNetboxAddress returns the first IP address in the prefix matching the filter, or an invalid address if none does.
+func findNetboxAddress(ctx context.Context, ipamClient ipam.IPAM, prefix *models.Prefix, filter ipam.ListIPAddressesRequestOption) (netip.Addr, error) {
+	addresses, err := netboxAddressesInPrefix(ctx, ipamClient, prefix, filter)
+	if err != nil || len(addresses) == 0 {
+		return netip.Addr{}, err
+	}
+	return parseNetboxAddress(addresses[0].Address)
+}
+
+// netboxAddressesInPrefix returns the IP addresses in the prefix and its VRF matching the filter, which is applied by
+// NetBox. IP addresses of a prefix without VRF are looked up in the global VRF only.
+func netboxAddressesInPrefix(ctx context.Context, ipamClient ipam.IPAM, prefix *models.Prefix, filter ipam.ListIPAddressesRequestOption) ([]models.IPAddress, error) {
+	addresses, err := ipamClient.GetIPAddresses(ctx, ipam.IPAddressesWithParent(prefix.Prefix), ipam.IPAddressesWithVrfID(prefix.Vrf.ID), filter)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get IP addresses in prefix %s: %w", prefix.Prefix, err)
+	}
+	return addresses, nil
+}
+
+func hasNetboxTag(tags []models.NestedTag, slug string) bool {
+	return slices.ContainsFunc(tags, func(tag models.NestedTag) bool {
+		return tag.Slug == slug
+	})
+}
+
+// customFieldValue returns the non-empty value of the custom field with the given name.
+func customFieldValue(customFields any, name string) (any, bool) {
+	fields, ok := customFields.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	value, ok := fields[name]
+	if !ok || value == nil || value == "" {
+		return nil, false
+	}
+	return value, true
+}
+
+// parseNetboxAddressValue parses a custom field value, which is either an address or a referenced IP address object.
+func parseNetboxAddressValue(value any) (netip.Addr, error) {
+	switch v := value.(type) {
+	case string:
+		return parseNetboxAddress(v)
+	case map[string]any:
+		if address, ok := v["address"].(string); ok {
+			return parseNetboxAddress(address)
+		}
+	}
+	return netip.Addr{}, fmt.Errorf("unexpected address value %v", value)
+}
+
+// parseNetboxAddress parses an address with or without prefix length, e.g. 10.0.0.1/24 or 10.0.0.1.
+func parseNetboxAddress(address string) (netip.Addr, error) {
+	if strings.Contains(address, "/") {
+		prefix, err := netip.ParsePrefix(address)
+		if err != nil {
+			return netip.Addr{}, err
+		}
+		return prefix.Addr(), nil
+	}
+	return netip.ParseAddr(address)
+}
@@ -0,0 +1,119 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sapcc/go-netbox-go/models"
+
+	"github.com/sapcc/argora/internal/controller/mock"
+	"github.com/sapcc/argora/internal/netbox/ipam"
+)
+
+var _ = Describe("Gateway", func() {
+	ctx := context.Background()
+
+	addresses := []models.IPAddress{
+		{NestedIPAddress: models.NestedIPAddress{Address: "10.0.0.5/24"}, Role: models.IpamRole{Value: "vip"}},
+		{NestedIPAddress: models.NestedIPAddress{Address: "10.0.0.6/24"}, Tags: []models.NestedTag{{Slug: "gateway"}}},
+		{NestedIPAddress: models.NestedIPAddress{Address: "10.0.0.53/24"}, Tags: []models.NestedTag{{Slug: "dns"}}},
+		{NestedIPAddress: models.NestedIPAddress{Address: "10.0.0.54/24"}, Tags: []models.NestedTag{{Slug: "dns"}}},
+	}
+
+	// ipamMock filters the addresses by the tag and roles of the request, like NetBox does.
+	ipamMock := func(addresses []models.IPAddress) *mock.IPAMMock {
+		return &mock.IPAMMock{
+			GetIPAddressesFunc: func(_ context.Context, opts ...ipam.ListIPAddressesRequestOption) ([]models.IPAddress, error) {
+				query := ipam.NewListIPAddressesRequest(opts...).BuildQuery()
+				Expect(query.Get("parent")).To(Equal("10.0.0.0/24"))
+				Expect(query.Get("vrf_id")).To(Equal("null"))
+
+				var matching []models.IPAddress
+				for _, address := range addresses {
+					if tag := query.Get("tag"); tag != "" && !hasNetboxTag(address.Tags, tag) {
+						continue
+					}
+					if roles := query["role"]; len(roles) > 0 && !slices.Contains(roles, address.Role.Value) {
+						continue
+					}
+					matching = append(matching, address)
+				}
+				return matching, nil
+			},
+		}
+	}
+
+	DescribeTable("should resolve the gateway of a prefix",
+		func(customFields any, addresses []models.IPAddress, roles []string, expected string) {
+			gateway, err := resolveGateway(ctx, ipamMock(addresses), &models.Prefix{Prefix: "10.0.0.0/24", CustomFields: customFields}, roles)
+
+			Expect(err).ToNot(HaveOccurred())
+			if expected == "" {
+				Expect(gateway.IsValid()).To(BeFalse())
+			} else {
+				Expect(gateway).To(Equal(netip.MustParseAddr(expected)))
+			}
+		},
+		Entry("from the custom field", map[string]any{"gateway": "10.0.0.254"}, addresses, nil, "10.0.0.254"),
+		Entry("from the referenced IP address custom field", map[string]any{"gateway": map[string]any{"id": 1, "address": "10.0.0.253/24"}}, addresses, nil, "10.0.0.253"),
+		Entry("from the tagged IP address", map[string]any{"gateway": nil}, addresses, []string{"vip"}, "10.0.0.6"),
+		Entry("from the vip IP address", nil, addresses[:1], []string{"anycast", "vip"}, "10.0.0.5"),
+		Entry("not from the vip IP address without gateway roles", nil, addresses[:1], nil, ""),
+		Entry("not at all", nil, addresses[2:], []string{"vip"}, ""),
+	)
+
+	It("should not query NetBox for IP addresses if the custom field is set", func() {
+		ipamClient := ipamMock(addresses)
+
+		_, err := resolveGateway(ctx, ipamClient, &models.Prefix{Prefix: "10.0.0.0/24", CustomFields: map[string]any{"gateway": "10.0.0.254"}}, []string{"vip"})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ipamClient.GetIPAddressesCalls).To(BeZero())
+	})
+
+	It("should look up the IP addresses in the VRF of the prefix", func() {
+		var vrfIDs []string
+		ipamClient := &mock.IPAMMock{
+			GetIPAddressesFunc: func(_ context.Context, opts ...ipam.ListIPAddressesRequestOption) ([]models.IPAddress, error) {
+				vrfIDs = append(vrfIDs, ipam.NewListIPAddressesRequest(opts...).BuildQuery()["vrf_id"]...)
+				return nil, nil
+			},
+		}
+
+		_, err := resolveGateway(ctx, ipamClient, &models.Prefix{Prefix: "10.0.0.0/24"}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = resolveGateway(ctx, ipamClient, &models.Prefix{Prefix: "10.0.0.0/24", Vrf: models.NestedVRF{ID: 3}}, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(vrfIDs).To(Equal([]string{"null", "3"}))
+	})
+
+	It("should refuse gateways outside of the prefix", func() {
+		_, err := resolveGateway(ctx, ipamMock(nil), &models.Prefix{Prefix: "10.0.0.0/24", CustomFields: map[string]any{"gateway": "10.0.1.1"}}, nil)
+		Expect(err).To(MatchError("gateway 10.0.1.1 is not in prefix 10.0.0.0/24"))
+
+		_, err = resolveGateway(ctx, ipamMock(nil), &models.Prefix{Prefix: "10.0.0.0/24", CustomFields: map[string]any{"gateway": 42}}, nil)
+		Expect(err).To(MatchError(ContainSubstring("invalid gateway custom field of prefix 10.0.0.0/24")))
+	})
+
+	DescribeTable("should resolve the DNS servers of a prefix",
+		func(customFields any, expected []netip.Addr) {
+			servers, err := resolveDNSServers(ctx, ipamMock(addresses), &models.Prefix{Prefix: "10.0.0.0/24", CustomFields: customFields})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(servers).To(Equal(expected))
+		},
+		Entry("from the list custom field", map[string]any{"dns_servers": []any{"10.1.0.53", "2001:db8::53"}},
+			[]netip.Addr{netip.MustParseAddr("10.1.0.53"), netip.MustParseAddr("2001:db8::53")}),
+		Entry("from the comma separated custom field", map[string]any{"dns_servers": "10.1.0.53, 10.1.0.54"},
+			[]netip.Addr{netip.MustParseAddr("10.1.0.53"), netip.MustParseAddr("10.1.0.54")}),
+		Entry("from the tagged IP addresses", nil,
+			[]netip.Addr{netip.MustParseAddr("10.0.0.53"), netip.MustParseAddr("10.0.0.54")}),
+	)
+})
@@ -0,0 +1,120 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	ipamv1alpha2 "sigs.k8s.io/cluster-api-ipam-provider-in-cluster/api/v1alpha2"
+	ipamv1 "sigs.k8s.io/cluster-api/api/ipam/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	argorav1alpha1 "github.com/sapcc/argora/api/v1alpha1"
+)
+
+// errUnsafeIPPoolChange is returned if an IPPool update would strand addresses already allocated from the pool.
+var errUnsafeIPPoolChange = errors.New("unsafe ippool change")
+
+// checkIPPoolChange refuses changes of the IPPool spec that no longer cover all allocated addresses of the pool,
+// i.e. allocated addresses which would be outside of the addresses, excluded, or used as gateway.
+func (r *IPPoolImportReconciler) checkIPPoolChange(ctx context.Context, ippool client.Object, spec ipamv1alpha2.InClusterIPPoolSpec) error {
+	key := client.ObjectKeyFromObject(ippool)
+	allocated, err := r.allocatedIPPoolAddresses(ctx, ipPoolKindOf(ippool), key)
+	if err != nil {
+		return err
+	}
+	ippoolName := ipPoolKeyString(key)
+
+	for _, addr := range allocated {
+		inPool, err := addressInRanges(addr, spec.Addresses)
+		if err != nil {
+			return fmt.Errorf("unable to check addresses of ippool %s: %w", ippoolName, err)
+		}
+		if !inPool {
+			return fmt.Errorf("%w: allocated address %s of ippool %s is outside of %s", errUnsafeIPPoolChange, addr, ippoolName, strings.Join(spec.Addresses, ","))
+		}
+
+		excluded, err := addressInRanges(addr, spec.ExcludedAddresses)
+		if err != nil {
+			return fmt.Errorf("unable to check excluded addresses of ippool %s: %w", ippoolName, err)
+		}
+		if excluded {
+			return fmt.Errorf("%w: allocated address %s of ippool %s would be excluded", errUnsafeIPPoolChange, addr, ippoolName)
+		}
+
+		if addr.String() == spec.Gateway {
+			return fmt.Errorf("%w: allocated address %s of ippool %s would be the gateway", errUnsafeIPPoolChange, addr, ippoolName)
+		}
+	}
+
+	return nil
+}
+
+// allocatedIPPoolAddresses returns the addresses allocated from the IPPool of the given kind and key.
+// Addresses are only allocated from InClusterIPPools within their namespace.
+func (r *IPPoolImportReconciler) allocatedIPPoolAddresses(ctx context.Context, kind argorav1alpha1.IPPoolKind, key client.ObjectKey) ([]netip.Addr, error) {
+	ipAddresses := &ipamv1.IPAddressList{}
+	if err := r.k8sClient.List(ctx, ipAddresses, client.InNamespace(key.Namespace)); err != nil {
+		return nil, fmt.Errorf("unable to list ipaddresses: %w", err)
+	}
+
+	var allocated []netip.Addr
+	for _, ipAddress := range ipAddresses.Items {
+		if ipAddress.Spec.PoolRef.Kind != string(kind) || ipAddress.Spec.PoolRef.Name != key.Name {
+			continue
+		}
+
+		addr, err := netip.ParseAddr(ipAddress.Spec.Address)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse address of ipaddress %s/%s: %w", ipAddress.Namespace, ipAddress.Name, err)
+		}
+		allocated = append(allocated, addr)
+	}
+
+	return allocated, nil
+}
+
+// addressInRanges reports whether the address is contained in one of the ranges, given as single addresses,
+// prefixes (10.0.0.0/24) or address ranges (10.0.0.1-10.0.0.10) like in the IPPool spec.
+func addressInRanges(addr netip.Addr, ranges []string) (bool, error) {
+	for _, r := range ranges {
+		switch {
+		case strings.Contains(r, "/"):
+			prefix, err := netip.ParsePrefix(r)
+			if err != nil {
+				return false, err
+			}
+			if prefix.Contains(addr) {
+				return true, nil
+			}
+		case strings.Contains(r, "-"):
+			from, to, _ := strings.Cut(r, "-")
+			first, err := netip.ParseAddr(strings.TrimSpace(from))
+			if err != nil {
+				return false, err
+			}
+			last, err := netip.ParseAddr(strings.TrimSpace(to))
+			if err != nil {
+				return false, err
+			}
+			if first.Compare(addr) <= 0 && addr.Compare(last) <= 0 {
+				return true, nil
+			}
+		default:
+			single, err := netip.ParseAddr(r)
+			if err != nil {
+				return false, err
+			}
+			if single == addr {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
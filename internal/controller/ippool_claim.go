@@ -0,0 +1,235 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/sapcc/go-netbox-go/models"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	argorav1alpha1 "github.com/sapcc/argora/api/v1alpha1"
+	"github.com/sapcc/argora/internal/netbox/ipam"
+)
+
+const (
+	ipPoolImportFinalizer = "ippoolimport.argora.cloud.sap/finalizer"
+
+	netboxPrefixStatusContainer = "container"
+	netboxPrefixStatusActive    = "active"
+)
+
+// hasPrefixClaims reports whether the IPPoolImport claims prefixes, or claimed prefixes in the past which still
+// have to be released.
+func hasPrefixClaims(importCR *argorav1alpha1.IPPoolImport) bool {
+	return len(importCR.Status.ClaimedPrefixes) > 0 || slices.ContainsFunc(importCR.Spec.IPPools, func(ipPoolSelector *argorav1alpha1.IPPoolSelector) bool {
+		return ipPoolSelector.Claim != nil
+	})
+}
+
+// prefixClaimDescription returns the description identifying the child prefix of the selector in NetBox. It is keyed
+// on the UID of the IPPoolImport and the name of the claim, which do not change if the selector is renamed or moved.
+func prefixClaimDescription(importCR *argorav1alpha1.IPPoolImport, ipPoolSelector *argorav1alpha1.IPPoolSelector) string {
+	return fmt.Sprintf("claimed by ippoolimport %s/%s (%s) for claim %s", importCR.Namespace, importCR.Name, importCR.UID, ipPoolSelector.Claim.Name)
+}
+
+// claimPrefixes returns the child prefix claimed by the selector from the selected container prefix, allocating it
+// in NetBox on the first reconciliation. Selected prefixes not in status container are ignored, so the child prefix
+// is never mistaken for a container. It returns no prefix if no container prefix is selected.
+func (r *IPPoolImportReconciler) claimPrefixes(ctx context.Context, importCR *argorav1alpha1.IPPoolImport, ipPoolSelector *argorav1alpha1.IPPoolSelector, prefixes []models.Prefix) ([]models.Prefix, error) {
+	logger := log.FromContext(ctx)
+
+	sameName := 0
+	for _, other := range importCR.Spec.IPPools {
+		if other.Claim != nil && other.Claim.Name == ipPoolSelector.Claim.Name {
+			sameName++
+		}
+	}
+	if sameName > 1 {
+		return nil, fmt.Errorf("prefix claim name %s of ippool selector %s is not unique", ipPoolSelector.Claim.Name, ipPoolSelectorName(ipPoolSelector))
+	}
+
+	containers := slices.DeleteFunc(prefixes, func(prefix models.Prefix) bool {
+		return prefix.Status.Value != netboxPrefixStatusContainer
+	})
+	if len(containers) == 0 {
+		return nil, nil
+	}
+	if len(containers) > 1 {
+		return nil, fmt.Errorf("prefix claim of ippool selector %s matches %d container prefixes, expected one", ipPoolSelectorName(ipPoolSelector), len(containers))
+	}
+	container := &containers[0]
+
+	tag, err := r.netBox.Extras().GetTagByName(ipPoolSelector.Claim.Tag)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get tag %s: %w", ipPoolSelector.Claim.Tag, err)
+	}
+
+	claimed, err := r.findClaimedPrefix(ctx, importCR, ipPoolSelector, container, tag.Slug)
+	if err != nil {
+		return nil, err
+	}
+	if claimed == nil {
+		claimed, err = r.allocatePrefix(ctx, importCR, ipPoolSelector, container, tag)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("child prefix claimed", "prefix", claimed.Prefix, "ID", claimed.ID, "container", container.Prefix)
+	}
+
+	if !slices.ContainsFunc(importCR.Status.ClaimedPrefixes, func(prefix argorav1alpha1.ClaimedPrefix) bool { return prefix.ID == claimed.ID }) {
+		importCR.Status.ClaimedPrefixes = append(importCR.Status.ClaimedPrefixes, argorav1alpha1.ClaimedPrefix{ID: claimed.ID, Prefix: claimed.Prefix})
+	}
+	return []models.Prefix{*claimed}, nil
+}
+
+// findClaimedPrefix returns the child prefix already claimed by the selector from the container prefix, or nil.
+func (r *IPPoolImportReconciler) findClaimedPrefix(ctx context.Context, importCR *argorav1alpha1.IPPoolImport, ipPoolSelector *argorav1alpha1.IPPoolSelector, container *models.Prefix, tagSlug string) (*models.Prefix, error) {
+	children, err := r.netBox.IPAM().GetPrefixes(ctx,
+		ipam.PrefixWithin(container.Prefix),
+		ipam.PrefixWithTag(tagSlug),
+		ipam.PrefixWithMaskLength(ipPoolSelector.Claim.PrefixLength),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list child prefixes of prefix %s: %w", container.Prefix, err)
+	}
+
+	description := prefixClaimDescription(importCR, ipPoolSelector)
+	for _, child := range children {
+		if child.Description == description && child.Vrf.ID == container.Vrf.ID {
+			return &child, nil
+		}
+	}
+	return nil, nil
+}
+
+// allocatePrefix allocates the first available child prefix in the container prefix, marked with the tag and the
+// description of the claim. The child prefix is marked by the allocation itself, so it is always found by later
+// reconciliations.
+func (r *IPPoolImportReconciler) allocatePrefix(ctx context.Context, importCR *argorav1alpha1.IPPoolImport, ipPoolSelector *argorav1alpha1.IPPoolSelector, container *models.Prefix, tag *models.Tag) (*models.Prefix, error) {
+	return r.netBox.IPAM().CreateAvailablePrefix(ctx, container.ID, ipam.CreateAvailablePrefixParams{
+		PrefixLength: ipPoolSelector.Claim.PrefixLength,
+		SiteID:       container.Site.ID,
+		VrfID:        container.Vrf.ID,
+		TenantID:     container.Tenant.ID,
+		Status:       netboxPrefixStatusActive,
+		Description:  prefixClaimDescription(importCR, ipPoolSelector),
+		Tags:         []models.NestedTag{tag.NestedTag},
+	})
+}
+
+// reconcileDelete releases the child prefixes claimed by the IPPoolImport and removes its finalizer. The IPPools
+// generated from the child prefixes are deleted first, deletion is blocked while addresses are allocated from them.
+func (r *IPPoolImportReconciler) reconcileDelete(ctx context.Context, importCR *argorav1alpha1.IPPoolImport) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	if !controllerutil.ContainsFinalizer(importCR, ipPoolImportFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	claimed, err := r.claimedPrefixes(ctx, importCR)
+	if err != nil {
+		logger.Error(err, "unable to find claimed prefixes")
+		return ctrl.Result{}, err
+	}
+
+	for _, prefix := range claimed {
+		if err := r.releasePrefix(ctx, importCR, prefix); err != nil {
+			logger.Error(err, "unable to release claimed prefix", "prefix", prefix.Prefix, "ID", prefix.ID)
+			return ctrl.Result{}, err
+		}
+		logger.Info("claimed prefix released", "prefix", prefix.Prefix, "ID", prefix.ID)
+	}
+
+	base := importCR.DeepCopy()
+	if removed := controllerutil.RemoveFinalizer(importCR, ipPoolImportFinalizer); removed {
+		if err := r.k8sClient.Patch(ctx, importCR, client.MergeFrom(base)); err != nil {
+			logger.Error(err, "unable to remove finalizer")
+			return ctrl.Result{}, err
+		}
+	}
+
+	logger.Info("finalizer removed")
+	return ctrl.Result{}, nil
+}
+
+// claimedPrefixes returns the child prefixes claimed by the IPPoolImport which still exist in NetBox,
+// both recorded in the status and found for the claims of the spec.
+func (r *IPPoolImportReconciler) claimedPrefixes(ctx context.Context, importCR *argorav1alpha1.IPPoolImport) ([]argorav1alpha1.ClaimedPrefix, error) {
+	var claimed []argorav1alpha1.ClaimedPrefix
+	for _, recorded := range importCR.Status.ClaimedPrefixes {
+		prefixes, err := r.netBox.IPAM().GetPrefixesByPrefix(recorded.Prefix)
+		if err != nil {
+			return nil, err
+		}
+		if slices.ContainsFunc(prefixes, func(prefix models.Prefix) bool { return prefix.ID == recorded.ID }) {
+			claimed = append(claimed, recorded)
+		}
+	}
+
+	for _, ipPoolSelector := range importCR.Spec.IPPools {
+		if ipPoolSelector.Claim == nil {
+			continue
+		}
+
+		containers, err := r.selectPrefixes(ctx, ipPoolSelector)
+		if err != nil {
+			return nil, err
+		}
+		tag, err := r.netBox.Extras().GetTagByName(ipPoolSelector.Claim.Tag)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get tag %s: %w", ipPoolSelector.Claim.Tag, err)
+		}
+
+		for _, container := range containers {
+			if container.Status.Value != netboxPrefixStatusContainer {
+				continue
+			}
+			child, err := r.findClaimedPrefix(ctx, importCR, ipPoolSelector, &container, tag.Slug)
+			if err != nil {
+				return nil, err
+			}
+			if child != nil && !slices.ContainsFunc(claimed, func(prefix argorav1alpha1.ClaimedPrefix) bool { return prefix.ID == child.ID }) {
+				claimed = append(claimed, argorav1alpha1.ClaimedPrefix{ID: child.ID, Prefix: child.Prefix})
+			}
+		}
+	}
+
+	return claimed, nil
+}
+
+// releasePrefix deletes the IPPools generated from the claimed prefix and the prefix in NetBox.
+func (r *IPPoolImportReconciler) releasePrefix(ctx context.Context, importCR *argorav1alpha1.IPPoolImport, prefix argorav1alpha1.ClaimedPrefix) error {
+	labels := ipPoolImportLabels(importCR)
+	labels[ipPoolPrefixIDLabel] = strconv.Itoa(prefix.ID)
+
+	ippools, err := r.listIPPools(ctx, labels)
+	if err != nil {
+		return err
+	}
+
+	for _, ippool := range ippools {
+		key := client.ObjectKeyFromObject(ippool)
+		allocated, err := r.allocatedIPPoolAddresses(ctx, ipPoolKindOf(ippool), key)
+		if err != nil {
+			return err
+		}
+		if len(allocated) > 0 {
+			return fmt.Errorf("ippool %s of claimed prefix %s has %d allocated addresses", ipPoolKeyString(key), prefix.Prefix, len(allocated))
+		}
+
+		if err := r.k8sClient.Delete(ctx, ippool); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("unable to delete ippool %s: %w", ipPoolKeyString(key), err)
+		}
+	}
+
+	return r.netBox.IPAM().DeletePrefix(prefix.ID)
+}
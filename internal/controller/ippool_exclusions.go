@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+
+	"github.com/sapcc/go-netbox-go/models"
+	"k8s.io/utils/ptr"
+
+	argorav1alpha1 "github.com/sapcc/argora/api/v1alpha1"
+)
+
+// netboxExcludedAddresses returns the given IP addresses and the IP ranges documented in NetBox inside the prefix,
+// which match the exclusion, in the address format of the IPPool spec. IP addresses already allocated from the IPPool
+// are not excluded, as NetBox documents them once they are synced by the IPUpdate controller.
+func (r *IPPoolImportReconciler) netboxExcludedAddresses(ctx context.Context, exclusion *argorav1alpha1.NetboxAddressExclusion, prefix *models.Prefix, addresses []models.IPAddress, allocated []netip.Addr) ([]string, error) {
+	var excluded []string
+	for _, address := range addresses {
+		if !matchesNetboxFilter(exclusion.Statuses, address.Status.Value) || !matchesNetboxFilter(exclusion.Roles, address.Role.Value) {
+			continue
+		}
+
+		addr, err := netip.ParsePrefix(address.Address)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse netbox ip address %s: %w", address.Address, err)
+		}
+		if slices.Contains(allocated, addr.Addr()) {
+			continue
+		}
+		excluded = append(excluded, addr.Addr().String())
+	}
+
+	if ptr.Deref(exclusion.IPRanges, true) {
+		ranges, err := r.netBox.IPAM().GetIPRangesInPrefix(ctx, prefix.Prefix, prefix.Vrf.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ipRange := range ranges {
+			if !matchesNetboxFilter(exclusion.Statuses, ipRange.Status.Value) {
+				continue
+			}
+
+			start, err := netip.ParsePrefix(ipRange.StartAddress)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse start address of netbox ip range %d: %w", ipRange.ID, err)
+			}
+			end, err := netip.ParsePrefix(ipRange.EndAddress)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse end address of netbox ip range %d: %w", ipRange.ID, err)
+			}
+			excluded = append(excluded, fmt.Sprintf("%s-%s", start.Addr(), end.Addr()))
+		}
+	}
+
+	// sorted to keep the IPPool spec stable regardless of the NetBox ordering
+	slices.Sort(excluded)
+	return slices.Compact(excluded), nil
+}
+
+// matchesNetboxFilter reports whether the value is one of the allowed values. An empty filter matches every value.
+func matchesNetboxFilter(allowed []string, value string) bool {
+	return len(allowed) == 0 || slices.Contains(allowed, value)
+}
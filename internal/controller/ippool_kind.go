@@ -0,0 +1,119 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	"text/template"
+
+	"github.com/sapcc/go-netbox-go/models"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ipamv1alpha2 "sigs.k8s.io/cluster-api-ipam-provider-in-cluster/api/v1alpha2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	argorav1alpha1 "github.com/sapcc/argora/api/v1alpha1"
+)
+
+// ipPoolKind returns the kind of the IPPools generated for the selector, GlobalInClusterIPPool unless set otherwise.
+func ipPoolKind(ipPoolSelector *argorav1alpha1.IPPoolSelector) argorav1alpha1.IPPoolKind {
+	if ipPoolSelector.PoolKind == "" {
+		return argorav1alpha1.IPPoolKindGlobalInClusterIPPool
+	}
+	return ipPoolSelector.PoolKind
+}
+
+// newIPPool returns an empty IPPool of the given kind.
+func newIPPool(kind argorav1alpha1.IPPoolKind) client.Object {
+	if kind == argorav1alpha1.IPPoolKindInClusterIPPool {
+		return &ipamv1alpha2.InClusterIPPool{}
+	}
+	return &ipamv1alpha2.GlobalInClusterIPPool{}
+}
+
+// ipPoolSpec returns a pointer to the spec of a GlobalInClusterIPPool or InClusterIPPool.
+func ipPoolSpec(ippool client.Object) *ipamv1alpha2.InClusterIPPoolSpec {
+	switch ippool := ippool.(type) {
+	case *ipamv1alpha2.InClusterIPPool:
+		return &ippool.Spec
+	case *ipamv1alpha2.GlobalInClusterIPPool:
+		return &ippool.Spec
+	default:
+		panic(fmt.Sprintf("unexpected ippool type %T", ippool))
+	}
+}
+
+// ipPoolKindOf returns the kind of a GlobalInClusterIPPool or InClusterIPPool object.
+func ipPoolKindOf(ippool client.Object) argorav1alpha1.IPPoolKind {
+	if _, ok := ippool.(*ipamv1alpha2.InClusterIPPool); ok {
+		return argorav1alpha1.IPPoolKindInClusterIPPool
+	}
+	return argorav1alpha1.IPPoolKindGlobalInClusterIPPool
+}
+
+// ipPoolKeyString formats the key of an IPPool for logs and messages, omitting the namespace of cluster-wide pools.
+func ipPoolKeyString(key client.ObjectKey) string {
+	if key.Namespace == "" {
+		return key.Name
+	}
+	return key.String()
+}
+
+// generateIPPoolNamespace generates the namespace of the IPPool of a prefix. GlobalInClusterIPPools are cluster-wide,
+// InClusterIPPools are generated in the rendered target namespace of the selector or in the namespace of the IPPoolImport.
+func generateIPPoolNamespace(importCR *argorav1alpha1.IPPoolImport, ipPoolSelector *argorav1alpha1.IPPoolSelector, prefix *models.Prefix) (string, error) {
+	if ipPoolKind(ipPoolSelector) != argorav1alpha1.IPPoolKindInClusterIPPool {
+		return "", nil
+	}
+	if ipPoolSelector.TargetNamespace == "" {
+		return importCR.Namespace, nil
+	}
+
+	prefixParsed, err := netip.ParsePrefix(prefix.Prefix)
+	if err != nil {
+		return "", err
+	}
+
+	tmpl, err := template.New("targetNamespace").Funcs(ipPoolNameTemplateFuncs).Option("missingkey=error").Parse(ipPoolSelector.TargetNamespace)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse target namespace template: %w", err)
+	}
+
+	var namespace bytes.Buffer
+	if err := tmpl.Execute(&namespace, newIPPoolNameData(ipPoolSelector, prefix, prefixParsed)); err != nil {
+		return "", fmt.Errorf("unable to render target namespace template: %w", err)
+	}
+
+	if errs := validation.IsDNS1123Label(namespace.String()); len(errs) > 0 {
+		return "", fmt.Errorf("invalid target namespace %q: %s", namespace.String(), strings.Join(errs, ", "))
+	}
+
+	return namespace.String(), nil
+}
+
+// listIPPools returns the GlobalInClusterIPPools and the InClusterIPPools of all namespaces matching the labels.
+func (r *IPPoolImportReconciler) listIPPools(ctx context.Context, labels map[string]string) ([]client.Object, error) {
+	globalPools := &ipamv1alpha2.GlobalInClusterIPPoolList{}
+	if err := r.k8sClient.List(ctx, globalPools, client.MatchingLabels(labels)); err != nil {
+		return nil, fmt.Errorf("unable to list globalinclusterippools: %w", err)
+	}
+
+	pools := &ipamv1alpha2.InClusterIPPoolList{}
+	if err := r.k8sClient.List(ctx, pools, client.MatchingLabels(labels)); err != nil {
+		return nil, fmt.Errorf("unable to list inclusterippools: %w", err)
+	}
+
+	ippools := make([]client.Object, 0, len(globalPools.Items)+len(pools.Items))
+	for i := range globalPools.Items {
+		ippools = append(ippools, &globalPools.Items[i])
+	}
+	for i := range pools.Items {
+		ippools = append(ippools, &pools.Items[i])
+	}
+
+	return ippools, nil
+}
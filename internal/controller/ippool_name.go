@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/sapcc/go-netbox-go/models"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	argorav1alpha1 "github.com/sapcc/argora/api/v1alpha1"
+)
+
+// errIPPoolNameCollision is returned if the IPPool name of a prefix is already taken by another prefix or IPPoolImport.
+var errIPPoolNameCollision = errors.New("ippool name collision")
+
+var invalidDNSLabelChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+var ipPoolNameTemplateFuncs = func() template.FuncMap {
+	funcs := maps.Clone(labelTemplateFuncs)
+	maps.Copy(funcs, template.FuncMap{
+		"submatch": submatch,
+		"atoi":     strconv.Atoi,
+		"add":      func(a, b int) int { return a + b },
+		"sub":      func(a, b int) int { return a - b },
+		"dnsLabel": dnsLabel,
+	})
+	return funcs
+}()
+
+// ipPoolNameData holds the NetBox attributes of a prefix which are available in IPPool name templates.
+type ipPoolNameData struct {
+	// Prefix is the prefix in CIDR notation, e.g. 10.0.0.0/24.
+	Prefix string
+	// Network is the network address of the prefix, e.g. 10.0.0.0.
+	Network string
+	Mask    int
+	// Family is the address family of the prefix, either 4 or 6.
+	Family int
+	ID     int
+	Site   string
+	Region string
+	Vlan   string
+	VlanID int
+	Vrf    string
+	Tenant string
+	Role   string
+}
+
+func newIPPoolNameData(ipPoolSelector *argorav1alpha1.IPPoolSelector, prefix *models.Prefix, prefixParsed netip.Prefix) *ipPoolNameData {
+	family := 4
+	if prefixParsed.Addr().Is6() {
+		family = 6
+	}
+
+	region := prefix.Site.Region.Slug
+	if region == "" {
+		region = strings.ToLower(ipPoolSelector.Region)
+	}
+
+	return &ipPoolNameData{
+		Prefix:  prefix.Prefix,
+		Network: prefixParsed.Masked().Addr().String(),
+		Mask:    prefixParsed.Bits(),
+		Family:  family,
+		ID:      prefix.ID,
+		Site:    prefix.Site.Slug,
+		Region:  region,
+		Vlan:    prefix.Vlan.Name,
+		VlanID:  prefix.Vlan.VID,
+		Vrf:     prefix.Vrf.Name,
+		Tenant:  prefix.Tenant.Slug,
+		Role:    prefix.Role.Slug,
+	}
+}
+
+// renderIPPoolName renders the name template of the selector for the prefix and validates the result as object name.
+func renderIPPoolName(nameTemplate string, data *ipPoolNameData) (string, error) {
+	tmpl, err := template.New("nameTemplate").Funcs(ipPoolNameTemplateFuncs).Option("missingkey=error").Parse(nameTemplate)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse name template: %w", err)
+	}
+
+	var name bytes.Buffer
+	if err := tmpl.Execute(&name, data); err != nil {
+		return "", fmt.Errorf("unable to render name template: %w", err)
+	}
+
+	if errs := validation.IsDNS1123Subdomain(name.String()); len(errs) > 0 {
+		return "", fmt.Errorf("invalid ippool name %q: %s", name.String(), strings.Join(errs, ", "))
+	}
+
+	return name.String(), nil
+}
+
+// ipPoolSelectorName returns the name, name prefix or name template of the selector for logs and status messages.
+func ipPoolSelectorName(ipPoolSelector *argorav1alpha1.IPPoolSelector) string {
+	return cmp.Or(ipPoolSelector.NamePrefix, ipPoolSelector.NameOverride, ipPoolSelector.NameTemplate)
+}
+
+// claimIPPoolName records the IPPool key for the prefix in pools. It fails if another prefix already claimed the key.
+func claimIPPoolName(pools map[client.ObjectKey]string, key client.ObjectKey, prefix *models.Prefix) error {
+	if claimedBy, ok := pools[key]; ok {
+		return fmt.Errorf("%w: ippool %s of prefix %s is already generated for prefix %s", errIPPoolNameCollision, ipPoolKeyString(key), prefix.Prefix, claimedBy)
+	}
+	pools[key] = prefix.Prefix
+	return nil
+}
+
+// submatch returns the first capture group of the regular expression in s, or an empty string if it does not match.
+func submatch(expr, s string) (string, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return "", err
+	}
+	if matches := re.FindStringSubmatch(s); len(matches) > 1 {
+		return matches[1], nil
+	}
+	return "", nil
+}
+
+// dnsLabel lowercases s, replaces characters which are not allowed in DNS labels with dashes
+// and trims dashes at both ends.
+func dnsLabel(s string) string {
+	return strings.Trim(invalidDNSLabelChars.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
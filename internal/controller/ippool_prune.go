@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	argorav1alpha1 "github.com/sapcc/argora/api/v1alpha1"
+)
+
+// pruneIPPools handles the IPPools generated by the IPPoolImport which are not in pools, i.e. whose prefix is no longer
+// selected. Depending on the deletion policy they are deleted, unless addresses are still allocated from them.
+// IPPools which are kept are recorded in the status of the IPPoolImport.
+func (r *IPPoolImportReconciler) pruneIPPools(ctx context.Context, importCR *argorav1alpha1.IPPoolImport, pools map[client.ObjectKey]string) error {
+	logger := log.FromContext(ctx)
+
+	ippools, err := r.listIPPools(ctx, ipPoolImportLabels(importCR))
+	if err != nil {
+		return err
+	}
+
+	var orphaned []argorav1alpha1.OrphanedIPPool
+	for _, ippool := range ippools {
+		key := client.ObjectKeyFromObject(ippool)
+		if _, ok := pools[key]; ok {
+			continue
+		}
+
+		orphan := argorav1alpha1.OrphanedIPPool{
+			Name:      ippool.GetName(),
+			Namespace: ippool.GetNamespace(),
+			PrefixID:  ippool.GetLabels()[ipPoolPrefixIDLabel],
+		}
+
+		if importCR.Spec.DeletionPolicy != argorav1alpha1.IPPoolDeletionPolicyDelete {
+			logger.Info("retaining orphaned IPPool", "name", ipPoolKeyString(key))
+			orphan.Message = "retained by deletion policy"
+			orphaned = append(orphaned, orphan)
+			continue
+		}
+
+		allocated, err := r.allocatedIPPoolAddresses(ctx, ipPoolKindOf(ippool), key)
+		if err != nil {
+			return err
+		}
+		if len(allocated) > 0 {
+			logger.Info("orphaned IPPool has allocated addresses, not deleting", "name", ipPoolKeyString(key), "allocated", len(allocated))
+			orphan.Message = fmt.Sprintf("deletion blocked by %d allocated addresses", len(allocated))
+			orphaned = append(orphaned, orphan)
+			continue
+		}
+
+		if err := r.k8sClient.Delete(ctx, ippool); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("unable to delete orphaned ippool %s: %w", ipPoolKeyString(key), err)
+		}
+		logger.Info("orphaned IPPool deleted", "name", ipPoolKeyString(key))
+	}
+
+	importCR.Status.OrphanedPools = orphaned
+	return nil
+}
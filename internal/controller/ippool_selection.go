@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/sapcc/go-netbox-go/models"
+
+	argorav1alpha1 "github.com/sapcc/argora/api/v1alpha1"
+	"github.com/sapcc/argora/internal/netbox/ipam"
+)
+
+// selectPrefixes returns the prefixes matching the prefix filter of the selector, without the prefixes matching
+// any of its exclude filters.
+func (r *IPPoolImportReconciler) selectPrefixes(ctx context.Context, ipPoolSelector *argorav1alpha1.IPPoolSelector) ([]models.Prefix, error) {
+	prefixes, err := r.listPrefixes(ctx, &ipPoolSelector.PrefixFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	excluded := make(map[int]bool)
+	for i := range ipPoolSelector.Exclude {
+		excludedPrefixes, err := r.listPrefixes(ctx, &ipPoolSelector.Exclude[i])
+		if err != nil {
+			return nil, fmt.Errorf("unable to list excluded prefixes: %w", err)
+		}
+		for _, prefix := range excludedPrefixes {
+			excluded[prefix.ID] = true
+		}
+	}
+
+	return slices.DeleteFunc(prefixes, func(prefix models.Prefix) bool {
+		return excluded[prefix.ID]
+	}), nil
+}
+
+// listPrefixes returns the prefixes matching the filter. NetBox filters by all fields but the VLAN group, which is
+// matched on the returned prefixes. No prefixes match if no VRF has the name of the filter.
+func (r *IPPoolImportReconciler) listPrefixes(ctx context.Context, filter *argorav1alpha1.PrefixFilter) ([]models.Prefix, error) {
+	opts := []ipam.ListPrefixesRequestOption{
+		ipam.PrefixWithRegion(filter.Region),
+		ipam.PrefixWithRole(filter.Role),
+		ipam.PrefixWithSite(filter.Site),
+		ipam.PrefixWithTenant(filter.Tenant),
+		ipam.PrefixWithTag(filter.Tag),
+		ipam.PrefixWithStatus(filter.Status),
+		ipam.PrefixWithFamily(filter.Family),
+		ipam.PrefixWithMaskLength(filter.MaskLength),
+	}
+	if filter.Vrf != "" {
+		vrfs, err := r.netBox.IPAM().GetVrfsByName(filter.Vrf)
+		if err != nil {
+			return nil, err
+		}
+		if len(vrfs) == 0 {
+			return nil, nil
+		}
+		vrfIDs := make([]int, 0, len(vrfs))
+		for _, vrf := range vrfs {
+			vrfIDs = append(vrfIDs, vrf.ID)
+		}
+		opts = append(opts, ipam.PrefixWithVrf(vrfIDs...))
+	}
+
+	prefixes, err := r.netBox.IPAM().GetPrefixes(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if filter.VlanGroup == "" {
+		return prefixes, nil
+	}
+
+	vlans, err := r.netBox.IPAM().GetVlansByGroup(filter.VlanGroup)
+	if err != nil {
+		return nil, err
+	}
+	vlanIDs := make(map[int]bool, len(vlans))
+	for _, vlan := range vlans {
+		vlanIDs[vlan.ID] = true
+	}
+
+	return slices.DeleteFunc(prefixes, func(prefix models.Prefix) bool {
+		return !vlanIDs[prefix.Vlan.ID]
+	}), nil
+}
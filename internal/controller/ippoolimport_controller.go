@@ -5,7 +5,9 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"net/netip"
 	"regexp"
 	"strconv"
@@ -16,8 +18,11 @@ import (
 
 	"github.com/sapcc/go-netbox-go/models"
 	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
@@ -25,6 +30,7 @@ import (
 
 	"github.com/sapcc/argora/internal/credentials"
 	"github.com/sapcc/argora/internal/netbox"
+	"github.com/sapcc/argora/internal/netbox/ipam"
 	"github.com/sapcc/argora/internal/status"
 
 	"k8s.io/apimachinery/pkg/runtime"
@@ -34,6 +40,14 @@ import (
 
 const (
 	ComputeTransitPrefixRoleName = "kubernetes-compute-transit"
+
+	// ipv6PoolNameSuffix is appended to the names of IPPools of IPv6 prefixes, so that dual-stack prefix roles
+	// result in one IPPool per address family.
+	ipv6PoolNameSuffix = "-v6"
+
+	ipPoolImportNameLabel      = "ippoolimport.argora.cloud.sap/name"
+	ipPoolImportNamespaceLabel = "ippoolimport.argora.cloud.sap/namespace"
+	ipPoolPrefixIDLabel        = "ippoolimport.argora.cloud.sap/prefix-id"
 )
 
 // IPPoolImportReconciler reconciles a IPPoolImport object
@@ -78,7 +92,9 @@ func (r *IPPoolImportReconciler) SetupWithManager(mgr ctrl.Manager, rateLimiter
 // +kubebuilder:rbac:groups=argora.cloud.sap,resources=ippoolimports,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=argora.cloud.sap,resources=ippoolimports/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=argora.cloud.sap,resources=ippoolimports/finalizers,verbs=update
-// +kubebuilder:rbac:groups=ipam.cluster.x-k8s.io,resources=globalinclusterippools,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=ipam.cluster.x-k8s.io,resources=globalinclusterippools,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=ipam.cluster.x-k8s.io,resources=inclusterippools,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=ipam.cluster.x-k8s.io,resources=ipaddresses,verbs=get;list;watch
 
 func (r *IPPoolImportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
@@ -87,10 +103,15 @@ func (r *IPPoolImportReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	importCR := &argorav1alpha1.IPPoolImport{}
 	err := r.k8sClient.Get(ctx, req.NamespacedName, importCR)
 	if err != nil {
+		if apierrors.IsNotFound(err) {
+			deleteReconcileMetrics(controllerNameIPPoolImport, req.NamespacedName)
+		}
 		logger.Error(err, "failed to get IPPoolImport")
 		return ctrl.Result{}, err
 	}
+	recordReconcileStarted(controllerNameIPPoolImport, importCR)
 
+	observeReload := observePhase(controllerNameIPPoolImport, phaseNetboxReload)
 	err = r.credentials.Reload()
 	if err != nil {
 		logger.Error(err, "unable to reload credentials")
@@ -116,12 +137,80 @@ func (r *IPPoolImportReconciler) Reconcile(ctx context.Context, req ctrl.Request
 
 		return ctrl.Result{}, err
 	}
+	observeReload()
+
+	if !importCR.DeletionTimestamp.IsZero() {
+		return r.reconcileDelete(ctx, importCR)
+	}
 
+	if hasPrefixClaims(importCR) {
+		base := importCR.DeepCopy()
+		if added := controllerutil.AddFinalizer(importCR, ipPoolImportFinalizer); added {
+			if err := r.k8sClient.Patch(ctx, importCR, client.MergeFrom(base)); err != nil {
+				logger.Error(err, "unable to add finalizer")
+				return ctrl.Result{}, err
+			}
+			logger.Info("finalizer added")
+		}
+	}
+
+	observeIPPools := observePhase(controllerNameIPPoolImport, phaseIPPools)
+	pools := make(map[client.ObjectKey]string)
+	var refused []error
+	var unmatched []string
 	for _, ipPoolSelector := range importCR.Spec.IPPools {
-		err = r.reconcileIPPoolSelection(ctx, importCR, ipPoolSelector)
+		selected, err := r.reconcileIPPoolSelection(ctx, importCR, ipPoolSelector, pools)
+		if errors.Is(err, errUnsafeIPPoolChange) || errors.Is(err, errIPPoolNameCollision) {
+			refused = append(refused, err)
+			continue
+		}
 		if err != nil {
 			return ctrl.Result{}, err
 		}
+		if selected == 0 {
+			unmatched = append(unmatched, ipPoolSelectorName(ipPoolSelector))
+		}
+	}
+	observeIPPools()
+
+	if len(unmatched) > 0 {
+		logger.Info("ippool selectors match no prefixes", "selectors", unmatched)
+		r.statusHandler.SetCondition(importCR, argorav1alpha1.NewReasonWithMessage(argorav1alpha1.ConditionReasonIPPoolImportNoPrefixesMatched,
+			"no prefixes match the ippool selectors "+strings.Join(unmatched, ", ")))
+	} else {
+		r.statusHandler.RemoveCondition(importCR, argorav1alpha1.ConditionTypePrefixesMatched)
+	}
+
+	observePrune := observePhase(controllerNameIPPoolImport, phasePrune)
+	err = r.pruneIPPools(ctx, importCR, pools)
+	if err != nil {
+		logger.Error(err, "unable to prune ippools")
+
+		r.statusHandler.SetCondition(importCR, argorav1alpha1.NewReasonWithMessage(argorav1alpha1.ConditionReasonIPPoolImportFailed))
+		if errUpdateStatus := r.statusHandler.UpdateToError(ctx, importCR, err); errUpdateStatus != nil {
+			return ctrl.Result{}, errUpdateStatus
+		}
+
+		return ctrl.Result{}, err
+	}
+	observePrune()
+
+	if len(refused) > 0 {
+		err = errors.Join(refused...)
+
+		reason := argorav1alpha1.ConditionReasonIPPoolImportUnsafeChange
+		if errors.Is(err, errIPPoolNameCollision) {
+			reason = argorav1alpha1.ConditionReasonIPPoolImportNameCollision
+		}
+
+		r.statusHandler.SetCondition(importCR, argorav1alpha1.NewReasonWithMessage(reason, err.Error()))
+		if errUpdateStatus := r.statusHandler.UpdateToError(ctx, importCR, err); errUpdateStatus != nil {
+			return ctrl.Result{}, errUpdateStatus
+		}
+
+		// refused changes are retried with the regular interval, as they only resolve once addresses are released
+		// or the colliding names are fixed
+		return ctrl.Result{RequeueAfter: r.reconcileInterval}, nil
 	}
 
 	r.statusHandler.SetCondition(importCR, argorav1alpha1.NewReasonWithMessage(argorav1alpha1.ConditionReasonIPPoolImportSucceeded))
@@ -129,112 +218,247 @@ func (r *IPPoolImportReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, errUpdateStatus
 	}
 
+	recordReconcileSuccess(controllerNameIPPoolImport, importCR)
+
 	return ctrl.Result{RequeueAfter: r.reconcileInterval}, nil
 }
 
-func (r *IPPoolImportReconciler) reconcileIPPoolSelection(ctx context.Context, importCR *argorav1alpha1.IPPoolImport, ipPoolSelector *argorav1alpha1.IPPoolSelector) error {
+// reconcileIPPoolSelection reconciles the IPPools of all prefixes matching the selector and records their keys
+// with the generating prefix in pools. It returns the number of selected prefixes.
+func (r *IPPoolImportReconciler) reconcileIPPoolSelection(ctx context.Context, importCR *argorav1alpha1.IPPoolImport, ipPoolSelector *argorav1alpha1.IPPoolSelector, pools map[client.ObjectKey]string) (int, error) {
 	logger := log.FromContext(ctx)
-	logger.Info("fetching prefixes", "region", ipPoolSelector.Region, "role", ipPoolSelector.Role)
+	logger.Info("fetching prefixes", "filter", ipPoolSelector.PrefixFilter, "exclude", ipPoolSelector.Exclude)
 
-	prefixes, err := r.netBox.IPAM().GetPrefixesByRegionRole(ipPoolSelector.Region, ipPoolSelector.Role)
+	prefixes, err := r.selectPrefixes(ctx, ipPoolSelector)
+	if err == nil && ipPoolSelector.Claim != nil {
+		prefixes, err = r.claimPrefixes(ctx, importCR, ipPoolSelector, prefixes)
+	}
 	if err != nil {
-		logger.Error(err, "unable to find prefixes", "region", ipPoolSelector.Region, "role", ipPoolSelector.Role)
+		logger.Error(err, "unable to find prefixes", "filter", ipPoolSelector.PrefixFilter)
 
 		r.statusHandler.SetCondition(importCR, argorav1alpha1.NewReasonWithMessage(argorav1alpha1.ConditionReasonIPPoolImportFailed))
 		if errUpdateStatus := r.statusHandler.UpdateToError(ctx, importCR, fmt.Errorf("unable to import prefix: %w", err)); errUpdateStatus != nil {
-			return errUpdateStatus
+			return 0, errUpdateStatus
 		}
 
-		return err
+		return 0, err
 	}
 
+	var refused []error
 	for _, prefix := range prefixes {
 		logger.Info("reconciling prefix", "prefix", prefix.Prefix, "ID", prefix.ID)
 
-		err = r.reconcileIPPool(ctx, ipPoolSelector, &prefix)
+		err = r.reconcileIPPool(ctx, importCR, ipPoolSelector, &prefix, pools)
+		if errors.Is(err, errUnsafeIPPoolChange) || errors.Is(err, errIPPoolNameCollision) {
+			logger.Error(err, "refusing to reconcile ippool", "prefix", prefix.Prefix, "ID", prefix.ID)
+			refused = append(refused, err)
+			continue
+		}
 		if err != nil {
-			logger.Error(err, "unable to reconcile ippool", "ippool", ipPoolSelector.NamePrefix, "prefix", prefix.Prefix, "ID", prefix.ID)
+			logger.Error(err, "unable to reconcile ippool", "ippool", ipPoolSelectorName(ipPoolSelector), "prefix", prefix.Prefix, "ID", prefix.ID)
 
 			r.statusHandler.SetCondition(importCR, argorav1alpha1.NewReasonWithMessage(argorav1alpha1.ConditionReasonIPPoolImportFailed))
-			if errUpdateStatus := r.statusHandler.UpdateToError(ctx, importCR, fmt.Errorf("unable to reconcile prefix %s on ippool %s: %w", prefix.Prefix, ipPoolSelector.NamePrefix, err)); errUpdateStatus != nil {
-				return errUpdateStatus
+			if errUpdateStatus := r.statusHandler.UpdateToError(ctx, importCR, fmt.Errorf("unable to reconcile prefix %s on ippool %s: %w", prefix.Prefix, ipPoolSelectorName(ipPoolSelector), err)); errUpdateStatus != nil {
+				return 0, errUpdateStatus
 			}
 
-			return err
+			return 0, err
 		}
 	}
 
-	return nil
+	return len(prefixes), errors.Join(refused...)
 }
 
-func (r *IPPoolImportReconciler) reconcileIPPool(ctx context.Context, ipPoolSelector *argorav1alpha1.IPPoolSelector, prefix *models.Prefix) error {
+func (r *IPPoolImportReconciler) reconcileIPPool(ctx context.Context, importCR *argorav1alpha1.IPPoolImport, ipPoolSelector *argorav1alpha1.IPPoolSelector, prefix *models.Prefix, pools map[client.ObjectKey]string) error {
 	logger := log.FromContext(ctx)
 	logger.Info("reconciling IPPool", "prefix", prefix.Prefix, "ID", prefix.ID)
 
-	ippool := &ipamv1alpha2.GlobalInClusterIPPool{}
 	ippoolName, err := generateIPPoolName(ipPoolSelector, prefix)
 	if err != nil {
 		return fmt.Errorf("unable to generate ippool name for prefix %s: %w", prefix.Prefix, err)
 	}
+	ippoolNamespace, err := generateIPPoolNamespace(importCR, ipPoolSelector, prefix)
+	if err != nil {
+		return fmt.Errorf("unable to generate ippool namespace for prefix %s: %w", prefix.Prefix, err)
+	}
+	key := client.ObjectKey{Namespace: ippoolNamespace, Name: ippoolName}
+	if err := claimIPPoolName(pools, key, prefix); err != nil {
+		return err
+	}
+	ippoolName = ipPoolKeyString(key)
 
-	net, gateway, mask, err := generateNetGatewayIP(prefix)
+	spec, err := generateIPPoolSpec(ipPoolSelector, prefix)
 	if err != nil {
-		return fmt.Errorf("unable to generate gateway IP for prefix %s: %w", prefix.Prefix, err)
+		return err
 	}
 
-	err = r.k8sClient.Get(ctx, client.ObjectKey{Name: ippoolName}, ippool)
+	gateway, err := resolveGateway(ctx, r.netBox.IPAM(), prefix, ipPoolSelector.GatewayRoles)
 	if err != nil {
-		logger.Info("IPPool not found, creating", "name", ippoolName)
+		return fmt.Errorf("unable to resolve gateway of prefix %s: %w", prefix.Prefix, err)
+	}
+	if gateway.IsValid() {
+		spec.Gateway = gateway.String()
+	}
 
-		newIPPool := &ipamv1alpha2.GlobalInClusterIPPool{
-			ObjectMeta: ctrl.ObjectMeta{
-				Name: ippoolName,
-			},
-			Spec: ipamv1alpha2.InClusterIPPoolSpec{
-				Addresses: []string{prefix.Prefix},
-				Gateway:   gateway,
-				Prefix:    mask,
-			},
+	if ipPoolSelector.ExcludeNetboxAddresses != nil {
+		// all addresses are only listed if they are excluded, as prefixes may hold a lot of them
+		addresses, err := r.netBox.IPAM().GetIPAddresses(ctx, ipam.IPAddressesWithParent(prefix.Prefix), ipam.IPAddressesWithVrfID(prefix.Vrf.ID))
+		if err != nil {
+			return fmt.Errorf("unable to get netbox addresses in prefix %s: %w", prefix.Prefix, err)
 		}
-		if ipPoolSelector.ExcludeMask != nil {
-			if mask >= *ipPoolSelector.ExcludeMask {
-				return fmt.Errorf("excludeMask (%d) must be longer than prefix mask (%d) for prefix %s", *ipPoolSelector.ExcludeMask, mask, prefix.Prefix)
-			}
-			newIPPool.Spec.ExcludedAddresses = []string{fmt.Sprintf("%s/%d", net, *ipPoolSelector.ExcludeMask)}
+
+		allocated, err := r.allocatedIPPoolAddresses(ctx, ipPoolKind(ipPoolSelector), key)
+		if err != nil {
+			return err
 		}
 
-		if ipPoolSelector.ExcludedAddresses != nil {
-			newIPPool.Spec.ExcludedAddresses = append(newIPPool.Spec.ExcludedAddresses, ipPoolSelector.ExcludedAddresses...)
+		excluded, err := r.netboxExcludedAddresses(ctx, ipPoolSelector.ExcludeNetboxAddresses, prefix, addresses, allocated)
+		if err != nil {
+			return fmt.Errorf("unable to get netbox addresses in prefix %s: %w", prefix.Prefix, err)
 		}
+		spec.ExcludedAddresses = append(spec.ExcludedAddresses, excluded...)
+	}
 
-		if ipPoolSelector.ExcludeLastNAddresses != nil {
-			prefixParsed, err := netip.ParsePrefix(prefix.Prefix)
-			if err != nil {
-				return fmt.Errorf("unable to parse prefix %s: %w", prefix.Prefix, err)
-			}
-			lastN := getLastNIPs(prefixParsed, *ipPoolSelector.ExcludeLastNAddresses)
+	ippool := newIPPool(ipPoolKind(ipPoolSelector))
+	err = r.k8sClient.Get(ctx, key, ippool)
+	if apierrors.IsNotFound(err) {
+		logger.Info("IPPool not found, creating", "name", ippoolName)
 
-			for _, ip := range lastN {
-				newIPPool.Spec.ExcludedAddresses = append(newIPPool.Spec.ExcludedAddresses, ip.String())
-			}
-		}
+		ippool.SetName(key.Name)
+		ippool.SetNamespace(key.Namespace)
+		ippool.SetLabels(ipPoolLabels(importCR, prefix))
+		*ipPoolSpec(ippool) = spec
 
-		err = r.k8sClient.Create(ctx, newIPPool)
+		err = r.k8sClient.Create(ctx, ippool)
 		if err != nil {
 			logger.Error(err, "unable to create IPPool", "name", ippoolName)
 			return err
 		}
 
 		logger.Info("IPPool created", "name", ippoolName)
+		ipPoolsCreatedTotal.WithLabelValues(importCR.Namespace, importCR.Name, string(ipPoolKindOf(ippool))).Inc()
 		return nil
 	}
+	if err != nil {
+		return fmt.Errorf("unable to get ippool %s: %w", ippoolName, err)
+	}
+
+	if owner := ippool.GetLabels()[ipPoolImportNameLabel]; owner != "" && (owner != importCR.Name || ippool.GetLabels()[ipPoolImportNamespaceLabel] != importCR.Namespace) {
+		return fmt.Errorf("%w: ippool %s of prefix %s is already generated by ippoolimport %s/%s", errIPPoolNameCollision, ippoolName, prefix.Prefix, ippool.GetLabels()[ipPoolImportNamespaceLabel], owner)
+	}
+
+	labels := maps.Clone(ippool.GetLabels())
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	maps.Copy(labels, ipPoolLabels(importCR, prefix))
+
+	if equality.Semantic.DeepEqual(*ipPoolSpec(ippool), spec) && equality.Semantic.DeepEqual(ippool.GetLabels(), labels) {
+		logger.Info("IPPool is up to date", "name", ippoolName)
+		return nil
+	}
+
+	if err := r.checkIPPoolChange(ctx, ippool, spec); err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(ippool.DeepCopyObject().(client.Object))
+	ippool.SetLabels(labels)
+	*ipPoolSpec(ippool) = spec
+	err = r.k8sClient.Patch(ctx, ippool, patch)
+	if err != nil {
+		logger.Error(err, "unable to patch IPPool", "name", ippoolName)
+		return err
+	}
 
-	logger.Info("IPPool already exists, skipping", "name", ippoolName)
+	logger.Info("IPPool updated", "name", ippoolName)
 	return nil
 }
 
-// generateIPPoolName generates the name of the IPPool based on the given name prefix and prefix information.
+// generateIPPoolSpec generates the desired spec of the IPPool of the given prefix.
+func generateIPPoolSpec(ipPoolSelector *argorav1alpha1.IPPoolSelector, prefix *models.Prefix) (ipamv1alpha2.InClusterIPPoolSpec, error) {
+	net, gateway, mask, err := generateNetGatewayIP(prefix)
+	if err != nil {
+		return ipamv1alpha2.InClusterIPPoolSpec{}, fmt.Errorf("unable to generate gateway IP for prefix %s: %w", prefix.Prefix, err)
+	}
+
+	spec := ipamv1alpha2.InClusterIPPoolSpec{
+		Addresses: []string{prefix.Prefix},
+		Gateway:   gateway,
+		Prefix:    mask,
+	}
+
+	if ipPoolSelector.ExcludeMask != nil {
+		if mask >= *ipPoolSelector.ExcludeMask {
+			return ipamv1alpha2.InClusterIPPoolSpec{}, fmt.Errorf("excludeMask (%d) must be longer than prefix mask (%d) for prefix %s", *ipPoolSelector.ExcludeMask, mask, prefix.Prefix)
+		}
+		if bitLen := net.BitLen(); *ipPoolSelector.ExcludeMask > bitLen {
+			return ipamv1alpha2.InClusterIPPoolSpec{}, fmt.Errorf("excludeMask (%d) must not be longer than %d for prefix %s", *ipPoolSelector.ExcludeMask, bitLen, prefix.Prefix)
+		}
+		spec.ExcludedAddresses = []string{fmt.Sprintf("%s/%d", net, *ipPoolSelector.ExcludeMask)}
+	}
+
+	if ipPoolSelector.ExcludedAddresses != nil {
+		spec.ExcludedAddresses = append(spec.ExcludedAddresses, ipPoolSelector.ExcludedAddresses...)
+	}
+
+	if ipPoolSelector.ExcludeLastNAddresses != nil {
+		prefixParsed, err := netip.ParsePrefix(prefix.Prefix)
+		if err != nil {
+			return ipamv1alpha2.InClusterIPPoolSpec{}, fmt.Errorf("unable to parse prefix %s: %w", prefix.Prefix, err)
+		}
+		lastN := getLastNIPs(prefixParsed, *ipPoolSelector.ExcludeLastNAddresses)
+
+		for _, ip := range lastN {
+			spec.ExcludedAddresses = append(spec.ExcludedAddresses, ip.String())
+		}
+	}
+
+	return spec, nil
+}
+
+// ipPoolLabels returns the labels tracking the IPPoolImport and NetBox prefix an IPPool is generated from.
+// GlobalInClusterIPPools are cluster scoped and InClusterIPPools may live in other namespaces,
+// so they cannot be owned by the IPPoolImport.
+func ipPoolLabels(importCR *argorav1alpha1.IPPoolImport, prefix *models.Prefix) map[string]string {
+	labels := ipPoolImportLabels(importCR)
+	labels[ipPoolPrefixIDLabel] = strconv.Itoa(prefix.ID)
+	return labels
+}
+
+// ipPoolImportLabels returns the labels selecting all IPPools generated by the IPPoolImport.
+func ipPoolImportLabels(importCR *argorav1alpha1.IPPoolImport) map[string]string {
+	return map[string]string{
+		ipPoolImportNameLabel:      importCR.Name,
+		ipPoolImportNamespaceLabel: importCR.Namespace,
+	}
+}
+
+// generateIPPoolName generates the name of the IPPool from the name template of the selector, or based on the given
+// name prefix and prefix information. Names of IPPools for IPv6 prefixes not using a template are suffixed with -v6.
 func generateIPPoolName(ipPoolSelector *argorav1alpha1.IPPoolSelector, prefix *models.Prefix) (string, error) {
+	prefixParsed, err := netip.ParsePrefix(prefix.Prefix)
+	if err != nil {
+		return "", err
+	}
+
+	if ipPoolSelector.NameTemplate != "" {
+		return renderIPPoolName(ipPoolSelector.NameTemplate, newIPPoolNameData(ipPoolSelector, prefix, prefixParsed))
+	}
+
+	name, err := generateIPPoolBaseName(ipPoolSelector, prefix)
+	if err != nil {
+		return "", err
+	}
+
+	if prefixParsed.Addr().Is6() {
+		name += ipv6PoolNameSuffix
+	}
+
+	return name, nil
+}
+
+func generateIPPoolBaseName(ipPoolSelector *argorav1alpha1.IPPoolSelector, prefix *models.Prefix) (string, error) {
 	if ipPoolSelector.NameOverride != "" {
 		return ipPoolSelector.NameOverride, nil
 	}
@@ -255,12 +479,21 @@ func generateIPPoolName(ipPoolSelector *argorav1alpha1.IPPoolSelector, prefix *m
 }
 
 // generateNetGatewayIP generates the network address and gateway IP from the given prefix.
-func generateNetGatewayIP(prefix *models.Prefix) (net, gw string, mask int, err error) {
+// The gateway is the first address after the network address, for IPv4 and IPv6 prefixes alike.
+// It is overridden by the gateway documented in NetBox, see resolveGateway.
+func generateNetGatewayIP(prefix *models.Prefix) (net netip.Addr, gw string, mask int, err error) {
 	prefixParsed, err := netip.ParsePrefix(prefix.Prefix)
 	if err != nil {
-		return "", "", 0, err
+		return netip.Addr{}, "", 0, err
+	}
+	prefixParsed = prefixParsed.Masked()
+
+	gateway := prefixParsed.Addr().Next()
+	if !prefixParsed.Contains(gateway) {
+		return netip.Addr{}, "", 0, fmt.Errorf("prefix %s has no room for a gateway", prefix.Prefix)
 	}
-	return prefixParsed.Addr().String(), prefixParsed.Addr().Next().String(), prefixParsed.Bits(), nil
+
+	return prefixParsed.Addr(), gateway.String(), prefixParsed.Bits(), nil
 }
 
 // getLastNIPs returns the last N IPs in the given prefix.
@@ -278,19 +511,19 @@ func getLastNIPs(prefix netip.Prefix, n int) []netip.Addr {
 	return ips
 }
 
-// lastAddrInPrefix returns the last address in the given prefix.
-//
-//nolint:gosec
+// lastAddrInPrefix returns the last address in the given prefix, i.e. the address with all host bits set.
 func lastAddrInPrefix(p netip.Prefix) netip.Addr {
 	p = p.Masked()
-	addr := p.Addr().As4()
+	addr := p.Addr().AsSlice()
 	bits := p.Bits()
-	mask := uint32(0xFFFFFFFF) >> uint32(bits)
-	last := (uint32(addr[0])<<24 | uint32(addr[1])<<16 | uint32(addr[2])<<8 | uint32(addr[3])) | mask
-	return netip.AddrFrom4([4]byte{
-		byte(last >> 24),
-		byte(last >> 16),
-		byte(last >> 8),
-		byte(last),
-	})
+	for i := range addr {
+		if bits >= 8 {
+			bits -= 8
+			continue
+		}
+		addr[i] |= byte(0xFF) >> bits
+		bits = 0
+	}
+	last, _ := netip.AddrFromSlice(addr)
+	return last
 }
@@ -6,6 +6,9 @@ package controller
 import (
 	"context"
 	"errors"
+	"net/netip"
+	"net/url"
+	"slices"
 	"time"
 
 	argorav1alpha1 "github.com/sapcc/argora/api/v1alpha1"
@@ -14,14 +17,20 @@ import (
 
 	"github.com/sapcc/argora/internal/controller/mock"
 	"github.com/sapcc/argora/internal/credentials"
+	"github.com/sapcc/argora/internal/netbox/ipam"
 	"github.com/sapcc/argora/internal/status"
 
 	ipamv1alpha2 "sigs.k8s.io/cluster-api-ipam-provider-in-cluster/api/v1alpha2"
+	ipamv1 "sigs.k8s.io/cluster-api/api/ipam/v1beta2"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -105,13 +114,18 @@ var _ = Describe("IPPoolImport Controller", func() {
 				ReturnError:        false,
 				VirtualizationMock: &mock.VirtualizationMock{},
 				DCIMMock:           &mock.DCIMMock{},
-				IPAMMock:           &mock.IPAMMock{},
-				ExtrasMock:         &mock.ExtrasMock{},
+				IPAMMock: &mock.IPAMMock{
+					GetIPAddressesFunc: func(_ context.Context, _ ...ipam.ListIPAddressesRequestOption) ([]models.IPAddress, error) {
+						return nil, nil
+					},
+				},
+				ExtrasMock: &mock.ExtrasMock{},
 			}
 
-			netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesByRegionRoleFunc = func(region, role string) ([]models.Prefix, error) {
-				Expect(region).To(Equal(regionName))
-				Expect(role).To(Equal(roleName))
+			netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesFunc = func(_ context.Context, opts ...ipam.ListPrefixesRequestOption) ([]models.Prefix, error) {
+				req := ipam.NewListPrefixesRequest(opts...).BuildRequest()
+				Expect(req.Region).To(Equal(regionName))
+				Expect(req.Role).To(Equal(roleName))
 				return []models.Prefix{
 					{
 						ID:     1,
@@ -161,8 +175,10 @@ var _ = Describe("IPPoolImport Controller", func() {
 						IPPools: []*argorav1alpha1.IPPoolSelector{
 							{
 								NamePrefix: namePrefix,
-								Region:     regionName,
-								Role:       roleName,
+								PrefixFilter: argorav1alpha1.PrefixFilter{
+									Region: regionName,
+									Role:   roleName,
+								},
 							},
 						},
 					},
@@ -187,6 +203,14 @@ var _ = Describe("IPPoolImport Controller", func() {
 				Expect(k8sClient.Delete(ctx, &pool)).To(Succeed())
 			}
 
+			inClusterIPPoolList := &ipamv1alpha2.InClusterIPPoolList{}
+			Expect(k8sClient.List(ctx, inClusterIPPoolList)).To(Succeed())
+
+			By("delete created InClusterIPPools")
+			for _, pool := range inClusterIPPoolList.Items {
+				Expect(k8sClient.Delete(ctx, &pool)).To(Succeed())
+			}
+
 			By("delete IPPoolImport CR")
 			Expect(k8sClient.Delete(ctx, ipPoolImport)).To(Succeed())
 		})
@@ -204,7 +228,7 @@ var _ = Describe("IPPoolImport Controller", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(res.RequeueAfter).To(Equal(reconcileInterval))
 
-			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesByRegionRoleCalls).To(Equal(1))
+			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesCalls).To(Equal(1))
 
 			// Fetch and validate first pool
 			pool1 := &ipamv1alpha2.GlobalInClusterIPPool{}
@@ -224,6 +248,11 @@ var _ = Describe("IPPoolImport Controller", func() {
 		It("should successfully create a GlobalInClusterIPPool CR with Name Override", func() {
 			// given
 			netBoxMock := prepareNetboxMock()
+			netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesFunc = func(_ context.Context, _ ...ipam.ListPrefixesRequestOption) ([]models.Prefix, error) {
+				return []models.Prefix{
+					{ID: 1, Prefix: iPPoolPrefix1, Site: models.Site{ID: 1, Name: iPPoolPrefixSite1, Slug: iPPoolPrefixSite1}},
+				}, nil
+			}
 
 			By("update IPPoolImport CR to add Name Override")
 			err := k8sClient.Get(ctx, typeNamespacedIPPoolImportName, ipPoolImport)
@@ -244,7 +273,7 @@ var _ = Describe("IPPoolImport Controller", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(res.RequeueAfter).To(Equal(reconcileInterval))
 
-			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesByRegionRoleCalls).To(Equal(1))
+			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesCalls).To(Equal(1))
 
 			pool := &ipamv1alpha2.GlobalInClusterIPPool{}
 			err = k8sClient.Get(ctx, types.NamespacedName{
@@ -257,6 +286,113 @@ var _ = Describe("IPPoolImport Controller", func() {
 			expectStatus(argorav1alpha1.Ready, typeNamespacedIPPoolImportName, "")
 		})
 
+		It("should report name collisions of prefixes sharing a Name Override", func() {
+			// given
+			netBoxMock := prepareNetboxMock()
+
+			By("update IPPoolImport CR to add Name Override")
+			Expect(k8sClient.Get(ctx, typeNamespacedIPPoolImportName, ipPoolImport)).To(Succeed())
+			ipPoolImport.Spec.IPPools[0].NameOverride = "ippool-override-name"
+			ipPoolImport.Spec.IPPools[0].NamePrefix = ""
+			Expect(k8sClient.Update(ctx, ipPoolImport)).To(Succeed())
+
+			controllerReconciler := createIPPoolImportReconciler(netBoxMock, fileReaderMock)
+
+			// when
+			By("reconciling IPPoolImport CR")
+			res, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedIPPoolImportName})
+
+			// then
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.RequeueAfter).To(Equal(reconcileInterval))
+
+			pool := &ipamv1alpha2.GlobalInClusterIPPool{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "ippool-override-name"}, pool)).To(Succeed())
+			expectIPPool(pool, "ippool-override-name", iPPoolPrefix1, iPPoolPrefixMask1, nil)
+
+			By("checking the collision is reported")
+			description := "ippool name collision: ippool ippool-override-name of prefix 10.10.20.0/25 is already generated for prefix 10.10.10.0/24"
+			Expect(k8sClient.Get(ctx, typeNamespacedIPPoolImportName, ipPoolImport)).To(Succeed())
+			Expect(ipPoolImport.Status.State).To(Equal(argorav1alpha1.Error))
+			Expect(ipPoolImport.Status.Description).To(Equal(description))
+			Expect(*ipPoolImport.Status.Conditions).To(HaveLen(1))
+			Expect((*ipPoolImport.Status.Conditions)[0].Reason).To(Equal(string(argorav1alpha1.ConditionReasonIPPoolImportNameCollision)))
+			Expect((*ipPoolImport.Status.Conditions)[0].Message).To(Equal(description))
+		})
+
+		It("should successfully create GlobalInClusterIPPool CRs with Name Template", func() {
+			// given
+			netBoxMock := prepareNetboxMock()
+
+			By("update IPPoolImport CR to add Name Template")
+			Expect(k8sClient.Get(ctx, typeNamespacedIPPoolImportName, ipPoolImport)).To(Succeed())
+			ipPoolImport.Spec.IPPools[0].NameTemplate = "pool-{{ .Site }}-{{ .Mask }}"
+			ipPoolImport.Spec.IPPools[0].NamePrefix = ""
+			Expect(k8sClient.Update(ctx, ipPoolImport)).To(Succeed())
+
+			controllerReconciler := createIPPoolImportReconciler(netBoxMock, fileReaderMock)
+
+			// when
+			By("reconciling IPPoolImport CR")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedIPPoolImportName})
+
+			// then
+			Expect(err).ToNot(HaveOccurred())
+
+			pool := &ipamv1alpha2.GlobalInClusterIPPool{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "pool-site-1a-24"}, pool)).To(Succeed())
+			expectIPPool(pool, "pool-site-1a-24", iPPoolPrefix1, iPPoolPrefixMask1, nil)
+
+			pool = &ipamv1alpha2.GlobalInClusterIPPool{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "pool-site-1b-25"}, pool)).To(Succeed())
+			expectIPPool(pool, "pool-site-1b-25", iPPoolPrefix2, iPPoolPrefixMask2, nil)
+
+			expectStatus(argorav1alpha1.Ready, typeNamespacedIPPoolImportName, "")
+		})
+
+		It("should successfully create InClusterIPPool CRs in the target namespaces", func() {
+			// given
+			netBoxMock := prepareNetboxMock()
+
+			By("create target namespaces")
+			for _, namespace := range []string{iPPoolPrefixSite1, iPPoolPrefixSite2} {
+				err := k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})
+				if !apierrors.IsAlreadyExists(err) {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			}
+
+			By("update IPPoolImport CR to generate InClusterIPPools")
+			Expect(k8sClient.Get(ctx, typeNamespacedIPPoolImportName, ipPoolImport)).To(Succeed())
+			ipPoolImport.Spec.IPPools[0].PoolKind = argorav1alpha1.IPPoolKindInClusterIPPool
+			ipPoolImport.Spec.IPPools[0].TargetNamespace = "{{ .Site }}"
+			Expect(k8sClient.Update(ctx, ipPoolImport)).To(Succeed())
+
+			controllerReconciler := createIPPoolImportReconciler(netBoxMock, fileReaderMock)
+
+			// when
+			By("reconciling IPPoolImport CR")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedIPPoolImportName})
+
+			// then
+			Expect(err).ToNot(HaveOccurred())
+
+			pool := &ipamv1alpha2.InClusterIPPool{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: iPPoolName1, Namespace: iPPoolPrefixSite1}, pool)).To(Succeed())
+			Expect(pool.Labels).To(HaveKeyWithValue("ippoolimport.argora.cloud.sap/name", resourceName))
+			Expect(pool.Spec.Addresses).To(Equal([]string{iPPoolPrefix1}))
+
+			pool = &ipamv1alpha2.InClusterIPPool{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: iPPoolName2, Namespace: iPPoolPrefixSite2}, pool)).To(Succeed())
+			Expect(pool.Spec.Addresses).To(Equal([]string{iPPoolPrefix2}))
+
+			globalPools := &ipamv1alpha2.GlobalInClusterIPPoolList{}
+			Expect(k8sClient.List(ctx, globalPools)).To(Succeed())
+			Expect(globalPools.Items).To(BeEmpty())
+
+			expectStatus(argorav1alpha1.Ready, typeNamespacedIPPoolImportName, "")
+		})
+
 		It("should successfully create a GlobalInClusterIPPool CR with Excluded Mask field", func() {
 			// given
 			netBoxMock := prepareNetboxMock()
@@ -280,7 +416,7 @@ var _ = Describe("IPPoolImport Controller", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(res.RequeueAfter).To(Equal(reconcileInterval))
 
-			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesByRegionRoleCalls).To(Equal(1))
+			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesCalls).To(Equal(1))
 
 			pool := &ipamv1alpha2.GlobalInClusterIPPool{}
 			err = k8sClient.Get(ctx, typeNamespacedIPPoolName1, pool)
@@ -315,7 +451,7 @@ var _ = Describe("IPPoolImport Controller", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(res.RequeueAfter).To(Equal(reconcileInterval))
 
-			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesByRegionRoleCalls).To(Equal(1))
+			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesCalls).To(Equal(1))
 
 			pool := &ipamv1alpha2.GlobalInClusterIPPool{}
 			err = k8sClient.Get(ctx, typeNamespacedIPPoolName1, pool)
@@ -353,7 +489,7 @@ var _ = Describe("IPPoolImport Controller", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(res.RequeueAfter).To(Equal(reconcileInterval))
 
-			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesByRegionRoleCalls).To(Equal(1))
+			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesCalls).To(Equal(1))
 
 			pool := &ipamv1alpha2.GlobalInClusterIPPool{}
 			err = k8sClient.Get(ctx, typeNamespacedIPPoolName1, pool)
@@ -377,9 +513,10 @@ var _ = Describe("IPPoolImport Controller", func() {
 
 			// Mock a /30 prefix to limit total addresses to 4
 			netBoxMock := prepareNetboxMock()
-			netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesByRegionRoleFunc = func(region, role string) ([]models.Prefix, error) {
-				Expect(region).To(Equal(regionName))
-				Expect(role).To(Equal(roleName))
+			netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesFunc = func(_ context.Context, opts ...ipam.ListPrefixesRequestOption) ([]models.Prefix, error) {
+				req := ipam.NewListPrefixesRequest(opts...).BuildRequest()
+				Expect(req.Region).To(Equal(regionName))
+				Expect(req.Role).To(Equal(roleName))
 				return []models.Prefix{
 					{
 						ID:     1,
@@ -409,7 +546,7 @@ var _ = Describe("IPPoolImport Controller", func() {
 			// then
 			Expect(err).ToNot(HaveOccurred())
 			Expect(res.RequeueAfter).To(Equal(reconcileInterval))
-			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesByRegionRoleCalls).To(Equal(1))
+			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesCalls).To(Equal(1))
 
 			pool := &ipamv1alpha2.GlobalInClusterIPPool{}
 			Expect(k8sClient.Get(ctx, typeNamespacedIPPoolName, pool)).To(Succeed())
@@ -449,7 +586,7 @@ var _ = Describe("IPPoolImport Controller", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(res.RequeueAfter).To(Equal(reconcileInterval))
 
-			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesByRegionRoleCalls).To(Equal(1))
+			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesCalls).To(Equal(1))
 
 			pool := &ipamv1alpha2.GlobalInClusterIPPool{}
 			err = k8sClient.Get(ctx, typeNamespacedIPPoolName1, pool)
@@ -477,7 +614,7 @@ var _ = Describe("IPPoolImport Controller", func() {
 			By("reconciling IPPoolImport CR")
 			res, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedIPPoolImportName})
 
-			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesByRegionRoleCalls).To(Equal(1))
+			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesCalls).To(Equal(1))
 
 			// then
 			Expect(err).To(HaveOccurred())
@@ -511,8 +648,10 @@ var _ = Describe("IPPoolImport Controller", func() {
 					IPPools: []*argorav1alpha1.IPPoolSelector{
 						{
 							NamePrefix: computeNamePrefix,
-							Region:     computeRegion,
-							Role:       computeRole,
+							PrefixFilter: argorav1alpha1.PrefixFilter{
+								Region: computeRegion,
+								Role:   computeRole,
+							},
 						},
 					},
 				},
@@ -524,9 +663,10 @@ var _ = Describe("IPPoolImport Controller", func() {
 				Expect(k8sClient.Delete(ctx, computeIPPoolImportCR)).To(Succeed())
 			}()
 
-			netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesByRegionRoleFunc = func(region, role string) ([]models.Prefix, error) {
-				Expect(region).To(Equal(computeRegion))
-				Expect(role).To(Equal(computeRole))
+			netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesFunc = func(_ context.Context, opts ...ipam.ListPrefixesRequestOption) ([]models.Prefix, error) {
+				req := ipam.NewListPrefixesRequest(opts...).BuildRequest()
+				Expect(req.Region).To(Equal(computeRegion))
+				Expect(req.Role).To(Equal(computeRole))
 				return []models.Prefix{
 					{
 						ID:     1,
@@ -554,7 +694,7 @@ var _ = Describe("IPPoolImport Controller", func() {
 			By("reconciling IPPoolImport CR")
 			res, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: computePoolName, Namespace: resourceNamespace}})
 
-			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesByRegionRoleCalls).To(Equal(1))
+			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesCalls).To(Equal(1))
 
 			// then
 			Expect(err).ToNot(HaveOccurred())
@@ -568,65 +708,1220 @@ var _ = Describe("IPPoolImport Controller", func() {
 			expectStatus(argorav1alpha1.Ready, types.NamespacedName{Name: computePoolName, Namespace: resourceNamespace}, "")
 		})
 
-		It("should return an error if credentials reload fails", func() {
+		It("should create one GlobalInClusterIPPool CR per address family for dual-stack prefix roles", func() {
 			// given
 			netBoxMock := prepareNetboxMock()
-			fileReaderMockToError := &mock.FileReaderMock{
-				FileContent: make(map[string]string),
-				ReturnError: true,
+			netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesFunc = func(_ context.Context, _ ...ipam.ListPrefixesRequestOption) ([]models.Prefix, error) {
+				return []models.Prefix{
+					{
+						ID:     1,
+						Prefix: iPPoolPrefix1,
+						Site:   models.Site{ID: 1, Name: iPPoolPrefixSite1, Slug: iPPoolPrefixSite1},
+					},
+					{
+						ID:     3,
+						Prefix: "2001:db8:10::/64",
+						Site:   models.Site{ID: 1, Name: iPPoolPrefixSite1, Slug: iPPoolPrefixSite1},
+					},
+				}, nil
 			}
-			controllerReconciler := createIPPoolImportReconciler(netBoxMock, fileReaderMockToError)
+
+			excludeMask = 120
+			excludedLastNAddress := 2
+			Expect(k8sClient.Get(ctx, typeNamespacedIPPoolImportName, ipPoolImport)).To(Succeed())
+			ipPoolImport.Spec.IPPools[0].ExcludeLastNAddresses = &excludedLastNAddress
+			Expect(k8sClient.Update(ctx, ipPoolImport)).To(Succeed())
+
+			controllerReconciler := createIPPoolImportReconciler(netBoxMock, fileReaderMock)
 
 			// when
 			By("reconciling IPPoolImport CR")
 			res, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedIPPoolImportName})
 
 			// then
-			Expect(err).To(HaveOccurred())
-			Expect(err).To(MatchError("unable to read credentials.json: error"))
-			Expect(res.RequeueAfter).To(Equal(0 * time.Second))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.RequeueAfter).To(Equal(reconcileInterval))
 
-			expectStatus(argorav1alpha1.Error, typeNamespacedIPPoolImportName, "unable to read credentials.json: error")
+			pool4 := &ipamv1alpha2.GlobalInClusterIPPool{}
+			Expect(k8sClient.Get(ctx, typeNamespacedIPPoolName1, pool4)).To(Succeed())
+			expectIPPool(pool4, iPPoolName1, iPPoolPrefix1, iPPoolPrefixMask1, []string{"10.10.10.254", "10.10.10.255"})
+			Expect(pool4.Spec.Gateway).To(Equal("10.10.10.1"))
+
+			pool6 := &ipamv1alpha2.GlobalInClusterIPPool{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: iPPoolName1 + "-v6"}, pool6)).To(Succeed())
+			expectIPPool(pool6, iPPoolName1+"-v6", "2001:db8:10::/64", 64,
+				[]string{"2001:db8:10::ffff:ffff:ffff:fffe", "2001:db8:10::ffff:ffff:ffff:ffff"})
+			Expect(pool6.Spec.Gateway).To(Equal("2001:db8:10::1"))
+
+			expectStatus(argorav1alpha1.Ready, typeNamespacedIPPoolImportName, "")
 		})
 
-		It("should return an error if netbox reload fails", func() {
+		It("should return an error if excluded address mask is longer than the address length", func() {
 			// given
-			controllerReconciler := createIPPoolImportReconciler(&mock.NetBoxMock{ReturnError: true}, fileReaderMock)
+			netBoxMock := prepareNetboxMock()
+			excludeMask = 33
+
+			Expect(k8sClient.Get(ctx, typeNamespacedIPPoolImportName, ipPoolImport)).To(Succeed())
+			ipPoolImport.Spec.IPPools[0].ExcludeMask = &excludeMask
+			Expect(k8sClient.Update(ctx, ipPoolImport)).To(Succeed())
+
+			controllerReconciler := createIPPoolImportReconciler(netBoxMock, fileReaderMock)
+
+			// when
+			By("reconciling IPPoolImport CR")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedIPPoolImportName})
+
+			// then
+			Expect(err).To(MatchError("excludeMask (33) must not be longer than 32 for prefix 10.10.10.0/24"))
+		})
+
+		It("should update an existing GlobalInClusterIPPool CR", func() {
+			// given
+			netBoxMock := prepareNetboxMock()
+			controllerReconciler := createIPPoolImportReconciler(netBoxMock, fileReaderMock)
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedIPPoolImportName})
+			Expect(err).ToNot(HaveOccurred())
+
+			By("update IPPoolImport CR to add ExcludedAddresses")
+			Expect(k8sClient.Get(ctx, typeNamespacedIPPoolImportName, ipPoolImport)).To(Succeed())
+			ipPoolImport.Spec.IPPools[0].ExcludedAddresses = []string{"10.10.10.30"}
+			Expect(k8sClient.Update(ctx, ipPoolImport)).To(Succeed())
 
 			// when
 			By("reconciling IPPoolImport CR")
 			res, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedIPPoolImportName})
 
 			// then
-			Expect(err).To(HaveOccurred())
-			Expect(err).To(MatchError("unable to reload netbox"))
-			Expect(res.RequeueAfter).To(Equal(0 * time.Second))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.RequeueAfter).To(Equal(reconcileInterval))
 
-			expectStatus(argorav1alpha1.Error, typeNamespacedIPPoolImportName, "unable to reload netbox")
+			pool := &ipamv1alpha2.GlobalInClusterIPPool{}
+			Expect(k8sClient.Get(ctx, typeNamespacedIPPoolName1, pool)).To(Succeed())
+			expectIPPool(pool, iPPoolName1, iPPoolPrefix1, iPPoolPrefixMask1, []string{"10.10.10.30"})
+			Expect(pool.Labels).To(SatisfyAll(
+				HaveKeyWithValue("ippoolimport.argora.cloud.sap/name", resourceName),
+				HaveKeyWithValue("ippoolimport.argora.cloud.sap/namespace", resourceNamespace),
+			))
+
+			expectStatus(argorav1alpha1.Ready, typeNamespacedIPPoolImportName, "")
 		})
 
-		It("should return an error if GetPrefixesByRegionRole fails", func() {
+		It("should refuse to exclude addresses already allocated from an existing GlobalInClusterIPPool CR", func() {
 			// given
 			netBoxMock := prepareNetboxMock()
-			netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesByRegionRoleFunc = func(region, role string) ([]models.Prefix, error) {
-				Expect(region).To(Equal("region1"))
-				Expect(role).To(Equal("role1"))
-				return nil, errors.New("unable to find prefixes")
+			controllerReconciler := createIPPoolImportReconciler(netBoxMock, fileReaderMock)
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedIPPoolImportName})
+			Expect(err).ToNot(HaveOccurred())
+
+			ipAddress := &ipamv1.IPAddress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "allocated-address",
+					Namespace: resourceNamespace,
+				},
+				Spec: ipamv1.IPAddressSpec{
+					Address:  "10.10.10.30",
+					Prefix:   ptr.To(int32(iPPoolPrefixMask1)),
+					ClaimRef: ipamv1.IPAddressClaimReference{Name: "allocated-claim"},
+					PoolRef: ipamv1.IPPoolReference{
+						APIGroup: "ipam.cluster.x-k8s.io",
+						Kind:     "GlobalInClusterIPPool",
+						Name:     iPPoolName1,
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, ipAddress)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, ipAddress)).To(Succeed())
+			})
+
+			By("update IPPoolImport CR to exclude the allocated address")
+			Expect(k8sClient.Get(ctx, typeNamespacedIPPoolImportName, ipPoolImport)).To(Succeed())
+			ipPoolImport.Spec.IPPools[0].ExcludedAddresses = []string{"10.10.10.16-10.10.10.31"}
+			Expect(k8sClient.Update(ctx, ipPoolImport)).To(Succeed())
+
+			// when
+			By("reconciling IPPoolImport CR")
+			res, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedIPPoolImportName})
+
+			// then
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.RequeueAfter).To(Equal(reconcileInterval))
+
+			pool := &ipamv1alpha2.GlobalInClusterIPPool{}
+			Expect(k8sClient.Get(ctx, typeNamespacedIPPoolName1, pool)).To(Succeed())
+			Expect(pool.Spec.ExcludedAddresses).To(BeEmpty())
+
+			By("checking the refused change is reported")
+			description := "unsafe ippool change: allocated address 10.10.10.30 of ippool ippool-site-1a would be excluded"
+			Expect(k8sClient.Get(ctx, typeNamespacedIPPoolImportName, ipPoolImport)).To(Succeed())
+			Expect(ipPoolImport.Status.State).To(Equal(argorav1alpha1.Error))
+			Expect(ipPoolImport.Status.Description).To(Equal(description))
+			Expect(*ipPoolImport.Status.Conditions).To(HaveLen(1))
+			Expect((*ipPoolImport.Status.Conditions)[0].Status).To(Equal(metav1.ConditionFalse))
+			Expect((*ipPoolImport.Status.Conditions)[0].Reason).To(Equal(string(argorav1alpha1.ConditionReasonIPPoolImportUnsafeChange)))
+			Expect((*ipPoolImport.Status.Conditions)[0].Message).To(Equal(description))
+
+			By("checking the other IPPool is still reconciled")
+			pool2 := &ipamv1alpha2.GlobalInClusterIPPool{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: iPPoolName2}, pool2)).To(Succeed())
+			Expect(pool2.Spec.ExcludedAddresses).To(ConsistOf("10.10.10.16-10.10.10.31"))
+		})
+
+		It("should exclude addresses and ranges documented in NetBox", func() {
+			// given
+			netBoxMock := prepareNetboxMock()
+			ipamMock := netBoxMock.IPAMMock.(*mock.IPAMMock)
+			var prefixQueries int
+			ipamMock.GetIPAddressesFunc = func(_ context.Context, opts ...ipam.ListIPAddressesRequestOption) ([]models.IPAddress, error) {
+				query := ipam.NewListIPAddressesRequest(opts...).BuildQuery()
+				if query.Get("tag") != "" || query.Has("role") {
+					return nil, nil
+				}
+				prefixQueries++
+				if query.Get("parent") != iPPoolPrefix1 {
+					return nil, nil
+				}
+				return []models.IPAddress{
+					{NestedIPAddress: models.NestedIPAddress{Address: "10.10.10.1/24"}, Status: models.IPAddressStatus{Value: "active"}, Role: models.IpamRole{Value: "anycast"}},
+					{NestedIPAddress: models.NestedIPAddress{Address: "10.10.10.5/24"}, Status: models.IPAddressStatus{Value: "reserved"}},
+					{NestedIPAddress: models.NestedIPAddress{Address: "10.10.10.7/24"}, Status: models.IPAddressStatus{Value: "deprecated"}},
+				}, nil
+			}
+			ipamMock.GetIPRangesInPrefixFunc = func(_ context.Context, prefix string, _ int) ([]ipam.IPRange, error) {
+				if prefix != iPPoolPrefix1 {
+					return nil, nil
+				}
+				return []ipam.IPRange{
+					{ID: 1, StartAddress: "10.10.10.100/24", EndAddress: "10.10.10.150/24", Status: models.IPAddressStatus{Value: "reserved"}},
+				}, nil
 			}
 
+			Expect(k8sClient.Get(ctx, typeNamespacedIPPoolImportName, ipPoolImport)).To(Succeed())
+			ipPoolImport.Spec.IPPools[0].ExcludedAddresses = []string{"10.10.10.30"}
+			ipPoolImport.Spec.IPPools[0].ExcludeNetboxAddresses = &argorav1alpha1.NetboxAddressExclusion{
+				Statuses: []string{"active", "reserved"},
+			}
+			Expect(k8sClient.Update(ctx, ipPoolImport)).To(Succeed())
+
+			controllerReconciler := createIPPoolImportReconciler(netBoxMock, fileReaderMock)
+
+			// when
+			By("reconciling IPPoolImport CR")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedIPPoolImportName})
+
+			// then
+			Expect(err).ToNot(HaveOccurred())
+			Expect(prefixQueries).To(Equal(2))
+			Expect(ipamMock.GetIPRangesInPrefixCalls).To(Equal(2))
+
+			pool := &ipamv1alpha2.GlobalInClusterIPPool{}
+			Expect(k8sClient.Get(ctx, typeNamespacedIPPoolName1, pool)).To(Succeed())
+			Expect(pool.Spec.ExcludedAddresses).To(Equal([]string{"10.10.10.30", "10.10.10.1", "10.10.10.100-10.10.10.150", "10.10.10.5"}))
+
+			expectStatus(argorav1alpha1.Ready, typeNamespacedIPPoolImportName, "")
+		})
+
+		It("should not exclude NetBox addresses already allocated from the GlobalInClusterIPPool CR", func() {
+			// given
+			netBoxMock := prepareNetboxMock()
+			ipamMock := netBoxMock.IPAMMock.(*mock.IPAMMock)
+			ipamMock.GetIPRangesInPrefixFunc = func(_ context.Context, _ string, _ int) ([]ipam.IPRange, error) {
+				return nil, nil
+			}
 			controllerReconciler := createIPPoolImportReconciler(netBoxMock, fileReaderMock)
 
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedIPPoolImportName})
+			Expect(err).ToNot(HaveOccurred())
+
+			ipAddress := &ipamv1.IPAddress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "allocated-address",
+					Namespace: resourceNamespace,
+				},
+				Spec: ipamv1.IPAddressSpec{
+					Address:  "10.10.10.30",
+					Prefix:   ptr.To(int32(iPPoolPrefixMask1)),
+					ClaimRef: ipamv1.IPAddressClaimReference{Name: "allocated-claim"},
+					PoolRef: ipamv1.IPPoolReference{
+						APIGroup: "ipam.cluster.x-k8s.io",
+						Kind:     "GlobalInClusterIPPool",
+						Name:     iPPoolName1,
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, ipAddress)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, ipAddress)).To(Succeed())
+			})
+
+			By("documenting the allocated address in NetBox")
+			ipamMock.GetIPAddressesFunc = func(_ context.Context, opts ...ipam.ListIPAddressesRequestOption) ([]models.IPAddress, error) {
+				query := ipam.NewListIPAddressesRequest(opts...).BuildQuery()
+				if query.Get("parent") != iPPoolPrefix1 || query.Get("tag") != "" || query.Has("role") {
+					return nil, nil
+				}
+				return []models.IPAddress{
+					{NestedIPAddress: models.NestedIPAddress{Address: "10.10.10.5/24"}, Status: models.IPAddressStatus{Value: "reserved"}},
+					{NestedIPAddress: models.NestedIPAddress{Address: "10.10.10.30/24"}, Status: models.IPAddressStatus{Value: "active"}},
+				}, nil
+			}
+
+			Expect(k8sClient.Get(ctx, typeNamespacedIPPoolImportName, ipPoolImport)).To(Succeed())
+			ipPoolImport.Spec.IPPools[0].ExcludeNetboxAddresses = &argorav1alpha1.NetboxAddressExclusion{}
+			Expect(k8sClient.Update(ctx, ipPoolImport)).To(Succeed())
+
 			// when
 			By("reconciling IPPoolImport CR")
 			res, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedIPPoolImportName})
 
 			// then
-			Expect(err).To(HaveOccurred())
-			Expect(err).To(MatchError("unable to find prefixes"))
-			Expect(res.RequeueAfter).To(Equal(0 * time.Second))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.RequeueAfter).To(Equal(reconcileInterval))
 
-			expectStatus(argorav1alpha1.Error, typeNamespacedIPPoolImportName, "unable to import prefix: unable to find prefixes")
+			pool := &ipamv1alpha2.GlobalInClusterIPPool{}
+			Expect(k8sClient.Get(ctx, typeNamespacedIPPoolName1, pool)).To(Succeed())
+			Expect(pool.Spec.ExcludedAddresses).To(Equal([]string{"10.10.10.5"}))
+
+			expectStatus(argorav1alpha1.Ready, typeNamespacedIPPoolImportName, "")
 		})
+
+		It("should use the gateway documented in NetBox", func() {
+			// given
+			netBoxMock := prepareNetboxMock()
+			ipamMock := netBoxMock.IPAMMock.(*mock.IPAMMock)
+			ipamMock.GetIPAddressesFunc = func(_ context.Context, opts ...ipam.ListIPAddressesRequestOption) ([]models.IPAddress, error) {
+				query := ipam.NewListIPAddressesRequest(opts...).BuildQuery()
+				if query.Get("parent") != iPPoolPrefix1 || query.Get("tag") != "gateway" {
+					return nil, nil
+				}
+				return []models.IPAddress{
+					{NestedIPAddress: models.NestedIPAddress{Address: "10.10.10.254/24"}, Tags: []models.NestedTag{{Slug: "gateway"}}},
+				}, nil
+			}
+			controllerReconciler := createIPPoolImportReconciler(netBoxMock, fileReaderMock)
+
+			// when
+			By("reconciling IPPoolImport CR")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedIPPoolImportName})
+
+			// then
+			Expect(err).ToNot(HaveOccurred())
+
+			pool := &ipamv1alpha2.GlobalInClusterIPPool{}
+			Expect(k8sClient.Get(ctx, typeNamespacedIPPoolName1, pool)).To(Succeed())
+			Expect(pool.Spec.Gateway).To(Equal("10.10.10.254"))
+
+			pool = &ipamv1alpha2.GlobalInClusterIPPool{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: iPPoolName2, Namespace: resourceNamespace}, pool)).To(Succeed())
+			Expect(pool.Spec.Gateway).To(Equal("10.10.20.1"))
+
+			By("querying NetBox for the tagged IP addresses only")
+			Expect(ipamMock.GetIPAddressesCalls).To(Equal(2))
+
+			expectStatus(argorav1alpha1.Ready, typeNamespacedIPPoolImportName, "")
+		})
+
+		It("should prune GlobalInClusterIPPool CRs whose prefix disappeared", func() {
+			// given
+			netBoxMock := prepareNetboxMock()
+			controllerReconciler := createIPPoolImportReconciler(netBoxMock, fileReaderMock)
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedIPPoolImportName})
+			Expect(err).ToNot(HaveOccurred())
+
+			pool2 := &ipamv1alpha2.GlobalInClusterIPPool{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: iPPoolName2}, pool2)).To(Succeed())
+			Expect(pool2.Labels).To(HaveKeyWithValue("ippoolimport.argora.cloud.sap/prefix-id", "2"))
+
+			By("removing the second prefix from NetBox")
+			netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesFunc = func(_ context.Context, _ ...ipam.ListPrefixesRequestOption) ([]models.Prefix, error) {
+				return []models.Prefix{
+					{
+						ID:     1,
+						Prefix: iPPoolPrefix1,
+						Site:   models.Site{ID: 1, Name: iPPoolPrefixSite1, Slug: iPPoolPrefixSite1},
+					},
+				}, nil
+			}
+
+			// when
+			By("reconciling IPPoolImport CR with the default deletion policy")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedIPPoolImportName})
+
+			// then
+			Expect(err).ToNot(HaveOccurred())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: iPPoolName2}, pool2)).To(Succeed())
+			Expect(k8sClient.Get(ctx, typeNamespacedIPPoolImportName, ipPoolImport)).To(Succeed())
+			Expect(ipPoolImport.Status.OrphanedPools).To(Equal([]argorav1alpha1.OrphanedIPPool{
+				{Name: iPPoolName2, PrefixID: "2", Message: "retained by deletion policy"},
+			}))
+
+			// when
+			By("reconciling IPPoolImport CR with the Delete deletion policy")
+			ipPoolImport.Spec.DeletionPolicy = argorav1alpha1.IPPoolDeletionPolicyDelete
+			Expect(k8sClient.Update(ctx, ipPoolImport)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedIPPoolImportName})
+
+			// then
+			Expect(err).ToNot(HaveOccurred())
+			Expect(apierrors.IsNotFound(k8sClient.Get(ctx, types.NamespacedName{Name: iPPoolName2}, pool2))).To(BeTrue())
+			Expect(k8sClient.Get(ctx, typeNamespacedIPPoolName1, &ipamv1alpha2.GlobalInClusterIPPool{})).To(Succeed())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedIPPoolImportName, ipPoolImport)).To(Succeed())
+			Expect(ipPoolImport.Status.OrphanedPools).To(BeEmpty())
+			expectStatus(argorav1alpha1.Ready, typeNamespacedIPPoolImportName, "")
+		})
+
+		It("should warn about IPPool selectors matching no prefixes", func() {
+			// given
+			netBoxMock := prepareNetboxMock()
+			prefixesFunc := netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesFunc
+			netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesFunc = func(_ context.Context, _ ...ipam.ListPrefixesRequestOption) ([]models.Prefix, error) {
+				return nil, nil
+			}
+			controllerReconciler := createIPPoolImportReconciler(netBoxMock, fileReaderMock)
+
+			// when
+			By("reconciling IPPoolImport CR")
+			res, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedIPPoolImportName})
+
+			// then
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.RequeueAfter).To(Equal(reconcileInterval))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedIPPoolImportName, ipPoolImport)).To(Succeed())
+			Expect(ipPoolImport.Status.State).To(Equal(argorav1alpha1.Ready))
+			condition := meta.FindStatusCondition(*ipPoolImport.Status.Conditions, string(argorav1alpha1.ConditionTypePrefixesMatched))
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(string(argorav1alpha1.ConditionReasonIPPoolImportNoPrefixesMatched)))
+			Expect(condition.Message).To(Equal("no prefixes match the ippool selectors " + namePrefix))
+
+			By("reconciling IPPoolImport CR with matching prefixes")
+			netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesFunc = prefixesFunc
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedIPPoolImportName})
+			Expect(err).ToNot(HaveOccurred())
+
+			expectStatus(argorav1alpha1.Ready, typeNamespacedIPPoolImportName, "")
+		})
+
+		It("should not import prefixes matching an exclude filter", func() {
+			// given
+			netBoxMock := prepareNetboxMock()
+			ipamMock := netBoxMock.IPAMMock.(*mock.IPAMMock)
+			prefixesFunc := ipamMock.GetPrefixesFunc
+			ipamMock.GetPrefixesFunc = func(ctx context.Context, opts ...ipam.ListPrefixesRequestOption) ([]models.Prefix, error) {
+				req := ipam.NewListPrefixesRequest(opts...).BuildRequest()
+				if req.Site == iPPoolPrefixSite2 {
+					return []models.Prefix{{ID: 2, Prefix: iPPoolPrefix2}}, nil
+				}
+				return prefixesFunc(ctx, opts...)
+			}
+
+			Expect(k8sClient.Get(ctx, typeNamespacedIPPoolImportName, ipPoolImport)).To(Succeed())
+			ipPoolImport.Spec.IPPools[0].Exclude = []argorav1alpha1.PrefixFilter{{Site: iPPoolPrefixSite2}}
+			Expect(k8sClient.Update(ctx, ipPoolImport)).To(Succeed())
+
+			controllerReconciler := createIPPoolImportReconciler(netBoxMock, fileReaderMock)
+
+			// when
+			By("reconciling IPPoolImport CR")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedIPPoolImportName})
+
+			// then
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ipamMock.GetPrefixesCalls).To(Equal(2))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedIPPoolName1, &ipamv1alpha2.GlobalInClusterIPPool{})).To(Succeed())
+			err = k8sClient.Get(ctx, types.NamespacedName{Name: iPPoolName2}, &ipamv1alpha2.GlobalInClusterIPPool{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+			expectStatus(argorav1alpha1.Ready, typeNamespacedIPPoolImportName, "")
+		})
+
+		It("should return an error if credentials reload fails", func() {
+			// given
+			netBoxMock := prepareNetboxMock()
+			fileReaderMockToError := &mock.FileReaderMock{
+				FileContent: make(map[string]string),
+				ReturnError: true,
+			}
+			controllerReconciler := createIPPoolImportReconciler(netBoxMock, fileReaderMockToError)
+
+			// when
+			By("reconciling IPPoolImport CR")
+			res, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedIPPoolImportName})
+
+			// then
+			Expect(err).To(HaveOccurred())
+			Expect(err).To(MatchError("unable to read credentials.json: error"))
+			Expect(res.RequeueAfter).To(Equal(0 * time.Second))
+
+			expectStatus(argorav1alpha1.Error, typeNamespacedIPPoolImportName, "unable to read credentials.json: error")
+		})
+
+		It("should return an error if netbox reload fails", func() {
+			// given
+			controllerReconciler := createIPPoolImportReconciler(&mock.NetBoxMock{ReturnError: true}, fileReaderMock)
+
+			// when
+			By("reconciling IPPoolImport CR")
+			res, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedIPPoolImportName})
+
+			// then
+			Expect(err).To(HaveOccurred())
+			Expect(err).To(MatchError("unable to reload netbox"))
+			Expect(res.RequeueAfter).To(Equal(0 * time.Second))
+
+			expectStatus(argorav1alpha1.Error, typeNamespacedIPPoolImportName, "unable to reload netbox")
+		})
+
+		It("should return an error if GetPrefixes fails", func() {
+			// given
+			netBoxMock := prepareNetboxMock()
+			netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesFunc = func(_ context.Context, opts ...ipam.ListPrefixesRequestOption) ([]models.Prefix, error) {
+				req := ipam.NewListPrefixesRequest(opts...).BuildRequest()
+				Expect(req.Region).To(Equal("region1"))
+				Expect(req.Role).To(Equal("role1"))
+				return nil, errors.New("unable to find prefixes")
+			}
+
+			controllerReconciler := createIPPoolImportReconciler(netBoxMock, fileReaderMock)
+
+			// when
+			By("reconciling IPPoolImport CR")
+			res, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedIPPoolImportName})
+
+			// then
+			Expect(err).To(HaveOccurred())
+			Expect(err).To(MatchError("unable to find prefixes"))
+			Expect(res.RequeueAfter).To(Equal(0 * time.Second))
+
+			expectStatus(argorav1alpha1.Error, typeNamespacedIPPoolImportName, "unable to import prefix: unable to find prefixes")
+		})
+	})
+})
+
+var _ = Describe("IPPoolImport prefix helpers", func() {
+	ctx := context.Background()
+
+	DescribeTable("lastAddrInPrefix",
+		func(prefix, last string) {
+			Expect(lastAddrInPrefix(netip.MustParsePrefix(prefix)).String()).To(Equal(last))
+		},
+		Entry("IPv4", "10.10.10.0/24", "10.10.10.255"),
+		Entry("IPv4 unaligned", "10.10.10.0/27", "10.10.10.31"),
+		Entry("IPv4 host", "10.10.10.7/32", "10.10.10.7"),
+		Entry("IPv6", "2001:db8::/64", "2001:db8::ffff:ffff:ffff:ffff"),
+		Entry("IPv6 unaligned", "2001:db8::/126", "2001:db8::3"),
+		Entry("IPv6 host", "2001:db8::5/128", "2001:db8::5"),
+	)
+
+	It("should return the last N IPv6 addresses", func() {
+		Expect(getLastNIPs(netip.MustParsePrefix("2001:db8::/126"), 2)).To(Equal([]netip.Addr{
+			netip.MustParseAddr("2001:db8::2"),
+			netip.MustParseAddr("2001:db8::3"),
+		}))
+	})
+
+	It("should derive network and gateway of IPv6 prefixes", func() {
+		net, gw, mask, err := generateNetGatewayIP(&models.Prefix{Prefix: "2001:db8:10::/64"})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(net.String()).To(Equal("2001:db8:10::"))
+		Expect(gw).To(Equal("2001:db8:10::1"))
+		Expect(mask).To(Equal(64))
+	})
+
+	It("should fail to derive a gateway for host prefixes", func() {
+		_, _, _, err := generateNetGatewayIP(&models.Prefix{Prefix: "2001:db8::5/128"})
+
+		Expect(err).To(MatchError("prefix 2001:db8::5/128 has no room for a gateway"))
+	})
+
+	DescribeTable("addressInRanges",
+		func(addr string, ranges []string, contained bool) {
+			inRanges, err := addressInRanges(netip.MustParseAddr(addr), ranges)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(inRanges).To(Equal(contained))
+		},
+		Entry("address", "10.10.10.5", []string{"10.10.10.4", "10.10.10.5"}, true),
+		Entry("prefix", "10.10.10.5", []string{"10.10.10.0/29"}, true),
+		Entry("range", "10.10.10.5", []string{"10.10.10.1-10.10.10.5"}, true),
+		Entry("IPv6 range", "2001:db8::5", []string{"2001:db8::1-2001:db8::10"}, true),
+		Entry("outside", "10.10.10.9", []string{"10.10.10.0/29", "10.10.10.1-10.10.10.5", "10.10.10.10"}, false),
+		Entry("empty", "10.10.10.9", nil, false),
+	)
+
+	It("should refuse IPPool changes stranding allocated addresses", func() {
+		ippool := &ipamv1alpha2.GlobalInClusterIPPool{ObjectMeta: metav1.ObjectMeta{Name: "ippool-site-1a"}}
+		reconciler := &IPPoolImportReconciler{k8sClient: createFakeClient(
+			&ipamv1.IPAddress{
+				ObjectMeta: metav1.ObjectMeta{Name: "address", Namespace: "default"},
+				Spec: ipamv1.IPAddressSpec{
+					Address: "10.10.10.200",
+					PoolRef: ipamv1.IPPoolReference{Kind: "GlobalInClusterIPPool", Name: "ippool-site-1a"},
+				},
+			},
+			&ipamv1.IPAddress{
+				ObjectMeta: metav1.ObjectMeta{Name: "other-pool-address", Namespace: "default"},
+				Spec: ipamv1.IPAddressSpec{
+					Address: "10.10.20.1",
+					PoolRef: ipamv1.IPPoolReference{Kind: "GlobalInClusterIPPool", Name: "ippool-site-2a"},
+				},
+			},
+		)}
+
+		Expect(reconciler.checkIPPoolChange(context.Background(), ippool, ipamv1alpha2.InClusterIPPoolSpec{
+			Addresses:         []string{"10.10.10.0/24"},
+			ExcludedAddresses: []string{"10.10.10.0/27"},
+			Gateway:           "10.10.10.1",
+		})).To(Succeed())
+
+		Expect(reconciler.checkIPPoolChange(context.Background(), ippool, ipamv1alpha2.InClusterIPPoolSpec{
+			Addresses: []string{"10.10.10.0/25"},
+			Gateway:   "10.10.10.1",
+		})).To(MatchError(errUnsafeIPPoolChange))
+
+		Expect(reconciler.checkIPPoolChange(context.Background(), ippool, ipamv1alpha2.InClusterIPPoolSpec{
+			Addresses: []string{"10.10.10.0/24"},
+			Gateway:   "10.10.10.200",
+		})).To(MatchError(ContainSubstring("would be the gateway")))
+	})
+
+	It("should filter NetBox addresses by status and role", func() {
+		ipamMock := &mock.IPAMMock{}
+		reconciler := &IPPoolImportReconciler{netBox: &mock.NetBoxMock{IPAMMock: ipamMock}}
+
+		excluded, err := reconciler.netboxExcludedAddresses(ctx, &argorav1alpha1.NetboxAddressExclusion{
+			Roles:    []string{"vip"},
+			IPRanges: ptr.To(false),
+		}, &models.Prefix{Prefix: "2001:db8::/64"}, []models.IPAddress{
+			{NestedIPAddress: models.NestedIPAddress{Address: "2001:db8::1/64"}, Role: models.IpamRole{Value: "vip"}},
+			{NestedIPAddress: models.NestedIPAddress{Address: "2001:db8::2/64"}, Role: models.IpamRole{Value: "loopback"}},
+			{NestedIPAddress: models.NestedIPAddress{Address: "2001:db8::1/64"}, Role: models.IpamRole{Value: "vip"}},
+			{NestedIPAddress: models.NestedIPAddress{Address: "2001:db8::3/64"}, Role: models.IpamRole{Value: "vip"}},
+		}, []netip.Addr{netip.MustParseAddr("2001:db8::3")})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(excluded).To(Equal([]string{"2001:db8::1"}))
+		Expect(ipamMock.GetIPRangesInPrefixCalls).To(BeZero())
+	})
+
+	It("should render IPPool name templates", func() {
+		selector := &argorav1alpha1.IPPoolSelector{
+			PrefixFilter: argorav1alpha1.PrefixFilter{Region: "QA-DE-1"},
+			NameTemplate: `transit-{{ trimPrefix .Site .Region }}{{ sub (atoi (submatch "(?i)compute(\\d+)" .Vlan)) 1 }}-{{ .Region }}{{ if eq .Family 6 }}-v6{{ end }}`,
+		}
+
+		name, err := generateIPPoolName(selector, &models.Prefix{
+			Prefix: "10.10.10.0/24",
+			Site:   models.Site{Slug: "qa-de-1a"},
+			Vlan:   models.NestedVLAN{Name: "Compute2 Transit"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(name).To(Equal("transit-a1-qa-de-1"))
+
+		name, err = generateIPPoolName(selector, &models.Prefix{
+			Prefix: "2001:db8::/64",
+			Site:   models.Site{Slug: "qa-de-1b", Region: models.NestedRegion{Slug: "qa-de-1"}},
+			Vlan:   models.NestedVLAN{Name: "compute1"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(name).To(Equal("transit-b0-qa-de-1-v6"))
+
+		name, err = generateIPPoolName(&argorav1alpha1.IPPoolSelector{
+			NameTemplate: "{{ .Role }}-{{ dnsLabel .Vrf }}-{{ replace .Network \".\" \"-\" }}",
+		}, &models.Prefix{
+			Prefix: "10.10.10.0/24",
+			Role:   models.Role{Slug: "transit"},
+			Vrf:    models.NestedVRF{Name: "CC Cloud01"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(name).To(Equal("transit-cc-cloud01-10-10-10-0"))
+	})
+
+	It("should refuse invalid IPPool name templates", func() {
+		prefix := &models.Prefix{Prefix: "10.10.10.0/24", Site: models.Site{Slug: "Site_1"}}
+
+		_, err := generateIPPoolName(&argorav1alpha1.IPPoolSelector{NameTemplate: "{{ .Site"}, prefix)
+		Expect(err).To(MatchError(ContainSubstring("unable to parse name template")))
+
+		_, err = generateIPPoolName(&argorav1alpha1.IPPoolSelector{NameTemplate: "{{ .Rack }}"}, prefix)
+		Expect(err).To(MatchError(ContainSubstring("unable to render name template")))
+
+		_, err = generateIPPoolName(&argorav1alpha1.IPPoolSelector{NameTemplate: "pool-{{ .Site }}"}, prefix)
+		Expect(err).To(MatchError(ContainSubstring(`invalid ippool name "pool-Site_1"`)))
+	})
+
+	It("should detect IPPool name collisions", func() {
+		pools := make(map[client.ObjectKey]string)
+
+		Expect(claimIPPoolName(pools, client.ObjectKey{Name: "pool"}, &models.Prefix{Prefix: "10.10.10.0/24"})).To(Succeed())
+		Expect(claimIPPoolName(pools, client.ObjectKey{Name: "pool-v6"}, &models.Prefix{Prefix: "2001:db8::/64"})).To(Succeed())
+		Expect(claimIPPoolName(pools, client.ObjectKey{Namespace: "tenant", Name: "pool"}, &models.Prefix{Prefix: "10.10.30.0/24"})).To(Succeed())
+
+		err := claimIPPoolName(pools, client.ObjectKey{Name: "pool"}, &models.Prefix{Prefix: "10.10.20.0/24"})
+		Expect(err).To(MatchError(errIPPoolNameCollision))
+		Expect(err).To(MatchError(ContainSubstring("ippool pool of prefix 10.10.20.0/24 is already generated for prefix 10.10.10.0/24")))
+
+		err = claimIPPoolName(pools, client.ObjectKey{Namespace: "tenant", Name: "pool"}, &models.Prefix{Prefix: "10.10.40.0/24"})
+		Expect(err).To(MatchError(ContainSubstring("ippool tenant/pool of prefix 10.10.40.0/24 is already generated for prefix 10.10.30.0/24")))
+		Expect(pools).To(Equal(map[client.ObjectKey]string{
+			{Name: "pool"}:                      "10.10.10.0/24",
+			{Name: "pool-v6"}:                   "2001:db8::/64",
+			{Namespace: "tenant", Name: "pool"}: "10.10.30.0/24",
+		}))
+	})
+
+	It("should generate IPPool namespaces", func() {
+		importCR := &argorav1alpha1.IPPoolImport{ObjectMeta: metav1.ObjectMeta{Name: "import", Namespace: "default"}}
+		prefix := &models.Prefix{Prefix: "10.10.10.0/24", Site: models.Site{Slug: "site-1a"}, Tenant: models.Tenant{NestedTenant: models.NestedTenant{Slug: "tenant-a"}}}
+
+		namespace, err := generateIPPoolNamespace(importCR, &argorav1alpha1.IPPoolSelector{TargetNamespace: "{{ .Tenant }}"}, prefix)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(namespace).To(BeEmpty())
+
+		namespace, err = generateIPPoolNamespace(importCR, &argorav1alpha1.IPPoolSelector{PoolKind: argorav1alpha1.IPPoolKindInClusterIPPool}, prefix)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(namespace).To(Equal("default"))
+
+		namespace, err = generateIPPoolNamespace(importCR, &argorav1alpha1.IPPoolSelector{
+			PoolKind:        argorav1alpha1.IPPoolKindInClusterIPPool,
+			TargetNamespace: "{{ if .Tenant }}{{ .Tenant }}{{ else }}site-{{ .Site }}{{ end }}",
+		}, prefix)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(namespace).To(Equal("tenant-a"))
+
+		_, err = generateIPPoolNamespace(importCR, &argorav1alpha1.IPPoolSelector{
+			PoolKind:        argorav1alpha1.IPPoolKindInClusterIPPool,
+			TargetNamespace: "{{ .Vrf }}",
+		}, prefix)
+		Expect(err).To(MatchError(ContainSubstring(`invalid target namespace ""`)))
+	})
+
+	It("should suffix IPv6 pool names", func() {
+		selector := &argorav1alpha1.IPPoolSelector{NamePrefix: "ippool"}
+
+		name, err := generateIPPoolName(selector, &models.Prefix{Prefix: "10.10.10.0/24", Site: models.Site{Slug: "site-1a"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(name).To(Equal("ippool-site-1a"))
+
+		name, err = generateIPPoolName(selector, &models.Prefix{Prefix: "2001:db8::/64", Site: models.Site{Slug: "site-1a"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(name).To(Equal("ippool-site-1a-v6"))
+	})
+})
+
+var _ = Describe("IPPoolImport prefix selection", func() {
+	ctx := context.Background()
+
+	prefixes := []models.Prefix{
+		{ID: 1, Prefix: "10.10.10.0/24", Tenant: models.Tenant{NestedTenant: models.NestedTenant{Slug: "cc"}}, Vlan: models.NestedVLAN{ID: 11}},
+		{ID: 2, Prefix: "2001:db8::/64", Tenant: models.Tenant{NestedTenant: models.NestedTenant{Slug: "cc"}}, Vrf: models.NestedVRF{Name: "CC-CLOUD01"}},
+		{ID: 3, Prefix: "10.10.20.0/24", Vrf: models.NestedVRF{Name: "CC-CLOUD01"}, Vlan: models.NestedVLAN{ID: 12}},
+	}
+
+	DescribeTable("should let NetBox filter the prefixes",
+		func(filter argorav1alpha1.PrefixFilter, expected url.Values) {
+			ipamMock := &mock.IPAMMock{
+				GetPrefixesFunc: func(_ context.Context, opts ...ipam.ListPrefixesRequestOption) ([]models.Prefix, error) {
+					Expect(ipam.NewListPrefixesRequest(opts...).BuildQuery()).To(Equal(expected))
+					return slices.Clone(prefixes), nil
+				},
+				GetVrfsByNameFunc: func(name string) ([]models.VRF, error) {
+					Expect(name).To(Equal("CC-CLOUD01"))
+					return []models.VRF{{NestedVRF: models.NestedVRF{ID: 3}}, {NestedVRF: models.NestedVRF{ID: 4}}}, nil
+				},
+			}
+			reconciler := &IPPoolImportReconciler{netBox: &mock.NetBoxMock{IPAMMock: ipamMock}}
+
+			selected, err := reconciler.selectPrefixes(ctx, &argorav1alpha1.IPPoolSelector{PrefixFilter: filter})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(selected).To(Equal(prefixes))
+		},
+		Entry("by tenant", argorav1alpha1.PrefixFilter{Tenant: "cc"}, url.Values{"tenant": {"cc"}}),
+		Entry("by the VRFs with the name", argorav1alpha1.PrefixFilter{Vrf: "CC-CLOUD01"}, url.Values{"vrf_id": {"3", "4"}}),
+		Entry("by family", argorav1alpha1.PrefixFilter{Family: 6, Tenant: "cc"}, url.Values{"family": {"6"}, "tenant": {"cc"}}),
+	)
+
+	It("should match prefixes by the VLAN group", func() {
+		ipamMock := &mock.IPAMMock{
+			GetPrefixesFunc: func(_ context.Context, _ ...ipam.ListPrefixesRequestOption) ([]models.Prefix, error) {
+				return slices.Clone(prefixes), nil
+			},
+			GetVlansByGroupFunc: func(group string) ([]models.Vlan, error) {
+				Expect(group).To(Equal("transit"))
+				return []models.Vlan{{NestedVLAN: models.NestedVLAN{ID: 12}}}, nil
+			},
+		}
+		reconciler := &IPPoolImportReconciler{netBox: &mock.NetBoxMock{IPAMMock: ipamMock}}
+
+		selected, err := reconciler.selectPrefixes(ctx, &argorav1alpha1.IPPoolSelector{PrefixFilter: argorav1alpha1.PrefixFilter{VlanGroup: "transit"}})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(selected).To(Equal(prefixes[2:]))
+	})
+
+	It("should select no prefixes if no VRF has the name of the filter", func() {
+		ipamMock := &mock.IPAMMock{
+			GetVrfsByNameFunc: func(_ string) ([]models.VRF, error) {
+				return nil, nil
+			},
+		}
+		reconciler := &IPPoolImportReconciler{netBox: &mock.NetBoxMock{IPAMMock: ipamMock}}
+
+		selected, err := reconciler.selectPrefixes(ctx, &argorav1alpha1.IPPoolSelector{PrefixFilter: argorav1alpha1.PrefixFilter{Vrf: "CC-CLOUD02"}})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(selected).To(BeEmpty())
+		Expect(ipamMock.GetPrefixesCalls).To(BeZero())
+	})
+
+	It("should pass the NetBox filters and drop excluded prefixes", func() {
+		ipamMock := &mock.IPAMMock{
+			GetPrefixesFunc: func(_ context.Context, opts ...ipam.ListPrefixesRequestOption) ([]models.Prefix, error) {
+				req := ipam.NewListPrefixesRequest(opts...).BuildRequest()
+				if req.Tag == "reserved" {
+					return []models.Prefix{prefixes[2]}, nil
+				}
+				Expect(req.Region).To(Equal("qa-de-1"))
+				Expect(req.Role).To(Equal("transit"))
+				Expect(req.Site).To(Equal("qa-de-1a"))
+				Expect(req.Status).To(Equal("active"))
+				Expect(req.MaskLength).To(Equal(24))
+				return slices.Clone(prefixes), nil
+			},
+		}
+		reconciler := &IPPoolImportReconciler{netBox: &mock.NetBoxMock{IPAMMock: ipamMock}}
+
+		selected, err := reconciler.selectPrefixes(ctx, &argorav1alpha1.IPPoolSelector{
+			PrefixFilter: argorav1alpha1.PrefixFilter{Region: "qa-de-1", Role: "transit", Site: "qa-de-1a", Status: "active", MaskLength: 24},
+			Exclude:      []argorav1alpha1.PrefixFilter{{Tag: "reserved"}},
+		})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(selected).To(Equal(prefixes[:2]))
+		Expect(ipamMock.GetPrefixesCalls).To(Equal(2))
+	})
+})
+
+var _ = Describe("IPPoolImport pruning", func() {
+	importCR := &argorav1alpha1.IPPoolImport{
+		ObjectMeta: metav1.ObjectMeta{Name: "import", Namespace: "default"},
+		Spec:       argorav1alpha1.IPPoolImportSpec{DeletionPolicy: argorav1alpha1.IPPoolDeletionPolicyDelete},
+	}
+
+	ippool := func(name string, labels map[string]string) *ipamv1alpha2.GlobalInClusterIPPool {
+		return &ipamv1alpha2.GlobalInClusterIPPool{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+
+	namespacedIPPool := func(namespace, name string, labels map[string]string) *ipamv1alpha2.InClusterIPPool {
+		return &ipamv1alpha2.InClusterIPPool{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels}}
+	}
+
+	It("should delete orphaned IPPools without allocated addresses only", func() {
+		labels := map[string]string{
+			"ippoolimport.argora.cloud.sap/name":      "import",
+			"ippoolimport.argora.cloud.sap/namespace": "default",
+			"ippoolimport.argora.cloud.sap/prefix-id": "7",
+		}
+		k8sClient := createFakeClient(
+			ippool("selected", labels),
+			ippool("orphaned", labels),
+			ippool("allocated", labels),
+			ippool("foreign", map[string]string{"ippoolimport.argora.cloud.sap/name": "other"}),
+			namespacedIPPool("tenant-a", "selected", labels),
+			namespacedIPPool("tenant-a", "orphaned", labels),
+			namespacedIPPool("tenant-b", "allocated", labels),
+			&ipamv1.IPAddress{
+				ObjectMeta: metav1.ObjectMeta{Name: "address", Namespace: "default"},
+				Spec: ipamv1.IPAddressSpec{
+					Address: "10.10.10.5",
+					PoolRef: ipamv1.IPPoolReference{Kind: "GlobalInClusterIPPool", Name: "allocated"},
+				},
+			},
+			&ipamv1.IPAddress{
+				ObjectMeta: metav1.ObjectMeta{Name: "address", Namespace: "tenant-a"},
+				Spec: ipamv1.IPAddressSpec{
+					Address: "10.10.20.5",
+					PoolRef: ipamv1.IPPoolReference{Kind: "InClusterIPPool", Name: "allocated"},
+				},
+			},
+			&ipamv1.IPAddress{
+				ObjectMeta: metav1.ObjectMeta{Name: "address", Namespace: "tenant-b"},
+				Spec: ipamv1.IPAddressSpec{
+					Address: "10.10.30.5",
+					PoolRef: ipamv1.IPPoolReference{Kind: "InClusterIPPool", Name: "allocated"},
+				},
+			},
+		)
+		reconciler := &IPPoolImportReconciler{k8sClient: k8sClient}
+
+		Expect(reconciler.pruneIPPools(context.Background(), importCR, map[client.ObjectKey]string{
+			{Name: "selected"}:                        "10.10.10.0/24",
+			{Namespace: "tenant-a", Name: "selected"}: "10.10.20.0/24",
+		})).To(Succeed())
+
+		ippools := &ipamv1alpha2.GlobalInClusterIPPoolList{}
+		Expect(k8sClient.List(context.Background(), ippools)).To(Succeed())
+		Expect(ippools.Items).To(HaveLen(3))
+		namespacedIPPools := &ipamv1alpha2.InClusterIPPoolList{}
+		Expect(k8sClient.List(context.Background(), namespacedIPPools)).To(Succeed())
+		Expect(namespacedIPPools.Items).To(HaveLen(2))
+		Expect(importCR.Status.OrphanedPools).To(Equal([]argorav1alpha1.OrphanedIPPool{
+			{Name: "allocated", PrefixID: "7", Message: "deletion blocked by 1 allocated addresses"},
+			{Name: "allocated", Namespace: "tenant-b", PrefixID: "7", Message: "deletion blocked by 1 allocated addresses"},
+		}))
+	})
+})
+
+var _ = Describe("IPPoolImport labels", func() {
+	ctx := context.Background()
+
+	fileReaderMock := &mock.FileReaderMock{
+		FileContent: map[string]string{
+			"/etc/credentials/credentials.json": `{"bmcUser": "user", "bmcPassword": "password", "netboxToken": "token"}`,
+		},
+	}
+
+	var prefixes []models.Prefix
+
+	newReconciler := func(importCR *argorav1alpha1.IPPoolImport) *IPPoolImportReconciler {
+		prefixes = []models.Prefix{{ID: 7, Prefix: "10.30.0.0/24", Site: models.Site{ID: 1, Slug: "site-1a"}}}
+		fakeClient := createFakeClient(importCR)
+		return &IPPoolImportReconciler{
+			k8sClient:     fakeClient,
+			scheme:        fakeClient.Scheme(),
+			statusHandler: status.NewIPPoolImportStatusHandler(fakeClient),
+			netBox: &mock.NetBoxMock{IPAMMock: &mock.IPAMMock{
+				GetPrefixesFunc: func(_ context.Context, _ ...ipam.ListPrefixesRequestOption) ([]models.Prefix, error) {
+					return prefixes, nil
+				},
+				GetIPAddressesFunc: func(_ context.Context, _ ...ipam.ListIPAddressesRequestOption) ([]models.IPAddress, error) {
+					return nil, nil
+				},
+			}},
+			credentials:       credentials.NewDefaultCredentials(fileReaderMock),
+			reconcileInterval: reconcileInterval,
+		}
+	}
+
+	newImport := func() *argorav1alpha1.IPPoolImport {
+		return &argorav1alpha1.IPPoolImport{
+			ObjectMeta: metav1.ObjectMeta{Name: "import", Namespace: "default"},
+			Spec: argorav1alpha1.IPPoolImportSpec{
+				DeletionPolicy: argorav1alpha1.IPPoolDeletionPolicyDelete,
+				IPPools: []*argorav1alpha1.IPPoolSelector{{
+					NamePrefix:   "ippool",
+					PrefixFilter: argorav1alpha1.PrefixFilter{Role: "cluster-networks"},
+				}},
+			},
+		}
+	}
+
+	// relabel reconciles the IPPoolImport after the labels of its existing IPPool are replaced.
+	relabel := func(reconciler *IPPoolImportReconciler, importCR *argorav1alpha1.IPPoolImport, labels map[string]string) *ipamv1alpha2.GlobalInClusterIPPool {
+		req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(importCR)}
+		_, err := reconciler.Reconcile(ctx, req)
+		Expect(err).ToNot(HaveOccurred())
+
+		pool := &ipamv1alpha2.GlobalInClusterIPPool{}
+		Expect(reconciler.k8sClient.Get(ctx, client.ObjectKey{Name: "ippool-site-1a"}, pool)).To(Succeed())
+		pool.Labels = labels
+		Expect(reconciler.k8sClient.Update(ctx, pool)).To(Succeed())
+
+		_, err = reconciler.Reconcile(ctx, req)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(reconciler.k8sClient.Get(ctx, client.ObjectKey{Name: "ippool-site-1a"}, pool)).To(Succeed())
+		return pool
+	}
+
+	It("should add the tracking labels to existing IPPools", func() {
+		importCR := newImport()
+		reconciler := newReconciler(importCR)
+
+		pool := relabel(reconciler, importCR, map[string]string{
+			"team": "network",
+			"ippoolimport.argora.cloud.sap/prefix-id": "3",
+		})
+
+		Expect(pool.Labels).To(Equal(map[string]string{
+			"team":                               "network",
+			"ippoolimport.argora.cloud.sap/name": "import",
+			"ippoolimport.argora.cloud.sap/namespace": "default",
+			"ippoolimport.argora.cloud.sap/prefix-id": "7",
+		}))
+	})
+
+	It("should prune relabeled IPPools whose prefix is no longer selected", func() {
+		importCR := newImport()
+		reconciler := newReconciler(importCR)
+
+		pool := relabel(reconciler, importCR, nil)
+		Expect(pool.Labels).To(HaveKeyWithValue("ippoolimport.argora.cloud.sap/name", "import"))
+
+		By("removing the prefix from NetBox")
+		prefixes = nil
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(importCR)})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(apierrors.IsNotFound(reconciler.k8sClient.Get(ctx, client.ObjectKey{Name: "ippool-site-1a"}, pool))).To(BeTrue())
+	})
+})
+
+var _ = Describe("IPPoolImport prefix claims", func() {
+	ctx := context.Background()
+
+	fileReaderMock := &mock.FileReaderMock{
+		FileContent: map[string]string{
+			"/etc/credentials/credentials.json": `{"bmcUser": "user", "bmcPassword": "password", "netboxToken": "token"}`,
+		},
+	}
+
+	container := models.Prefix{
+		ID:     10,
+		Prefix: "10.20.0.0/16",
+		Status: models.Status{Value: "container"},
+		Site:   models.Site{ID: 1, Slug: "site-1a"},
+		Vrf:    models.NestedVRF{ID: 3},
+	}
+
+	var (
+		children   []models.Prefix
+		ipamMock   *mock.IPAMMock
+		netBoxMock *mock.NetBoxMock
+	)
+
+	BeforeEach(func() {
+		children = nil
+		ipamMock = &mock.IPAMMock{
+			GetPrefixesFunc: func(_ context.Context, opts ...ipam.ListPrefixesRequestOption) ([]models.Prefix, error) {
+				req := ipam.NewListPrefixesRequest(opts...).BuildRequest()
+				if req.Within == "" {
+					return append([]models.Prefix{container}, children...), nil
+				}
+				Expect(req.Within).To(Equal(container.Prefix))
+				Expect(req.Tag).To(Equal("cluster-a"))
+				return children, nil
+			},
+			GetPrefixesByPrefixesFunc: func(prefix string) ([]models.Prefix, error) {
+				return slices.DeleteFunc(slices.Clone(children), func(child models.Prefix) bool { return child.Prefix != prefix }), nil
+			},
+			CreateAvailablePrefixFunc: func(_ context.Context, containerID int, params ipam.CreateAvailablePrefixParams) (*models.Prefix, error) {
+				Expect(containerID).To(Equal(container.ID))
+				Expect(params.PrefixLength).To(Equal(26))
+				Expect(params.SiteID).To(Equal(container.Site.ID))
+				Expect(params.VrfID).To(Equal(container.Vrf.ID))
+				Expect(params.Tags).To(Equal([]models.NestedTag{{ID: 5, Name: "cluster-a", Slug: "cluster-a"}}))
+				child := models.Prefix{
+					ID:          11,
+					Prefix:      "10.20.0.0/26",
+					Status:      models.Status{Value: params.Status},
+					Site:        container.Site,
+					Vrf:         container.Vrf,
+					Description: params.Description,
+					Tags:        params.Tags,
+				}
+				children = append(children, child)
+				return &child, nil
+			},
+			DeletePrefixFunc: func(id int) error {
+				children = slices.DeleteFunc(children, func(child models.Prefix) bool { return child.ID == id })
+				return nil
+			},
+			GetIPAddressesFunc: func(_ context.Context, _ ...ipam.ListIPAddressesRequestOption) ([]models.IPAddress, error) {
+				return nil, nil
+			},
+		}
+		netBoxMock = &mock.NetBoxMock{
+			IPAMMock: ipamMock,
+			ExtrasMock: &mock.ExtrasMock{
+				GetTagByNameFunc: func(tagName string) (*models.Tag, error) {
+					return &models.Tag{NestedTag: models.NestedTag{ID: 5, Name: tagName, Slug: tagName}}, nil
+				},
+			},
+		}
+	})
+
+	newReconciler := func(importCR *argorav1alpha1.IPPoolImport, objects ...client.Object) *IPPoolImportReconciler {
+		fakeClient := createFakeClient(append(objects, importCR)...)
+		return &IPPoolImportReconciler{
+			k8sClient:         fakeClient,
+			scheme:            fakeClient.Scheme(),
+			statusHandler:     status.NewIPPoolImportStatusHandler(fakeClient),
+			netBox:            netBoxMock,
+			credentials:       credentials.NewDefaultCredentials(fileReaderMock),
+			reconcileInterval: reconcileInterval,
+		}
+	}
+
+	newImport := func() *argorav1alpha1.IPPoolImport {
+		return &argorav1alpha1.IPPoolImport{
+			ObjectMeta: metav1.ObjectMeta{Name: "import", Namespace: "default", UID: "import-uid"},
+			Spec: argorav1alpha1.IPPoolImportSpec{
+				IPPools: []*argorav1alpha1.IPPoolSelector{{
+					NamePrefix:   "ippool",
+					PrefixFilter: argorav1alpha1.PrefixFilter{Role: "cluster-networks"},
+					Claim:        &argorav1alpha1.PrefixClaim{Name: "nodes", PrefixLength: 26, Tag: "cluster-a"},
+				}},
+			},
+		}
+	}
+
+	It("should claim a child prefix once and release it on deletion", func() {
+		importCR := newImport()
+		reconciler := newReconciler(importCR)
+		req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(importCR)}
+
+		By("reconciling the IPPoolImport twice")
+		for range 2 {
+			_, err := reconciler.Reconcile(ctx, req)
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Expect(ipamMock.CreateAvailablePrefixCalls).To(Equal(1))
+		Expect(children).To(HaveLen(1))
+		Expect(children[0].Description).To(Equal("claimed by ippoolimport default/import (import-uid) for claim nodes"))
+
+		pool := &ipamv1alpha2.GlobalInClusterIPPool{}
+		Expect(reconciler.k8sClient.Get(ctx, client.ObjectKey{Name: "ippool-site-1a"}, pool)).To(Succeed())
+		Expect(pool.Spec.Addresses).To(Equal([]string{"10.20.0.0/26"}))
+
+		Expect(reconciler.k8sClient.Get(ctx, req.NamespacedName, importCR)).To(Succeed())
+		Expect(importCR.Finalizers).To(ContainElement(ipPoolImportFinalizer))
+		Expect(importCR.Status.State).To(Equal(argorav1alpha1.Ready))
+		Expect(importCR.Status.ClaimedPrefixes).To(Equal([]argorav1alpha1.ClaimedPrefix{{ID: 11, Prefix: "10.20.0.0/26"}}))
+
+		By("deleting the IPPoolImport")
+		Expect(reconciler.k8sClient.Delete(ctx, importCR)).To(Succeed())
+		_, err := reconciler.Reconcile(ctx, req)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ipamMock.DeletePrefixCalls).To(Equal(1))
+		Expect(children).To(BeEmpty())
+		Expect(apierrors.IsNotFound(reconciler.k8sClient.Get(ctx, client.ObjectKey{Name: "ippool-site-1a"}, pool))).To(BeTrue())
+		Expect(apierrors.IsNotFound(reconciler.k8sClient.Get(ctx, req.NamespacedName, importCR))).To(BeTrue())
+	})
+
+	It("should not release a child prefix with allocated addresses", func() {
+		importCR := newImport()
+		importCR.Finalizers = []string{ipPoolImportFinalizer}
+		importCR.Status.ClaimedPrefixes = []argorav1alpha1.ClaimedPrefix{{ID: 11, Prefix: "10.20.0.0/26"}}
+		children = []models.Prefix{{ID: 11, Prefix: "10.20.0.0/26"}}
+		reconciler := newReconciler(importCR,
+			&ipamv1alpha2.GlobalInClusterIPPool{ObjectMeta: metav1.ObjectMeta{Name: "ippool-site-1a", Labels: map[string]string{
+				"ippoolimport.argora.cloud.sap/name":      "import",
+				"ippoolimport.argora.cloud.sap/namespace": "default",
+				"ippoolimport.argora.cloud.sap/prefix-id": "11",
+			}}},
+			&ipamv1.IPAddress{
+				ObjectMeta: metav1.ObjectMeta{Name: "address", Namespace: "default"},
+				Spec: ipamv1.IPAddressSpec{
+					Address: "10.20.0.5",
+					PoolRef: ipamv1.IPPoolReference{Kind: "GlobalInClusterIPPool", Name: "ippool-site-1a"},
+				},
+			},
+		)
+		req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(importCR)}
+
+		Expect(reconciler.k8sClient.Delete(ctx, importCR)).To(Succeed())
+		_, err := reconciler.Reconcile(ctx, req)
+		Expect(err).To(MatchError("ippool ippool-site-1a of claimed prefix 10.20.0.0/26 has 1 allocated addresses"))
+
+		Expect(ipamMock.DeletePrefixCalls).To(BeZero())
+		Expect(reconciler.k8sClient.Get(ctx, req.NamespacedName, importCR)).To(Succeed())
+	})
+
+	It("should leave no child prefix behind when the allocation fails", func() {
+		importCR := newImport()
+		reconciler := newReconciler(importCR)
+		allocate := ipamMock.CreateAvailablePrefixFunc
+		ipamMock.CreateAvailablePrefixFunc = func(_ context.Context, _ int, _ ipam.CreateAvailablePrefixParams) (*models.Prefix, error) {
+			return nil, errors.New("unable to create available prefix in prefix (10): insufficient space")
+		}
+
+		By("failing to allocate the child prefix")
+		_, err := reconciler.claimPrefixes(ctx, importCR, importCR.Spec.IPPools[0], []models.Prefix{container})
+		Expect(err).To(MatchError("unable to create available prefix in prefix (10): insufficient space"))
+		Expect(children).To(BeEmpty())
+		Expect(ipamMock.DeletePrefixCalls).To(BeZero())
+		Expect(importCR.Status.ClaimedPrefixes).To(BeEmpty())
+
+		By("allocating the child prefix on the next attempt")
+		ipamMock.CreateAvailablePrefixFunc = allocate
+		claimed, err := reconciler.claimPrefixes(ctx, importCR, importCR.Spec.IPPools[0], []models.Prefix{container})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claimed).To(Equal(children))
+		Expect(ipamMock.CreateAvailablePrefixCalls).To(Equal(2))
+		Expect(importCR.Status.ClaimedPrefixes).To(Equal([]argorav1alpha1.ClaimedPrefix{{ID: 11, Prefix: "10.20.0.0/26"}}))
+	})
+
+	It("should keep the claimed child prefix if the selector is renamed", func() {
+		importCR := newImport()
+		reconciler := newReconciler(importCR)
+		req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(importCR)}
+
+		_, err := reconciler.Reconcile(ctx, req)
+		Expect(err).ToNot(HaveOccurred())
+
+		By("renaming the selector")
+		Expect(reconciler.k8sClient.Get(ctx, req.NamespacedName, importCR)).To(Succeed())
+		importCR.Spec.IPPools[0].NamePrefix = "renamed"
+		Expect(reconciler.k8sClient.Update(ctx, importCR)).To(Succeed())
+
+		_, err = reconciler.Reconcile(ctx, req)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ipamMock.CreateAvailablePrefixCalls).To(Equal(1))
+		Expect(children).To(HaveLen(1))
+
+		pool := &ipamv1alpha2.GlobalInClusterIPPool{}
+		Expect(reconciler.k8sClient.Get(ctx, client.ObjectKey{Name: "renamed-site-1a"}, pool)).To(Succeed())
+		Expect(pool.Spec.Addresses).To(Equal([]string{"10.20.0.0/26"}))
+	})
+
+	It("should key the claimed child prefix on the claim name", func() {
+		importCR := newImport()
+		ipPoolSelector := importCR.Spec.IPPools[0]
+		description := prefixClaimDescription(importCR, ipPoolSelector)
+
+		By("moving and renaming the selector")
+		ipPoolSelector.NamePrefix = "renamed"
+		importCR.Spec.IPPools = append([]*argorav1alpha1.IPPoolSelector{{
+			NamePrefix:   "other",
+			PrefixFilter: argorav1alpha1.PrefixFilter{Role: "other"},
+		}}, importCR.Spec.IPPools...)
+		Expect(prefixClaimDescription(importCR, ipPoolSelector)).To(Equal(description))
+
+		By("renaming the claim")
+		ipPoolSelector.Claim.Name = "renamed"
+		Expect(prefixClaimDescription(importCR, ipPoolSelector)).ToNot(Equal(description))
+	})
+
+	It("should refuse claims with the same name", func() {
+		importCR := newImport()
+		duplicate := *importCR.Spec.IPPools[0]
+		duplicate.NamePrefix = "duplicate"
+		importCR.Spec.IPPools = append(importCR.Spec.IPPools, &duplicate)
+		reconciler := newReconciler(importCR)
+
+		_, err := reconciler.claimPrefixes(ctx, importCR, importCR.Spec.IPPools[1], []models.Prefix{container})
+		Expect(err).To(MatchError("prefix claim name nodes of ippool selector duplicate is not unique"))
+		Expect(ipamMock.CreateAvailablePrefixCalls).To(BeZero())
+	})
+
+	It("should refuse claims matching several container prefixes", func() {
+		reconciler := newReconciler(newImport())
+
+		_, err := reconciler.claimPrefixes(ctx, newImport(), newImport().Spec.IPPools[0], []models.Prefix{container, container})
+		Expect(err).To(MatchError("prefix claim of ippool selector ippool matches 2 container prefixes, expected one"))
+		Expect(ipamMock.CreateAvailablePrefixCalls).To(BeZero())
 	})
 })
 
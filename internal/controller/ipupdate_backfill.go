@@ -0,0 +1,406 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ipamv1 "sigs.k8s.io/cluster-api/api/ipam/v1beta2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// IPBackfillConfigMapName is the name of the ConfigMap holding the progress of the IPAddress backfill.
+	IPBackfillConfigMapName = "argora-ipupdate-backfill"
+
+	ipBackfillStateRunning   = "Running"
+	ipBackfillStateCompleted = "Completed"
+
+	ipBackfillKeyState      = "state"
+	ipBackfillKeyCursor     = "cursor"
+	ipBackfillKeyDone       = "done"
+	ipBackfillKeyFailed     = "failed"
+	ipBackfillKeyConflicted = "conflicted"
+	ipBackfillKeyRemaining  = "remaining"
+
+	// ipBackfillRequeueDelay requeues IPAddresses deferred while the backfill is starting or processing them.
+	ipBackfillRequeueDelay = 30 * time.Second
+)
+
+// ipBackfillRetryBackoff is the backoff of the backfill retrying to list IPAddresses or to load or save its progress.
+var ipBackfillRetryBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    math.MaxInt32,
+	Cap:      5 * time.Minute,
+}
+
+// IPBackfillOptions configures the backfill of IPAddresses not yet synced to NetBox.
+type IPBackfillOptions struct {
+	// Namespace of the ConfigMap holding the progress of the backfill.
+	Namespace string
+	// BatchSize is the number of IPAddresses processed before the progress is saved.
+	BatchSize int
+	// Concurrency is the number of IPAddresses processed in parallel.
+	Concurrency int
+	// Rate is the number of IPAddresses processed per second.
+	Rate float64
+}
+
+// ipBackfillProgress is the progress of the backfill, persisted in a ConfigMap to resume after a restart.
+type ipBackfillProgress struct {
+	State string
+	// Cursor is the key of the last IPAddress of the last completed batch, IPAddresses are processed in key order.
+	Cursor     string
+	Done       int
+	Failed     int
+	Conflicted int
+	Remaining  int
+}
+
+// ipBackfill syncs the IPAddresses existing when the IPUpdate controller is enabled in controlled batches, with its
+// own concurrency and rate limit. IPAddresses not yet synced are deferred by the controller until the backfill has
+// processed them, IPAddresses failing in the backfill are handed over to the controller.
+type ipBackfill struct {
+	k8sClient client.Client
+	apiReader client.Reader
+	options   IPBackfillOptions
+	limiter   *rate.Limiter
+	reconcile func(ctx context.Context, ipAddress *ipamv1.IPAddress) (ctrl.Result, error)
+	failed    chan event.GenericEvent
+	filter    IPUpdateFilter
+	backoff   wait.Backoff
+
+	mu       sync.RWMutex
+	loaded   bool
+	progress ipBackfillProgress
+	// active holds the IPAddresses of the current batch being processed, processed holds the completed ones.
+	active    map[string]bool
+	processed map[string]bool
+}
+
+func newIPBackfill(r *IPUpdateReconciler, apiReader client.Reader, options IPBackfillOptions) *ipBackfill {
+	return &ipBackfill{
+		k8sClient: r.k8sClient,
+		apiReader: apiReader,
+		options:   options,
+		limiter:   rate.NewLimiter(rate.Limit(options.Rate), 1),
+		reconcile: r.backfillIPAddress,
+		failed:    make(chan event.GenericEvent, max(options.BatchSize, 1)),
+		backoff:   ipBackfillRetryBackoff,
+		active:    make(map[string]bool),
+		processed: make(map[string]bool),
+	}
+}
+
+// backfillIPAddress syncs the IPAddress to NetBox in a single call. The first reconciliation of an IPAddress only
+// adds the finalizer, so it is reconciled a second time instead of leaving the sync to the controller queue.
+func (r *IPUpdateReconciler) backfillIPAddress(ctx context.Context, ipAddress *ipamv1.IPAddress) (ctrl.Result, error) {
+	hadFinalizer := controllerutil.ContainsFinalizer(ipAddress, ipAddressFinalizer)
+
+	result, err := r.reconcileIPAddress(ctx, ipAddress)
+	if err != nil || hadFinalizer || !ipAddress.DeletionTimestamp.IsZero() {
+		return result, err
+	}
+
+	return r.reconcileIPAddress(ctx, ipAddress)
+}
+
+// EnableBackfill enables the backfill of IPAddresses not yet synced to NetBox. It must be called before
+// SetupWithManager.
+func (r *IPUpdateReconciler) EnableBackfill(mgr ctrl.Manager, options IPBackfillOptions) {
+	r.backfill = newIPBackfill(r, mgr.GetAPIReader(), options)
+}
+
+func ipBackfillKey(ipAddress *ipamv1.IPAddress) string {
+	return client.ObjectKeyFromObject(ipAddress).String()
+}
+
+// defers reports whether the controller has to leave the IPAddress to the backfill, and after which delay it is
+// requeued. IPAddresses already synced to NetBox are never deferred.
+func (b *ipBackfill) defers(ipAddress *ipamv1.IPAddress) (bool, time.Duration) {
+	if b == nil {
+		return false, 0
+	}
+	if _, synced := ipAddress.Annotations[annotationDeviceKey]; synced {
+		return false, 0
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	key := ipBackfillKey(ipAddress)
+	switch {
+	case !b.loaded, b.active[key]:
+		return true, ipBackfillRequeueDelay
+	case b.progress.State == ipBackfillStateCompleted, key <= b.progress.Cursor, b.processed[key]:
+		return false, 0
+	default:
+		// the backfill reconciles it and hands it over to the controller afterwards
+		return true, 0
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, the backfill runs on the leader only.
+func (b *ipBackfill) NeedLeaderElection() bool {
+	return true
+}
+
+// Start implements manager.Runnable. It processes batches of IPAddresses until none are left. Errors are retried
+// with backoff instead of being returned, which would stop the manager.
+func (b *ipBackfill) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("ipupdate-backfill")
+	ctx = log.IntoContext(ctx, logger)
+
+	var progress ipBackfillProgress
+	if !b.retry(ctx, func() (err error) {
+		progress, err = b.loadProgress(ctx)
+		return err
+	}) {
+		return nil
+	}
+
+	b.mu.Lock()
+	b.progress, b.loaded = progress, true
+	b.mu.Unlock()
+
+	if progress.State == ipBackfillStateCompleted {
+		logger.Info("backfill already completed")
+		return nil
+	}
+
+	logger.Info("starting backfill", "cursor", progress.Cursor, "batchSize", b.options.BatchSize,
+		"concurrency", b.options.Concurrency, "rate", b.options.Rate)
+
+	for {
+		var batch []ipamv1.IPAddress
+		var remaining int
+		if !b.retry(ctx, func() (err error) {
+			batch, remaining, err = b.nextBatch(ctx)
+			return err
+		}) {
+			return nil
+		}
+
+		if len(batch) == 0 {
+			progress := b.completeBatch("", ipBackfillStateCompleted, 0)
+			logger.Info("backfill completed", "done", progress.Done, "failed", progress.Failed, "conflicted", progress.Conflicted)
+			b.retry(ctx, func() error { return b.saveProgress(ctx, progress) })
+			return nil
+		}
+
+		if err := b.processBatch(ctx, batch); err != nil {
+			// the batch is processed again after a restart
+			return nil
+		}
+
+		progress := b.completeBatch(ipBackfillKey(&batch[len(batch)-1]), ipBackfillStateRunning, remaining-len(batch))
+		logger.Info("backfill batch completed", "cursor", progress.Cursor, "done", progress.Done,
+			"failed", progress.Failed, "conflicted", progress.Conflicted, "remaining", progress.Remaining)
+		if !b.retry(ctx, func() error { return b.saveProgress(ctx, progress) }) {
+			return nil
+		}
+	}
+}
+
+// retry calls fn until it succeeds, backing off after each error. It returns false if the context is cancelled.
+func (b *ipBackfill) retry(ctx context.Context, fn func() error) bool {
+	backoff := b.backoff
+	for {
+		err := fn()
+		if err == nil {
+			return true
+		}
+
+		delay := backoff.Step()
+		log.FromContext(ctx).Error(err, "backfill failed, retrying", "delay", delay)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(delay):
+		}
+	}
+}
+
+// nextBatch returns the next IPAddresses not yet synced to NetBox after the cursor in key order, and the number of
+// all IPAddresses left.
+func (b *ipBackfill) nextBatch(ctx context.Context) ([]ipamv1.IPAddress, int, error) {
+	ipAddresses := &ipamv1.IPAddressList{}
+	if err := b.k8sClient.List(ctx, ipAddresses); err != nil {
+		return nil, 0, fmt.Errorf("unable to list IPAddresses: %w", err)
+	}
+
+	b.mu.RLock()
+	cursor := b.progress.Cursor
+	b.mu.RUnlock()
+
+	pending := slices.DeleteFunc(ipAddresses.Items, func(ipAddress ipamv1.IPAddress) bool {
+		_, synced := ipAddress.Annotations[annotationDeviceKey]
+		return synced || !b.filter.matches(&ipAddress) || ipBackfillKey(&ipAddress) <= cursor
+	})
+	slices.SortFunc(pending, func(a, b ipamv1.IPAddress) int {
+		return strings.Compare(ipBackfillKey(&a), ipBackfillKey(&b))
+	})
+
+	return pending[:min(len(pending), max(b.options.BatchSize, 1))], len(pending), nil
+}
+
+// processBatch reconciles the IPAddresses of the batch with the concurrency and rate limit of the backfill.
+// It returns an error only if the context is cancelled.
+func (b *ipBackfill) processBatch(ctx context.Context, batch []ipamv1.IPAddress) error {
+	items := make(chan *ipamv1.IPAddress)
+	var wg sync.WaitGroup
+	for range max(b.options.Concurrency, 1) {
+		wg.Go(func() {
+			for ipAddress := range items {
+				b.process(ctx, ipAddress)
+			}
+		})
+	}
+
+	var err error
+	for i := range batch {
+		if err = b.limiter.Wait(ctx); err != nil {
+			break
+		}
+		b.setActive(ipBackfillKey(&batch[i]))
+		items <- &batch[i]
+	}
+	close(items)
+	wg.Wait()
+
+	return err
+}
+
+func (b *ipBackfill) process(ctx context.Context, ipAddress *ipamv1.IPAddress) {
+	logger := log.FromContext(ctx).WithValues("IPAddress", client.ObjectKeyFromObject(ipAddress))
+
+	_, err := b.reconcile(log.IntoContext(ctx, logger), ipAddress)
+
+	b.mu.Lock()
+	key := ipBackfillKey(ipAddress)
+	delete(b.active, key)
+	b.processed[key] = true
+	_, conflicted := errors.AsType[NetboxConflictError](err)
+	switch {
+	case err == nil:
+		b.progress.Done++
+	case conflicted:
+		b.progress.Conflicted++
+	default:
+		b.progress.Failed++
+	}
+	b.mu.Unlock()
+
+	if err != nil && !conflicted {
+		logger.Info("backfill of ip address failed, handing over to the controller", "error", err)
+		select {
+		case b.failed <- event.GenericEvent{Object: ipAddress}:
+		case <-ctx.Done():
+		}
+	}
+}
+
+func (b *ipBackfill) setActive(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.active[key] = true
+}
+
+// completeBatch advances the cursor and returns the progress to save.
+func (b *ipBackfill) completeBatch(cursor, state string, remaining int) ipBackfillProgress {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if cursor != "" {
+		b.progress.Cursor = cursor
+	}
+	b.progress.State = state
+	b.progress.Remaining = remaining
+	clear(b.processed)
+
+	return b.progress
+}
+
+func (b *ipBackfill) loadProgress(ctx context.Context) (ipBackfillProgress, error) {
+	configMap := &corev1.ConfigMap{}
+	err := b.apiReader.Get(ctx, client.ObjectKey{Namespace: b.options.Namespace, Name: IPBackfillConfigMapName}, configMap)
+	if apierrors.IsNotFound(err) {
+		return ipBackfillProgress{State: ipBackfillStateRunning}, nil
+	}
+	if err != nil {
+		return ipBackfillProgress{}, fmt.Errorf("unable to get backfill progress: %w", err)
+	}
+
+	progress := ipBackfillProgress{
+		State:  configMap.Data[ipBackfillKeyState],
+		Cursor: configMap.Data[ipBackfillKeyCursor],
+	}
+	for key, count := range map[string]*int{
+		ipBackfillKeyDone:       &progress.Done,
+		ipBackfillKeyFailed:     &progress.Failed,
+		ipBackfillKeyConflicted: &progress.Conflicted,
+		ipBackfillKeyRemaining:  &progress.Remaining,
+	} {
+		if value, ok := configMap.Data[key]; ok {
+			if *count, err = strconv.Atoi(value); err != nil {
+				return ipBackfillProgress{}, fmt.Errorf("invalid backfill progress %s: %w", key, err)
+			}
+		}
+	}
+
+	return progress, nil
+}
+
+func (b *ipBackfill) saveProgress(ctx context.Context, progress ipBackfillProgress) error {
+	data := map[string]string{
+		ipBackfillKeyState:      progress.State,
+		ipBackfillKeyCursor:     progress.Cursor,
+		ipBackfillKeyDone:       strconv.Itoa(progress.Done),
+		ipBackfillKeyFailed:     strconv.Itoa(progress.Failed),
+		ipBackfillKeyConflicted: strconv.Itoa(progress.Conflicted),
+		ipBackfillKeyRemaining:  strconv.Itoa(progress.Remaining),
+	}
+
+	// the ConfigMap is read through the API reader, to not cache all ConfigMaps of the cluster
+	configMap := &corev1.ConfigMap{}
+	err := b.apiReader.Get(ctx, client.ObjectKey{Namespace: b.options.Namespace, Name: IPBackfillConfigMapName}, configMap)
+	switch {
+	case apierrors.IsNotFound(err):
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: b.options.Namespace,
+				Name:      IPBackfillConfigMapName,
+			},
+			Data: data,
+		}
+		err = b.k8sClient.Create(ctx, configMap)
+	case err == nil:
+		configMap.Data = data
+		err = b.k8sClient.Update(ctx, configMap)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to save backfill progress: %w", err)
+	}
+
+	return nil
+}
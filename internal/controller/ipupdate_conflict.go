@@ -0,0 +1,219 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/sapcc/go-netbox-go/models"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ipamv1 "sigs.k8s.io/cluster-api/api/ipam/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	argorav1alpha1 "github.com/sapcc/argora/api/v1alpha1"
+)
+
+// IPConflictPolicy defines how the IPUpdate controller resolves IP addresses assigned to another interface or
+// device in NetBox.
+type IPConflictPolicy string
+
+const (
+	// IPConflictPolicyReport only reports the conflict, the IP address is left untouched in NetBox.
+	IPConflictPolicyReport IPConflictPolicy = "Report"
+	// IPConflictPolicyTakeover reassigns the IP address to the expected interface.
+	IPConflictPolicyTakeover IPConflictPolicy = "Takeover"
+	// IPConflictPolicyTakeoverIfStale reassigns the IP address only if the device holding it is not active in NetBox.
+	IPConflictPolicyTakeoverIfStale IPConflictPolicy = "TakeoverIfStale"
+
+	// annotationConflictPolicyKey overrides the conflict policy on an IPAddress or on the IP pool it is allocated from.
+	annotationConflictPolicyKey = "netbox.argora.cloud.sap/conflict-policy"
+
+	netboxDeviceStatusActive = "active"
+	netboxInterfaceType      = "dcim.interface"
+
+	eventReasonIPConflict         = "IPConflict"
+	eventReasonIPConflictTakeover = "IPConflictTakeover"
+)
+
+// ParseIPConflictPolicy parses the name of an IP conflict policy.
+func ParseIPConflictPolicy(policy string) (IPConflictPolicy, error) {
+	switch p := IPConflictPolicy(policy); p {
+	case IPConflictPolicyReport, IPConflictPolicyTakeover, IPConflictPolicyTakeoverIfStale:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid ip conflict policy %q, expected one of %s, %s or %s",
+			policy, IPConflictPolicyReport, IPConflictPolicyTakeover, IPConflictPolicyTakeoverIfStale)
+	}
+}
+
+// ipConflictPolicy returns the conflict policy annotated on the IPAddress or on its IP pool, falling back to
+// the default policy of the reconciler.
+func (r *IPUpdateReconciler) ipConflictPolicy(ctx context.Context, ipAddr *ipamv1.IPAddress) (IPConflictPolicy, error) {
+	policy, ok, err := r.policyAnnotation(ctx, ipAddr, annotationConflictPolicyKey)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return ParseIPConflictPolicy(policy)
+	}
+
+	if r.conflictPolicy == "" {
+		return IPConflictPolicyReport, nil
+	}
+	return r.conflictPolicy, nil
+}
+
+// policyAnnotation returns the value of a policy annotation of the IPAddress, or of its IP pool if the IPAddress
+// is not annotated.
+func (r *IPUpdateReconciler) policyAnnotation(ctx context.Context, ipAddr *ipamv1.IPAddress, key string) (string, bool, error) {
+	if policy, ok := ipAddr.Annotations[key]; ok {
+		return policy, true, nil
+	}
+
+	pool, err := r.ipAddressPool(ctx, ipAddr)
+	if err != nil {
+		return "", false, err
+	}
+	if pool != nil {
+		if policy, ok := pool.GetAnnotations()[key]; ok {
+			return policy, true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+// ipAddressPool returns the GlobalInClusterIPPool or InClusterIPPool the IPAddress is allocated from,
+// or nil if the pool is of another kind or does not exist.
+func (r *IPUpdateReconciler) ipAddressPool(ctx context.Context, ipAddr *ipamv1.IPAddress) (client.Object, error) {
+	poolRef := ipAddr.Spec.PoolRef
+	if poolRef.APIGroup != ipamv1.GroupVersion.Group {
+		return nil, nil
+	}
+
+	key := client.ObjectKey{Name: poolRef.Name}
+	switch argorav1alpha1.IPPoolKind(poolRef.Kind) {
+	case argorav1alpha1.IPPoolKindInClusterIPPool:
+		key.Namespace = ipAddr.Namespace
+	case argorav1alpha1.IPPoolKindGlobalInClusterIPPool:
+	default:
+		return nil, nil
+	}
+
+	pool := newIPPool(argorav1alpha1.IPPoolKind(poolRef.Kind))
+	if err := r.k8sClient.Get(ctx, key, pool); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to get ip pool %s: %w", ipPoolKeyString(key), err)
+	}
+	return pool, nil
+}
+
+// resolveConflict applies the conflict policy to an IP address assigned to another interface or device in NetBox.
+// It returns the IP address reassigned to the target interface, or the conflict error if the conflict is kept.
+func (r *IPUpdateReconciler) resolveConflict(
+	ctx context.Context,
+	ipAddr *ipamv1.IPAddress,
+	target *netboxTarget,
+	addr *models.IPAddress,
+	conflictErr NetboxConflictError,
+	logger logr.Logger,
+) (*models.IPAddress, error) {
+
+	policy, err := r.ipConflictPolicy(ctx, ipAddr)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.WithValues("conflictPolicy", policy)
+
+	if policy == IPConflictPolicyReport {
+		r.recorder.Eventf(ipAddr, nil, corev1.EventTypeWarning, eventReasonIPConflict, string(policy), "%s", conflictErr.Error())
+		return nil, conflictErr
+	}
+
+	holder, err := r.holderDevice(addr, target)
+	if err != nil {
+		return nil, err
+	}
+
+	if policy == IPConflictPolicyTakeoverIfStale && holder != nil && holder.Status.Value == netboxDeviceStatusActive {
+		logger.Info("ip address is held by an active device, keeping conflict", "holder", holder.Name)
+		r.recorder.Eventf(ipAddr, nil, corev1.EventTypeWarning, eventReasonIPConflict, string(policy),
+			"%s, device %s holding it is active", conflictErr.Error(), holder.Name)
+		return nil, conflictErr
+	}
+
+	taken, err := r.takeoverIPAddress(ctx, ipAddr, target, addr, holder, logger)
+	if err != nil {
+		return nil, fmt.Errorf("unable to take over ip address %d: %w", addr.ID, err)
+	}
+
+	logger.Info("ip address taken over", "address_id", addr.ID, "previous_interface_id", addr.AssignedInterface.ID)
+	r.recorder.Eventf(ipAddr, nil, corev1.EventTypeNormal, eventReasonIPConflictTakeover, string(policy),
+		"ip address %s reassigned from interface %d to interface %s of device %s",
+		addr.Address, addr.AssignedInterface.ID, target.iface.Name, target.device.Name)
+
+	return taken, nil
+}
+
+// holderDevice returns the device the IP address is assigned to in NetBox, or nil if it is unassigned or assigned
+// to the target device.
+func (r *IPUpdateReconciler) holderDevice(addr *models.IPAddress, target *netboxTarget) (*models.Device, error) {
+	holderID := addr.AssignedInterface.Device.ID
+	if holderID == 0 || holderID == target.device.ID {
+		return nil, nil
+	}
+
+	holder, err := r.netBox.DCIM().GetDeviceByID(holderID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get device holding ip address %d: %w", addr.ID, err)
+	}
+	return holder, nil
+}
+
+// takeoverIPAddress assigns the IP address to the target interface. The IP address is unset as primary address of
+// its holder device first, as NetBox rejects reassigning primary addresses.
+func (r *IPUpdateReconciler) takeoverIPAddress(ctx context.Context, ipAddr *ipamv1.IPAddress, target *netboxTarget, addr *models.IPAddress, holder *models.Device, logger logr.Logger) (*models.IPAddress, error) {
+	prefix, err := getPrefix(ipAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	if holder != nil {
+		primaryIP := holder.PrimaryIP4.ID
+		if prefix.Addr().Is6() {
+			primaryIP = holder.PrimaryIP6.ID
+		}
+		if primaryIP == addr.ID {
+			if err := r.netBox.DCIM().ClearDevicePrimaryIP(ctx, holder.ID, prefix.Addr().Is6()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	vrfID, err := r.prefixVrfID(prefix, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.netBox.IPAM().UpdateIPAddress(models.WriteableIPAddress{
+		NestedIPAddress: models.NestedIPAddress{
+			ID:      addr.ID,
+			Address: addr.Address,
+		},
+		Vrf:                vrfID,
+		Tenant:             target.device.Tenant.ID,
+		Status:             addr.Status.Value,
+		Role:               addr.Role.Value,
+		DNSName:            addr.DNSName,
+		Description:        addr.Description,
+		Tags:               addr.Tags,
+		AssignedObjectType: netboxInterfaceType,
+		AssignedObjectID:   target.iface.ID,
+	})
+}
@@ -8,27 +8,33 @@ import (
 	"errors"
 	"fmt"
 	"net/netip"
-	"regexp"
+	"slices"
 	"strconv"
-	"strings"
+	"sync"
+	"time"
 
 	"github.com/sapcc/argora/internal/credentials"
 	"github.com/sapcc/argora/internal/netbox"
 	"github.com/sapcc/argora/internal/netbox/ipam"
 
 	"github.com/go-logr/logr"
-	metalv1alpha1 "github.com/ironcore-dev/metal-operator/api/v1alpha1"
 	"github.com/sapcc/go-netbox-go/models"
+	"golang.org/x/time/rate"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/utils/ptr"
 	ipamv1 "sigs.k8s.io/cluster-api/api/ipam/v1beta2"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 type NetboxConflictError struct {
@@ -49,6 +55,9 @@ const (
 	annotationDeviceKey     = "netbox.argora.cloud.sap/device-id"
 	annotationInterfaceKey  = "netbox.argora.cloud.sap/interface-id"
 	annotationConflictedKey = "netbox.argora.cloud.sap/conflicted"
+
+	// ipResyncJitter spreads the resyncs of IPAddresses synced at the same time, e.g. after a restart.
+	ipResyncJitter = 0.1
 )
 
 // IPUpdateReconciler reconciles a ipam.cluster.x-k8s.io.IPAddress object
@@ -57,28 +66,80 @@ type IPUpdateReconciler struct {
 	scheme      *runtime.Scheme
 	credentials *credentials.Credentials
 	netBox      netbox.Netbox
+	recorder    events.EventRecorder
+
+	conflictPolicy IPConflictPolicy
+	ownerResolvers map[string]ownerResolver
+	interfaceRules map[string]*InterfaceRule
+	// ipAddressTemplate renders the metadata of IP addresses, the metadata is not managed if nil.
+	ipAddressTemplate *IPAddressTemplate
+	// resyncInterval requeues synced IPAddresses to detect drift in NetBox, disabled if zero.
+	resyncInterval time.Duration
+	driftPolicy    IPDriftPolicy
+	deletionPolicy IPDeletionPolicy
+	// backfill syncs existing IPAddresses in controlled batches, it is disabled if nil.
+	backfill *ipBackfill
+
+	// reloadMu serializes the reloads of the credentials and NetBox clients, which are shared by the concurrent
+	// reconciles of the controller and the backfill.
+	reloadMu sync.Mutex
 }
 
-func NewIPUpdateReconciler(mgr ctrl.Manager, creds *credentials.Credentials, netBox netbox.Netbox) *IPUpdateReconciler {
+func NewIPUpdateReconciler(mgr ctrl.Manager, creds *credentials.Credentials, netBox netbox.Netbox, conflictPolicy IPConflictPolicy, interfaceRules map[string]*InterfaceRule, ipAddressTemplate *IPAddressTemplate, resyncInterval time.Duration, driftPolicy IPDriftPolicy, deletionPolicy IPDeletionPolicy) *IPUpdateReconciler {
 	return &IPUpdateReconciler{
-		k8sClient:   mgr.GetClient(),
-		scheme:      mgr.GetScheme(),
-		credentials: creds,
-		netBox:      netBox,
+		k8sClient:      mgr.GetClient(),
+		scheme:         mgr.GetScheme(),
+		credentials:    creds,
+		netBox:         netBox,
+		recorder:       mgr.GetEventRecorder("ipupdate"),
+		conflictPolicy: conflictPolicy,
+		ownerResolvers: defaultOwnerResolvers(),
+		interfaceRules: interfaceRules,
+
+		ipAddressTemplate: ipAddressTemplate,
+		resyncInterval:    resyncInterval,
+		driftPolicy:       driftPolicy,
+		deletionPolicy:    deletionPolicy,
 	}
 }
 
 // SetupWithManager sets up the controller with the Manager.
-func (r *IPUpdateReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		Watches(&ipamv1.IPAddress{}, &handler.EnqueueRequestForObject{}).
-		Named("ipupdate").
-		Complete(r)
+func (r *IPUpdateReconciler) SetupWithManager(mgr ctrl.Manager, rateLimiter RateLimiter, maxConcurrentReconciles int, filter IPUpdateFilter) error {
+	b := ctrl.NewControllerManagedBy(mgr).
+		Watches(&ipamv1.IPAddress{}, &handler.EnqueueRequestForObject{},
+			builder.WithPredicates(filter.predicate(), ipAddressChangedPredicate{})).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: maxConcurrentReconciles,
+			RateLimiter: workqueue.NewTypedMaxOfRateLimiter(
+				workqueue.NewTypedItemExponentialFailureRateLimiter[ctrl.Request](rateLimiter.BaseDelay,
+					rateLimiter.FailureMaxDelay),
+				&workqueue.TypedBucketRateLimiter[ctrl.Request]{
+					Limiter: rate.NewLimiter(rate.Limit(rateLimiter.Frequency), rateLimiter.Burst),
+				},
+			),
+		}).
+		Named("ipupdate")
+
+	if r.backfill != nil {
+		r.backfill.filter = filter
+		if err := mgr.Add(r.backfill); err != nil {
+			return fmt.Errorf("unable to add backfill: %w", err)
+		}
+		b = b.WatchesRawSource(source.Channel(r.backfill.failed, &handler.EnqueueRequestForObject{}))
+	}
+
+	return b.Complete(r)
 }
 
 // +kubebuilder:rbac:groups=ipam.cluster.x-k8s.io,resources=ipaddresses,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=ipam.cluster.x-k8s.io,resources=ipaddressclaims,verbs=get;list;watch
 // +kubebuilder:rbac:groups=metal.ironcore.dev,resources=serverclaims;servers,verbs=list;get;watch
+// +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machines,verbs=get;list;watch
+// +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=metal3machines,verbs=get;list;watch
+// +kubebuilder:rbac:groups=metal3.io,resources=baremetalhosts,verbs=get;list;watch
+// +kubebuilder:rbac:groups=ipam.cluster.x-k8s.io,resources=globalinclusterippools;inclusterippools,verbs=get;list;watch
+// +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;create;update
 
 func (r *IPUpdateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
@@ -94,6 +155,18 @@ func (r *IPUpdateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{}, err
 	}
 
+	if deferred, requeueAfter := r.backfill.defers(ipAddress); deferred {
+		logger.V(1).Info("ip address is deferred to the backfill")
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+
+	return r.reconcileIPAddress(ctx, ipAddress)
+}
+
+// reconcileIPAddress syncs the IPAddress to NetBox, it is shared by the controller and the backfill.
+func (r *IPUpdateReconciler) reconcileIPAddress(ctx context.Context, ipAddress *ipamv1.IPAddress) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
 	prefix, err := getPrefix(ipAddress)
 	if err != nil {
 		logger.Error(err, "unable to get ip prefix")
@@ -103,25 +176,19 @@ func (r *IPUpdateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	logger = logger.WithValues("ipAddress", prefix.String())
 	ctx = log.IntoContext(ctx, logger)
 
-	err = r.credentials.Reload()
-	if err != nil {
-		logger.Error(err, "unable to reload credentials")
-		return ctrl.Result{}, err
-	}
-
-	logger.Info("credentials reloaded", "credentials", r.credentials)
-
-	err = r.netBox.Reload(r.credentials.NetboxToken, logger)
-	if err != nil {
-		logger.Error(err, "unable to reload netbox")
+	observeReload := observePhase(controllerNameIPUpdate, phaseNetboxReload)
+	if err = r.reload(logger); err != nil {
 		return ctrl.Result{}, err
 	}
+	observeReload()
 
 	if !ipAddress.DeletionTimestamp.IsZero() {
+		observeDeletion := observePhase(controllerNameIPUpdate, phaseDeletion)
 		if err = r.reconcileDelete(ctx, ipAddress); err != nil {
 			logger.Error(err, "unable to delete IP Address")
 			return ctrl.Result{}, err
 		}
+		observeDeletion()
 
 		base := ipAddress.DeepCopy()
 		if removed := controllerutil.RemoveFinalizer(ipAddress, ipAddressFinalizer); removed {
@@ -147,29 +214,39 @@ func (r *IPUpdateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{}, err
 	}
 
-	target, err := r.findNetboxTarget(ctx, req.Namespace, ipAddress)
+	observeTarget := observePhase(controllerNameIPUpdate, phaseTarget)
+	target, err := r.findNetboxTarget(ctx, ipAddress.Namespace, ipAddress)
 	if err != nil {
 		logger.Error(err, "unable to find target in NetBox")
 		return ctrl.Result{}, err
 	}
+	observeTarget()
 
 	logger = logger.WithValues("deviceName", target.device.Name, "interface", target.iface.Name)
 	logger.Info("target device and interface are found")
 
-	err = r.reconcileNetbox(target.iface, target.device, ipAddress, logger)
+	reported, err := r.reconcileDrift(ctx, ipAddress, target, logger)
 	if err != nil {
 		if netboxConflictErr, ok := errors.AsType[NetboxConflictError](err); ok {
-			logger.Info("netbox ipaddress conflict", "error", err)
-			err := r.setConflictAnnotation(ctx, ipAddress, netboxConflictErr)
-			if err != nil {
-				return ctrl.Result{}, err
-			}
+			return r.reportConflict(ctx, ipAddress, netboxConflictErr, logger)
+		}
+		logger.Error(err, "unable to reconcile drift")
+		return ctrl.Result{}, err
+	}
+	if reported {
+		return ctrl.Result{RequeueAfter: r.resyncAfter()}, nil
+	}
 
-			return ctrl.Result{}, nil
+	observeNetbox := observePhase(controllerNameIPUpdate, phaseNetbox)
+	err = r.reconcileNetbox(ctx, target, ipAddress, logger)
+	if err != nil {
+		if netboxConflictErr, ok := errors.AsType[NetboxConflictError](err); ok {
+			return r.reportConflict(ctx, ipAddress, netboxConflictErr, logger)
 		}
 		logger.Error(err, "netbox ip reconciliation failed")
 		return ctrl.Result{}, err
 	}
+	observeNetbox()
 
 	err = r.updateIPAddressMetadata(ctx, ipAddress, target, logger)
 	if err != nil {
@@ -179,22 +256,85 @@ func (r *IPUpdateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 
 	logger.Info("reconcile completed successfully")
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: r.resyncAfter()}, nil
+}
+
+// resyncAfter returns the jittered resync interval, zero if the resync is disabled.
+func (r *IPUpdateReconciler) resyncAfter() time.Duration {
+	if r.resyncInterval <= 0 {
+		return 0
+	}
+	return wait.Jitter(r.resyncInterval, ipResyncJitter)
+}
+
+// reportConflict annotates the IPAddress with a conflict kept by the conflict policy. It is requeued with backoff,
+// the conflict may be resolved in NetBox or by a changed policy.
+func (r *IPUpdateReconciler) reportConflict(ctx context.Context, ipAddress *ipamv1.IPAddress, conflictErr NetboxConflictError, logger logr.Logger) (ctrl.Result, error) {
+	netboxIPAddressesTotal.WithLabelValues(operationConflicted).Inc()
+	logger.Info("netbox ipaddress conflict", "error", conflictErr)
+	if err := r.setConflictAnnotation(ctx, ipAddress, conflictErr); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, conflictErr
+}
+
+// reload reloads the credentials and the NetBox clients. Concurrent reconciles are serialized, as the credentials and
+// NetBox clients are shared.
+func (r *IPUpdateReconciler) reload(logger logr.Logger) error {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+
+	if err := r.credentials.Reload(); err != nil {
+		logger.Error(err, "unable to reload credentials")
+		return err
+	}
+
+	logger.Info("credentials reloaded", "credentials", r.credentials)
+
+	if err := r.netBox.Reload(r.credentials.NetboxToken, logger); err != nil {
+		logger.Error(err, "unable to reload netbox")
+		return err
+	}
+
+	return nil
 }
 
 func (r *IPUpdateReconciler) reconcileNetbox(
-	iface models.Interface,
-	device models.Device,
+	ctx context.Context,
+	target *netboxTarget,
 	ipAddr *ipamv1.IPAddress,
 	logger logr.Logger,
 ) error {
 
-	addr, err := r.reconcileNetboxAddressIP(iface, ipAddr, device, logger)
+	prefix, err := getPrefix(ipAddr)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := r.ipAddressMetadata(ipAddr, target)
+	if err != nil {
+		return fmt.Errorf("unable to render ip address metadata: %w", err)
+	}
+
+	addr, err := r.reconcileNetboxAddressIP(target.iface, ipAddr, target.device, metadata, logger)
+	if conflictErr, ok := errors.AsType[NetboxConflictError](err); ok {
+		addr, err = r.resolveConflict(ctx, ipAddr, target, addr, conflictErr, logger)
+	}
 	if err != nil {
 		return err
 	}
 
-	err = r.reconcileDevicePrimaryIP(addr, device, logger)
+	if metadata != nil {
+		if update := metadata.update(addr); update != nil {
+			if addr, err = r.netBox.IPAM().UpdateIPAddress(*update); err != nil {
+				return fmt.Errorf("unable to update ip address metadata: %w", err)
+			}
+			logger.Info("ip address metadata updated", "address_id", addr.ID)
+		}
+	}
+
+	err = r.reconcileDevicePrimaryIP(ctx, ipAddr, addr, prefix, target.device, logger)
 	if err != nil {
 		return err
 	}
@@ -216,25 +356,28 @@ func (r *IPUpdateReconciler) updateIPAddressMetadata(
 	}
 
 	delete(ipAddr.Annotations, annotationConflictedKey)
+	delete(ipAddr.Annotations, annotationDriftedKey)
 	ipAddr.Annotations[annotationDeviceKey] = strconv.Itoa(target.device.ID)
-	ipAddr.Annotations[annotationInterfaceKey] = strconv.Itoa(target.iface.ID)
+	ipAddr.Annotations[annotationInterfaceKey] = interfaceAnnotation(target.iface.ID, target.rule)
 
 	if err := r.k8sClient.Patch(ctx, ipAddr, client.MergeFrom(base)); err != nil {
 		return fmt.Errorf("unable to patch ipaddress with netbox metadata: %w", err)
 	}
 
-	logger.V(1).Info("ipaddress metadata updated", "device-id", target.device.ID, "interface-id", target.iface.ID)
+	logger.V(1).Info("ipaddress metadata updated", "device-id", target.device.ID, "interface-id", target.iface.ID, "interface-rule", target.rule)
 
 	return nil
 }
 
+// getPrefix returns the address with its prefix length. A missing prefix defaults to a host prefix,
+// i.e. /32 for IPv4 and /128 for IPv6 addresses.
 func getPrefix(address *ipamv1.IPAddress) (netip.Prefix, error) {
 	addr, err := netip.ParseAddr(address.Spec.Address)
 	if err != nil {
 		return netip.Prefix{}, err
 	}
 
-	cidr := ptr.Deref(address.Spec.Prefix, 32)
+	cidr := ptr.Deref(address.Spec.Prefix, int32(addr.BitLen()))
 
 	return netip.PrefixFrom(addr, int(cidr)), nil
 }
@@ -243,6 +386,7 @@ func (r *IPUpdateReconciler) reconcileNetboxAddressIP(
 	iface models.Interface,
 	ipAddr *ipamv1.IPAddress,
 	neededDevice models.Device,
+	metadata *ipAddressMetadata,
 	logger logr.Logger,
 ) (*models.IPAddress, error) {
 
@@ -258,7 +402,7 @@ func (r *IPUpdateReconciler) reconcileNetboxAddressIP(
 			return nil, err
 		}
 		logger.V(1).Info("no ip address found, creating ip")
-		addr, err = r.createIPAddress(prefix, iface.ID, neededDevice.Tenant.ID, logger)
+		addr, err = r.createIPAddress(prefix, iface.ID, neededDevice.Tenant.ID, metadata, logger)
 		if err != nil {
 			return nil, fmt.Errorf("unable to create IPAddress: %w", err)
 		}
@@ -280,7 +424,7 @@ func (r *IPUpdateReconciler) reconcileNetboxAddressIP(
 
 	currDeviceID := addr.AssignedInterface.Device.ID
 	if neededDevice.ID != currDeviceID {
-		return nil, NetboxConflictError{
+		return addr, NetboxConflictError{
 			IPAddressID:      addr.ID,
 			ConflictObj:      "device",
 			AssignedNetboxID: currDeviceID,
@@ -293,53 +437,102 @@ func (r *IPUpdateReconciler) reconcileNetboxAddressIP(
 	return addr, nil
 }
 
-func (r *IPUpdateReconciler) createIPAddress(prefix netip.Prefix, ifaceID, tenantID int, logger logr.Logger) (*models.IPAddress, error) {
-	netboxPrefixes, err := r.netBox.IPAM().GetPrefixesByPrefix(prefix.Masked().String())
+func (r *IPUpdateReconciler) createIPAddress(prefix netip.Prefix, ifaceID, tenantID int, metadata *ipAddressMetadata, logger logr.Logger) (*models.IPAddress, error) {
+	vrfID, err := r.prefixVrfID(prefix, logger)
 	if err != nil {
 		return nil, err
 	}
 
-	vrfID := 0 // default(global) vrf
-	if len(netboxPrefixes) == 1 {
-		vrfID = netboxPrefixes[0].Vrf.ID
-	} else {
-		logger.V(1).Info("cannot determine a single prefix for this IP, fallback to default VRF",
-			"prefix", prefix.Masked().String(), "found_amount", len(netboxPrefixes))
-	}
-
 	ipParams := ipam.CreateIPAddressParams{
 		Address:     prefix.String(),
 		TenantID:    tenantID,
 		InterfaceID: ifaceID,
 		VrfID:       vrfID,
 	}
+	if metadata != nil {
+		ipParams.DNSName = metadata.DNSName
+		ipParams.Status = metadata.Status
+		ipParams.Role = metadata.Role
+		ipParams.Description = metadata.Description
+		ipParams.Tags = metadata.Tags
+		ipParams.CustomFields = metadata.CustomFields
+	}
 
 	address, err := r.netBox.IPAM().CreateIPAddress(ipParams)
 	if err != nil {
 		return nil, err
 	}
 
+	netboxIPAddressesTotal.WithLabelValues(operationCreated).Inc()
+
 	return address, nil
 }
 
+// prefixVrfID returns the VRF of the NetBox prefix containing the address, falling back to the global VRF
+// if no single prefix of the address family is found.
+func (r *IPUpdateReconciler) prefixVrfID(prefix netip.Prefix, logger logr.Logger) (int, error) {
+	netboxPrefixes, err := r.netBox.IPAM().GetPrefixesByPrefix(prefix.Masked().String())
+	if err != nil {
+		return 0, err
+	}
+
+	netboxPrefixes = slices.DeleteFunc(netboxPrefixes, func(netboxPrefix models.Prefix) bool {
+		parsed, err := netip.ParsePrefix(netboxPrefix.Prefix)
+		return err == nil && parsed.Addr().Is4() != prefix.Addr().Is4()
+	})
+
+	vrfID := 0 // default(global) vrf
+	if len(netboxPrefixes) == 1 {
+		vrfID = netboxPrefixes[0].Vrf.ID
+	} else {
+		logger.V(1).Info("cannot determine a single prefix for this IP, fallback to default VRF",
+			"prefix", prefix.Masked().String(), "found_amount", len(netboxPrefixes))
+	}
+
+	return vrfID, nil
+}
+
+// reconcileDevicePrimaryIP sets the address as primary IPv4 or IPv6 address of the device, depending on its address family.
+// A primary IP held by another tracked IPAddress of the device is kept.
 func (r *IPUpdateReconciler) reconcileDevicePrimaryIP(
+	ctx context.Context,
+	ipAddr *ipamv1.IPAddress,
 	addr *models.IPAddress,
+	prefix netip.Prefix,
 	device models.Device,
 	logger logr.Logger,
 ) error {
 
-	if device.PrimaryIP.ID == addr.ID {
+	currentPrimaryIP := device.PrimaryIP4.ID
+	if prefix.Addr().Is6() {
+		currentPrimaryIP = device.PrimaryIP6.ID
+	}
+
+	if currentPrimaryIP == addr.ID {
 		logger.V(1).Info("primary device id is same", "ipaddress_id", addr.ID)
 		return nil
 	}
 
+	held, err := r.primaryIPHeldByTrackedIPAddress(ctx, ipAddr, prefix, device)
+	if err != nil {
+		return err
+	}
+	if held {
+		logger.V(1).Info("primary device id is held by another ipaddress", "current_primary_ip", currentPrimaryIP)
+		return nil
+	}
+
 	logger.V(1).Info("updating device primary id", "device_id", device.ID,
-		"current_primary_ip", device.PrimaryIP.ID, "needed_primary_ip", addr.ID)
+		"current_primary_ip", currentPrimaryIP, "needed_primary_ip", addr.ID)
 
 	wDevice := device.Writeable()
-	wDevice.PrimaryIP4 = addr.ID
+	if prefix.Addr().Is6() {
+		wDevice.PrimaryIP6 = addr.ID
+	} else {
+		wDevice.PrimaryIP4 = addr.ID
+	}
 
-	_, err := r.netBox.DCIM().UpdateDevice(wDevice)
+	_, err = r.netBox.DCIM().UpdateDevice(wDevice)
 	if err != nil {
 		return err
 	}
@@ -371,19 +564,19 @@ func (r *IPUpdateReconciler) reconcileDelete(ctx context.Context, ipAddr *ipamv1
 
 	deviceID, interfaceID, err := r.deviceIDAndInterfaceIDFromAnnotations(ipAddr)
 	if err != nil {
-		logger.Info("failed to get device and interface id from ipaddress annotations", "error", err)
+		r.skipDeletion(ipAddr, nbIP, logger, "ipaddress was not synced to NetBox: %s", err)
 		return nil
 	}
 
-	if nbIP.AssignedObjectID != interfaceID {
-		logger.Info("IP is assigned to a different interface in NetBox; skipping deletion to prevent collapse",
-			"actualInterfaceID", nbIP.AssignedObjectID, "expectedInterfaceID", interfaceID, "deviceID", deviceID,
-		)
+	if nbIP.AssignedObjectType != netboxInterfaceType || nbIP.AssignedObjectID != interfaceID ||
+		nbIP.AssignedInterface.Device.ID != deviceID {
+		r.skipDeletion(ipAddr, nbIP, logger, "ip address is assigned to %s %d of device %d, expected interface %d of device %d",
+			nbIP.AssignedObjectType, nbIP.AssignedObjectID, nbIP.AssignedInterface.Device.ID, interfaceID, deviceID)
 		return nil
 	}
 
-	if err = r.netBox.IPAM().DeleteIPAddress(nbIP.ID); err != nil {
-		return fmt.Errorf("delete ip from netbox: %w", err)
+	if err = r.applyDeletionPolicy(ctx, ipAddr, nbIP, prefix, deviceID, logger); err != nil {
+		return err
 	}
 
 	logger.Info("delete reconciliation was successful")
@@ -410,7 +603,7 @@ func (r *IPUpdateReconciler) deviceIDAndInterfaceIDFromAnnotations(ipAddr *ipamv
 		return 0, 0, fmt.Errorf("invalid device id in annotation: %w", err)
 	}
 
-	interfaceID, err = strconv.Atoi(interfaceIDStr)
+	interfaceID, err = interfaceIDFromAnnotation(interfaceIDStr)
 	if err != nil {
 		return 0, 0, fmt.Errorf("invalid interface id in annotation: %w", err)
 	}
@@ -421,10 +614,18 @@ func (r *IPUpdateReconciler) deviceIDAndInterfaceIDFromAnnotations(ipAddr *ipamv
 type netboxTarget struct {
 	device models.Device
 	iface  models.Interface
+	// rule is the name of the interface rule which selected the interface.
+	rule    string
+	ipClaim *ipamv1.IPAddressClaim
 }
 
 func (r *IPUpdateReconciler) findNetboxTarget(ctx context.Context, namespace string, ipAddress *ipamv1.IPAddress) (*netboxTarget, error) {
-	deviceName, err := r.findDeviceName(ctx, namespace, ipAddress)
+	ipClaim := &ipamv1.IPAddressClaim{}
+	if err := r.k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ipAddress.Spec.ClaimRef.Name}, ipClaim); err != nil {
+		return nil, fmt.Errorf("failed to get IPAddressClaim for IPAddress: %w", err)
+	}
+
+	deviceName, err := r.findDeviceName(ctx, ipClaim)
 	if err != nil {
 		return nil, fmt.Errorf("unable to find device name: %w", err)
 	}
@@ -439,59 +640,40 @@ func (r *IPUpdateReconciler) findNetboxTarget(ctx context.Context, namespace str
 		return nil, fmt.Errorf("unable to find interfaces for device %w", err)
 	}
 
-	targetInterface, err := r.findTargetInterface(interfaces)
+	ruleName, rule, err := r.interfaceRule(ctx, ipAddress, ipClaim)
 	if err != nil {
-		return nil, fmt.Errorf("unable to find target interface: %w", err)
+		return nil, fmt.Errorf("unable to select interface rule: %w", err)
+	}
+
+	targetInterface, ok := rule.selectInterface(interfaces)
+	if !ok {
+		return nil, fmt.Errorf("unable to find target interface: no interface of device %s matches interface rule %s", device.Name, ruleName)
 	}
 
 	return &netboxTarget{
-		device: *device,
-		iface:  targetInterface,
+		device:  *device,
+		iface:   targetInterface,
+		rule:    ruleName,
+		ipClaim: ipClaim,
 	}, nil
 }
 
-func (r *IPUpdateReconciler) findDeviceName(ctx context.Context, namespace string, ipAddr *ipamv1.IPAddress) (string, error) {
-	claimName := ipAddr.Spec.ClaimRef.Name
-
-	ipClaim := &ipamv1.IPAddressClaim{}
-	if err := r.k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: claimName}, ipClaim); err != nil {
-		return "", fmt.Errorf("failed to get IPAddressClaim for IPAddress: %w", err)
-	}
-
-	serverClaimName := getOwnerByKind(ipClaim.OwnerReferences, "ServerClaim")
-	if serverClaimName == "" {
-		return "", fmt.Errorf("no ServerClaim owner found for IpAddressClaim %s", ipClaim.Name)
+// findDeviceName resolves the NetBox device name from the device annotation of the IPAddressClaim,
+// or from the first owner of the claim with a resolver for its kind.
+func (r *IPUpdateReconciler) findDeviceName(ctx context.Context, ipClaim *ipamv1.IPAddressClaim) (string, error) {
+	if deviceName := ipClaim.Annotations[annotationDeviceNameKey]; deviceName != "" {
+		return deviceName, nil
 	}
 
-	serverClaim := &metalv1alpha1.ServerClaim{}
-	if err := r.k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: serverClaimName}, serverClaim); err != nil {
-		return "", fmt.Errorf("failed to get ServerClaim: %w", err)
-	}
-
-	if serverClaim.Spec.ServerRef == nil {
-		return "", fmt.Errorf("ServerClaim %s not yet bound to a server", serverClaimName)
-	}
-
-	server := &metalv1alpha1.Server{}
-	if err := r.k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: serverClaim.Spec.ServerRef.Name}, server); err != nil {
-		return "", fmt.Errorf("failed to get Server: %w", err)
-	}
-
-	if server.Spec.BMCRef.Name == "" {
-		return "", fmt.Errorf("server %s has no bmcRef name", server.Name)
-	}
-
-	return server.Spec.BMCRef.Name, nil
-}
-
-func getOwnerByKind(owners []metav1.OwnerReference, kind string) string {
-	for _, o := range owners {
-		if o.Kind == kind {
-			return o.Name
+	for _, owner := range ipClaim.OwnerReferences {
+		resolve, ok := r.ownerResolvers[owner.Kind]
+		if !ok {
+			continue
 		}
+		return resolve(ctx, r.k8sClient, ipClaim.Namespace, owner.Name)
 	}
 
-	return ""
+	return "", fmt.Errorf("no supported owner found for IPAddressClaim %s", ipClaim.Name)
 }
 
 func (r *IPUpdateReconciler) setConflictAnnotation(ctx context.Context, ipAddr *ipamv1.IPAddress, conflictErr NetboxConflictError) error {
@@ -508,34 +690,3 @@ func (r *IPUpdateReconciler) setConflictAnnotation(ctx context.Context, ipAddr *
 
 	return nil
 }
-
-func (r *IPUpdateReconciler) findTargetInterface(interfaces []models.Interface) (models.Interface, error) {
-	var target models.Interface
-	maxLag := ""
-
-	re := regexp.MustCompile(`^LAG(\d)$`)
-
-	for _, iface := range interfaces {
-		if strings.ToLower(iface.Type.Value) != interfaceTypeLag {
-			continue
-		}
-
-		m := re.FindStringSubmatch(iface.Name)
-		if len(m) != 2 {
-			continue
-		}
-
-		lag := m[1]
-
-		if lag > maxLag {
-			maxLag = lag
-			target = iface
-		}
-	}
-
-	if maxLag == "" {
-		return models.Interface{}, errors.New("no LAG interface found for device")
-	}
-
-	return target, nil
-}
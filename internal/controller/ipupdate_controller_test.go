@@ -6,19 +6,33 @@ package controller
 import (
 	"context"
 	"errors"
+	"math"
 	"strconv"
+	"sync"
+	"time"
 
+	"github.com/go-logr/logr"
 	metalv1alpha1 "github.com/ironcore-dev/metal-operator/api/v1alpha1"
+	bmov1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/sapcc/go-netbox-go/models"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/events"
 	"k8s.io/utils/ptr"
+	ipamv1alpha2 "sigs.k8s.io/cluster-api-ipam-provider-in-cluster/api/v1alpha2"
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
 	ipamv1 "sigs.k8s.io/cluster-api/api/ipam/v1beta2"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/sapcc/argora/internal/controller/mock"
@@ -27,12 +41,16 @@ import (
 )
 
 const (
-	ipAddressString       = "192.168.1.100"
-	ipAddressMask   int32 = 24
-	ipAddressID           = 456
-	fullIPAddress         = "192.168.1.100/24"
-	interfaceID           = 123
-	deviceID              = 321
+	ipAddressString         = "192.168.1.100"
+	ipAddressMask     int32 = 24
+	ipAddressID             = 456
+	fullIPAddress           = "192.168.1.100/24"
+	ipv6AddressString       = "2001:db8::10"
+	ipv6AddressMask   int32 = 64
+	ipv6AddressID           = 654
+	fullIPv6Address         = "2001:db8::10/64"
+	interfaceID             = 123
+	deviceID                = 321
 )
 
 var _ = Describe("IP Update Controller", func() {
@@ -50,6 +68,109 @@ var _ = Describe("IP Update Controller", func() {
 		"netboxToken": "token"
 	}`
 
+	prepareNetboxMock := func() *mock.NetBoxMock {
+		netBoxMock := &mock.NetBoxMock{
+			ReturnError:        false,
+			VirtualizationMock: &mock.VirtualizationMock{},
+			DCIMMock: &mock.DCIMMock{
+				GetDeviceByNameFunc: func(_ string) (*models.Device, error) {
+					return &models.Device{
+						ID:         deviceID,
+						Name:       "node001-rack01",
+						PrimaryIP:  models.NestedIPAddress{ID: ipAddressID},
+						PrimaryIP4: models.NestedIPAddress{ID: ipAddressID},
+					}, nil
+				},
+				GetDeviceByIDFunc: func(id int) (*models.Device, error) {
+					return &models.Device{ID: id, Name: "node001-rack01"}, nil
+				},
+				GetInterfacesForDeviceFunc: func(_ *models.Device) ([]models.Interface, error) {
+					return []models.Interface{
+						{
+							Name: "LAG0",
+							Type: models.InterfaceType{Value: "lag"},
+						},
+						{
+							NestedInterface: models.NestedInterface{
+								ID: interfaceID,
+							},
+							Name: "LAG1",
+							Type: models.InterfaceType{Value: "lag"},
+						},
+						{
+							Name: "eth0",
+							Type: models.InterfaceType{Value: "1000base-t"},
+						},
+					}, nil
+				},
+			},
+			IPAMMock: &mock.IPAMMock{
+				GetIPAddressByAddressFunc: func(_ string) (*models.IPAddress, error) {
+					return &models.IPAddress{
+						NestedIPAddress: models.NestedIPAddress{
+							ID:      ipAddressID,
+							Address: fullIPAddress,
+						},
+						AssignedObjectID: interfaceID,
+						AssignedInterface: models.NestedInterface{
+							ID: interfaceID,
+							Device: models.NestedDevice{
+								ID: deviceID,
+							},
+						},
+					}, nil
+				},
+			},
+
+			ExtrasMock: &mock.ExtrasMock{},
+		}
+
+		return netBoxMock
+	}
+
+	newIPAddress := func(name string) *ipamv1.IPAddress {
+		return &ipamv1.IPAddress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       name,
+				Namespace:  resourceNamespace,
+				Generation: 1,
+			},
+			Spec: ipamv1.IPAddressSpec{
+				Address:  ipAddressString,
+				Prefix:   ptr.To(ipAddressMask),
+				ClaimRef: ipamv1.IPAddressClaimReference{Name: "claim"},
+				PoolRef: ipamv1.IPPoolReference{
+					APIGroup: "ipam.cluster.x-k8s.io",
+					Kind:     "GlobalInClusterIPPool",
+					Name:     "test-pool",
+				},
+			},
+		}
+	}
+
+	newTrackedIPAddress := func(name string) *ipamv1.IPAddress {
+		ipAddr := newIPAddress(name)
+		ipAddr.Annotations = map[string]string{
+			"netbox.argora.cloud.sap/device-id":    strconv.Itoa(deviceID),
+			"netbox.argora.cloud.sap/interface-id": strconv.Itoa(interfaceID) + ";rule=default",
+		}
+		return ipAddr
+	}
+
+	newReconciler := func(netBoxMock *mock.NetBoxMock, objects ...client.Object) *IPUpdateReconciler {
+		k8sClient := createFakeClient(objects...)
+		return &IPUpdateReconciler{
+			k8sClient:   k8sClient,
+			scheme:      k8sClient.Scheme(),
+			netBox:      netBoxMock,
+			credentials: credentials.NewDefaultCredentials(fileReaderMock),
+			recorder:    events.NewFakeRecorder(10),
+
+			ownerResolvers: defaultOwnerResolvers(),
+			interfaceRules: DefaultInterfaceRules(),
+		}
+	}
+
 	Context("Reconcile", func() {
 		ctx := context.Background()
 
@@ -65,62 +186,6 @@ var _ = Describe("IP Update Controller", func() {
 			},
 		}
 
-		prepareNetboxMock := func() *mock.NetBoxMock {
-			netBoxMock := &mock.NetBoxMock{
-				ReturnError:        false,
-				VirtualizationMock: &mock.VirtualizationMock{},
-				DCIMMock: &mock.DCIMMock{
-					GetDeviceByNameFunc: func(_ string) (*models.Device, error) {
-						return &models.Device{
-							ID:        deviceID,
-							Name:      "node001-rack01",
-							PrimaryIP: models.NestedIPAddress{ID: ipAddressID},
-						}, nil
-					},
-					GetInterfacesForDeviceFunc: func(_ *models.Device) ([]models.Interface, error) {
-						return []models.Interface{
-							{
-								Name: "LAG0",
-								Type: models.InterfaceType{Value: "lag"},
-							},
-							{
-								NestedInterface: models.NestedInterface{
-									ID: interfaceID,
-								},
-								Name: "LAG1",
-								Type: models.InterfaceType{Value: "lag"},
-							},
-							{
-								Name: "eth0",
-								Type: models.InterfaceType{Value: "1000base-t"},
-							},
-						}, nil
-					},
-				},
-				IPAMMock: &mock.IPAMMock{
-					GetIPAddressByAddressFunc: func(_ string) (*models.IPAddress, error) {
-						return &models.IPAddress{
-							NestedIPAddress: models.NestedIPAddress{
-								ID:      ipAddressID,
-								Address: fullIPAddress,
-							},
-							AssignedObjectID: interfaceID,
-							AssignedInterface: models.NestedInterface{
-								ID: interfaceID,
-								Device: models.NestedDevice{
-									ID: deviceID,
-								},
-							},
-						}, nil
-					},
-				},
-
-				ExtrasMock: &mock.ExtrasMock{},
-			}
-
-			return netBoxMock
-		}
-
 		BeforeEach(func() {
 			By("create Server CR")
 			server := &metalv1alpha1.Server{
@@ -292,7 +357,7 @@ var _ = Describe("IP Update Controller", func() {
 
 			By("verifying netbox metadata annotations")
 			Expect(ipAddress.Annotations).To(HaveKeyWithValue("netbox.argora.cloud.sap/device-id", "321"))
-			Expect(ipAddress.Annotations).To(HaveKeyWithValue("netbox.argora.cloud.sap/interface-id", "123"))
+			Expect(ipAddress.Annotations).To(HaveKeyWithValue("netbox.argora.cloud.sap/interface-id", "123;rule=default"))
 		})
 
 		It("fails when IPAddressClaim does not exist", func() {
@@ -313,7 +378,7 @@ var _ = Describe("IP Update Controller", func() {
 			Expect(err.Error()).To(ContainSubstring("failed to get IPAddressClaim for IPAddress:"))
 		})
 
-		It("fails when IPAddressClaim has no ServerClaim owner", func() {
+		It("fails when IPAddressClaim has no supported owner", func() {
 			netBoxMock := prepareNetboxMock()
 			controllerReconciler := createIPUpdateReconciler(netBoxMock, fileReaderMock)
 
@@ -330,7 +395,7 @@ var _ = Describe("IP Update Controller", func() {
 			})
 
 			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(ContainSubstring("no ServerClaim owner"))
+			Expect(err.Error()).To(ContainSubstring("no supported owner found"))
 		})
 
 		It("fails when ServerClaim is not yet bound to a Server", func() {
@@ -460,6 +525,33 @@ var _ = Describe("IP Update Controller", func() {
 			Expect(err).To(Succeed())
 		})
 
+		It("report a synced ip address deleted in NetBox and requeue it", func() {
+			netBoxMock := prepareNetboxMock()
+			netBoxMock.IPAMMock.(*mock.IPAMMock).GetIPAddressByAddressFunc = func(_ string) (*models.IPAddress, error) {
+				return nil, ipam.ErrNoObjectsFound
+			}
+
+			Expect(k8sClient.Get(ctx, typeNamespacedUpdateName, ipAddress)).To(Succeed())
+			ipAddress.Annotations = map[string]string{
+				"netbox.argora.cloud.sap/device-id":    strconv.Itoa(deviceID),
+				"netbox.argora.cloud.sap/interface-id": strconv.Itoa(interfaceID) + ";rule=default",
+			}
+			Expect(k8sClient.Update(ctx, ipAddress)).To(Succeed())
+
+			controllerRecociler := createIPUpdateReconciler(netBoxMock, fileReaderMock)
+			controllerRecociler.driftPolicy = IPDriftPolicyReport
+			controllerRecociler.resyncInterval = time.Minute
+
+			result, err := controllerRecociler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedUpdateName})
+
+			Expect(err).To(Succeed())
+			Expect(result.RequeueAfter).To(BeNumerically(">=", time.Minute))
+			Expect(result.RequeueAfter).To(BeNumerically("<=", time.Minute+6*time.Second))
+			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).CreateIPAddressCalls).To(Equal(0))
+			Expect(k8sClient.Get(ctx, typeNamespacedUpdateName, ipAddress)).To(Succeed())
+			Expect(ipAddress.Annotations).To(HaveKeyWithValue("netbox.argora.cloud.sap/drifted", "missing"))
+		})
+
 		It("raise error if unable to create IP address", func() {
 			netBoxMock := prepareNetboxMock()
 			netBoxMock.IPAMMock = &mock.IPAMMock{
@@ -552,7 +644,7 @@ var _ = Describe("IP Update Controller", func() {
 
 			_, err := controllerRecociler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedUpdateName})
 
-			Expect(err).ToNot(HaveOccurred())
+			Expect(err).To(BeAssignableToTypeOf(NetboxConflictError{}))
 			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).GetIPAddressByAddressCalls).To(Equal(1))
 
 			ipAddress := &ipamv1.IPAddress{}
@@ -582,7 +674,7 @@ var _ = Describe("IP Update Controller", func() {
 
 			_, err := controllerRecociler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedUpdateName})
 
-			Expect(err).ToNot(HaveOccurred())
+			Expect(err).To(BeAssignableToTypeOf(NetboxConflictError{}))
 			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).GetIPAddressByAddressCalls).To(Equal(1))
 
 			ipAddress := &ipamv1.IPAddress{}
@@ -590,6 +682,109 @@ var _ = Describe("IP Update Controller", func() {
 			Expect(ipAddress.Annotations).To(HaveKeyWithValue("netbox.argora.cloud.sap/conflicted", "device"))
 		})
 
+		It("takes over the ip address from another device with the takeover policy", func() {
+			Expect(k8sClient.Get(ctx, typeNamespacedUpdateName, ipAddress)).To(Succeed())
+			ipAddress.Annotations = map[string]string{
+				"netbox.argora.cloud.sap/conflict-policy": "Takeover",
+			}
+			Expect(k8sClient.Update(ctx, ipAddress)).To(Succeed())
+
+			netBoxMock := prepareNetboxMock()
+			dcimMock := netBoxMock.DCIMMock.(*mock.DCIMMock)
+			dcimMock.GetDeviceByIDFunc = func(id int) (*models.Device, error) {
+				Expect(id).To(Equal(999))
+				return &models.Device{
+					ID:         999,
+					Name:       "node002-rack01",
+					Status:     models.DeviceStatus{Value: "active"},
+					PrimaryIP4: models.NestedIPAddress{ID: ipAddressID},
+				}, nil
+			}
+			dcimMock.ClearDevicePrimaryIPFunc = func(_ context.Context, deviceID int, ipv6 bool) error {
+				Expect(deviceID).To(Equal(999))
+				Expect(ipv6).To(BeFalse())
+				return nil
+			}
+			netBoxMock.IPAMMock = &mock.IPAMMock{
+				GetIPAddressByAddressFunc: func(_ string) (*models.IPAddress, error) {
+					return &models.IPAddress{
+						NestedIPAddress: models.NestedIPAddress{
+							ID:      ipAddressID,
+							Address: fullIPAddress,
+						},
+						Status: models.IPAddressStatus{Value: "active"},
+						AssignedInterface: models.NestedInterface{
+							ID:     77,
+							Device: models.NestedDevice{ID: 999},
+						},
+					}, nil
+				},
+				GetPrefixesByPrefixesFunc: func(_ string) ([]models.Prefix, error) {
+					return []models.Prefix{{Prefix: "192.168.1.0/24", Vrf: models.NestedVRF{ID: 5}}}, nil
+				},
+				UpdateIPAddressFunc: func(addr models.WriteableIPAddress) (*models.IPAddress, error) {
+					Expect(addr.ID).To(Equal(ipAddressID))
+					Expect(addr.Vrf).To(Equal(5))
+					Expect(addr.Status).To(Equal("active"))
+					Expect(addr.AssignedObjectType).To(Equal("dcim.interface"))
+					Expect(addr.AssignedObjectID).To(Equal(interfaceID))
+					return &models.IPAddress{
+						NestedIPAddress:   addr.NestedIPAddress,
+						AssignedObjectID:  interfaceID,
+						AssignedInterface: models.NestedInterface{ID: interfaceID, Device: models.NestedDevice{ID: deviceID}},
+					}, nil
+				},
+			}
+
+			controllerReconciler := createIPUpdateReconciler(netBoxMock, fileReaderMock)
+			recorder := controllerReconciler.recorder.(*events.FakeRecorder)
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedUpdateName})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dcimMock.ClearDevicePrimaryIPCalls).To(Equal(1))
+			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).UpdateIPAddressCalls).To(Equal(1))
+			Expect(recorder.Events).To(Receive(ContainSubstring("IPConflictTakeover")))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedUpdateName, ipAddress)).To(Succeed())
+			Expect(ipAddress.Annotations).To(Not(HaveKey("netbox.argora.cloud.sap/conflicted")))
+			Expect(ipAddress.Annotations).To(HaveKeyWithValue("netbox.argora.cloud.sap/interface-id", strconv.Itoa(interfaceID)+";rule=default"))
+		})
+
+		It("keeps the conflict if the holding device is active with the takeover if stale policy", func() {
+			netBoxMock := prepareNetboxMock()
+			netBoxMock.DCIMMock.(*mock.DCIMMock).GetDeviceByIDFunc = func(id int) (*models.Device, error) {
+				return &models.Device{ID: id, Name: "node002-rack01", Status: models.DeviceStatus{Value: "active"}}, nil
+			}
+			netBoxMock.IPAMMock = &mock.IPAMMock{
+				GetIPAddressByAddressFunc: func(_ string) (*models.IPAddress, error) {
+					return &models.IPAddress{
+						NestedIPAddress: models.NestedIPAddress{
+							ID:      ipAddressID,
+							Address: fullIPAddress,
+						},
+						AssignedInterface: models.NestedInterface{
+							ID:     77,
+							Device: models.NestedDevice{ID: 999},
+						},
+					}, nil
+				},
+			}
+
+			controllerReconciler := createIPUpdateReconciler(netBoxMock, fileReaderMock)
+			controllerReconciler.conflictPolicy = IPConflictPolicyTakeoverIfStale
+			recorder := controllerReconciler.recorder.(*events.FakeRecorder)
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedUpdateName})
+
+			Expect(err).To(BeAssignableToTypeOf(NetboxConflictError{}))
+			Expect(netBoxMock.DCIMMock.(*mock.DCIMMock).GetDeviceByIDCalls).To(Equal(1))
+			Expect(recorder.Events).To(Receive(ContainSubstring("IPConflict")))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedUpdateName, ipAddress)).To(Succeed())
+			Expect(ipAddress.Annotations).To(HaveKeyWithValue("netbox.argora.cloud.sap/conflicted", "interface"))
+		})
+
 		It("delete conflicted annotation, in case if conflict ended", func() {
 			ipAddress := &ipamv1.IPAddress{}
 			Expect(k8sClient.Get(ctx, typeNamespacedUpdateName, ipAddress)).To(Succeed())
@@ -671,6 +866,86 @@ var _ = Describe("IP Update Controller", func() {
 			Expect(err).To(MatchError(updateErr))
 		})
 
+		It("sets primary ipv6 and keeps primary ipv4 for an ipv6 address on the same device", func() {
+			ipAddress := &ipamv1.IPAddress{}
+			Expect(k8sClient.Get(ctx, typeNamespacedUpdateName, ipAddress)).To(Succeed())
+			ipAddress.Spec.Address = ipv6AddressString
+			ipAddress.Spec.Prefix = ptr.To(ipv6AddressMask)
+			Expect(k8sClient.Update(ctx, ipAddress)).To(Succeed())
+
+			netBoxMock := prepareNetboxMock()
+			netBoxMock.IPAMMock = &mock.IPAMMock{
+				GetIPAddressByAddressFunc: func(address string) (*models.IPAddress, error) {
+					Expect(address).To(Equal(fullIPv6Address))
+					return nil, ipam.ErrNoObjectsFound
+				},
+				GetPrefixesByPrefixesFunc: func(_ string) ([]models.Prefix, error) {
+					return []models.Prefix{
+						{Prefix: "192.168.1.0/24", Vrf: models.NestedVRF{ID: 1}},
+						{Prefix: "2001:db8::/64", Vrf: models.NestedVRF{ID: 2}},
+					}, nil
+				},
+				CreateIPAddressFunc: func(params ipam.CreateIPAddressParams) (*models.IPAddress, error) {
+					Expect(params.Address).To(Equal(fullIPv6Address))
+					Expect(params.VrfID).To(Equal(2))
+					return &models.IPAddress{
+						NestedIPAddress:   models.NestedIPAddress{ID: ipv6AddressID, Address: fullIPv6Address},
+						AssignedInterface: models.NestedInterface{ID: interfaceID, Device: models.NestedDevice{ID: deviceID}},
+					}, nil
+				},
+			}
+			dcimMock := netBoxMock.DCIMMock.(*mock.DCIMMock)
+			dcimMock.UpdateDeviceFunc = func(device models.WritableDeviceWithConfigContext) (*models.Device, error) {
+				Expect(device.PrimaryIP4).To(Equal(ipAddressID))
+				Expect(device.PrimaryIP6).To(Equal(ipv6AddressID))
+				return &models.Device{ID: deviceID}, nil
+			}
+
+			controllerRecociler := createIPUpdateReconciler(netBoxMock, fileReaderMock)
+
+			_, err := controllerRecociler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedUpdateName})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).CreateIPAddressCalls).To(Equal(1))
+			Expect(dcimMock.UpdateDeviceCalls).To(Equal(1))
+		})
+
+		It("does not update primary ipv6 if it is matching", func() {
+			ipAddress := &ipamv1.IPAddress{}
+			Expect(k8sClient.Get(ctx, typeNamespacedUpdateName, ipAddress)).To(Succeed())
+			ipAddress.Spec.Address = ipv6AddressString
+			ipAddress.Spec.Prefix = ptr.To(ipv6AddressMask)
+			Expect(k8sClient.Update(ctx, ipAddress)).To(Succeed())
+
+			netBoxMock := prepareNetboxMock()
+			netBoxMock.IPAMMock = &mock.IPAMMock{
+				GetIPAddressByAddressFunc: func(_ string) (*models.IPAddress, error) {
+					return &models.IPAddress{
+						NestedIPAddress: models.NestedIPAddress{ID: ipv6AddressID, Address: fullIPv6Address},
+						AssignedInterface: models.NestedInterface{
+							ID:     interfaceID,
+							Device: models.NestedDevice{ID: deviceID},
+						},
+					}, nil
+				},
+			}
+			dcimMock := netBoxMock.DCIMMock.(*mock.DCIMMock)
+			dcimMock.GetDeviceByNameFunc = func(_ string) (*models.Device, error) {
+				return &models.Device{
+					ID:         deviceID,
+					PrimaryIP4: models.NestedIPAddress{ID: ipAddressID},
+					PrimaryIP6: models.NestedIPAddress{ID: ipv6AddressID},
+				}, nil
+			}
+
+			controllerRecociler := createIPUpdateReconciler(netBoxMock, fileReaderMock)
+
+			_, err := controllerRecociler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedUpdateName})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dcimMock.UpdateDeviceCalls).To(Equal(0))
+		})
+
 		Context("Deletion", func() {
 			BeforeEach(func() {
 				ip := &ipamv1.IPAddress{}
@@ -848,8 +1123,10 @@ var _ = Describe("IP Update Controller", func() {
 				ipamMock := netBoxMock.IPAMMock.(*mock.IPAMMock)
 				ipamMock.GetIPAddressByAddressFunc = func(address string) (*models.IPAddress, error) {
 					return &models.IPAddress{
-						NestedIPAddress:  models.NestedIPAddress{ID: ipAddressID, Address: fullIPAddress},
-						AssignedObjectID: interfaceID,
+						NestedIPAddress:    models.NestedIPAddress{ID: ipAddressID, Address: fullIPAddress},
+						AssignedObjectType: "dcim.interface",
+						AssignedObjectID:   interfaceID,
+						AssignedInterface:  models.NestedInterface{ID: interfaceID, Device: models.NestedDevice{ID: deviceID}},
 					}, nil
 				}
 				ipamMock.DeleteIPAddressFunc = func(id int) error {
@@ -886,8 +1163,10 @@ var _ = Describe("IP Update Controller", func() {
 				ipamMock := netBoxMock.IPAMMock.(*mock.IPAMMock)
 				ipamMock.GetIPAddressByAddressFunc = func(address string) (*models.IPAddress, error) {
 					return &models.IPAddress{
-						NestedIPAddress:  models.NestedIPAddress{ID: ipAddressID, Address: fullIPAddress},
-						AssignedObjectID: interfaceID,
+						NestedIPAddress:    models.NestedIPAddress{ID: ipAddressID, Address: fullIPAddress},
+						AssignedObjectType: "dcim.interface",
+						AssignedObjectID:   interfaceID,
+						AssignedInterface:  models.NestedInterface{ID: interfaceID, Device: models.NestedDevice{ID: deviceID}},
 					}, nil
 				}
 				ipamMock.DeleteIPAddressFunc = func(id int) error {
@@ -931,8 +1210,10 @@ var _ = Describe("IP Update Controller", func() {
 				ipamMock := netBoxMock.IPAMMock.(*mock.IPAMMock)
 				ipamMock.GetIPAddressByAddressFunc = func(address string) (*models.IPAddress, error) {
 					return &models.IPAddress{
-						NestedIPAddress:  models.NestedIPAddress{ID: ipAddressID, Address: fullIPAddress},
-						AssignedObjectID: interfaceID,
+						NestedIPAddress:    models.NestedIPAddress{ID: ipAddressID, Address: fullIPAddress},
+						AssignedObjectType: "dcim.interface",
+						AssignedObjectID:   interfaceID,
+						AssignedInterface:  models.NestedInterface{ID: interfaceID, Device: models.NestedDevice{ID: deviceID}},
 					}, nil
 				}
 				ipamMock.DeleteIPAddressFunc = func(id int) error {
@@ -961,72 +1242,1100 @@ var _ = Describe("IP Update Controller", func() {
 				Expect(err.Error()).To(ContainSubstring("delete ip from netbox"))
 			})
 
-			It("returns error when IP prefix cannot be parsed", func() {
+			It("skips deletion when IP assigned to the interface of another device", func() {
 				netBoxMock := prepareNetboxMock()
+				ipamMock := netBoxMock.IPAMMock.(*mock.IPAMMock)
+				ipamMock.GetIPAddressByAddressFunc = func(address string) (*models.IPAddress, error) {
+					return &models.IPAddress{
+						NestedIPAddress:    models.NestedIPAddress{ID: ipAddressID, Address: fullIPAddress},
+						AssignedObjectType: "dcim.interface",
+						AssignedObjectID:   interfaceID,
+						AssignedInterface:  models.NestedInterface{ID: interfaceID, Device: models.NestedDevice{ID: 999}},
+					}, nil
+				}
+
 				controllerReconciler := createIPUpdateReconciler(netBoxMock, fileReaderMock)
 
 				ip := &ipamv1.IPAddress{}
 				Expect(k8sClient.Get(ctx, typeNamespacedUpdateName, ip)).To(Succeed())
-				ip.Spec.Address = "not-an-ip"
-				Expect(k8sClient.Update(ctx, ip)).To(Succeed())
 				Expect(k8sClient.Delete(ctx, ip)).To(Succeed())
 
 				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedUpdateName})
-				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("ParseAddr"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ipamMock.DeleteIPAddressCalls).To(Equal(0))
+				Expect(controllerReconciler.recorder.(*events.FakeRecorder).Events).To(Receive(ContainSubstring("IPDeletionSkipped")))
 			})
-		})
-	})
-
-	Context("findTargetInterface", func() {
-		It("returns error when no LAG interfaces exist", func() {
-			r := &IPUpdateReconciler{}
 
-			_, err := r.findTargetInterface([]models.Interface{
-				{
-					Name: "eth0",
-					Type: models.InterfaceType{Value: "1000base-t"},
-				},
-			})
+			It("clears the primary ip of the device before deleting IP", func() {
+				netBoxMock := prepareNetboxMock()
+				ipamMock := netBoxMock.IPAMMock.(*mock.IPAMMock)
+				ipamMock.GetIPAddressByAddressFunc = func(address string) (*models.IPAddress, error) {
+					return &models.IPAddress{
+						NestedIPAddress:    models.NestedIPAddress{ID: ipAddressID, Address: fullIPAddress},
+						AssignedObjectType: "dcim.interface",
+						AssignedObjectID:   interfaceID,
+						AssignedInterface:  models.NestedInterface{ID: interfaceID, Device: models.NestedDevice{ID: deviceID}},
+					}, nil
+				}
+				ipamMock.DeleteIPAddressFunc = func(id int) error {
+					return nil
+				}
+				dcimMock := netBoxMock.DCIMMock.(*mock.DCIMMock)
+				dcimMock.GetDeviceByIDFunc = func(id int) (*models.Device, error) {
+					return &models.Device{ID: id, PrimaryIP4: models.NestedIPAddress{ID: ipAddressID}}, nil
+				}
+				dcimMock.ClearDevicePrimaryIPFunc = func(_ context.Context, id int, ipv6 bool) error {
+					Expect(id).To(Equal(deviceID))
+					Expect(ipv6).To(BeFalse())
+					return nil
+				}
 
-			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(ContainSubstring("no LAG interface"))
-		})
+				controllerReconciler := createIPUpdateReconciler(netBoxMock, fileReaderMock)
 
-		It("returns the only LAG interface when only one exists", func() {
-			r := &IPUpdateReconciler{}
+				ip := &ipamv1.IPAddress{}
+				Expect(k8sClient.Get(ctx, typeNamespacedUpdateName, ip)).To(Succeed())
+				Expect(k8sClient.Delete(ctx, ip)).To(Succeed())
 
-			iface, err := r.findTargetInterface([]models.Interface{
-				{
-					Name: "LAG0",
-					Type: models.InterfaceType{Value: "lag"},
-				},
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedUpdateName})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(dcimMock.ClearDevicePrimaryIPCalls).To(Equal(1))
+				Expect(ipamMock.DeleteIPAddressCalls).To(Equal(1))
 			})
 
-			Expect(err).ToNot(HaveOccurred())
-			Expect(iface.Name).To(Equal("LAG0"))
-		})
-
-		It("returns the highest numbered LAG interface", func() {
-			r := &IPUpdateReconciler{}
-
-			iface, err := r.findTargetInterface([]models.Interface{
-				{
-					Name: "LAG0",
+			It("marks IP deprecated with MarkDeprecated deletion policy", func() {
+				netBoxMock := prepareNetboxMock()
+				ipamMock := netBoxMock.IPAMMock.(*mock.IPAMMock)
+				ipamMock.GetIPAddressByAddressFunc = func(address string) (*models.IPAddress, error) {
+					return &models.IPAddress{
+						NestedIPAddress:    models.NestedIPAddress{ID: ipAddressID, Address: fullIPAddress},
+						AssignedObjectType: "dcim.interface",
+						AssignedObjectID:   interfaceID,
+						AssignedInterface:  models.NestedInterface{ID: interfaceID, Device: models.NestedDevice{ID: deviceID}},
+					}, nil
+				}
+				ipamMock.UpdateIPAddressFunc = func(addr models.WriteableIPAddress) (*models.IPAddress, error) {
+					Expect(addr.ID).To(Equal(ipAddressID))
+					Expect(addr.Status).To(Equal("deprecated"))
+					Expect(addr.AssignedObjectID).To(BeZero())
+					return &models.IPAddress{}, nil
+				}
+
+				controllerReconciler := createIPUpdateReconciler(netBoxMock, fileReaderMock)
+				controllerReconciler.deletionPolicy = IPDeletionPolicyMarkDeprecated
+				deprecated := testutil.ToFloat64(netboxIPAddressesTotal.WithLabelValues(operationDeprecated))
+
+				ip := &ipamv1.IPAddress{}
+				Expect(k8sClient.Get(ctx, typeNamespacedUpdateName, ip)).To(Succeed())
+				Expect(k8sClient.Delete(ctx, ip)).To(Succeed())
+
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedUpdateName})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ipamMock.UpdateIPAddressCalls).To(Equal(1))
+				Expect(ipamMock.DeleteIPAddressCalls).To(Equal(0))
+				Expect(testutil.ToFloat64(netboxIPAddressesTotal.WithLabelValues(operationDeprecated))).To(Equal(deprecated + 1))
+			})
+
+			It("unassigns IP with Unassign deletion policy annotation", func() {
+				netBoxMock := prepareNetboxMock()
+				ipamMock := netBoxMock.IPAMMock.(*mock.IPAMMock)
+				ipamMock.GetIPAddressByAddressFunc = func(address string) (*models.IPAddress, error) {
+					return &models.IPAddress{
+						NestedIPAddress:    models.NestedIPAddress{ID: ipAddressID, Address: fullIPAddress},
+						AssignedObjectType: "dcim.interface",
+						AssignedObjectID:   interfaceID,
+						AssignedInterface:  models.NestedInterface{ID: interfaceID, Device: models.NestedDevice{ID: deviceID}},
+					}, nil
+				}
+				ipamMock.UnassignIPAddressFunc = func(_ context.Context, id int) error {
+					Expect(id).To(Equal(ipAddressID))
+					return nil
+				}
+
+				controllerReconciler := createIPUpdateReconciler(netBoxMock, fileReaderMock)
+
+				ip := &ipamv1.IPAddress{}
+				Expect(k8sClient.Get(ctx, typeNamespacedUpdateName, ip)).To(Succeed())
+				ip.Annotations["netbox.argora.cloud.sap/deletion-policy"] = "Unassign"
+				Expect(k8sClient.Update(ctx, ip)).To(Succeed())
+				Expect(k8sClient.Delete(ctx, ip)).To(Succeed())
+
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedUpdateName})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ipamMock.UnassignIPAddressCalls).To(Equal(1))
+				Expect(ipamMock.DeleteIPAddressCalls).To(Equal(0))
+			})
+
+			It("returns error when IP prefix cannot be parsed", func() {
+				netBoxMock := prepareNetboxMock()
+				controllerReconciler := createIPUpdateReconciler(netBoxMock, fileReaderMock)
+
+				ip := &ipamv1.IPAddress{}
+				Expect(k8sClient.Get(ctx, typeNamespacedUpdateName, ip)).To(Succeed())
+				ip.Spec.Address = "not-an-ip"
+				Expect(k8sClient.Update(ctx, ip)).To(Succeed())
+				Expect(k8sClient.Delete(ctx, ip)).To(Succeed())
+
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedUpdateName})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("ParseAddr"))
+			})
+		})
+	})
+
+	Context("getPrefix", func() {
+		It("defaults to a host prefix of the address family", func() {
+			prefix, err := getPrefix(&ipamv1.IPAddress{Spec: ipamv1.IPAddressSpec{Address: ipAddressString}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(prefix.String()).To(Equal("192.168.1.100/32"))
+
+			prefix, err = getPrefix(&ipamv1.IPAddress{Spec: ipamv1.IPAddressSpec{Address: ipv6AddressString}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(prefix.String()).To(Equal("2001:db8::10/128"))
+		})
+
+		It("uses the prefix of the address", func() {
+			prefix, err := getPrefix(&ipamv1.IPAddress{Spec: ipamv1.IPAddressSpec{
+				Address: ipv6AddressString,
+				Prefix:  ptr.To(ipv6AddressMask),
+			}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(prefix.String()).To(Equal(fullIPv6Address))
+		})
+	})
+
+	Context("interface rules", func() {
+		defaultRule := DefaultInterfaceRules()[DefaultInterfaceRuleName]
+
+		It("returns no interface when no LAG interfaces exist", func() {
+			_, ok := defaultRule.selectInterface([]models.Interface{
+				{
+					Name: "eth0",
+					Type: models.InterfaceType{Value: "1000base-t"},
+				},
+			})
+
+			Expect(ok).To(BeFalse())
+		})
+
+		It("returns the only LAG interface when only one exists", func() {
+			iface, ok := defaultRule.selectInterface([]models.Interface{
+				{
+					Name: "LAG0",
 					Type: models.InterfaceType{Value: "lag"},
 				},
+			})
+
+			Expect(ok).To(BeTrue())
+			Expect(iface.Name).To(Equal("LAG0"))
+		})
+
+		It("returns the highest numbered LAG interface", func() {
+			iface, ok := defaultRule.selectInterface([]models.Interface{
 				{
-					Name: "LAG2",
+					Name: "LAG0",
+					Type: models.InterfaceType{Value: "lag"},
+				},
+				{
+					Name: "LAG10",
 					Type: models.InterfaceType{Value: "lag"},
 				},
 				{
-					Name: "LAG1",
+					Name: "LAG2",
 					Type: models.InterfaceType{Value: "lag"},
 				},
 			})
 
+			Expect(ok).To(BeTrue())
+			Expect(iface.Name).To(Equal("LAG10"))
+		})
+
+		It("selects interfaces by name, pattern order, type and tag", func() {
+			rulesReader := &mock.FileReaderMock{FileContent: map[string]string{"rules.json": `{
+				"loopback": {"name": "lo0"},
+				"vlan": {"pattern": "^LAG1\\.(\\d+)$", "order": "Lowest", "type": "virtual"},
+				"storage": {"tag": "storage"}
+			}`}}
+			rules, err := LoadInterfaceRules(rulesReader, "rules.json")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rules).To(HaveKey(DefaultInterfaceRuleName))
+
+			interfaces := []models.Interface{
+				{Name: "lo0", Type: models.InterfaceType{Value: "virtual"}},
+				{Name: "LAG1.300", Type: models.InterfaceType{Value: "virtual"}},
+				{Name: "LAG1.100", Type: models.InterfaceType{Value: "virtual"}},
+				{Name: "LAG1", Type: models.InterfaceType{Value: "lag"}},
+				{Name: "eth1", Type: models.InterfaceType{Value: "25gbase-x-sfp28"}, Tags: []models.NestedTag{{Slug: "storage"}}},
+			}
+
+			for rule, expected := range map[string]string{
+				"loopback":               "lo0",
+				"vlan":                   "LAG1.100",
+				"storage":                "eth1",
+				DefaultInterfaceRuleName: "LAG1",
+			} {
+				iface, ok := rules[rule].selectInterface(interfaces)
+				Expect(ok).To(BeTrue(), rule)
+				Expect(iface.Name).To(Equal(expected), rule)
+			}
+		})
+
+		It("rejects invalid interface rules", func() {
+			rulesReader := &mock.FileReaderMock{FileContent: map[string]string{
+				"empty.json":   `{"empty": {}}`,
+				"pattern.json": `{"broken": {"pattern": "LAG("}}`,
+				"order.json":   `{"sorted": {"type": "lag", "order": "Random"}}`,
+			}}
+
+			_, err := LoadInterfaceRules(rulesReader, "empty.json")
+			Expect(err).To(MatchError("invalid interface rule empty: one of name, pattern, type or tag must be set"))
+			_, err = LoadInterfaceRules(rulesReader, "pattern.json")
+			Expect(err).To(MatchError(ContainSubstring("invalid interface rule broken: invalid pattern")))
+			_, err = LoadInterfaceRules(rulesReader, "order.json")
+			Expect(err).To(MatchError(`invalid interface rule sorted: unsupported order "Random"`))
+		})
+
+		It("selects the rule from the claim annotation, the pool label or the default", func() {
+			ctx := context.Background()
+			pool := &ipamv1alpha2.GlobalInClusterIPPool{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "pool",
+					Labels: map[string]string{"netbox.argora.cloud.sap/interface-rule": "storage"},
+				},
+			}
+			reconciler := &IPUpdateReconciler{
+				k8sClient: createFakeClient(pool),
+				interfaceRules: map[string]*InterfaceRule{
+					DefaultInterfaceRuleName: DefaultInterfaceRules()[DefaultInterfaceRuleName],
+					"storage":                {Tag: "storage"},
+					"loopback":               {Name: "lo0"},
+				},
+			}
+			ipAddr := &ipamv1.IPAddress{
+				ObjectMeta: metav1.ObjectMeta{Name: "ip", Namespace: "default"},
+				Spec: ipamv1.IPAddressSpec{PoolRef: ipamv1.IPPoolReference{
+					APIGroup: "ipam.cluster.x-k8s.io",
+					Kind:     "GlobalInClusterIPPool",
+					Name:     "pool",
+				}},
+			}
+			claim := &ipamv1.IPAddressClaim{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{"netbox.argora.cloud.sap/interface-rule": "loopback"},
+			}}
+
+			name, _, err := reconciler.interfaceRule(ctx, ipAddr, claim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(name).To(Equal("loopback"))
+
+			name, _, err = reconciler.interfaceRule(ctx, ipAddr, &ipamv1.IPAddressClaim{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(name).To(Equal("storage"))
+
+			ipAddr.Spec.PoolRef.Name = "other"
+			name, _, err = reconciler.interfaceRule(ctx, ipAddr, &ipamv1.IPAddressClaim{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(name).To(Equal(DefaultInterfaceRuleName))
+
+			claim.Annotations["netbox.argora.cloud.sap/interface-rule"] = "unknown"
+			_, _, err = reconciler.interfaceRule(ctx, ipAddr, claim)
+			Expect(err).To(MatchError(`unknown interface rule "unknown"`))
+		})
+	})
+
+	Context("conflict policy", func() {
+		ctx := context.Background()
+
+		It("should parse conflict policies", func() {
+			policy, err := ParseIPConflictPolicy("TakeoverIfStale")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(policy).To(Equal(IPConflictPolicyTakeoverIfStale))
+
+			_, err = ParseIPConflictPolicy("Steal")
+			Expect(err).To(MatchError(ContainSubstring(`invalid ip conflict policy "Steal"`)))
+		})
+
+		It("should resolve the policy from the ipaddress, its pool and the default", func() {
+			globalPool := &ipamv1alpha2.GlobalInClusterIPPool{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "global-pool",
+					Annotations: map[string]string{"netbox.argora.cloud.sap/conflict-policy": "Takeover"},
+				},
+			}
+			pool := &ipamv1alpha2.InClusterIPPool{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "pool",
+					Namespace:   "default",
+					Annotations: map[string]string{"netbox.argora.cloud.sap/conflict-policy": "TakeoverIfStale"},
+				},
+			}
+			reconciler := newReconciler(&mock.NetBoxMock{}, globalPool, pool)
+
+			ipAddr := newIPAddress("ip")
+			ipAddr.Annotations = map[string]string{"netbox.argora.cloud.sap/conflict-policy": "Report"}
+			ipAddr.Spec.PoolRef.Name = "global-pool"
+			policy, err := reconciler.ipConflictPolicy(ctx, ipAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(policy).To(Equal(IPConflictPolicyReport))
+
+			ipAddr.Annotations = nil
+			policy, err = reconciler.ipConflictPolicy(ctx, ipAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(policy).To(Equal(IPConflictPolicyTakeover))
+
+			ipAddr.Spec.PoolRef.Kind = "InClusterIPPool"
+			ipAddr.Spec.PoolRef.Name = "pool"
+			policy, err = reconciler.ipConflictPolicy(ctx, ipAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(policy).To(Equal(IPConflictPolicyTakeoverIfStale))
+
+			ipAddr.Spec.PoolRef.Name = "missing"
+			policy, err = reconciler.ipConflictPolicy(ctx, ipAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(policy).To(Equal(IPConflictPolicyReport))
+
+			reconciler.conflictPolicy = IPConflictPolicyTakeover
+			ipAddr.Spec.PoolRef.Kind = "IPPool"
+			ipAddr.Spec.PoolRef.Name = "other"
+			policy, err = reconciler.ipConflictPolicy(ctx, ipAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(policy).To(Equal(IPConflictPolicyTakeover))
+		})
+
+		It("should reject invalid policy annotations", func() {
+			ipAddr := newIPAddress("ip")
+			ipAddr.Annotations = map[string]string{"netbox.argora.cloud.sap/conflict-policy": "Steal"}
+
+			_, err := newReconciler(&mock.NetBoxMock{}).ipConflictPolicy(ctx, ipAddr)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("deletion policy", func() {
+		ctx := context.Background()
+
+		It("should parse deletion policies", func() {
+			policy, err := ParseIPDeletionPolicy("MarkDeprecated")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(policy).To(Equal(IPDeletionPolicyMarkDeprecated))
+
+			_, err = ParseIPDeletionPolicy("Keep")
+			Expect(err).To(MatchError(ContainSubstring(`invalid ip deletion policy "Keep"`)))
+		})
+
+		It("should resolve the policy from the ipaddress, its pool and the default", func() {
+			pool := &ipamv1alpha2.InClusterIPPool{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "pool",
+					Namespace:   "default",
+					Annotations: map[string]string{"netbox.argora.cloud.sap/deletion-policy": "Unassign"},
+				},
+			}
+			reconciler := newReconciler(&mock.NetBoxMock{}, pool)
+			ipAddr := newIPAddress("ip")
+			ipAddr.Spec.PoolRef.Kind = "InClusterIPPool"
+			ipAddr.Spec.PoolRef.Name = "pool"
+
+			policy, err := reconciler.ipDeletionPolicy(ctx, ipAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(policy).To(Equal(IPDeletionPolicyUnassign))
+
+			ipAddr.Annotations = map[string]string{"netbox.argora.cloud.sap/deletion-policy": "MarkDeprecated"}
+			policy, err = reconciler.ipDeletionPolicy(ctx, ipAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(policy).To(Equal(IPDeletionPolicyMarkDeprecated))
+
+			ipAddr.Annotations = nil
+			ipAddr.Spec.PoolRef.Name = "missing"
+			policy, err = reconciler.ipDeletionPolicy(ctx, ipAddr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(policy).To(Equal(IPDeletionPolicyDelete))
+		})
+	})
+
+	Context("owner resolvers", func() {
+		ctx := context.Background()
+
+		newIPAddressClaim := func(annotations map[string]string, owners ...metav1.OwnerReference) *ipamv1.IPAddressClaim {
+			return &ipamv1.IPAddressClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "claim",
+					Namespace:       "default",
+					Annotations:     annotations,
+					OwnerReferences: owners,
+				},
+			}
+		}
+
+		bareMetalHost := &bmov1alpha1.BareMetalHost{
+			ObjectMeta: metav1.ObjectMeta{Name: "node001-bb001", Namespace: "metal3"},
+		}
+
+		It("should resolve the device name from the claim annotation", func() {
+			claim := newIPAddressClaim(map[string]string{"netbox.argora.cloud.sap/device": "node007-bb001"},
+				metav1.OwnerReference{Kind: "ServerClaim", Name: "missing"})
+
+			deviceName, err := newReconciler(&mock.NetBoxMock{}, claim).findDeviceName(ctx, claim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deviceName).To(Equal("node007-bb001"))
+		})
+
+		It("should resolve the device name from a Machine through its Metal3Machine", func() {
+			metal3Machine := &unstructured.Unstructured{}
+			metal3Machine.SetGroupVersionKind(metal3MachineGVK)
+			metal3Machine.SetName("metal3-machine")
+			metal3Machine.SetNamespace("default")
+			metal3Machine.SetAnnotations(map[string]string{"metal3.io/BareMetalHost": "metal3/node001-bb001"})
+
+			machine := &clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{Name: "machine", Namespace: "default"},
+				Spec: clusterv1.MachineSpec{
+					ClusterName: "cluster",
+					InfrastructureRef: clusterv1.ContractVersionedObjectReference{
+						APIGroup: "infrastructure.cluster.x-k8s.io",
+						Kind:     "Metal3Machine",
+						Name:     "metal3-machine",
+					},
+				},
+			}
+			claim := newIPAddressClaim(nil,
+				metav1.OwnerReference{Kind: "Metal3Data", Name: "data"},
+				metav1.OwnerReference{Kind: "Machine", Name: "machine"})
+
+			deviceName, err := newReconciler(&mock.NetBoxMock{}, claim, machine, metal3Machine, bareMetalHost).findDeviceName(ctx, claim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deviceName).To(Equal("node001-bb001"))
+		})
+
+		It("should fail for a Metal3Machine not bound to a BareMetalHost", func() {
+			metal3Machine := &unstructured.Unstructured{}
+			metal3Machine.SetGroupVersionKind(metal3MachineGVK)
+			metal3Machine.SetName("metal3-machine")
+			metal3Machine.SetNamespace("default")
+
+			claim := newIPAddressClaim(nil, metav1.OwnerReference{Kind: "Metal3Machine", Name: "metal3-machine"})
+
+			_, err := newReconciler(&mock.NetBoxMock{}, claim, metal3Machine).findDeviceName(ctx, claim)
+			Expect(err).To(MatchError(ContainSubstring("not yet bound to a BareMetalHost")))
+		})
+
+		It("should resolve the device name from a BareMetalHost owner", func() {
+			bmh := bareMetalHost.DeepCopy()
+			bmh.Namespace = "default"
+			claim := newIPAddressClaim(nil, metav1.OwnerReference{Kind: "BareMetalHost", Name: bmh.Name})
+
+			deviceName, err := newReconciler(&mock.NetBoxMock{}, claim, bmh).findDeviceName(ctx, claim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deviceName).To(Equal("node001-bb001"))
+		})
+
+		It("should fail without a supported owner", func() {
+			claim := newIPAddressClaim(nil, metav1.OwnerReference{Kind: "Metal3Data", Name: "data"})
+
+			_, err := newReconciler(&mock.NetBoxMock{}, claim).findDeviceName(ctx, claim)
+			Expect(err).To(MatchError("no supported owner found for IPAddressClaim claim"))
+		})
+	})
+
+	Context("address metadata", func() {
+		ipAddr := newIPAddress("ip")
+		ipAddr.Spec.PoolRef.Name = "pool"
+		target := &netboxTarget{
+			ipClaim: &ipamv1.IPAddressClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "claim",
+					Namespace: "default",
+					Labels:    map[string]string{"cluster.x-k8s.io/cluster-name": "cluster"},
+				},
+			},
+			device: models.Device{Name: "node001-bb001"},
+			iface:  models.Interface{Name: "LAG1"},
+		}
+
+		prepareTemplateNetboxMock := func() *mock.NetBoxMock {
+			return &mock.NetBoxMock{
+				ExtrasMock: &mock.ExtrasMock{
+					GetTagByNameFunc: func(tagName string) (*models.Tag, error) {
+						return &models.Tag{NestedTag: models.NestedTag{ID: 7, Name: tagName, Slug: tagName}}, nil
+					},
+				},
+			}
+		}
+
+		loadTemplate := func(templateJSON string) *IPAddressTemplate {
+			fileReader := &mock.FileReaderMock{FileContent: map[string]string{"template.json": templateJSON}}
+			ipAddressTemplate, err := LoadIPAddressTemplate(fileReader, "template.json")
+			Expect(err).ToNot(HaveOccurred())
+			return ipAddressTemplate
+		}
+
+		It("should render the metadata from the template", func() {
+			reconciler := newReconciler(prepareTemplateNetboxMock())
+			reconciler.ipAddressTemplate = loadTemplate(`{
+				"dnsName": "{{ .Device }}.example.com",
+				"status": "active",
+				"description": "{{ .Namespace }}/{{ .Claim }} of {{ .Pool }} on {{ .Interface }}",
+				"tags": ["argora"],
+				"customFields": {"cluster": "{{ .Cluster }}"}
+			}`)
+
+			metadata, err := reconciler.ipAddressMetadata(ipAddr, target)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(metadata.DNSName).To(Equal("node001-bb001.example.com"))
+			Expect(metadata.Status).To(Equal("active"))
+			Expect(metadata.Role).To(BeEmpty())
+			Expect(metadata.Description).To(Equal("default/claim of pool on LAG1"))
+			Expect(metadata.Tags).To(ConsistOf(models.NestedTag{ID: 7, Name: "argora", Slug: "argora"}))
+			Expect(metadata.CustomFields).To(Equal(map[string]any{"cluster": "cluster"}))
+		})
+
+		It("should return no metadata without template", func() {
+			metadata, err := newReconciler(prepareTemplateNetboxMock()).ipAddressMetadata(ipAddr, target)
 			Expect(err).ToNot(HaveOccurred())
-			Expect(iface.Name).To(Equal("LAG2"))
+			Expect(metadata).To(BeNil())
+		})
+
+		It("should reject invalid templates", func() {
+			fileReader := &mock.FileReaderMock{FileContent: map[string]string{"template.json": `{"dnsName": "{{ .Device"}`}}
+			_, err := LoadIPAddressTemplate(fileReader, "template.json")
+			Expect(err).To(MatchError(ContainSubstring("unable to parse dnsName template")))
+
+			reconciler := newReconciler(prepareTemplateNetboxMock())
+			reconciler.ipAddressTemplate = loadTemplate(`{"description": "{{ .Unknown }}"}`)
+			_, err = reconciler.ipAddressMetadata(ipAddr, target)
+			Expect(err).To(MatchError(ContainSubstring("unable to render description template")))
+		})
+
+		It("should update only changed fields and keep existing tags", func() {
+			metadata := &ipAddressMetadata{
+				DNSName:      "node001-bb001.example.com",
+				Status:       "active",
+				Tags:         []models.NestedTag{{ID: 7, Name: "argora", Slug: "argora"}},
+				CustomFields: map[string]any{"cluster": "cluster"},
+			}
+			addr := &models.IPAddress{
+				NestedIPAddress: models.NestedIPAddress{ID: ipAddressID, Address: fullIPAddress},
+				DNSName:         "node001-bb001.example.com",
+				Tags:            []models.NestedTag{{ID: 3, Name: "other", Slug: "other"}},
+				CustomFields:    map[string]any{"cluster": "old"},
+			}
+			addr.Status.Value = "reserved"
+
+			update := metadata.update(addr)
+			Expect(update).ToNot(BeNil())
+			Expect(update.ID).To(Equal(ipAddressID))
+			Expect(update.Address).To(Equal(fullIPAddress))
+			Expect(update.DNSName).To(BeEmpty())
+			Expect(update.Status).To(Equal("active"))
+			Expect(update.Tags).To(ConsistOf(
+				models.NestedTag{ID: 3, Name: "other", Slug: "other"},
+				models.NestedTag{ID: 7, Name: "argora", Slug: "argora"},
+			))
+			Expect(update.CustomFields).To(Equal(map[string]any{"cluster": "cluster"}))
+
+			addr.Status.Value = "active"
+			addr.Tags = update.Tags
+			addr.CustomFields = map[string]any{"cluster": "cluster", "owner": "team"}
+			Expect(metadata.update(addr)).To(BeNil())
+		})
+
+		It("should compare custom fields by their text", func() {
+			metadata := &ipAddressMetadata{
+				CustomFields: map[string]any{"vlan": "42", "managed": "true", "owner": ""},
+			}
+			addr := &models.IPAddress{
+				NestedIPAddress: models.NestedIPAddress{ID: ipAddressID, Address: fullIPAddress},
+				CustomFields:    map[string]any{"vlan": float64(42), "managed": true, "owner": nil},
+			}
+
+			Expect(metadata.update(addr)).To(BeNil())
+
+			addr.CustomFields = map[string]any{"vlan": float64(43), "managed": true}
+			update := metadata.update(addr)
+			Expect(update).ToNot(BeNil())
+			Expect(update.CustomFields).To(Equal(metadata.CustomFields))
+		})
+	})
+
+	Context("drift", func() {
+		ctx := context.Background()
+
+		target := &netboxTarget{
+			device: models.Device{ID: deviceID, Name: "node001-bb001", PrimaryIP4: models.NestedIPAddress{ID: ipAddressID}},
+			iface:  models.Interface{NestedInterface: models.NestedInterface{ID: interfaceID}, Name: "LAG1"},
+		}
+
+		newAddress := func(ifaceID, devID int) *models.IPAddress {
+			return &models.IPAddress{
+				NestedIPAddress: models.NestedIPAddress{ID: ipAddressID, Address: fullIPAddress},
+				AssignedInterface: models.NestedInterface{
+					ID:     ifaceID,
+					Device: models.NestedDevice{ID: devID},
+				},
+			}
+		}
+
+		prepareDriftNetboxMock := func(addr *models.IPAddress) *mock.NetBoxMock {
+			return &mock.NetBoxMock{
+				DCIMMock: &mock.DCIMMock{
+					GetDeviceByIDFunc: func(id int) (*models.Device, error) {
+						return &models.Device{ID: id, Name: "node002-bb001"}, nil
+					},
+				},
+				IPAMMock: &mock.IPAMMock{
+					GetIPAddressByAddressFunc: func(_ string) (*models.IPAddress, error) {
+						if addr == nil {
+							return nil, ipam.ErrNoObjectsFound
+						}
+						return addr, nil
+					},
+					GetPrefixesByPrefixesFunc: func(_ string) ([]models.Prefix, error) {
+						return nil, nil
+					},
+					UpdateIPAddressFunc: func(ip models.WriteableIPAddress) (*models.IPAddress, error) {
+						return newAddress(ip.AssignedObjectID, deviceID), nil
+					},
+				},
+			}
+		}
+
+		It("should parse drift policies", func() {
+			policy, err := ParseIPDriftPolicy("Report")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(policy).To(Equal(IPDriftPolicyReport))
+
+			_, err = ParseIPDriftPolicy("Ignore")
+			Expect(err).To(MatchError(ContainSubstring(`invalid ip drift policy "Ignore"`)))
+		})
+
+		It("should jitter the resync interval", func() {
+			reconciler := &IPUpdateReconciler{}
+			Expect(reconciler.resyncAfter()).To(BeZero())
+
+			reconciler.resyncInterval = time.Minute
+			Expect(reconciler.resyncAfter()).To(BeNumerically(">=", time.Minute))
+			Expect(reconciler.resyncAfter()).To(BeNumerically("<=", time.Minute+6*time.Second))
+		})
+
+		It("should detect the kinds of drift", func() {
+			reconciler := newReconciler(prepareDriftNetboxMock(newAddress(interfaceID, deviceID)))
+			drift, _, err := reconciler.detectDrift(ctx, newTrackedIPAddress("ip"), target)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift).To(BeEmpty())
+
+			reconciler = newReconciler(prepareDriftNetboxMock(nil))
+			drift, _, err = reconciler.detectDrift(ctx, newTrackedIPAddress("ip"), target)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift).To(ConsistOf("missing"))
+
+			reconciler = newReconciler(prepareDriftNetboxMock(newAddress(interfaceID+1, deviceID)))
+			drift, _, err = reconciler.detectDrift(ctx, newTrackedIPAddress("ip"), target)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift).To(ConsistOf("interface"))
+
+			movedTarget := *target
+			movedTarget.device.PrimaryIP4 = models.NestedIPAddress{}
+			reconciler = newReconciler(prepareDriftNetboxMock(newAddress(interfaceID+1, deviceID+1)))
+			drift, _, err = reconciler.detectDrift(ctx, newTrackedIPAddress("ip"), &movedTarget)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift).To(ConsistOf("device", "primary_ip"))
+		})
+
+		It("should not flag the primary IP held by another tracked ipaddress of the device", func() {
+			other := newTrackedIPAddress("other-ip")
+			other.Spec.Address = "192.168.1.101"
+
+			primaryTarget := *target
+			primaryTarget.device.PrimaryIP4 = models.NestedIPAddress{ID: ipAddressID + 1, Address: "192.168.1.101/24"}
+
+			reconciler := newReconciler(prepareDriftNetboxMock(newAddress(interfaceID, deviceID)))
+			drift, _, err := reconciler.detectDrift(ctx, newTrackedIPAddress("ip"), &primaryTarget)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift).To(ConsistOf("primary_ip"))
+
+			netBoxMock := prepareDriftNetboxMock(newAddress(interfaceID, deviceID))
+			reconciler = newReconciler(netBoxMock, other)
+			drift, addr, err := reconciler.detectDrift(ctx, newTrackedIPAddress("ip"), &primaryTarget)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift).To(BeEmpty())
+
+			prefix, err := getPrefix(newTrackedIPAddress("ip"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reconciler.reconcileDevicePrimaryIP(ctx, newTrackedIPAddress("ip"), addr, prefix, primaryTarget.device, logr.Discard())).To(Succeed())
+			Expect(netBoxMock.DCIMMock.(*mock.DCIMMock).UpdateDeviceCalls).To(BeZero())
+		})
+
+		It("should skip untracked and conflicted ipaddresses", func() {
+			netBoxMock := prepareDriftNetboxMock(nil)
+			reconciler := newReconciler(netBoxMock)
+			reconciler.driftPolicy = IPDriftPolicyReport
+
+			ipAddr := newTrackedIPAddress("ip")
+			delete(ipAddr.Annotations, "netbox.argora.cloud.sap/interface-id")
+			reported, err := reconciler.reconcileDrift(ctx, ipAddr, target, logr.Discard())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reported).To(BeFalse())
+
+			ipAddr = newTrackedIPAddress("ip")
+			ipAddr.Annotations["netbox.argora.cloud.sap/conflicted"] = "interface"
+			reported, err = reconciler.reconcileDrift(ctx, ipAddr, target, logr.Discard())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reported).To(BeFalse())
+
+			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).GetIPAddressByAddressCalls).To(Equal(0))
+		})
+
+		It("should report drift without touching NetBox", func() {
+			ipAddr := newTrackedIPAddress("ip")
+			netBoxMock := prepareDriftNetboxMock(nil)
+			reconciler := newReconciler(netBoxMock, ipAddr)
+			reconciler.driftPolicy = IPDriftPolicyReport
+			before := testutil.ToFloat64(ipDriftTotal.WithLabelValues("missing", "Report"))
+
+			reported, err := reconciler.reconcileDrift(ctx, ipAddr, target, logr.Discard())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reported).To(BeTrue())
+			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).UpdateIPAddressCalls).To(Equal(0))
+			Expect(testutil.ToFloat64(ipDriftTotal.WithLabelValues("missing", "Report"))).To(Equal(before + 1))
+
+			updated := &ipamv1.IPAddress{}
+			Expect(reconciler.k8sClient.Get(ctx, client.ObjectKeyFromObject(ipAddr), updated)).To(Succeed())
+			Expect(updated.Annotations).To(HaveKeyWithValue("netbox.argora.cloud.sap/drifted", "missing"))
+			Expect(reconciler.recorder.(*events.FakeRecorder).Events).To(Receive(ContainSubstring("IPDrift")))
+		})
+
+		It("should reassign an ip address moved to another device with the takeover conflict policy", func() {
+			netBoxMock := prepareDriftNetboxMock(newAddress(interfaceID+1, deviceID+1))
+			reconciler := newReconciler(netBoxMock)
+			reconciler.conflictPolicy = IPConflictPolicyTakeover
+			before := testutil.ToFloat64(ipDriftTotal.WithLabelValues("device", "Repair"))
+
+			reported, err := reconciler.reconcileDrift(ctx, newTrackedIPAddress("ip"), target, logr.Discard())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reported).To(BeFalse())
+			Expect(netBoxMock.DCIMMock.(*mock.DCIMMock).GetDeviceByIDCalls).To(Equal(1))
+			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).UpdateIPAddressCalls).To(Equal(1))
+			Expect(testutil.ToFloat64(ipDriftTotal.WithLabelValues("device", "Repair"))).To(Equal(before + 1))
+			Expect(reconciler.recorder.(*events.FakeRecorder).Events).To(Receive(ContainSubstring("IPConflictTakeover")))
+			Expect(reconciler.recorder.(*events.FakeRecorder).Events).To(Receive(ContainSubstring("IPDriftRepaired")))
+		})
+
+		It("should keep an ip address moved to another device with the default conflict policy", func() {
+			netBoxMock := prepareDriftNetboxMock(newAddress(interfaceID+1, deviceID+1))
+			reconciler := newReconciler(netBoxMock)
+
+			reported, err := reconciler.reconcileDrift(ctx, newTrackedIPAddress("ip"), target, logr.Discard())
+			Expect(err).To(MatchError(NetboxConflictError{
+				IPAddressID:      ipAddressID,
+				ConflictObj:      "device",
+				AssignedNetboxID: deviceID + 1,
+				NeededNetboxID:   deviceID,
+			}))
+			Expect(reported).To(BeFalse())
+			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).UpdateIPAddressCalls).To(BeZero())
+			Expect(reconciler.recorder.(*events.FakeRecorder).Events).To(Receive(ContainSubstring("IPConflict")))
+		})
+
+		It("should keep an ip address moved to an active device with the takeover if stale conflict policy", func() {
+			netBoxMock := prepareDriftNetboxMock(newAddress(interfaceID+1, deviceID+1))
+			reconciler := newReconciler(netBoxMock)
+			reconciler.conflictPolicy = IPConflictPolicyTakeoverIfStale
+			netBoxMock.DCIMMock.(*mock.DCIMMock).GetDeviceByIDFunc = func(id int) (*models.Device, error) {
+				return &models.Device{ID: id, Name: "node002-bb001", Status: models.DeviceStatus{Value: "active"}}, nil
+			}
+
+			_, err := reconciler.reconcileDrift(ctx, newTrackedIPAddress("ip"), target, logr.Discard())
+			_, conflicted := errors.AsType[NetboxConflictError](err)
+			Expect(conflicted).To(BeTrue())
+			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).UpdateIPAddressCalls).To(BeZero())
+		})
+	})
+
+	Context("backfill", func() {
+		ctx := context.Background()
+
+		newBackfill := func(outcomes map[string]error, objects ...client.Object) (*ipBackfill, *[]string) {
+			reconciler := newReconciler(&mock.NetBoxMock{}, objects...)
+			backfill := newIPBackfill(reconciler, reconciler.k8sClient, IPBackfillOptions{
+				Namespace:   "kube-system",
+				BatchSize:   2,
+				Concurrency: 1,
+				Rate:        1000,
+			})
+
+			var reconciled []string
+			backfill.reconcile = func(_ context.Context, ipAddress *ipamv1.IPAddress) (ctrl.Result, error) {
+				reconciled = append(reconciled, ipAddress.Name)
+				return ctrl.Result{}, outcomes[ipAddress.Name]
+			}
+			return backfill, &reconciled
+		}
+
+		getProgress := func(backfill *ipBackfill) map[string]string {
+			configMap := &v1.ConfigMap{}
+			Expect(backfill.k8sClient.Get(ctx, client.ObjectKey{Namespace: "kube-system", Name: IPBackfillConfigMapName}, configMap)).To(Succeed())
+			return configMap.Data
+		}
+
+		It("should not defer ip addresses without backfill", func() {
+			var backfill *ipBackfill
+			deferred, _ := backfill.defers(newIPAddress("ip-a"))
+			Expect(deferred).To(BeFalse())
+		})
+
+		It("should defer ip addresses not yet processed by the backfill", func() {
+			backfill, _ := newBackfill(nil)
+
+			deferred, requeueAfter := backfill.defers(newIPAddress("ip-a"))
+			Expect(deferred).To(BeTrue())
+			Expect(requeueAfter).To(Equal(ipBackfillRequeueDelay))
+
+			deferred, _ = backfill.defers(newTrackedIPAddress("ip-a"))
+			Expect(deferred).To(BeFalse())
+
+			backfill.loaded = true
+			backfill.progress.Cursor = "default/ip-b"
+			deferred, _ = backfill.defers(newIPAddress("ip-a"))
+			Expect(deferred).To(BeFalse())
+
+			deferred, requeueAfter = backfill.defers(newIPAddress("ip-c"))
+			Expect(deferred).To(BeTrue())
+			Expect(requeueAfter).To(BeZero())
+
+			backfill.active["default/ip-c"] = true
+			deferred, requeueAfter = backfill.defers(newIPAddress("ip-c"))
+			Expect(deferred).To(BeTrue())
+			Expect(requeueAfter).To(Equal(ipBackfillRequeueDelay))
+
+			delete(backfill.active, "default/ip-c")
+			backfill.processed["default/ip-c"] = true
+			deferred, _ = backfill.defers(newIPAddress("ip-c"))
+			Expect(deferred).To(BeFalse())
+
+			backfill.progress.State = ipBackfillStateCompleted
+			deferred, _ = backfill.defers(newIPAddress("ip-d"))
+			Expect(deferred).To(BeFalse())
+		})
+
+		It("should process ip addresses in batches and report the progress", func() {
+			backfill, reconciled := newBackfill(map[string]error{
+				"ip-b": NetboxConflictError{ConflictObj: "interface"},
+				"ip-d": errors.New("netbox error"),
+			},
+				newIPAddress("ip-d"), newIPAddress("ip-a"), newTrackedIPAddress("ip-c"),
+				newIPAddress("ip-b"), newIPAddress("ip-e"),
+			)
+
+			Expect(backfill.Start(ctx)).To(Succeed())
+
+			Expect(*reconciled).To(Equal([]string{"ip-a", "ip-b", "ip-d", "ip-e"}))
+			Expect(getProgress(backfill)).To(Equal(map[string]string{
+				"state":      "Completed",
+				"cursor":     "default/ip-e",
+				"done":       "2",
+				"failed":     "1",
+				"conflicted": "1",
+				"remaining":  "0",
+			}))
+
+			var failed event.GenericEvent
+			Expect(backfill.failed).To(Receive(&failed))
+			Expect(failed.Object.GetName()).To(Equal("ip-d"))
+		})
+
+		It("should resume after the cursor", func() {
+			progress := &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: IPBackfillConfigMapName},
+				Data: map[string]string{
+					"state":  "Running",
+					"cursor": "default/ip-b",
+					"done":   "2",
+				},
+			}
+			backfill, reconciled := newBackfill(nil, progress, newIPAddress("ip-a"), newIPAddress("ip-c"))
+
+			Expect(backfill.Start(ctx)).To(Succeed())
+
+			Expect(*reconciled).To(Equal([]string{"ip-c"}))
+			Expect(getProgress(backfill)).To(HaveKeyWithValue("done", "3"))
+			Expect(getProgress(backfill)).To(HaveKeyWithValue("state", "Completed"))
+		})
+
+		It("should retry failing lists and saves of the progress instead of stopping", func() {
+			backfill, reconciled := newBackfill(nil, newIPAddress("ip-a"))
+			backfill.backoff = wait.Backoff{Duration: time.Millisecond, Steps: math.MaxInt32}
+
+			listErrors, createErrors := 2, 1
+			backfill.k8sClient = interceptor.NewClient(backfill.k8sClient.(client.WithWatch), interceptor.Funcs{
+				List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+					if listErrors > 0 {
+						listErrors--
+						return errors.New("list failed")
+					}
+					return c.List(ctx, list, opts...)
+				},
+				Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+					if createErrors > 0 {
+						createErrors--
+						return errors.New("create failed")
+					}
+					return c.Create(ctx, obj, opts...)
+				},
+			})
+
+			Expect(backfill.Start(ctx)).To(Succeed())
+
+			Expect(listErrors).To(BeZero())
+			Expect(createErrors).To(BeZero())
+			Expect(*reconciled).To(Equal([]string{"ip-a"}))
+			Expect(getProgress(backfill)).To(HaveKeyWithValue("state", "Completed"))
+		})
+
+		It("should stop retrying when the context is cancelled", func() {
+			backfill, _ := newBackfill(nil)
+			backfill.k8sClient = interceptor.NewClient(backfill.k8sClient.(client.WithWatch), interceptor.Funcs{
+				List: func(_ context.Context, _ client.WithWatch, _ client.ObjectList, _ ...client.ListOption) error {
+					return errors.New("list failed")
+				},
+			})
+
+			cancelCtx, cancel := context.WithCancel(ctx)
+			cancel()
+			Expect(backfill.Start(cancelCtx)).To(Succeed())
+		})
+
+		It("should serialize the reloads of concurrent reconciles", func() {
+			reconciler := newReconciler(&mock.NetBoxMock{})
+
+			var wg sync.WaitGroup
+			for range 4 {
+				wg.Go(func() {
+					defer GinkgoRecover()
+					Expect(reconciler.reload(logr.Discard())).To(Succeed())
+				})
+			}
+			wg.Wait()
+			Expect(reconciler.credentials.NetboxToken).To(Equal("token"))
+		})
+
+		It("should not run a completed backfill again", func() {
+			progress := &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: IPBackfillConfigMapName},
+				Data:       map[string]string{"state": "Completed"},
+			}
+			backfill, reconciled := newBackfill(nil, progress, newIPAddress("ip-a"))
+
+			Expect(backfill.Start(ctx)).To(Succeed())
+			Expect(*reconciled).To(BeEmpty())
+		})
+
+		It("should add the finalizer and sync a never synced ip address in one pass", func() {
+			ipClaim := &ipamv1.IPAddressClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "claim",
+					Namespace:   resourceNamespace,
+					Annotations: map[string]string{"netbox.argora.cloud.sap/device": "node001-rack01"},
+				},
+			}
+			ipAddr := newIPAddress("ip-a")
+
+			reconciler := newReconciler(prepareNetboxMock(), ipClaim, ipAddr)
+			backfill := newIPBackfill(reconciler, reconciler.k8sClient, IPBackfillOptions{
+				Namespace:   "kube-system",
+				BatchSize:   2,
+				Concurrency: 1,
+				Rate:        1000,
+			})
+
+			Expect(backfill.Start(ctx)).To(Succeed())
+
+			synced := &ipamv1.IPAddress{}
+			Expect(reconciler.k8sClient.Get(ctx, client.ObjectKeyFromObject(ipAddr), synced)).To(Succeed())
+			Expect(synced.Finalizers).To(ContainElement(ipAddressFinalizer))
+			Expect(synced.Annotations).To(HaveKeyWithValue("netbox.argora.cloud.sap/device-id", strconv.Itoa(deviceID)))
+			Expect(getProgress(backfill)).To(HaveKeyWithValue("done", "1"))
+			Expect(backfill.failed).ToNot(Receive())
+		})
+	})
+
+	Context("filter", func() {
+		It("should parse comma separated namespaces and pools", func() {
+			filter := NewIPUpdateFilter(" ns-a, ,ns-b", "")
+			Expect(filter.Namespaces).To(Equal([]string{"ns-a", "ns-b"}))
+			Expect(filter.Pools).To(BeEmpty())
+		})
+
+		It("should match ip addresses by namespace and pool", func() {
+			ipAddr := newIPAddress("ip-a")
+			ipAddr.Namespace, ipAddr.Spec.PoolRef.Name = "ns-a", "pool-a"
+			Expect(IPUpdateFilter{}.matches(ipAddr)).To(BeTrue())
+
+			filter := NewIPUpdateFilter("ns-a", "pool-a,pool-b")
+			ipAddr.Spec.PoolRef.Name = "pool-b"
+			Expect(filter.matches(ipAddr)).To(BeTrue())
+			ipAddr.Namespace, ipAddr.Spec.PoolRef.Name = "ns-b", "pool-a"
+			Expect(filter.matches(ipAddr)).To(BeFalse())
+			ipAddr.Namespace, ipAddr.Spec.PoolRef.Name = "ns-a", "pool-c"
+			Expect(filter.matches(ipAddr)).To(BeFalse())
+		})
+
+		It("should match deleted ip addresses carrying the finalizer", func() {
+			ipAddr := newIPAddress("ip-a")
+			ipAddr.Namespace = "ns-b"
+			ipAddr.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			filter := NewIPUpdateFilter("ns-a", "")
+			Expect(filter.matches(ipAddr)).To(BeFalse())
+
+			controllerutil.AddFinalizer(ipAddr, ipAddressFinalizer)
+			Expect(filter.matches(ipAddr)).To(BeTrue())
+		})
+
+		It("should skip updates irrelevant to NetBox", func() {
+			oldIPAddr := newIPAddress("ip-a")
+			oldIPAddr.Annotations = map[string]string{"other": "a"}
+
+			changed := func(mutate func(ipAddr *ipamv1.IPAddress)) bool {
+				newIPAddr := oldIPAddr.DeepCopy()
+				mutate(newIPAddr)
+				return ipAddressChangedPredicate{}.Update(event.UpdateEvent{ObjectOld: oldIPAddr, ObjectNew: newIPAddr})
+			}
+
+			Expect(changed(func(ipAddr *ipamv1.IPAddress) {
+				ipAddr.Labels = map[string]string{"label": "a"}
+			})).To(BeFalse())
+			Expect(changed(func(ipAddr *ipamv1.IPAddress) {
+				ipAddr.Annotations[annotationDeviceKey] = strconv.Itoa(deviceID)
+				ipAddr.Annotations[annotationDriftedKey] = ipDriftMissing
+			})).To(BeFalse())
+			Expect(changed(func(ipAddr *ipamv1.IPAddress) {
+				ipAddr.Annotations["other"] = "b"
+			})).To(BeTrue())
+			Expect(changed(func(ipAddr *ipamv1.IPAddress) {
+				controllerutil.AddFinalizer(ipAddr, ipAddressFinalizer)
+			})).To(BeTrue())
+			Expect(changed(func(ipAddr *ipamv1.IPAddress) {
+				ipAddr.Generation = 2
+			})).To(BeTrue())
+		})
+
+		It("should not backfill ip addresses excluded by the filter", func() {
+			ipAddr := newIPAddress("ip-a")
+			ipAddr.Namespace = "ns-b"
+			reconciler := newReconciler(&mock.NetBoxMock{}, ipAddr)
+			backfill := newIPBackfill(reconciler, reconciler.k8sClient, IPBackfillOptions{
+				Namespace:   "kube-system",
+				BatchSize:   2,
+				Concurrency: 1,
+				Rate:        1000,
+			})
+			backfill.filter = NewIPUpdateFilter("ns-a", "")
+
+			var reconciled []string
+			backfill.reconcile = func(_ context.Context, ipAddress *ipamv1.IPAddress) (ctrl.Result, error) {
+				reconciled = append(reconciled, ipAddress.Name)
+				return ctrl.Result{}, nil
+			}
+
+			Expect(backfill.Start(context.Background())).To(Succeed())
+			Expect(reconciled).To(BeEmpty())
 		})
 	})
 })
@@ -1037,5 +2346,9 @@ func createIPUpdateReconciler(netBoxMock *mock.NetBoxMock, fileReaderMock creden
 		scheme:      k8sClient.Scheme(),
 		netBox:      netBoxMock,
 		credentials: credentials.NewDefaultCredentials(fileReaderMock),
+		recorder:    events.NewFakeRecorder(10),
+
+		ownerResolvers: defaultOwnerResolvers(),
+		interfaceRules: DefaultInterfaceRules(),
 	}
 }
@@ -0,0 +1,136 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	"github.com/go-logr/logr"
+	"github.com/sapcc/go-netbox-go/models"
+	corev1 "k8s.io/api/core/v1"
+	ipamv1 "sigs.k8s.io/cluster-api/api/ipam/v1beta2"
+)
+
+// IPDeletionPolicy defines what the IPUpdate controller does with the NetBox IP address of a deleted IPAddress.
+type IPDeletionPolicy string
+
+const (
+	// IPDeletionPolicyDelete deletes the IP address in NetBox.
+	IPDeletionPolicyDelete IPDeletionPolicy = "Delete"
+	// IPDeletionPolicyMarkDeprecated keeps the IP address in NetBox with status deprecated.
+	IPDeletionPolicyMarkDeprecated IPDeletionPolicy = "MarkDeprecated"
+	// IPDeletionPolicyUnassign keeps the IP address in NetBox, detached from its interface.
+	IPDeletionPolicyUnassign IPDeletionPolicy = "Unassign"
+
+	// annotationDeletionPolicyKey overrides the deletion policy on an IPAddress or on the IP pool it is allocated from.
+	annotationDeletionPolicyKey = "netbox.argora.cloud.sap/deletion-policy"
+
+	netboxIPAddressStatusDeprecated = "deprecated"
+
+	eventReasonIPDeletionSkipped = "IPDeletionSkipped"
+	eventReasonIPDeleted         = "IPDeleted"
+)
+
+// ParseIPDeletionPolicy parses the name of an IP deletion policy.
+func ParseIPDeletionPolicy(policy string) (IPDeletionPolicy, error) {
+	switch p := IPDeletionPolicy(policy); p {
+	case IPDeletionPolicyDelete, IPDeletionPolicyMarkDeprecated, IPDeletionPolicyUnassign:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid ip deletion policy %q, expected one of %s, %s or %s",
+			policy, IPDeletionPolicyDelete, IPDeletionPolicyMarkDeprecated, IPDeletionPolicyUnassign)
+	}
+}
+
+// ipDeletionPolicy returns the deletion policy annotated on the IPAddress or on its IP pool, falling back to
+// the default policy of the reconciler.
+func (r *IPUpdateReconciler) ipDeletionPolicy(ctx context.Context, ipAddr *ipamv1.IPAddress) (IPDeletionPolicy, error) {
+	policy, ok, err := r.policyAnnotation(ctx, ipAddr, annotationDeletionPolicyKey)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return ParseIPDeletionPolicy(policy)
+	}
+
+	if r.deletionPolicy == "" {
+		return IPDeletionPolicyDelete, nil
+	}
+	return r.deletionPolicy, nil
+}
+
+// skipDeletion keeps the IP address in NetBox, as it is not known to be owned by the IPAddress.
+func (r *IPUpdateReconciler) skipDeletion(ipAddr *ipamv1.IPAddress, addr *models.IPAddress, logger logr.Logger, reason string, args ...any) {
+	message := fmt.Sprintf(reason, args...)
+	logger.Info("keeping ip address in NetBox", "address_id", addr.ID, "reason", message)
+	r.recorder.Eventf(ipAddr, nil, corev1.EventTypeWarning, eventReasonIPDeletionSkipped, "Delete",
+		"ip address %s kept in NetBox: %s", addr.Address, message)
+}
+
+// applyDeletionPolicy deletes, deprecates or unassigns the IP address owned by the deleted IPAddress. The IP address
+// is unset as primary address of the device first.
+func (r *IPUpdateReconciler) applyDeletionPolicy(
+	ctx context.Context,
+	ipAddr *ipamv1.IPAddress,
+	addr *models.IPAddress,
+	prefix netip.Prefix,
+	deviceID int,
+	logger logr.Logger,
+) error {
+
+	policy, err := r.ipDeletionPolicy(ctx, ipAddr)
+	if err != nil {
+		return err
+	}
+
+	device, err := r.netBox.DCIM().GetDeviceByID(deviceID)
+	if err != nil {
+		return fmt.Errorf("unable to get device of ip address: %w", err)
+	}
+
+	primaryIP := device.PrimaryIP4.ID
+	if prefix.Addr().Is6() {
+		primaryIP = device.PrimaryIP6.ID
+	}
+	if primaryIP == addr.ID {
+		if err := r.netBox.DCIM().ClearDevicePrimaryIP(ctx, device.ID, prefix.Addr().Is6()); err != nil {
+			return err
+		}
+		logger.Info("primary ip of device cleared", "device_id", device.ID, "address_id", addr.ID)
+	}
+
+	operation := operationDeleted
+	switch policy {
+	case IPDeletionPolicyMarkDeprecated:
+		operation = operationDeprecated
+		_, err = r.netBox.IPAM().UpdateIPAddress(models.WriteableIPAddress{
+			NestedIPAddress: models.NestedIPAddress{
+				ID:      addr.ID,
+				Address: addr.Address,
+			},
+			Status: netboxIPAddressStatusDeprecated,
+		})
+		if err != nil {
+			return fmt.Errorf("deprecate ip in netbox: %w", err)
+		}
+	case IPDeletionPolicyUnassign:
+		operation = operationUnassigned
+		if err = r.netBox.IPAM().UnassignIPAddress(ctx, addr.ID); err != nil {
+			return fmt.Errorf("unassign ip in netbox: %w", err)
+		}
+	default:
+		if err = r.netBox.IPAM().DeleteIPAddress(addr.ID); err != nil {
+			return fmt.Errorf("delete ip from netbox: %w", err)
+		}
+	}
+
+	netboxIPAddressesTotal.WithLabelValues(operation).Inc()
+	logger.Info("deletion policy applied", "deletionPolicy", policy, "address_id", addr.ID)
+	r.recorder.Eventf(ipAddr, nil, corev1.EventTypeNormal, eventReasonIPDeleted, string(policy),
+		"applied deletion policy %s to ip address %s of device %s", policy, addr.Address, device.Name)
+
+	return nil
+}
@@ -0,0 +1,217 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/sapcc/go-netbox-go/models"
+	corev1 "k8s.io/api/core/v1"
+	ipamv1 "sigs.k8s.io/cluster-api/api/ipam/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/sapcc/argora/internal/netbox/ipam"
+)
+
+// IPDriftPolicy defines how the IPUpdate controller handles IP addresses changed in NetBox after they were synced.
+type IPDriftPolicy string
+
+const (
+	// IPDriftPolicyRepair restores the IP address in NetBox.
+	IPDriftPolicyRepair IPDriftPolicy = "Repair"
+	// IPDriftPolicyReport only reports the drift, the IP address is left untouched in NetBox.
+	IPDriftPolicyReport IPDriftPolicy = "Report"
+
+	// annotationDriftedKey lists the kinds of drift reported for an IPAddress.
+	annotationDriftedKey = "netbox.argora.cloud.sap/drifted"
+
+	ipDriftMissing   = "missing"
+	ipDriftInterface = "interface"
+	ipDriftDevice    = "device"
+	ipDriftPrimaryIP = "primary_ip"
+
+	eventReasonIPDrift         = "IPDrift"
+	eventReasonIPDriftRepaired = "IPDriftRepaired"
+)
+
+// ParseIPDriftPolicy parses the name of an IP drift policy.
+func ParseIPDriftPolicy(policy string) (IPDriftPolicy, error) {
+	switch p := IPDriftPolicy(policy); p {
+	case IPDriftPolicyRepair, IPDriftPolicyReport:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid ip drift policy %q, expected one of %s or %s", policy, IPDriftPolicyRepair, IPDriftPolicyReport)
+	}
+}
+
+// ipDriftPolicy returns the drift policy of the reconciler, defaulting to Repair.
+func (r *IPUpdateReconciler) ipDriftPolicy() IPDriftPolicy {
+	if r.driftPolicy == "" {
+		return IPDriftPolicyRepair
+	}
+	return r.driftPolicy
+}
+
+// isTracked reports whether the IPAddress was synced to NetBox before, i.e. carries the NetBox annotations
+// and is not in conflict.
+func isTracked(ipAddr *ipamv1.IPAddress) bool {
+	_, hasDevice := ipAddr.Annotations[annotationDeviceKey]
+	_, hasInterface := ipAddr.Annotations[annotationInterfaceKey]
+	_, conflicted := ipAddr.Annotations[annotationConflictedKey]
+	return hasDevice && hasInterface && !conflicted
+}
+
+// detectDrift compares the IP address in NetBox with the target, returning the kinds of drift and the IP address,
+// which is nil if it is missing in NetBox. A primary IP held by another tracked IPAddress of the device is no drift.
+func (r *IPUpdateReconciler) detectDrift(ctx context.Context, ipAddr *ipamv1.IPAddress, target *netboxTarget) ([]string, *models.IPAddress, error) {
+	prefix, err := getPrefix(ipAddr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	addr, err := r.netBox.IPAM().GetIPAddressByAddress(prefix.String())
+	if err != nil {
+		if errors.Is(err, ipam.ErrNoObjectsFound) {
+			return []string{ipDriftMissing}, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	var drift []string
+	switch {
+	case addr.AssignedInterface.Device.ID != 0 && addr.AssignedInterface.Device.ID != target.device.ID:
+		drift = append(drift, ipDriftDevice)
+	case addr.AssignedInterface.ID != target.iface.ID:
+		drift = append(drift, ipDriftInterface)
+	}
+
+	primaryIP := target.device.PrimaryIP4.ID
+	if prefix.Addr().Is6() {
+		primaryIP = target.device.PrimaryIP6.ID
+	}
+	if primaryIP != addr.ID {
+		held, err := r.primaryIPHeldByTrackedIPAddress(ctx, ipAddr, prefix, target.device)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !held {
+			drift = append(drift, ipDriftPrimaryIP)
+		}
+	}
+
+	return drift, addr, nil
+}
+
+// reconcileDrift detects the drift of a tracked IP address in NetBox and applies the drift policy. It returns true
+// if the drift is reported only, so the IP address must not be reconciled further. A missing IP address or primary IP
+// is repaired by the regular reconciliation, an IP address assigned to another interface of the target device is
+// reassigned to the target interface. An IP address moved to another device is resolved with the conflict policy,
+// a kept conflict is returned as NetboxConflictError.
+func (r *IPUpdateReconciler) reconcileDrift(ctx context.Context, ipAddr *ipamv1.IPAddress, target *netboxTarget, logger logr.Logger) (bool, error) {
+	if !isTracked(ipAddr) {
+		return false, nil
+	}
+
+	drift, addr, err := r.detectDrift(ctx, ipAddr, target)
+	if err != nil {
+		return false, fmt.Errorf("unable to detect drift: %w", err)
+	}
+	if len(drift) == 0 {
+		return false, nil
+	}
+
+	policy := r.ipDriftPolicy()
+	for _, kind := range drift {
+		ipDriftTotal.WithLabelValues(kind, string(policy)).Inc()
+	}
+	logger = logger.WithValues("drift", drift, "driftPolicy", policy)
+
+	if policy == IPDriftPolicyReport {
+		logger.Info("ip address drifted in NetBox")
+		r.recorder.Eventf(ipAddr, nil, corev1.EventTypeWarning, eventReasonIPDrift, string(policy),
+			"ip address drifted in NetBox: %s", strings.Join(drift, ", "))
+		return true, r.setDriftAnnotation(ctx, ipAddr, drift)
+	}
+
+	switch {
+	case slices.Contains(drift, ipDriftDevice):
+		conflictErr := NetboxConflictError{
+			IPAddressID:      addr.ID,
+			ConflictObj:      "device",
+			AssignedNetboxID: addr.AssignedInterface.Device.ID,
+			NeededNetboxID:   target.device.ID,
+		}
+		if _, err := r.resolveConflict(ctx, ipAddr, target, addr, conflictErr, logger); err != nil {
+			return false, err
+		}
+	case slices.Contains(drift, ipDriftInterface):
+		if _, err := r.takeoverIPAddress(ctx, ipAddr, target, addr, nil, logger); err != nil {
+			return false, fmt.Errorf("unable to reassign drifted ip address %d: %w", addr.ID, err)
+		}
+	}
+
+	logger.Info("repairing ip address drifted in NetBox")
+	r.recorder.Eventf(ipAddr, nil, corev1.EventTypeNormal, eventReasonIPDriftRepaired, string(policy),
+		"repairing ip address drifted in NetBox: %s", strings.Join(drift, ", "))
+
+	return false, nil
+}
+
+// primaryIPHeldByTrackedIPAddress reports whether the primary IP of the device in the address family of the prefix
+// is the address of another tracked IPAddress of the device, so IPAddresses of the same family on one device do not
+// take the primary IP from each other. The IPAddresses of a device are expected in the namespace of the IPAddress.
+func (r *IPUpdateReconciler) primaryIPHeldByTrackedIPAddress(ctx context.Context, ipAddr *ipamv1.IPAddress, prefix netip.Prefix, device models.Device) (bool, error) {
+	primaryIP := device.PrimaryIP4
+	if prefix.Addr().Is6() {
+		primaryIP = device.PrimaryIP6
+	}
+	if primaryIP.ID == 0 {
+		return false, nil
+	}
+
+	primaryPrefix, err := netip.ParsePrefix(primaryIP.Address)
+	if err != nil {
+		return false, nil
+	}
+
+	ipAddresses := &ipamv1.IPAddressList{}
+	if err := r.k8sClient.List(ctx, ipAddresses, client.InNamespace(ipAddr.Namespace)); err != nil {
+		return false, fmt.Errorf("unable to list ipaddresses: %w", err)
+	}
+
+	deviceID := strconv.Itoa(device.ID)
+	for i := range ipAddresses.Items {
+		other := &ipAddresses.Items[i]
+		if other.Name == ipAddr.Name || !isTracked(other) || other.Annotations[annotationDeviceKey] != deviceID {
+			continue
+		}
+		if otherPrefix, err := getPrefix(other); err == nil && otherPrefix.Addr() == primaryPrefix.Addr() {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *IPUpdateReconciler) setDriftAnnotation(ctx context.Context, ipAddr *ipamv1.IPAddress, drift []string) error {
+	base := ipAddr.DeepCopy()
+	if ipAddr.Annotations == nil {
+		ipAddr.Annotations = make(map[string]string)
+	}
+
+	ipAddr.Annotations[annotationDriftedKey] = strings.Join(drift, ",")
+
+	if err := r.k8sClient.Patch(ctx, ipAddr, client.MergeFrom(base)); err != nil {
+		return fmt.Errorf("unable to patch ipaddress with drift: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	ipamv1 "sigs.k8s.io/cluster-api/api/ipam/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// ipUpdateManagedAnnotations are the annotations written by the IPUpdate controller itself, changes of them do not
+// trigger a reconciliation.
+var ipUpdateManagedAnnotations = []string{
+	annotationDeviceKey,
+	annotationInterfaceKey,
+	annotationConflictedKey,
+	annotationDriftedKey,
+}
+
+// IPUpdateFilter restricts the IPAddresses reconciled by the IPUpdate controller to namespaces and IP pools,
+// to enable the controller step by step. Empty lists match all IPAddresses.
+type IPUpdateFilter struct {
+	Namespaces []string
+	// Pools are the names of the IP pools the IPAddresses are allocated from.
+	Pools []string
+}
+
+// NewIPUpdateFilter creates a filter from comma separated lists of namespaces and IP pool names.
+func NewIPUpdateFilter(namespaces, pools string) IPUpdateFilter {
+	return IPUpdateFilter{
+		Namespaces: splitList(namespaces),
+		Pools:      splitList(pools),
+	}
+}
+
+func splitList(list string) []string {
+	var items []string
+	for item := range strings.SplitSeq(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// matches reports whether the IPAddress is reconciled. IPAddresses being deleted are matched if they carry the
+// finalizer of the controller, so they are released after the filter is narrowed.
+func (f IPUpdateFilter) matches(ipAddress *ipamv1.IPAddress) bool {
+	if !ipAddress.DeletionTimestamp.IsZero() && controllerutil.ContainsFinalizer(ipAddress, ipAddressFinalizer) {
+		return true
+	}
+	if len(f.Namespaces) > 0 && !slices.Contains(f.Namespaces, ipAddress.Namespace) {
+		return false
+	}
+	if len(f.Pools) > 0 && !slices.Contains(f.Pools, ipAddress.Spec.PoolRef.Name) {
+		return false
+	}
+	return true
+}
+
+// predicate returns the predicate of the IPAddresses matching the filter.
+func (f IPUpdateFilter) predicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		ipAddress, ok := obj.(*ipamv1.IPAddress)
+		return ok && f.matches(ipAddress)
+	})
+}
+
+// ipAddressChangedPredicate skips updates of IPAddresses irrelevant to NetBox, i.e. changes of labels, owner
+// references or of the annotations written by the controller itself.
+type ipAddressChangedPredicate struct {
+	predicate.Funcs
+}
+
+func (ipAddressChangedPredicate) Update(e event.UpdateEvent) bool {
+	if e.ObjectOld == nil || e.ObjectNew == nil {
+		return false
+	}
+
+	if e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration() {
+		return true
+	}
+	if !e.ObjectOld.GetDeletionTimestamp().Equal(e.ObjectNew.GetDeletionTimestamp()) {
+		return true
+	}
+	if !slices.Equal(e.ObjectOld.GetFinalizers(), e.ObjectNew.GetFinalizers()) {
+		return true
+	}
+
+	oldAnnotations, newAnnotations := maps.Clone(e.ObjectOld.GetAnnotations()), maps.Clone(e.ObjectNew.GetAnnotations())
+	for _, key := range ipUpdateManagedAnnotations {
+		delete(oldAnnotations, key)
+		delete(newAnnotations, key)
+	}
+	return !maps.Equal(oldAnnotations, newAnnotations)
+}
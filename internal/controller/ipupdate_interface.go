@@ -0,0 +1,195 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sapcc/go-netbox-go/models"
+	ipamv1 "sigs.k8s.io/cluster-api/api/ipam/v1beta2"
+
+	"github.com/sapcc/argora/internal/credentials"
+)
+
+const (
+	// DefaultInterfaceRuleName is the name of the interface rule used if neither the IPAddressClaim nor its IP pool
+	// select a rule. It can be overridden in the interface rules file.
+	DefaultInterfaceRuleName = "default"
+
+	// annotationInterfaceRuleKey selects an interface rule by name, as annotation of an IPAddressClaim or
+	// as label of the IP pool of the IPAddress.
+	annotationInterfaceRuleKey = "netbox.argora.cloud.sap/interface-rule"
+
+	interfaceOrderHighest = "Highest"
+	interfaceOrderLowest  = "Lowest"
+)
+
+// InterfaceRule selects the NetBox interface of the device an IPAddress is assigned to. An interface has to match
+// all criteria set in the rule, the first interface in the order of the rule is selected.
+type InterfaceRule struct {
+	// Name is the exact name of the interface.
+	Name string `json:"name,omitempty"`
+	// Pattern is a regular expression the interface name has to match. Interfaces are ordered by the value of the
+	// first capture group of the pattern, numerically if possible, and by name if the pattern has no group.
+	Pattern string `json:"pattern,omitempty"`
+	// Order of the matching interfaces: Highest (default) or Lowest.
+	Order string `json:"order,omitempty"`
+	// Type is the NetBox interface type, e.g. lag, virtual or 1000base-t.
+	Type string `json:"type,omitempty"`
+	// Tag is the slug of a NetBox tag the interface has to carry.
+	Tag string `json:"tag,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+func (i *InterfaceRule) compile() error {
+	if i.Name == "" && i.Pattern == "" && i.Type == "" && i.Tag == "" {
+		return errors.New("one of name, pattern, type or tag must be set")
+	}
+	if !slices.Contains([]string{"", interfaceOrderHighest, interfaceOrderLowest}, i.Order) {
+		return fmt.Errorf("unsupported order %q", i.Order)
+	}
+	if i.Pattern != "" {
+		pattern, err := regexp.Compile(i.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+		i.pattern = pattern
+	}
+	return nil
+}
+
+func (i *InterfaceRule) matches(iface models.Interface) bool {
+	if i.Name != "" && iface.Name != i.Name {
+		return false
+	}
+	if i.pattern != nil && !i.pattern.MatchString(iface.Name) {
+		return false
+	}
+	if i.Type != "" && !strings.EqualFold(iface.Type.Value, i.Type) {
+		return false
+	}
+	if i.Tag != "" && !slices.ContainsFunc(iface.Tags, func(tag models.NestedTag) bool { return tag.Slug == i.Tag }) {
+		return false
+	}
+	return true
+}
+
+// orderKey returns the value of the first capture group of the pattern in the interface name, or the name itself.
+func (i *InterfaceRule) orderKey(iface models.Interface) string {
+	if i.pattern == nil || i.pattern.NumSubexp() == 0 {
+		return iface.Name
+	}
+	return i.pattern.FindStringSubmatch(iface.Name)[1]
+}
+
+func (i *InterfaceRule) compare(a, b models.Interface) int {
+	keyA, keyB := i.orderKey(a), i.orderKey(b)
+	numA, errA := strconv.Atoi(keyA)
+	numB, errB := strconv.Atoi(keyB)
+	if errA == nil && errB == nil {
+		return cmp.Compare(numA, numB)
+	}
+	return strings.Compare(keyA, keyB)
+}
+
+// selectInterface returns the first matching interface in the order of the rule.
+func (i *InterfaceRule) selectInterface(interfaces []models.Interface) (models.Interface, bool) {
+	matching := slices.DeleteFunc(slices.Clone(interfaces), func(iface models.Interface) bool {
+		return !i.matches(iface)
+	})
+	if len(matching) == 0 {
+		return models.Interface{}, false
+	}
+
+	if i.Order == interfaceOrderLowest {
+		return slices.MinFunc(matching, i.compare), true
+	}
+	return slices.MaxFunc(matching, i.compare), true
+}
+
+// DefaultInterfaceRules returns the built-in default rule, selecting the LAG interface with the highest number.
+func DefaultInterfaceRules() map[string]*InterfaceRule {
+	rule := &InterfaceRule{Pattern: `^LAG(\d+)$`, Type: interfaceTypeLag}
+	if err := rule.compile(); err != nil {
+		panic(err)
+	}
+	return map[string]*InterfaceRule{DefaultInterfaceRuleName: rule}
+}
+
+// LoadInterfaceRules reads named interface rules from a JSON file containing an object of rules by name.
+// The built-in default rule is added unless the file defines a rule named default.
+func LoadInterfaceRules(fileReader credentials.FileReader, fileName string) (map[string]*InterfaceRule, error) {
+	data, err := fileReader.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", fileName, err)
+	}
+
+	var rules map[string]*InterfaceRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal %s: %w", fileName, err)
+	}
+
+	for name, rule := range rules {
+		if rule == nil {
+			return nil, fmt.Errorf("invalid interface rule %s: rule is empty", name)
+		}
+		if err := rule.compile(); err != nil {
+			return nil, fmt.Errorf("invalid interface rule %s: %w", name, err)
+		}
+	}
+
+	if _, ok := rules[DefaultInterfaceRuleName]; !ok {
+		if rules == nil {
+			rules = make(map[string]*InterfaceRule)
+		}
+		rules[DefaultInterfaceRuleName] = DefaultInterfaceRules()[DefaultInterfaceRuleName]
+	}
+
+	return rules, nil
+}
+
+// interfaceRule returns the name of the interface rule selected by the annotation of the IPAddressClaim, the label
+// of the IP pool of the IPAddress or the default rule, and the rule itself.
+func (r *IPUpdateReconciler) interfaceRule(ctx context.Context, ipAddr *ipamv1.IPAddress, ipClaim *ipamv1.IPAddressClaim) (string, *InterfaceRule, error) {
+	name := ipClaim.Annotations[annotationInterfaceRuleKey]
+	if name == "" {
+		pool, err := r.ipAddressPool(ctx, ipAddr)
+		if err != nil {
+			return "", nil, err
+		}
+		if pool != nil {
+			name = pool.GetLabels()[annotationInterfaceRuleKey]
+		}
+	}
+	if name == "" {
+		name = DefaultInterfaceRuleName
+	}
+
+	rule, ok := r.interfaceRules[name]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown interface rule %q", name)
+	}
+	return name, rule, nil
+}
+
+// interfaceAnnotation formats the value of the interface annotation of an IPAddress, the interface ID followed
+// by the name of the interface rule which selected it.
+func interfaceAnnotation(interfaceID int, rule string) string {
+	return fmt.Sprintf("%d;rule=%s", interfaceID, rule)
+}
+
+// interfaceIDFromAnnotation parses the interface ID of the interface annotation of an IPAddress.
+func interfaceIDFromAnnotation(value string) (int, error) {
+	id, _, _ := strings.Cut(value, ";")
+	return strconv.Atoi(id)
+}
@@ -0,0 +1,250 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"text/template"
+
+	"github.com/sapcc/go-netbox-go/models"
+	ipamv1 "sigs.k8s.io/cluster-api/api/ipam/v1beta2"
+
+	"github.com/sapcc/argora/internal/credentials"
+)
+
+// clusterNameLabel is the CAPI label of objects belonging to a cluster, used if an IPAddressClaim has no cluster name.
+const clusterNameLabel = "cluster.x-k8s.io/cluster-name"
+
+// IPAddressTemplate defines the metadata of the NetBox IP addresses managed by the IPUpdate controller. All fields
+// but the tags are Go templates rendered with the owners of the IP address. Empty fields are not managed, hence
+// left untouched on existing IP addresses.
+type IPAddressTemplate struct {
+	// DNSName is the DNS name of the IP address, e.g. {{ .Device }}.example.com.
+	DNSName string `json:"dnsName,omitempty"`
+	// Status is the NetBox status of the IP address, e.g. active or reserved.
+	Status string `json:"status,omitempty"`
+	// Role is the NetBox role of the IP address, e.g. loopback or anycast.
+	Role string `json:"role,omitempty"`
+	// Description of the IP address, e.g. claim {{ .Namespace }}/{{ .Claim }} of pool {{ .Pool }}.
+	Description string `json:"description,omitempty"`
+	// Tags are the names of NetBox tags the IP address has to carry. Other tags of the IP address are kept.
+	Tags []string `json:"tags,omitempty"`
+	// CustomFields are the NetBox custom fields of the IP address by name. They are rendered as text, so they suit text
+	// custom fields, and other custom fields are compared with NetBox by their text.
+	CustomFields map[string]string `json:"customFields,omitempty"`
+
+	templates map[string]*template.Template
+}
+
+// ipAddressTemplateData holds the owners of an IP address exposed to the IPAddressTemplate.
+type ipAddressTemplateData struct {
+	Address   string
+	Namespace string
+	Claim     string
+	Pool      string
+	PoolKind  string
+	Cluster   string
+	Device    string
+	Interface string
+}
+
+func newIPAddressTemplateData(ipAddr *ipamv1.IPAddress, target *netboxTarget) ipAddressTemplateData {
+	cluster := target.ipClaim.Spec.ClusterName
+	if cluster == "" {
+		cluster = target.ipClaim.Labels[clusterNameLabel]
+	}
+
+	return ipAddressTemplateData{
+		Address:   ipAddr.Spec.Address,
+		Namespace: ipAddr.Namespace,
+		Claim:     target.ipClaim.Name,
+		Pool:      ipAddr.Spec.PoolRef.Name,
+		PoolKind:  ipAddr.Spec.PoolRef.Kind,
+		Cluster:   cluster,
+		Device:    target.device.Name,
+		Interface: target.iface.Name,
+	}
+}
+
+// ipAddressMetadata is the rendered metadata of an IP address.
+type ipAddressMetadata struct {
+	DNSName      string
+	Status       string
+	Role         string
+	Description  string
+	Tags         []models.NestedTag
+	CustomFields map[string]any
+}
+
+// LoadIPAddressTemplate reads the IP address template from a JSON file.
+func LoadIPAddressTemplate(fileReader credentials.FileReader, fileName string) (*IPAddressTemplate, error) {
+	data, err := fileReader.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", fileName, err)
+	}
+
+	ipAddressTemplate := &IPAddressTemplate{}
+	if err := json.Unmarshal(data, ipAddressTemplate); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal %s: %w", fileName, err)
+	}
+
+	if err := ipAddressTemplate.parse(); err != nil {
+		return nil, fmt.Errorf("invalid ip address template: %w", err)
+	}
+
+	return ipAddressTemplate, nil
+}
+
+func (t *IPAddressTemplate) parse() error {
+	fields := map[string]string{
+		"dnsName":     t.DNSName,
+		"status":      t.Status,
+		"role":        t.Role,
+		"description": t.Description,
+	}
+	for name, value := range t.CustomFields {
+		fields["customFields."+name] = value
+	}
+
+	t.templates = make(map[string]*template.Template, len(fields))
+	for name, text := range fields {
+		if text == "" {
+			continue
+		}
+		tmpl, err := template.New(name).Funcs(labelTemplateFuncs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return fmt.Errorf("unable to parse %s template: %w", name, err)
+		}
+		t.templates[name] = tmpl
+	}
+	return nil
+}
+
+func (t *IPAddressTemplate) execute(name string, data ipAddressTemplateData) (string, error) {
+	tmpl, ok := t.templates[name]
+	if !ok {
+		return "", nil
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return "", fmt.Errorf("unable to render %s template: %w", name, err)
+	}
+	return rendered.String(), nil
+}
+
+// render renders the metadata of an IP address. Tags are resolved by the caller.
+func (t *IPAddressTemplate) render(data ipAddressTemplateData) (*ipAddressMetadata, error) {
+	metadata := &ipAddressMetadata{}
+
+	var err error
+	if metadata.DNSName, err = t.execute("dnsName", data); err != nil {
+		return nil, err
+	}
+	if metadata.Status, err = t.execute("status", data); err != nil {
+		return nil, err
+	}
+	if metadata.Role, err = t.execute("role", data); err != nil {
+		return nil, err
+	}
+	if metadata.Description, err = t.execute("description", data); err != nil {
+		return nil, err
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(t.CustomFields)) {
+		value, err := t.execute("customFields."+name, data)
+		if err != nil {
+			return nil, err
+		}
+		if metadata.CustomFields == nil {
+			metadata.CustomFields = make(map[string]any, len(t.CustomFields))
+		}
+		metadata.CustomFields[name] = value
+	}
+
+	return metadata, nil
+}
+
+// ipAddressMetadata renders the metadata of the IP address with the template of the reconciler, or returns nil if
+// no template is configured.
+func (r *IPUpdateReconciler) ipAddressMetadata(ipAddr *ipamv1.IPAddress, target *netboxTarget) (*ipAddressMetadata, error) {
+	if r.ipAddressTemplate == nil {
+		return nil, nil
+	}
+
+	metadata, err := r.ipAddressTemplate.render(newIPAddressTemplateData(ipAddr, target))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tagName := range r.ipAddressTemplate.Tags {
+		tag, err := r.netBox.Extras().GetTagByName(tagName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get tag %s: %w", tagName, err)
+		}
+		metadata.Tags = append(metadata.Tags, tag.NestedTag)
+	}
+
+	return metadata, nil
+}
+
+// update returns the update of the IP address applying the metadata, or nil if the IP address is up to date.
+// Only the changed fields are set, as fields omitted in an update are kept by NetBox.
+func (m *ipAddressMetadata) update(addr *models.IPAddress) *models.WriteableIPAddress {
+	update := &models.WriteableIPAddress{
+		NestedIPAddress: models.NestedIPAddress{
+			ID:      addr.ID,
+			Address: addr.Address,
+		},
+	}
+	changed := false
+
+	if m.DNSName != "" && m.DNSName != addr.DNSName {
+		update.DNSName, changed = m.DNSName, true
+	}
+	if m.Status != "" && m.Status != addr.Status.Value {
+		update.Status, changed = m.Status, true
+	}
+	if m.Role != "" && m.Role != addr.Role.Value {
+		update.Role, changed = m.Role, true
+	}
+	if m.Description != "" && m.Description != addr.Description {
+		update.Description, changed = m.Description, true
+	}
+
+	tags := slices.Clone(addr.Tags)
+	for _, tag := range m.Tags {
+		if !slices.ContainsFunc(tags, func(existing models.NestedTag) bool { return existing.Slug == tag.Slug }) {
+			tags = append(tags, tag)
+			changed = true
+		}
+	}
+	if len(tags) != len(addr.Tags) {
+		update.Tags = tags
+	}
+
+	// The rendered custom fields are strings, while NetBox returns numbers and booleans as such, so the values are
+	// compared in their text form. An unset custom field equals an empty one.
+	currentFields, _ := addr.CustomFields.(map[string]any)
+	for name, value := range m.CustomFields {
+		current := ""
+		if currentFields[name] != nil {
+			current = fmt.Sprint(currentFields[name])
+		}
+		if current != fmt.Sprint(value) {
+			update.CustomFields = m.CustomFields
+			changed = true
+			break
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+	return update
+}
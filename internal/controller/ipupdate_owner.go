@@ -0,0 +1,121 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	metalv1alpha1 "github.com/ironcore-dev/metal-operator/api/v1alpha1"
+	bmov1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// annotationDeviceNameKey sets the NetBox device name on an IPAddressClaim directly, bypassing the owner resolvers.
+	annotationDeviceNameKey = "netbox.argora.cloud.sap/device"
+
+	// annotationBareMetalHostKey is set by CAPM3 on a Metal3Machine bound to a BareMetalHost, as <namespace>/<name>.
+	annotationBareMetalHostKey = "metal3.io/BareMetalHost"
+
+	ownerKindServerClaim   = "ServerClaim"
+	ownerKindMachine       = "Machine"
+	ownerKindMetal3Machine = "Metal3Machine"
+	ownerKindBareMetalHost = "BareMetalHost"
+)
+
+var metal3MachineGVK = schema.GroupVersionKind{
+	Group:   "infrastructure.cluster.x-k8s.io",
+	Version: "v1beta1",
+	Kind:    ownerKindMetal3Machine,
+}
+
+// ownerResolver resolves the NetBox device name from an owner of an IPAddressClaim.
+type ownerResolver func(ctx context.Context, k8sClient client.Client, namespace, name string) (string, error)
+
+// defaultOwnerResolvers returns the owner resolvers for IronCore ServerClaims, CAPI Machines, Metal3Machines
+// and BareMetalHosts, selected by the kind of the owner.
+func defaultOwnerResolvers() map[string]ownerResolver {
+	return map[string]ownerResolver{
+		ownerKindServerClaim:   deviceNameFromServerClaim,
+		ownerKindMachine:       deviceNameFromMachine,
+		ownerKindMetal3Machine: deviceNameFromMetal3Machine,
+		ownerKindBareMetalHost: deviceNameFromBareMetalHost,
+	}
+}
+
+// deviceNameFromServerClaim resolves the device name from the BMC of the Server bound to the ServerClaim.
+func deviceNameFromServerClaim(ctx context.Context, k8sClient client.Client, namespace, name string) (string, error) {
+	serverClaim := &metalv1alpha1.ServerClaim{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, serverClaim); err != nil {
+		return "", fmt.Errorf("failed to get ServerClaim: %w", err)
+	}
+
+	if serverClaim.Spec.ServerRef == nil {
+		return "", fmt.Errorf("ServerClaim %s not yet bound to a server", name)
+	}
+
+	server := &metalv1alpha1.Server{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: serverClaim.Spec.ServerRef.Name}, server); err != nil {
+		return "", fmt.Errorf("failed to get Server: %w", err)
+	}
+
+	if server.Spec.BMCRef == nil || server.Spec.BMCRef.Name == "" {
+		return "", fmt.Errorf("server %s has no bmcRef name", server.Name)
+	}
+
+	return server.Spec.BMCRef.Name, nil
+}
+
+// deviceNameFromMachine resolves the device name from the Metal3Machine infrastructure of the CAPI Machine.
+func deviceNameFromMachine(ctx context.Context, k8sClient client.Client, namespace, name string) (string, error) {
+	machine := &clusterv1.Machine{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, machine); err != nil {
+		return "", fmt.Errorf("failed to get Machine: %w", err)
+	}
+
+	infraRef := machine.Spec.InfrastructureRef
+	if infraRef.Kind != ownerKindMetal3Machine {
+		return "", fmt.Errorf("machine %s has unsupported infrastructure kind %q", name, infraRef.Kind)
+	}
+
+	return deviceNameFromMetal3Machine(ctx, k8sClient, namespace, infraRef.Name)
+}
+
+// deviceNameFromMetal3Machine resolves the device name from the BareMetalHost the Metal3Machine is bound to.
+// Metal3Machines are read unstructured, as the CAPM3 API is not a dependency of argora.
+func deviceNameFromMetal3Machine(ctx context.Context, k8sClient client.Client, namespace, name string) (string, error) {
+	metal3Machine := &unstructured.Unstructured{}
+	metal3Machine.SetGroupVersionKind(metal3MachineGVK)
+	if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, metal3Machine); err != nil {
+		return "", fmt.Errorf("failed to get Metal3Machine: %w", err)
+	}
+
+	host, ok := metal3Machine.GetAnnotations()[annotationBareMetalHostKey]
+	if !ok || host == "" {
+		return "", fmt.Errorf("Metal3Machine %s not yet bound to a BareMetalHost", name)
+	}
+
+	hostNamespace, hostName, found := strings.Cut(host, "/")
+	if !found {
+		hostNamespace, hostName = namespace, host
+	}
+
+	return deviceNameFromBareMetalHost(ctx, k8sClient, hostNamespace, hostName)
+}
+
+// deviceNameFromBareMetalHost resolves the device name from the BareMetalHost, which is named after the device
+// by the Metal3 controller.
+func deviceNameFromBareMetalHost(ctx context.Context, k8sClient client.Client, namespace, name string) (string, error) {
+	bmh := &bmov1alpha1.BareMetalHost{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, bmh); err != nil {
+		return "", fmt.Errorf("failed to get BareMetalHost: %w", err)
+	}
+
+	return bmh.Name, nil
+}
@@ -10,7 +10,7 @@ import (
 	"fmt"
 	"maps"
 	"net"
-	"strings"
+	"regexp"
 	"time"
 
 	argorav1alpha1 "github.com/sapcc/argora/api/v1alpha1"
@@ -41,25 +41,33 @@ const (
 
 type IronCoreReconciler struct {
 	k8sClient         client.Client
+	apiReader         client.Reader
 	scheme            *runtime.Scheme
 	credentials       *credentials.Credentials
 	statusHandler     status.ClusterImportStatus
 	netBox            netbox.Netbox
 	reconcileInterval time.Duration
+	deviceNamePattern *regexp.Regexp
 }
 
-func NewIronCoreReconciler(mgr ctrl.Manager, creds *credentials.Credentials, statusHandler status.ClusterImportStatus, netBox netbox.Netbox, reconcileInterval time.Duration) *IronCoreReconciler {
+func NewIronCoreReconciler(mgr ctrl.Manager, creds *credentials.Credentials, statusHandler status.ClusterImportStatus, netBox netbox.Netbox, reconcileInterval time.Duration, deviceNamePattern *regexp.Regexp) *IronCoreReconciler {
 	return &IronCoreReconciler{
 		k8sClient:         mgr.GetClient(),
+		apiReader:         mgr.GetAPIReader(),
 		scheme:            mgr.GetScheme(),
 		credentials:       creds,
 		statusHandler:     statusHandler,
 		netBox:            netBox,
 		reconcileInterval: reconcileInterval,
+		deviceNamePattern: deviceNamePattern,
 	}
 }
 
 func (r *IronCoreReconciler) SetupWithManager(mgr ctrl.Manager, rateLimiter RateLimiter) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &metalv1alpha1.Server{}, serverBMCRefField, serverBMCRefIndex); err != nil {
+		return fmt.Errorf("unable to index servers by BMC: %w", err)
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&argorav1alpha1.ClusterImport{}).
 		WithEventFilter(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{})).
@@ -78,6 +86,7 @@ func (r *IronCoreReconciler) SetupWithManager(mgr ctrl.Manager, rateLimiter Rate
 
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;create;update;delete
 // +kubebuilder:rbac:groups=argora.cloud.sap,resources=clusterimports,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=argora.cloud.sap,resources=clusterimports/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=argora.cloud.sap,resources=clusterimports/finalizers,verbs=update
@@ -85,6 +94,7 @@ func (r *IronCoreReconciler) SetupWithManager(mgr ctrl.Manager, rateLimiter Rate
 // +kubebuilder:rbac:groups=metal.ironcore.dev,resources=servers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=metal.ironcore.dev,resources=bmcs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=metal.ironcore.dev,resources=bmcsecrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=metal.ironcore.dev,resources=servermaintenances,verbs=get;list;watch;create;update;patch;delete
 
 func (r *IronCoreReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
@@ -93,10 +103,15 @@ func (r *IronCoreReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	clusterImportCR := &argorav1alpha1.ClusterImport{}
 	err := r.k8sClient.Get(ctx, req.NamespacedName, clusterImportCR)
 	if err != nil {
+		if apierrors.IsNotFound(err) {
+			deleteReconcileMetrics(controllerNameIronCore, req.NamespacedName)
+		}
 		logger.Error(err, "unable to get ClusterImport CR")
 		return ctrl.Result{}, err
 	}
+	recordReconcileStarted(controllerNameIronCore, clusterImportCR)
 
+	observeReload := observePhase(controllerNameIronCore, phaseNetboxReload)
 	err = r.credentials.Reload()
 	if err != nil {
 		logger.Error(err, "unable to reload credentials")
@@ -122,23 +137,33 @@ func (r *IronCoreReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 
 		return ctrl.Result{}, err
 	}
+	observeReload()
 
+	observeClusters := observePhase(controllerNameIronCore, phaseClusters)
+	selected := make(map[string]bool)
 	for _, clusterSelector := range clusterImportCR.Spec.Clusters {
-		err = r.reconcileClusterSelection(ctx, clusterImportCR, clusterSelector)
+		err = r.reconcileClusterSelection(ctx, clusterImportCR, clusterSelector, selected)
 		if err != nil {
 			return ctrl.Result{}, err
 		}
 	}
+	observeClusters()
+
+	pruneDeviceLifecycles(clusterImportCR, selected)
 
 	r.statusHandler.SetCondition(clusterImportCR, argorav1alpha1.NewReasonWithMessage(argorav1alpha1.ConditionReasonClusterImportSucceeded))
 	if errUpdateStatus := r.statusHandler.UpdateToReady(ctx, clusterImportCR); errUpdateStatus != nil {
 		return ctrl.Result{}, errUpdateStatus
 	}
 
+	recordReconcileSuccess(controllerNameIronCore, clusterImportCR)
+
 	return ctrl.Result{RequeueAfter: r.reconcileInterval}, nil
 }
 
-func (r *IronCoreReconciler) reconcileClusterSelection(ctx context.Context, clusterImportCR *argorav1alpha1.ClusterImport, clusterSelector *argorav1alpha1.ClusterSelector) error {
+// reconcileClusterSelection reconciles the devices of the clusters selected by the cluster selector and adds their
+// names to selected.
+func (r *IronCoreReconciler) reconcileClusterSelection(ctx context.Context, clusterImportCR *argorav1alpha1.ClusterImport, clusterSelector *argorav1alpha1.ClusterSelector, selected map[string]bool) error {
 	logger := log.FromContext(ctx)
 	logger.Info("fetching clusters data", "name", clusterSelector.Name, "region", clusterSelector.Region, "type", clusterSelector.Type)
 
@@ -170,8 +195,11 @@ func (r *IronCoreReconciler) reconcileClusterSelection(ctx context.Context, clus
 		}
 
 		for _, device := range devices {
+			selected[device.Name] = true
+			recordDeviceProcessed(controllerNameIronCore, clusterImportCR, cluster.Name)
 			err = r.reconcileDevice(ctx, clusterImportCR, clusterSelector, r.netBox, &cluster, &device)
 			if err != nil {
+				recordDeviceResult(controllerNameIronCore, clusterImportCR, cluster.Name, deviceResultFailed)
 				logger.Error(err, "unable to reconcile device", "device", device.Name, "ID", device.ID)
 
 				r.statusHandler.SetCondition(clusterImportCR, argorav1alpha1.NewReasonWithMessage(argorav1alpha1.ConditionReasonClusterImportFailed))
@@ -191,14 +219,50 @@ func (r *IronCoreReconciler) reconcileDevice(ctx context.Context, clusterImportC
 	logger := log.FromContext(ctx)
 	logger.Info("reconciling device", "device", device.Name, "ID", device.ID)
 
-	if device.Status.Value != deviceStatusActive {
+	namePattern, err := resolveDeviceNamePattern(r.deviceNamePattern, clusterSelector.NamePattern)
+	if err != nil {
+		return err
+	}
+
+	action := deviceStatusAction(clusterSelector, device.Status.Value)
+	message := ""
+
+	nameParts, err := parseDeviceName(namePattern, device.Name)
+	if err != nil && action == argorav1alpha1.DeviceStatusActionImport {
+		action = argorav1alpha1.DeviceStatusActionSkip
+		message = err.Error()
+	}
+
+	if recordDeviceLifecycle(clusterImportCR, device, action, message) {
+		logger.Info("device lifecycle transition", "status", device.Status.Value, "action", action, "message", message)
+	}
+
+	switch action {
+	case argorav1alpha1.DeviceStatusActionImport:
+	case argorav1alpha1.DeviceStatusActionMaintenance:
+		if err := r.requestMaintenance(ctx, clusterImportCR, device); err != nil {
+			return err
+		}
+		recordDeviceResult(controllerNameIronCore, clusterImportCR, cluster.Name, deviceResultMaintenance)
+		return nil
+	case argorav1alpha1.DeviceStatusActionDecommission:
+		if err := r.decommissionDevice(ctx, clusterImportCR, device); err != nil {
+			return err
+		}
+		recordDeviceResult(controllerNameIronCore, clusterImportCR, cluster.Name, deviceResultDecommissioned)
+		return nil
+	default:
+		recordDeviceResult(controllerNameIronCore, clusterImportCR, cluster.Name, deviceResultSkipped)
+		if message != "" {
+			logger.Info("device name does not match name pattern, will skip", "pattern", namePattern.String())
+			return nil
+		}
 		logger.Info("device is not active, will skip", "status", device.Status.Value)
 		return nil
 	}
 
-	deviceNameParts := strings.Split(device.Name, "-")
-	if len(deviceNameParts) != 2 {
-		return fmt.Errorf("unable to split in two device name: %s", device.Name)
+	if err := r.clearMaintenance(ctx, clusterImportCR, device); err != nil {
+		return fmt.Errorf("unable to clear maintenance: %w", err)
 	}
 
 	region, err := netBox.DCIM().GetRegionForDevice(device)
@@ -211,17 +275,19 @@ func (r *IronCoreReconciler) reconcileDevice(ctx context.Context, clusterImportC
 		return fmt.Errorf("unable to get OOB IP: %w", err)
 	}
 
-	commonLabels := map[string]string{
-		"topology.kubernetes.io/region":           region,
-		"topology.kubernetes.io/zone":             device.Site.Slug,
-		"kubernetes.metal.cloud.sap/cluster":      cluster.Name,
-		"kubernetes.metal.cloud.sap/cluster-type": cluster.Type.Slug,
-		"kubernetes.metal.cloud.sap/name":         device.Name,
-		"kubernetes.metal.cloud.sap/nodename":     deviceNameParts[0],
-		"kubernetes.metal.cloud.sap/bb":           deviceNameParts[1],
-		"kubernetes.metal.cloud.sap/type":         device.DeviceType.Slug,
-		"kubernetes.metal.cloud.sap/role":         device.DeviceRole.Slug,
-		"kubernetes.metal.cloud.sap/platform":     device.Platform.Slug,
+	topology, err := newDeviceTopology(netBox, device, region, cluster.Name, cluster.Type.Slug, device.DeviceRole.Slug, nameParts)
+	if err != nil {
+		return err
+	}
+
+	commonLabels, err := topology.labels(clusterSelector.LabelTemplate)
+	if err != nil {
+		return fmt.Errorf("unable to generate labels: %w", err)
+	}
+
+	configContextAnnotations, err := r.reconcileConfigContext(ctx, clusterImportCR, clusterSelector, netBox, device, commonLabels)
+	if err != nil {
+		return fmt.Errorf("unable to reconcile config context: %w", err)
 	}
 
 	bmcSecret, skipped, err := r.reconcileBmcSecret(ctx, clusterImportCR, clusterSelector, device, commonLabels)
@@ -231,10 +297,11 @@ func (r *IronCoreReconciler) reconcileDevice(ctx context.Context, clusterImportC
 
 	bmcObj := &metalv1alpha1.BMC{}
 	if err := r.k8sClient.Get(ctx, client.ObjectKey{Name: device.Name}, bmcObj); err == nil {
-		if err := r.patchBMCLabels(ctx, bmcObj, commonLabels); err != nil {
+		if err := r.patchBMCLabels(ctx, bmcObj, commonLabels, configContextAnnotations); err != nil {
 			return fmt.Errorf("unable to patch BMC labels: %w", err)
 		}
 
+		recordDeviceResult(controllerNameIronCore, clusterImportCR, cluster.Name, deviceResultImported)
 		logger.Info("BMC custom resource already exists, will skip", "bmc", device.Name)
 		return nil
 	}
@@ -246,7 +313,7 @@ func (r *IronCoreReconciler) reconcileDevice(ctx context.Context, clusterImportC
 		logger.Info("Got BMC hostname from netbox", "hostname", hostname)
 	}
 
-	bmc, err := r.createBmc(ctx, device, oobIP, hostname, bmcSecret, commonLabels)
+	bmc, err := r.createBmc(ctx, device, oobIP, hostname, bmcSecret, commonLabels, configContextAnnotations)
 	if err != nil {
 		return fmt.Errorf("unable to create bmc: %w", err)
 	}
@@ -258,6 +325,8 @@ func (r *IronCoreReconciler) reconcileDevice(ctx context.Context, clusterImportC
 			return err
 		}
 	}
+
+	recordDeviceResult(controllerNameIronCore, clusterImportCR, cluster.Name, deviceResultImported)
 	return nil
 }
 
@@ -304,6 +373,34 @@ func (r *IronCoreReconciler) reconcileBmcSecret(ctx context.Context, clusterImpo
 	return bmcSecret, false, nil
 }
 
+// reconcileConfigContext exports the config context of the device if configured on the cluster selector.
+// It returns the annotations to set on the BMC, or nil if the config context is not exported to annotations.
+func (r *IronCoreReconciler) reconcileConfigContext(ctx context.Context, clusterImportCR *argorav1alpha1.ClusterImport, clusterSelector *argorav1alpha1.ClusterSelector, netBox netbox.Netbox, device *models.Device, labels map[string]string) (map[string]string, error) {
+	export := clusterSelector.ConfigContext
+	if export == nil {
+		return nil, deleteConfigContextConfigMap(ctx, r.k8sClient, r.apiReader, clusterImportCR, clusterImportCR.Namespace, device.Name)
+	}
+
+	configContext, err := netBox.DCIM().GetConfigContextForDevice(device)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := renderConfigContext(configContext, export)
+	if err != nil {
+		return nil, err
+	}
+
+	if export.Target == argorav1alpha1.ConfigContextTargetAnnotations {
+		if err := deleteConfigContextConfigMap(ctx, r.k8sClient, r.apiReader, clusterImportCR, clusterImportCR.Namespace, device.Name); err != nil {
+			return nil, err
+		}
+		return renderConfigContextAnnotations(data)
+	}
+
+	return nil, reconcileConfigContextConfigMap(ctx, r.k8sClient, r.apiReader, r.scheme, clusterImportCR, clusterImportCR.Namespace, device.Name, labels, data)
+}
+
 func (r *IronCoreReconciler) resolveBMCCredentials(ctx context.Context, clusterImportCR *argorav1alpha1.ClusterImport, clusterSelector *argorav1alpha1.ClusterSelector) (user, password string, err error) {
 	if clusterSelector.BMCCredentialsRef == nil {
 		user = r.credentials.BMCUser
@@ -332,7 +429,7 @@ func (r *IronCoreReconciler) resolveBMCCredentials(ctx context.Context, clusterI
 	return user, password, nil
 }
 
-func (r *IronCoreReconciler) createBmc(ctx context.Context, device *models.Device, oobIP, hostname string, bmcSecret *metalv1alpha1.BMCSecret, labels map[string]string) (*metalv1alpha1.BMC, error) {
+func (r *IronCoreReconciler) createBmc(ctx context.Context, device *models.Device, oobIP, hostname string, bmcSecret *metalv1alpha1.BMCSecret, labels, annotations map[string]string) (*metalv1alpha1.BMC, error) {
 	logger := log.FromContext(ctx)
 
 	ip, err := metalv1alpha1.ParseIP(oobIP)
@@ -346,8 +443,8 @@ func (r *IronCoreReconciler) createBmc(ctx context.Context, device *models.Devic
 			Kind:       "BMC",
 		},
 		ObjectMeta: ctrl.ObjectMeta{
-			Name:   device.Name,
-			Labels: labels,
+			Name:        device.Name,
+			Annotations: annotations,
 		},
 		Spec: metalv1alpha1.BMCSpec{
 			Endpoint: &metalv1alpha1.InlineEndpoint{
@@ -367,6 +464,7 @@ func (r *IronCoreReconciler) createBmc(ctx context.Context, device *models.Devic
 		logger.Info("Setting hostname on BMC", "hostname", hostname, "bmcName", bmc.Name)
 		bmc.Spec.Hostname = &hostname
 	}
+	setManagedLabels(bmc, labels)
 
 	if err := r.k8sClient.Create(ctx, bmc); err != nil {
 		if apierrors.IsAlreadyExists(err) {
@@ -376,6 +474,7 @@ func (r *IronCoreReconciler) createBmc(ctx context.Context, device *models.Devic
 		return nil, fmt.Errorf("unable to create BMC: %w", err)
 	}
 
+	bmcOperationsTotal.WithLabelValues(bmcKindBMC, operationCreated).Inc()
 	return bmc, nil
 }
 
@@ -392,6 +491,117 @@ func (r *IronCoreReconciler) patchOwnerReference(ctx context.Context, bmc *metal
 	return nil
 }
 
+func (r *IronCoreReconciler) requestMaintenance(ctx context.Context, clusterImportCR *argorav1alpha1.ClusterImport, device *models.Device) error {
+	logger := log.FromContext(ctx)
+
+	servers, err := r.getServersForDevice(ctx, device)
+	if err != nil {
+		return err
+	}
+
+	if len(servers) == 0 {
+		logger.Info("no server found for device, will skip maintenance", "status", device.Status.Value)
+		return nil
+	}
+
+	for _, server := range servers {
+		serverMaintenance := &metalv1alpha1.ServerMaintenance{
+			ObjectMeta: ctrl.ObjectMeta{
+				Name:      server.Name,
+				Namespace: clusterImportCR.Namespace,
+			},
+		}
+
+		result, err := controllerutil.CreateOrUpdate(ctx, r.k8sClient, serverMaintenance, func() error {
+			if serverMaintenance.Labels == nil {
+				serverMaintenance.Labels = make(map[string]string)
+			}
+			serverMaintenance.Labels[labelDeviceKey] = device.Name
+			if serverMaintenance.Annotations == nil {
+				serverMaintenance.Annotations = make(map[string]string)
+			}
+			serverMaintenance.Annotations[metalv1alpha1.ServerMaintenanceReasonAnnotationKey] = "netbox device status is " + device.Status.Value
+			serverMaintenance.Spec.Policy = metalv1alpha1.ServerMaintenancePolicyOwnerApproval
+			serverMaintenance.Spec.ServerRef = &corev1.LocalObjectReference{Name: server.Name}
+			return controllerutil.SetControllerReference(clusterImportCR, serverMaintenance, r.scheme)
+		})
+		if err != nil {
+			return fmt.Errorf("unable to create or update server maintenance %s: %w", serverMaintenance.Name, err)
+		}
+
+		if result == controllerutil.OperationResultCreated {
+			logger.Info("created ServerMaintenance", "name", serverMaintenance.Name, "server", server.Name)
+		}
+	}
+
+	return nil
+}
+
+func (r *IronCoreReconciler) clearMaintenance(ctx context.Context, clusterImportCR *argorav1alpha1.ClusterImport, device *models.Device) error {
+	logger := log.FromContext(ctx)
+
+	serverMaintenances := &metalv1alpha1.ServerMaintenanceList{}
+	if err := r.k8sClient.List(ctx, serverMaintenances, client.InNamespace(clusterImportCR.Namespace), client.MatchingLabels{labelDeviceKey: device.Name}); err != nil {
+		return fmt.Errorf("unable to list server maintenances: %w", err)
+	}
+
+	for _, serverMaintenance := range serverMaintenances.Items {
+		if err := r.k8sClient.Delete(ctx, &serverMaintenance); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("unable to delete server maintenance %s: %w", serverMaintenance.Name, err)
+		}
+
+		logger.Info("deleted ServerMaintenance", "name", serverMaintenance.Name)
+	}
+
+	return nil
+}
+
+func (r *IronCoreReconciler) decommissionDevice(ctx context.Context, clusterImportCR *argorav1alpha1.ClusterImport, device *models.Device) error {
+	logger := log.FromContext(ctx)
+
+	if err := r.clearMaintenance(ctx, clusterImportCR, device); err != nil {
+		return fmt.Errorf("unable to clear maintenance: %w", err)
+	}
+
+	bmc := &metalv1alpha1.BMC{}
+	if err := r.k8sClient.Get(ctx, client.ObjectKey{Name: device.Name}, bmc); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("unable to get BMC: %w", err)
+	}
+
+	if bmc.Annotations[argorav1alpha1.AnnotationIgnore] == annotationValueTrue {
+		logger.Info("BMC has ignore annotation, will skip decommissioning", "bmc", bmc.Name)
+		return nil
+	}
+
+	if err := r.k8sClient.Delete(ctx, bmc); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("unable to delete BMC: %w", err)
+	}
+
+	logger.Info("deleted BMC of decommissioned device", "bmc", bmc.Name)
+	return nil
+}
+
+func (r *IronCoreReconciler) getServersForDevice(ctx context.Context, device *models.Device) ([]metalv1alpha1.Server, error) {
+	serverList := &metalv1alpha1.ServerList{}
+	if err := r.k8sClient.List(ctx, serverList, client.MatchingFields{serverBMCRefField: device.Name}); err != nil {
+		return nil, fmt.Errorf("unable to list servers: %w", err)
+	}
+
+	return serverList.Items, nil
+}
+
+// serverBMCRefIndex indexes the Servers by the name of their BMC, which is the name of the NetBox device.
+func serverBMCRefIndex(obj client.Object) []string {
+	server, ok := obj.(*metalv1alpha1.Server)
+	if !ok || server.Spec.BMCRef == nil || server.Spec.BMCRef.Name == "" {
+		return nil
+	}
+	return []string{server.Spec.BMCRef.Name}
+}
+
 func getOobIP(device *models.Device) (string, error) {
 	oobIP := device.OOBIp.Address
 	ip, _, err := net.ParseCIDR(oobIP)
@@ -421,20 +631,24 @@ func getRemoteboardHostname(netBox netbox.Netbox, device *models.Device) (string
 	return ipAddress.DNSName, nil
 }
 
-func (r *IronCoreReconciler) patchBMCLabels(ctx context.Context, bmc *metalv1alpha1.BMC, labels map[string]string) error {
+// patchBMCLabels sets the labels and config context annotations on the BMC, removing the labels no longer rendered.
+// The BMC is only patched if they change.
+func (r *IronCoreReconciler) patchBMCLabels(ctx context.Context, bmc *metalv1alpha1.BMC, labels, configContextAnnotations map[string]string) error {
 	logger := log.FromContext(ctx)
-	logger.Info("patching BMC labels", "bmc", bmc.Name)
 
 	bmcBase := bmc.DeepCopy()
-	if bmc.Labels == nil {
-		bmc.Labels = make(map[string]string)
+	setManagedLabels(bmc, labels)
+	setConfigContextAnnotations(bmc, configContextAnnotations)
+	if maps.Equal(bmc.Labels, bmcBase.Labels) && maps.Equal(bmc.Annotations, bmcBase.Annotations) {
+		return nil
 	}
-	maps.Copy(bmc.Labels, labels)
 
+	logger.Info("patching BMC labels", "bmc", bmc.Name)
 	if err := r.k8sClient.Patch(ctx, bmc, client.MergeFrom(bmcBase)); err != nil {
 		logger.Error(err, "failed to patch BMC labels")
 		return err
 	}
 
+	bmcOperationsTotal.WithLabelValues(bmcKindBMC, operationPatched).Inc()
 	return nil
 }
@@ -14,12 +14,14 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/sapcc/go-netbox-go/models"
@@ -148,6 +150,10 @@ var _ = Describe("Ironcore Controller", func() {
 				Expect(device.Name).To(BeElementOf("device-name1", "device-name2"))
 				return "region1", nil
 			}
+			netBoxMock.DCIMMock.(*mock.DCIMMock).GetSiteGroupForDeviceFunc = func(device *models.Device) (string, error) {
+				Expect(device.Name).To(BeElementOf("device-name1", "device-name2"))
+				return "group1", nil
+			}
 			netBoxMock.DCIMMock.(*mock.DCIMMock).GetInterfaceForDeviceFunc = func(device *models.Device, ifaceName string) (*models.Interface, error) {
 				Expect(ifaceName).To(Equal("remoteboard"))
 				return &models.Interface{
@@ -176,6 +182,7 @@ var _ = Describe("Ironcore Controller", func() {
 			Expect(labels).To(SatisfyAll(
 				HaveKeyWithValue("topology.kubernetes.io/region", "region1"),
 				HaveKeyWithValue("topology.kubernetes.io/zone", "site1"),
+				HaveKeyWithValue("kubernetes.metal.cloud.sap/site-group", "group1"),
 				HaveKeyWithValue("kubernetes.metal.cloud.sap/cluster", clusterName),
 				HaveKeyWithValue("kubernetes.metal.cloud.sap/cluster-type", clusterType1),
 				HaveKeyWithValue("kubernetes.metal.cloud.sap/name", bmcName),
@@ -736,7 +743,7 @@ var _ = Describe("Ironcore Controller", func() {
 				expectStatus(argorav1alpha1.Error, "unable to read credentials.json: error")
 			})
 
-			It("should fail if device name format is invalid", func() {
+			It("should skip a device whose name does not match the name pattern", func() {
 				// given
 				netBoxMock := prepareNetboxMock()
 
@@ -758,10 +765,14 @@ var _ = Describe("Ironcore Controller", func() {
 				})
 
 				// then
-				Expect(err).To(HaveOccurred())
-				Expect(err).To(MatchError("unable to split in two device name: invalidname"))
+				Expect(err).ToNot(HaveOccurred())
 
-				expectStatus(argorav1alpha1.Error, "unable to reconcile device invalidname (1) on cluster cluster1 (1): unable to split in two device name: invalidname")
+				expectStatus(argorav1alpha1.Ready, "")
+				Expect(clusterImport.Status.Devices).To(HaveLen(1))
+				Expect(clusterImport.Status.Devices[0].Name).To(Equal("invalidname"))
+				Expect(clusterImport.Status.Devices[0].Action).To(Equal(argorav1alpha1.DeviceStatusActionSkip))
+				Expect(clusterImport.Status.Devices[0].Message).To(Equal("device name does not match name pattern ^(?P<nodename>[^-]+)-(?P<bb>[^-]+)$: invalidname"))
+				Expect(netBoxMock.DCIMMock.(*mock.DCIMMock).GetRegionForDeviceCalls).To(Equal(0))
 			})
 
 			It("should return an error if netbox reload fails", func() {
@@ -1454,6 +1465,414 @@ var _ = Describe("Ironcore Controller", func() {
 				Expect(err.Error()).To(ContainSubstring("unable to resolve BMC credentials"))
 				Expect(err.Error()).To(ContainSubstring("missing-secret"))
 			})
+
+			withDeviceStatus := func(netBoxMock *mock.NetBoxMock, deviceStatus string) {
+				getDevicesByClusterID := netBoxMock.DCIMMock.(*mock.DCIMMock).GetDevicesByClusterIDFunc
+				netBoxMock.DCIMMock.(*mock.DCIMMock).GetDevicesByClusterIDFunc = func(clusterID int) ([]models.Device, error) {
+					devices, err := getDevicesByClusterID(clusterID)
+					for i := range devices {
+						devices[i].Status.Value = deviceStatus
+					}
+					return devices, err
+				}
+			}
+
+			server := &metalv1alpha1.Server{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "device-name1-system-0",
+				},
+				Spec: metalv1alpha1.ServerSpec{
+					BMCRef: &corev1.LocalObjectReference{Name: bmcName1},
+				},
+			}
+
+			It("should request maintenance for an offline device", func() {
+				// given
+				netBoxMock := prepareNetboxMock()
+				withDeviceStatus(netBoxMock, "offline")
+
+				fakeClient := createFakeClient(clusterImportCR, server)
+				controllerReconciler := createIronCoreReconciler(fakeClient, netBoxMock, fileReaderMock)
+				maintenance := testutil.ToFloat64(devicesTotal.WithLabelValues(controllerNameIronCore, resourceNamespace, resourceName, "cluster1", deviceResultMaintenance))
+
+				// when
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedClusterImportName})
+
+				// then
+				Expect(err).ToNot(HaveOccurred())
+				Expect(testutil.ToFloat64(devicesTotal.WithLabelValues(controllerNameIronCore, resourceNamespace, resourceName, "cluster1", deviceResultMaintenance))).To(Equal(maintenance + 1))
+
+				serverMaintenance := &metalv1alpha1.ServerMaintenance{}
+				Expect(fakeClient.Get(ctx, types.NamespacedName{Name: server.Name, Namespace: resourceNamespace}, serverMaintenance)).To(Succeed())
+				Expect(serverMaintenance.Labels).To(HaveKeyWithValue("argora.cloud.sap/device", bmcName1))
+				Expect(serverMaintenance.Annotations).To(HaveKeyWithValue(metalv1alpha1.ServerMaintenanceReasonAnnotationKey, "netbox device status is offline"))
+				Expect(serverMaintenance.Spec.Policy).To(Equal(metalv1alpha1.ServerMaintenancePolicyOwnerApproval))
+				Expect(serverMaintenance.Spec.ServerRef.Name).To(Equal(server.Name))
+
+				bmc := &metalv1alpha1.BMC{}
+				Expect(apierrors.IsNotFound(fakeClient.Get(ctx, client.ObjectKey{Name: bmcName1}, bmc))).To(BeTrue())
+
+				updatedClusterImport := &argorav1alpha1.ClusterImport{}
+				Expect(fakeClient.Get(ctx, typeNamespacedClusterImportName, updatedClusterImport)).To(Succeed())
+				Expect(updatedClusterImport.Status.Devices).To(HaveLen(1))
+				Expect(updatedClusterImport.Status.Devices[0].Name).To(Equal(bmcName1))
+				Expect(updatedClusterImport.Status.Devices[0].NetboxStatus).To(Equal("offline"))
+				Expect(updatedClusterImport.Status.Devices[0].Action).To(Equal(argorav1alpha1.DeviceStatusActionMaintenance))
+			})
+
+			It("should use the status mapping of the cluster selector", func() {
+				// given
+				netBoxMock := prepareNetboxMock()
+				withDeviceStatus(netBoxMock, "planned")
+
+				mappedClusterImportCR := clusterImportCR.DeepCopy()
+				mappedClusterImportCR.Spec.Clusters[0].StatusMapping = []argorav1alpha1.DeviceStatusMapping{
+					{Status: "planned", Action: argorav1alpha1.DeviceStatusActionMaintenance},
+				}
+
+				fakeClient := createFakeClient(mappedClusterImportCR, server)
+				controllerReconciler := createIronCoreReconciler(fakeClient, netBoxMock, fileReaderMock)
+
+				// when
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedClusterImportName})
+
+				// then
+				Expect(err).ToNot(HaveOccurred())
+
+				serverMaintenance := &metalv1alpha1.ServerMaintenance{}
+				Expect(fakeClient.Get(ctx, types.NamespacedName{Name: server.Name, Namespace: resourceNamespace}, serverMaintenance)).To(Succeed())
+			})
+
+			It("should clear maintenance once the device is active again", func() {
+				// given
+				netBoxMock := prepareNetboxMock()
+
+				serverMaintenance := &metalv1alpha1.ServerMaintenance{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      server.Name,
+						Namespace: resourceNamespace,
+						Labels:    map[string]string{"argora.cloud.sap/device": bmcName1},
+					},
+					Spec: metalv1alpha1.ServerMaintenanceSpec{
+						ServerRef: &corev1.LocalObjectReference{Name: server.Name},
+					},
+				}
+
+				fakeClient := createFakeClient(clusterImportCR, server, serverMaintenance)
+				controllerReconciler := createIronCoreReconciler(fakeClient, netBoxMock, fileReaderMock)
+
+				// when
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedClusterImportName})
+
+				// then
+				Expect(err).ToNot(HaveOccurred())
+				Expect(apierrors.IsNotFound(fakeClient.Get(ctx, client.ObjectKeyFromObject(serverMaintenance), &metalv1alpha1.ServerMaintenance{}))).To(BeTrue())
+
+				bmc := &metalv1alpha1.BMC{}
+				Expect(fakeClient.Get(ctx, client.ObjectKey{Name: bmcName1}, bmc)).To(Succeed())
+
+				updatedClusterImport := &argorav1alpha1.ClusterImport{}
+				Expect(fakeClient.Get(ctx, typeNamespacedClusterImportName, updatedClusterImport)).To(Succeed())
+				Expect(updatedClusterImport.Status.Devices).To(HaveLen(1))
+				Expect(updatedClusterImport.Status.Devices[0].Action).To(Equal(argorav1alpha1.DeviceStatusActionImport))
+			})
+
+			It("should remove the BMC of a decommissioning device", func() {
+				// given
+				netBoxMock := prepareNetboxMock()
+				withDeviceStatus(netBoxMock, "decommissioning")
+
+				bmc := &metalv1alpha1.BMC{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: bmcName1,
+					},
+				}
+
+				fakeClient := createFakeClient(clusterImportCR, bmc)
+				controllerReconciler := createIronCoreReconciler(fakeClient, netBoxMock, fileReaderMock)
+				decommissioned := testutil.ToFloat64(devicesTotal.WithLabelValues(controllerNameIronCore, resourceNamespace, resourceName, "cluster1", deviceResultDecommissioned))
+
+				// when
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedClusterImportName})
+
+				// then
+				Expect(err).ToNot(HaveOccurred())
+				Expect(apierrors.IsNotFound(fakeClient.Get(ctx, client.ObjectKey{Name: bmcName1}, &metalv1alpha1.BMC{}))).To(BeTrue())
+				Expect(testutil.ToFloat64(devicesTotal.WithLabelValues(controllerNameIronCore, resourceNamespace, resourceName, "cluster1", deviceResultDecommissioned))).To(Equal(decommissioned + 1))
+
+				updatedClusterImport := &argorav1alpha1.ClusterImport{}
+				Expect(fakeClient.Get(ctx, typeNamespacedClusterImportName, updatedClusterImport)).To(Succeed())
+				Expect(updatedClusterImport.Status.Devices).To(HaveLen(1))
+				Expect(updatedClusterImport.Status.Devices[0].Action).To(Equal(argorav1alpha1.DeviceStatusActionDecommission))
+			})
+
+			It("should prune the lifecycle of devices no longer selected from the status", func() {
+				// given
+				netBoxMock := prepareNetboxMock()
+				withDeviceStatus(netBoxMock, "offline")
+
+				prunedClusterImportCR := clusterImportCR.DeepCopy()
+				prunedClusterImportCR.Status.Devices = []argorav1alpha1.DeviceLifecycle{
+					{Name: "removed-device", NetboxStatus: "offline", Action: argorav1alpha1.DeviceStatusActionMaintenance},
+				}
+
+				fakeClient := createFakeClient(prunedClusterImportCR, server)
+				controllerReconciler := createIronCoreReconciler(fakeClient, netBoxMock, fileReaderMock)
+
+				// when
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedClusterImportName})
+
+				// then
+				Expect(err).ToNot(HaveOccurred())
+
+				updatedClusterImport := &argorav1alpha1.ClusterImport{}
+				Expect(fakeClient.Get(ctx, typeNamespacedClusterImportName, updatedClusterImport)).To(Succeed())
+				Expect(updatedClusterImport.Status.Devices).To(HaveLen(1))
+				Expect(updatedClusterImport.Status.Devices[0].Name).To(Equal(bmcName1))
+			})
+
+			It("should label the BMC with the named groups of the name pattern of the cluster selector", func() {
+				// given
+				netBoxMock := prepareNetboxMock()
+
+				patternClusterImportCR := clusterImportCR.DeepCopy()
+				patternClusterImportCR.Spec.Clusters[0].NamePattern = `^(?P<nodename>[a-z]+)-(?P<bb>[a-z]+)(?P<index>[0-9]+)$`
+
+				fakeClient := createFakeClient(patternClusterImportCR)
+				controllerReconciler := createIronCoreReconciler(fakeClient, netBoxMock, fileReaderMock)
+
+				// when
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedClusterImportName})
+
+				// then
+				Expect(err).ToNot(HaveOccurred())
+
+				bmc := &metalv1alpha1.BMC{}
+				Expect(fakeClient.Get(ctx, client.ObjectKey{Name: bmcName1}, bmc)).To(Succeed())
+				Expect(bmc.Labels).To(SatisfyAll(
+					HaveKeyWithValue("kubernetes.metal.cloud.sap/nodename", "device"),
+					HaveKeyWithValue("kubernetes.metal.cloud.sap/bb", "name"),
+					HaveKeyWithValue("kubernetes.metal.cloud.sap/index", "1"),
+				))
+			})
+
+			It("should remove the labels dropped from the label template of the cluster selector", func() {
+				// given
+				netBoxMock := prepareNetboxMock()
+
+				templateClusterImportCR := clusterImportCR.DeepCopy()
+				templateClusterImportCR.Spec.Clusters[0].LabelTemplate = &argorav1alpha1.LabelTemplate{
+					Labels: map[string]string{
+						"example.com/device": "{{ .Name }}",
+						"example.com/empty":  "{{ .Name }}",
+					},
+				}
+
+				fakeClient := createFakeClient(templateClusterImportCR)
+				controllerReconciler := createIronCoreReconciler(fakeClient, netBoxMock, fileReaderMock)
+
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedClusterImportName})
+				Expect(err).ToNot(HaveOccurred())
+
+				bmc := &metalv1alpha1.BMC{}
+				Expect(fakeClient.Get(ctx, client.ObjectKey{Name: bmcName1}, bmc)).To(Succeed())
+				Expect(bmc.Labels).To(SatisfyAll(
+					HaveKeyWithValue("example.com/device", bmcName1),
+					HaveKeyWithValue("example.com/empty", bmcName1),
+				))
+
+				By("adding a label not managed by argora")
+				bmc.Labels["example.com/other"] = "value"
+				Expect(fakeClient.Update(ctx, bmc)).To(Succeed())
+
+				By("shrinking the label template")
+				Expect(fakeClient.Get(ctx, typeNamespacedClusterImportName, templateClusterImportCR)).To(Succeed())
+				templateClusterImportCR.Spec.Clusters[0].LabelTemplate.Labels = map[string]string{
+					"example.com/empty": "",
+				}
+				Expect(fakeClient.Update(ctx, templateClusterImportCR)).To(Succeed())
+
+				// when
+				_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedClusterImportName})
+
+				// then
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeClient.Get(ctx, client.ObjectKey{Name: bmcName1}, bmc)).To(Succeed())
+				Expect(bmc.Labels).To(SatisfyAll(
+					Not(HaveKey("example.com/device")),
+					Not(HaveKey("example.com/empty")),
+					HaveKeyWithValue("example.com/other", "value"),
+					HaveKeyWithValue("topology.kubernetes.io/region", "region1"),
+				))
+				Expect(bmc.Annotations[annotationManagedLabelsKey]).ToNot(ContainSubstring("example.com/"))
+			})
+
+			It("should export the config context into a ConfigMap", func() {
+				// given
+				netBoxMock := prepareNetboxMock()
+				netBoxMock.DCIMMock.(*mock.DCIMMock).GetConfigContextForDeviceFunc = func(device *models.Device) (map[string]any, error) {
+					return map[string]any{"kernel": map[string]any{"args": "quiet"}, "ntp": "ntp.example.com"}, nil
+				}
+
+				configContextClusterImportCR := clusterImportCR.DeepCopy()
+				configContextClusterImportCR.Spec.Clusters[0].ConfigContext = &argorav1alpha1.ConfigContextExport{
+					Keys: map[string]string{"kernel-args": "{.kernel.args}"},
+				}
+
+				fakeClient := createFakeClient(configContextClusterImportCR)
+				controllerReconciler := createIronCoreReconciler(fakeClient, netBoxMock, fileReaderMock)
+
+				// when
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedClusterImportName})
+
+				// then
+				Expect(err).ToNot(HaveOccurred())
+
+				configMap := &corev1.ConfigMap{}
+				Expect(fakeClient.Get(ctx, types.NamespacedName{Name: "config-context-" + bmcName1, Namespace: resourceNamespace}, configMap)).To(Succeed())
+				Expect(configMap.Data).To(Equal(map[string]string{"kernel-args": "quiet"}))
+				Expect(configMap.Labels).To(HaveKeyWithValue("kubernetes.metal.cloud.sap/name", bmcName1))
+				Expect(configMap.OwnerReferences).To(HaveLen(1))
+				Expect(configMap.OwnerReferences[0].Name).To(Equal(resourceName))
+			})
+
+			It("should remove the config context no longer exported", func() {
+				// given
+				netBoxMock := prepareNetboxMock()
+				netBoxMock.DCIMMock.(*mock.DCIMMock).GetConfigContextForDeviceFunc = func(device *models.Device) (map[string]any, error) {
+					return map[string]any{"kernel": map[string]any{"args": "quiet"}}, nil
+				}
+
+				configContextClusterImportCR := clusterImportCR.DeepCopy()
+				configContextClusterImportCR.Spec.Clusters[0].ConfigContext = &argorav1alpha1.ConfigContextExport{
+					Target: argorav1alpha1.ConfigContextTargetConfigMap,
+				}
+
+				fakeClient := createFakeClient(configContextClusterImportCR)
+				controllerReconciler := createIronCoreReconciler(fakeClient, netBoxMock, fileReaderMock)
+				configMapKey := types.NamespacedName{Name: "config-context-" + bmcName1, Namespace: resourceNamespace}
+
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedClusterImportName})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fakeClient.Get(ctx, configMapKey, &corev1.ConfigMap{})).To(Succeed())
+
+				By("switching the export to annotations")
+				Expect(fakeClient.Get(ctx, typeNamespacedClusterImportName, configContextClusterImportCR)).To(Succeed())
+				configContextClusterImportCR.Spec.Clusters[0].ConfigContext.Target = argorav1alpha1.ConfigContextTargetAnnotations
+				Expect(fakeClient.Update(ctx, configContextClusterImportCR)).To(Succeed())
+
+				_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedClusterImportName})
+				Expect(err).ToNot(HaveOccurred())
+
+				err = fakeClient.Get(ctx, configMapKey, &corev1.ConfigMap{})
+				Expect(apierrors.IsNotFound(err)).To(BeTrue())
+				bmc := &metalv1alpha1.BMC{}
+				Expect(fakeClient.Get(ctx, client.ObjectKey{Name: bmcName1}, bmc)).To(Succeed())
+				Expect(bmc.Annotations).To(HaveKeyWithValue("config-context.argora.cloud.sap/kernel", `{"args":"quiet"}`))
+
+				By("turning the export off")
+				Expect(fakeClient.Get(ctx, typeNamespacedClusterImportName, configContextClusterImportCR)).To(Succeed())
+				configContextClusterImportCR.Spec.Clusters[0].ConfigContext = nil
+				Expect(fakeClient.Update(ctx, configContextClusterImportCR)).To(Succeed())
+
+				// when
+				_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedClusterImportName})
+
+				// then
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeClient.Get(ctx, client.ObjectKey{Name: bmcName1}, bmc)).To(Succeed())
+				Expect(bmc.Annotations).ToNot(HaveKey("config-context.argora.cloud.sap/kernel"))
+			})
+
+			It("should replace the config context annotations of an existing BMC", func() {
+				// given
+				netBoxMock := prepareNetboxMock()
+				netBoxMock.DCIMMock.(*mock.DCIMMock).GetConfigContextForDeviceFunc = func(device *models.Device) (map[string]any, error) {
+					return map[string]any{"kernel": map[string]any{"args": "quiet"}}, nil
+				}
+
+				configContextClusterImportCR := clusterImportCR.DeepCopy()
+				configContextClusterImportCR.Spec.Clusters[0].ConfigContext = &argorav1alpha1.ConfigContextExport{
+					Target: argorav1alpha1.ConfigContextTargetAnnotations,
+				}
+
+				bmc := &metalv1alpha1.BMC{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: bmcName1,
+						Annotations: map[string]string{
+							"config-context.argora.cloud.sap/ntp": "ntp.example.com",
+							"example.com/other":                   "value",
+						},
+					},
+				}
+
+				fakeClient := createFakeClient(configContextClusterImportCR, bmc)
+				controllerReconciler := createIronCoreReconciler(fakeClient, netBoxMock, fileReaderMock)
+
+				// when
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedClusterImportName})
+
+				// then
+				Expect(err).ToNot(HaveOccurred())
+
+				updatedBMC := &metalv1alpha1.BMC{}
+				Expect(fakeClient.Get(ctx, client.ObjectKey{Name: bmcName1}, updatedBMC)).To(Succeed())
+				Expect(updatedBMC.Annotations).To(SatisfyAll(
+					HaveKeyWithValue("config-context.argora.cloud.sap/kernel", `{"args":"quiet"}`),
+					HaveKeyWithValue("example.com/other", "value"),
+					Not(HaveKey("config-context.argora.cloud.sap/ntp")),
+				))
+			})
+
+			It("should fail if the name pattern of the cluster selector is invalid", func() {
+				// given
+				netBoxMock := prepareNetboxMock()
+
+				patternClusterImportCR := clusterImportCR.DeepCopy()
+				patternClusterImportCR.Spec.Clusters[0].NamePattern = `^[a-z]+-[a-z0-9]+$`
+
+				fakeClient := createFakeClient(patternClusterImportCR)
+				controllerReconciler := createIronCoreReconciler(fakeClient, netBoxMock, fileReaderMock)
+
+				// when
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedClusterImportName})
+
+				// then
+				Expect(err).To(MatchError("device name pattern ^[a-z]+-[a-z0-9]+$ has no named capture groups"))
+			})
+
+			It("should only patch BMCs whose labels or annotations change", func() {
+				// given
+				bmc := &metalv1alpha1.BMC{ObjectMeta: metav1.ObjectMeta{Name: bmcName1}}
+				patches := 0
+				k8sClient := interceptor.NewClient(createFakeClient(bmc).(client.WithWatch), interceptor.Funcs{
+					Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+						patches++
+						return c.Patch(ctx, obj, patch, opts...)
+					},
+				})
+				controllerReconciler := createIronCoreReconciler(k8sClient, prepareNetboxMock(), fileReaderMock)
+				patched := testutil.ToFloat64(bmcOperationsTotal.WithLabelValues(bmcKindBMC, operationPatched))
+				labels := map[string]string{"topology.kubernetes.io/region": "region1"}
+				annotations := map[string]string{configContextAnnotationPrefix + "kernel-args": "quiet"}
+
+				// when
+				for range 2 {
+					Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(bmc), bmc)).To(Succeed())
+					Expect(controllerReconciler.patchBMCLabels(ctx, bmc, labels, annotations)).To(Succeed())
+				}
+
+				// then
+				Expect(patches).To(Equal(1))
+				Expect(testutil.ToFloat64(bmcOperationsTotal.WithLabelValues(bmcKindBMC, operationPatched))).To(Equal(patched + 1))
+				Expect(bmc.Labels).To(Equal(labels))
+				Expect(bmc.Annotations).To(Equal(map[string]string{
+					configContextAnnotationPrefix + "kernel-args": "quiet",
+					annotationManagedLabelsKey:                    "topology.kubernetes.io/region",
+				}))
+			})
 		})
 	})
 })
@@ -1461,6 +1880,7 @@ var _ = Describe("Ironcore Controller", func() {
 func createIronCoreReconciler(k8sClient client.Client, netBoxMock *mock.NetBoxMock, fileReaderMock credentials.FileReader) *IronCoreReconciler {
 	return &IronCoreReconciler{
 		k8sClient:         k8sClient,
+		apiReader:         k8sClient,
 		scheme:            k8sClient.Scheme(),
 		credentials:       credentials.NewDefaultCredentials(fileReaderMock),
 		statusHandler:     status.NewClusterImportStatusHandler(k8sClient),
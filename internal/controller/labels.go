@@ -0,0 +1,209 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/sapcc/go-netbox-go/models"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	argorav1alpha1 "github.com/sapcc/argora/api/v1alpha1"
+	"github.com/sapcc/argora/internal/netbox"
+)
+
+// annotationManagedLabelsKey records the keys of the labels argora set on an imported object, so labels which are
+// no longer rendered, e.g. because they were removed from the label template, are removed from the object.
+const annotationManagedLabelsKey = "argora.cloud.sap/managed-labels"
+
+var invalidLabelValueChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+var labelTemplateFuncs = template.FuncMap{
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"replace":    strings.ReplaceAll,
+	"trimPrefix": strings.TrimPrefix,
+	"trimSuffix": strings.TrimSuffix,
+}
+
+// deviceTopology holds the NetBox attributes of a device which are exposed as labels on imported objects.
+type deviceTopology struct {
+	Region       string
+	Zone         string
+	SiteGroup    string
+	Location     string
+	Rack         string
+	RackPosition string
+	Tenant       string
+	Manufacturer string
+	Serial       string
+	Cluster      string
+	ClusterType  string
+	Name         string
+	NodeName     string
+	BB           string
+	Type         string
+	Role         string
+	Platform     string
+	// NameParts holds the named capture groups of the device name pattern.
+	NameParts map[string]string
+}
+
+func newDeviceTopology(netBox netbox.Netbox, device *models.Device, region, clusterName, clusterType, role string, nameParts map[string]string) (*deviceTopology, error) {
+	siteGroup, err := netBox.DCIM().GetSiteGroupForDevice(device)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get site group for device: %w", err)
+	}
+
+	rackPosition := ""
+	if device.Position > 0 {
+		rackPosition = strconv.FormatFloat(device.Position, 'f', -1, 64)
+	}
+
+	return &deviceTopology{
+		Region:       region,
+		Zone:         device.Site.Slug,
+		SiteGroup:    siteGroup,
+		Location:     device.Location.Slug,
+		Rack:         device.Rack.Name,
+		RackPosition: rackPosition,
+		Tenant:       device.Tenant.Slug,
+		Manufacturer: device.DeviceType.Manufacturer.Slug,
+		Serial:       device.Serial,
+		Cluster:      clusterName,
+		ClusterType:  clusterType,
+		Name:         device.Name,
+		NodeName:     nameParts[deviceNameGroupNodeName],
+		BB:           nameParts[deviceNameGroupBB],
+		Type:         device.DeviceType.Slug,
+		Role:         role,
+		Platform:     device.Platform.Slug,
+		NameParts:    nameParts,
+	}, nil
+}
+
+func (t *deviceTopology) defaultLabels() map[string]string {
+	labels := make(map[string]string, len(t.NameParts))
+	for name, value := range t.NameParts {
+		labels[deviceNameLabelPrefix+name] = value
+	}
+
+	maps.Copy(labels, map[string]string{
+		"topology.kubernetes.io/region":            t.Region,
+		"topology.kubernetes.io/zone":              t.Zone,
+		"kubernetes.metal.cloud.sap/site-group":    t.SiteGroup,
+		"kubernetes.metal.cloud.sap/location":      t.Location,
+		"kubernetes.metal.cloud.sap/rack":          t.Rack,
+		"kubernetes.metal.cloud.sap/rack-position": t.RackPosition,
+		"kubernetes.metal.cloud.sap/tenant":        t.Tenant,
+		"kubernetes.metal.cloud.sap/manufacturer":  t.Manufacturer,
+		"kubernetes.metal.cloud.sap/serial":        t.Serial,
+		"kubernetes.metal.cloud.sap/cluster":       t.Cluster,
+		"kubernetes.metal.cloud.sap/cluster-type":  t.ClusterType,
+		labelDeviceNameKey:                         t.Name,
+		"kubernetes.metal.cloud.sap/nodename":      t.NodeName,
+		"kubernetes.metal.cloud.sap/bb":            t.BB,
+		"kubernetes.metal.cloud.sap/type":          t.Type,
+		"kubernetes.metal.cloud.sap/role":          t.Role,
+		"kubernetes.metal.cloud.sap/platform":      t.Platform,
+	})
+
+	return labels
+}
+
+// labels renders the labels of the device topology. Template labels are merged into the default labels,
+// labels with empty values are dropped and values are sanitized unless disabled in the template.
+func (t *deviceTopology) labels(labelTemplate *argorav1alpha1.LabelTemplate) (map[string]string, error) {
+	labels := t.defaultLabels()
+	sanitize := true
+
+	if labelTemplate != nil {
+		if labelTemplate.Sanitize != nil {
+			sanitize = *labelTemplate.Sanitize
+		}
+
+		for key, text := range labelTemplate.Labels {
+			tmpl, err := template.New(key).Funcs(labelTemplateFuncs).Option("missingkey=error").Parse(text)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse label template for %s: %w", key, err)
+			}
+
+			var value bytes.Buffer
+			if err := tmpl.Execute(&value, t); err != nil {
+				return nil, fmt.Errorf("unable to render label template for %s: %w", key, err)
+			}
+
+			labels[key] = value.String()
+		}
+	}
+
+	for key, value := range labels {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid label key %s: %s", key, strings.Join(errs, ", "))
+		}
+
+		if sanitize {
+			value = sanitizeLabelValue(value)
+		}
+
+		if value == "" {
+			delete(labels, key)
+			continue
+		}
+
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid value %q for label %s: %s", value, key, strings.Join(errs, ", "))
+		}
+
+		labels[key] = value
+	}
+
+	return labels, nil
+}
+
+// sanitizeLabelValue replaces characters which are not allowed in label values with dashes,
+// truncates the value to the maximum label length and trims non-alphanumeric characters at both ends.
+func sanitizeLabelValue(value string) string {
+	value = invalidLabelValueChars.ReplaceAllString(value, "-")
+	if len(value) > validation.LabelValueMaxLength {
+		value = value[:validation.LabelValueMaxLength]
+	}
+	return strings.TrimFunc(value, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})
+}
+
+// setManagedLabels sets the labels on the object and removes the labels set by a previous import, which are no longer
+// rendered. Labels of the object not managed by argora are kept.
+func setManagedLabels(obj client.Object, labels map[string]string) {
+	objLabels := maps.Clone(obj.GetLabels())
+	if objLabels == nil {
+		objLabels = make(map[string]string)
+	}
+	annotations := maps.Clone(obj.GetAnnotations())
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+
+	if managed := annotations[annotationManagedLabelsKey]; managed != "" {
+		for key := range strings.SplitSeq(managed, ",") {
+			if _, ok := labels[key]; !ok {
+				delete(objLabels, key)
+			}
+		}
+	}
+	maps.Copy(objLabels, labels)
+	annotations[annotationManagedLabelsKey] = strings.Join(slices.Sorted(maps.Keys(labels)), ",")
+
+	obj.SetLabels(objLabels)
+	obj.SetAnnotations(annotations)
+}
@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/ptr"
+
+	argorav1alpha1 "github.com/sapcc/argora/api/v1alpha1"
+)
+
+var _ = Describe("Labels", func() {
+	topology := &deviceTopology{
+		Region:       "region1",
+		Zone:         "site1",
+		SiteGroup:    "group1",
+		Location:     "room-1",
+		Rack:         "Rack 01/A",
+		RackPosition: "42",
+		Manufacturer: "dell",
+		Serial:       "ABC123",
+		Cluster:      "cluster1",
+		Name:         "node001-bb01",
+		NodeName:     "node001",
+		BB:           "bb01",
+		Role:         "kvm",
+		NameParts:    map[string]string{"nodename": "node001", "bb": "bb01", "row": "r2"},
+	}
+
+	It("should generate default labels and drop empty values", func() {
+		labels, err := topology.labels(nil)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(labels).To(SatisfyAll(
+			HaveKeyWithValue("kubernetes.metal.cloud.sap/site-group", "group1"),
+			HaveKeyWithValue("kubernetes.metal.cloud.sap/location", "room-1"),
+			HaveKeyWithValue("kubernetes.metal.cloud.sap/rack", "Rack-01-A"),
+			HaveKeyWithValue("kubernetes.metal.cloud.sap/rack-position", "42"),
+			HaveKeyWithValue("kubernetes.metal.cloud.sap/manufacturer", "dell"),
+			HaveKeyWithValue("kubernetes.metal.cloud.sap/serial", "ABC123"),
+			HaveKeyWithValue("kubernetes.metal.cloud.sap/row", "r2"),
+			Not(HaveKey("kubernetes.metal.cloud.sap/tenant")),
+			Not(HaveKey("kubernetes.metal.cloud.sap/platform")),
+		))
+	})
+
+	It("should merge rendered template labels into the default labels", func() {
+		labels, err := topology.labels(&argorav1alpha1.LabelTemplate{
+			Labels: map[string]string{
+				"example.com/rack-slot":               "{{ .Rack | lower }}-u{{ .RackPosition }}",
+				"kubernetes.metal.cloud.sap/serial":   "",
+				"kubernetes.metal.cloud.sap/location": "{{ .SiteGroup }}.{{ .Location }}",
+			},
+		})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(labels).To(SatisfyAll(
+			HaveKeyWithValue("example.com/rack-slot", "rack-01-a-u42"),
+			HaveKeyWithValue("kubernetes.metal.cloud.sap/location", "group1.room-1"),
+			HaveKeyWithValue("topology.kubernetes.io/region", "region1"),
+			Not(HaveKey("kubernetes.metal.cloud.sap/serial")),
+		))
+	})
+
+	It("should fail on invalid label values if sanitization is disabled", func() {
+		_, err := topology.labels(&argorav1alpha1.LabelTemplate{
+			Sanitize: ptr.To(false),
+		})
+
+		Expect(err).To(MatchError(ContainSubstring(`invalid value "Rack 01/A" for label kubernetes.metal.cloud.sap/rack`)))
+	})
+
+	It("should fail on unknown template fields", func() {
+		_, err := topology.labels(&argorav1alpha1.LabelTemplate{
+			Labels: map[string]string{"example.com/pod": "{{ .Pod }}"},
+		})
+
+		Expect(err).To(MatchError(ContainSubstring("unable to render label template for example.com/pod")))
+	})
+
+	It("should sanitize label values", func() {
+		Expect(sanitizeLabelValue("-Rack 01/A_")).To(Equal("Rack-01-A"))
+		Expect(sanitizeLabelValue("///")).To(BeEmpty())
+		Expect(sanitizeLabelValue(strings.Repeat("a", 70))).To(HaveLen(63))
+	})
+})
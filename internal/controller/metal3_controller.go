@@ -7,13 +7,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"net"
+	"net/netip"
+	"regexp"
+	"slices"
 	"sort"
-	"strings"
 	"time"
 
 	"golang.org/x/time/rate"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/client-go/util/workqueue"
 
 	bmov1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
@@ -23,9 +29,11 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/dspinhirne/netaddr-go/v2"
 	"github.com/sapcc/go-netbox-go/models"
@@ -37,7 +45,15 @@ import (
 	"github.com/sapcc/argora/internal/networkdata"
 )
 
-const ClusterRoleLabel = "discovery.inf.sap.cloud/clusterRole"
+const (
+	ClusterRoleLabel = "discovery.inf.sap.cloud/clusterRole"
+
+	eventReasonDeviceNameMismatch = "DeviceNameMismatch"
+
+	// annotationMaintenanceReasonKey marks a BareMetalHost detached by argora for maintenance, so only the
+	// maintenance requested by argora is cleared.
+	annotationMaintenanceReasonKey = "argora.cloud.sap/maintenance-reason"
+)
 
 var (
 	rootHintMap = map[string]string{
@@ -73,25 +89,32 @@ var (
 
 type Metal3Reconciler struct {
 	k8sClient         client.Client
+	apiReader         client.Reader
 	scheme            *runtime.Scheme
 	credentials       *credentials.Credentials
 	netBox            netbox.Netbox
+	recorder          events.EventRecorder
 	reconcileInterval time.Duration
+	deviceNamePattern *regexp.Regexp
 }
 
-func NewMetal3Reconciler(k8sClient client.Client, scheme *runtime.Scheme, creds *credentials.Credentials, netBox netbox.Netbox, reconcileInterval time.Duration) *Metal3Reconciler {
+func NewMetal3Reconciler(k8sClient client.Client, apiReader client.Reader, scheme *runtime.Scheme, recorder events.EventRecorder, creds *credentials.Credentials, netBox netbox.Netbox, reconcileInterval time.Duration, deviceNamePattern *regexp.Regexp) *Metal3Reconciler {
 	return &Metal3Reconciler{
 		k8sClient:         k8sClient,
+		apiReader:         apiReader,
 		scheme:            scheme,
+		recorder:          recorder,
 		credentials:       creds,
 		netBox:            netBox,
 		reconcileInterval: reconcileInterval,
+		deviceNamePattern: deviceNamePattern,
 	}
 }
 
 func (r *Metal3Reconciler) SetupWithManager(mgr manager.Manager, rateLimiter RateLimiter) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&clusterv1.Cluster{}).
+		Watches(&argorav1alpha1.ClusterImport{}, handler.EnqueueRequestsFromMapFunc(selectedClusters)).
 		WithEventFilter(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{})).
 		WithOptions(controller.Options{
 			RateLimiter: workqueue.NewTypedMaxOfRateLimiter(
@@ -107,14 +130,19 @@ func (r *Metal3Reconciler) SetupWithManager(mgr manager.Manager, rateLimiter Rat
 }
 
 // +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=argora.cloud.sap,resources=clusterimports,verbs=get;list;watch
+// +kubebuilder:rbac:groups=argora.cloud.sap,resources=clusterimports/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups=metal3.io,resources=baremetalhosts,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;create;update;delete
 
 // Reconcile looks up a cluster in netbox and creates baremetal hosts for it
 func (r *Metal3Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 	logger.Info("reconciling metal3")
 
+	observeReload := observePhase(controllerNameMetal3, phaseNetboxReload)
 	err := r.credentials.Reload()
 	if err != nil {
 		logger.Error(err, "unable to reload credentials")
@@ -128,13 +156,18 @@ func (r *Metal3Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		logger.Error(err, "unable to reload netbox")
 		return ctrl.Result{}, err
 	}
+	observeReload()
 
 	capiCluster := &clusterv1.Cluster{}
 	err = r.k8sClient.Get(ctx, req.NamespacedName, capiCluster)
 	if err != nil {
+		if apierrors.IsNotFound(err) {
+			deleteReconcileMetrics(controllerNameMetal3, req.NamespacedName)
+		}
 		logger.Error(err, "unable to get CAPI cluster")
 		return ctrl.Result{}, err
 	}
+	recordReconcileStarted(controllerNameMetal3, capiCluster)
 
 	clusterType := capiCluster.Labels[ClusterRoleLabel]
 	logger.Info("fetching clusters data", "name", capiCluster.Name, "type", clusterType)
@@ -149,6 +182,18 @@ func (r *Metal3Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{}, errors.New("multiple clusters found")
 	}
 
+	clusterImport, clusterSelector, err := r.clusterSelector(ctx, capiCluster, clusterType)
+	if err != nil {
+		logger.Error(err, "unable to get cluster selector")
+		return ctrl.Result{}, err
+	}
+
+	var devicesBase []argorav1alpha1.DeviceLifecycle
+	if clusterImport != nil {
+		devicesBase = slices.Clone(clusterImport.Status.Devices)
+	}
+
+	observeClusters := observePhase(controllerNameMetal3, phaseClusters)
 	for _, cluster := range clusters {
 		logger.Info("reconciling cluster", "name", cluster.Name, "ID", cluster.ID)
 
@@ -159,56 +204,135 @@ func (r *Metal3Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		}
 
 		for _, device := range devices {
-			err = r.reconcileDevice(ctx, capiCluster, &device)
+			recordDeviceProcessed(controllerNameMetal3, capiCluster, cluster.Name)
+			err = r.reconcileDevice(ctx, capiCluster, clusterImport, clusterSelector, &cluster, &device)
 			if err != nil {
+				recordDeviceResult(controllerNameMetal3, capiCluster, cluster.Name, deviceResultFailed)
 				logger.Error(err, "unable to reconcile device", "device", device.Name, "ID", device.ID)
-				return ctrl.Result{}, err
+				return ctrl.Result{}, errors.Join(err, r.updateDeviceLifecycles(ctx, clusterImport, devicesBase))
 			}
 		}
 	}
+	observeClusters()
+
+	if err := r.updateDeviceLifecycles(ctx, clusterImport, devicesBase); err != nil {
+		logger.Error(err, "unable to update device lifecycles")
+		return ctrl.Result{}, err
+	}
+
+	recordReconcileSuccess(controllerNameMetal3, capiCluster)
 
 	return ctrl.Result{RequeueAfter: r.reconcileInterval}, nil
 }
 
-func (r *Metal3Reconciler) reconcileDevice(ctx context.Context, cluster *clusterv1.Cluster, device *models.Device) error {
-	logger := log.FromContext(ctx)
-	logger.Info("reconciling device", "device", device.Name, "ID", device.ID)
-
-	if device.Status.Value != deviceStatusActive {
-		logger.Info("device is not active", "status", device.Status.Value)
+// selectedClusters maps a ClusterImport to the CAPI clusters named by its cluster selectors.
+func selectedClusters(_ context.Context, obj client.Object) []reconcile.Request {
+	clusterImport, ok := obj.(*argorav1alpha1.ClusterImport)
+	if !ok {
 		return nil
 	}
 
-	bmcSecret, _, err := r.reconcileBmcSecret(ctx, cluster, device)
-	if err != nil {
-		return fmt.Errorf("unable to reconcile bmc secret: %w", err)
+	var requests []reconcile.Request
+	for _, clusterSelector := range clusterImport.Spec.Clusters {
+		if clusterSelector != nil && clusterSelector.Name != "" {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKey{Namespace: clusterImport.Namespace, Name: clusterSelector.Name}})
+		}
 	}
+	return requests
+}
 
-	bmh := &bmov1alpha1.BareMetalHost{}
-	if err := r.k8sClient.Get(ctx, client.ObjectKey{Name: device.Name, Namespace: cluster.Namespace}, bmh); err == nil {
-		logger.Info("BareMetalHost custom resource already exists, will skip", "host", bmh.Name)
+// clusterSelector returns the cluster selector of a ClusterImport in the namespace of the CAPI cluster selecting it
+// by name, so both backends are configured by the same fields, and the ClusterImport recording the device lifecycles.
+// Without one, the defaults apply and no ClusterImport is returned.
+func (r *Metal3Reconciler) clusterSelector(ctx context.Context, cluster *clusterv1.Cluster, clusterType string) (*argorav1alpha1.ClusterImport, *argorav1alpha1.ClusterSelector, error) {
+	clusterImports := &argorav1alpha1.ClusterImportList{}
+	if err := r.k8sClient.List(ctx, clusterImports, client.InNamespace(cluster.Namespace)); err != nil {
+		return nil, nil, fmt.Errorf("unable to list cluster imports: %w", err)
+	}
+
+	for i := range clusterImports.Items {
+		clusterImport := &clusterImports.Items[i]
+		for _, clusterSelector := range clusterImport.Spec.Clusters {
+			if clusterSelector != nil && clusterSelector.Name == cluster.Name && (clusterSelector.Type == "" || clusterSelector.Type == clusterType) {
+				return clusterImport, clusterSelector, nil
+			}
+		}
+	}
+
+	return nil, &argorav1alpha1.ClusterSelector{Name: cluster.Name, Type: clusterType}, nil
+}
+
+// updateDeviceLifecycles writes the device lifecycles recorded in the ClusterImport into its status, if they changed.
+// The lifecycles of devices of other clusters selected by the ClusterImport are kept, so they are not pruned.
+func (r *Metal3Reconciler) updateDeviceLifecycles(ctx context.Context, clusterImport *argorav1alpha1.ClusterImport, devicesBase []argorav1alpha1.DeviceLifecycle) error {
+	if clusterImport == nil || slices.Equal(clusterImport.Status.Devices, devicesBase) {
 		return nil
 	}
 
-	redfishURL, err := createRedFishURL(device)
+	devices := clusterImport.Status.Devices
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := r.k8sClient.Get(ctx, client.ObjectKeyFromObject(clusterImport), clusterImport); err != nil {
+			return fmt.Errorf("unable to get cluster import: %w", err)
+		}
+		clusterImport.Status.Devices = devices
+		return r.k8sClient.Status().Update(ctx, clusterImport)
+	})
+}
+
+func (r *Metal3Reconciler) reconcileDevice(ctx context.Context, cluster *clusterv1.Cluster, clusterImport *argorav1alpha1.ClusterImport, clusterSelector *argorav1alpha1.ClusterSelector, nbCluster *models.Cluster, device *models.Device) error {
+	logger := log.FromContext(ctx)
+	logger.Info("reconciling device", "device", device.Name, "ID", device.ID)
+
+	namePattern, err := resolveDeviceNamePattern(r.deviceNamePattern, clusterSelector.NamePattern)
 	if err != nil {
-		return errors.New("unable to create redfish url")
+		return err
 	}
 
-	deviceNameParts := strings.Split(device.Name, "-")
-	if len(deviceNameParts) != 2 {
-		return fmt.Errorf("unable to split in two device name: %s", device.Name)
+	action := deviceStatusAction(clusterSelector, device.Status.Value)
+	message := ""
+
+	nameParts, err := parseDeviceName(namePattern, device.Name)
+	if err != nil && action == argorav1alpha1.DeviceStatusActionImport {
+		action = argorav1alpha1.DeviceStatusActionSkip
+		message = err.Error()
 	}
 
-	rootHint, err := createRootHint(device)
-	if err != nil {
-		return fmt.Errorf("unable to create root hint: %w", err)
+	if clusterImport != nil && recordDeviceLifecycle(clusterImport, device, action, message) {
+		logger.Info("device lifecycle transition", "status", device.Status.Value, "action", action, "message", message)
 	}
 
-	mac, err := getMacForIP(r.netBox, device.PrimaryIP4.Address)
+	switch action {
+	case argorav1alpha1.DeviceStatusActionImport:
+	case argorav1alpha1.DeviceStatusActionMaintenance:
+		if err := r.requestMaintenance(ctx, cluster, device); err != nil {
+			return err
+		}
+		recordDeviceResult(controllerNameMetal3, cluster, nbCluster.Name, deviceResultMaintenance)
+		return nil
+	case argorav1alpha1.DeviceStatusActionDecommission:
+		if err := r.decommissionDevice(ctx, cluster, device); err != nil {
+			return err
+		}
+		recordDeviceResult(controllerNameMetal3, cluster, nbCluster.Name, deviceResultDecommissioned)
+		return nil
+	default:
+		recordDeviceResult(controllerNameMetal3, cluster, nbCluster.Name, deviceResultSkipped)
+		if message != "" {
+			logger.Info("device name does not match name pattern, will skip", "pattern", namePattern.String())
+			r.recorder.Eventf(cluster, nil, corev1.EventTypeWarning, eventReasonDeviceNameMismatch, string(argorav1alpha1.DeviceStatusActionSkip), "%s", message)
+			return nil
+		}
+		logger.Info("device is not active, will skip", "status", device.Status.Value)
+		return nil
+	}
+
+	if err := r.clearMaintenance(ctx, cluster, device); err != nil {
+		return fmt.Errorf("unable to clear maintenance: %w", err)
+	}
+
+	bmcSecret, _, err := r.reconcileBmcSecret(ctx, cluster, device)
 	if err != nil {
-		logger.Info("unable to lookup mac for ip", "error", err)
-		mac = ""
+		return fmt.Errorf("unable to reconcile bmc secret: %w", err)
 	}
 
 	region, err := r.netBox.DCIM().GetRegionForDevice(device)
@@ -227,19 +351,60 @@ func (r *Metal3Reconciler) reconcileDevice(ctx context.Context, cluster *cluster
 		logger.Info("role found in tags", "role", role)
 	}
 
+	labels, err := r.generateLabels(cluster, clusterSelector, nbCluster, device, region, role, nameParts)
+	if err != nil {
+		return err
+	}
+
+	configContextExport, configContext, err := r.renderConfigContext(clusterSelector, device)
+	if err != nil {
+		return fmt.Errorf("unable to render config context: %w", err)
+	}
+
+	var configContextAnnotations map[string]string
+	if configContextExport != nil && configContextExport.Target == argorav1alpha1.ConfigContextTargetAnnotations {
+		if configContextAnnotations, err = renderConfigContextAnnotations(configContext); err != nil {
+			return fmt.Errorf("unable to render config context: %w", err)
+		}
+	}
+
+	bmh := &bmov1alpha1.BareMetalHost{}
+	if err := r.k8sClient.Get(ctx, client.ObjectKey{Name: device.Name, Namespace: cluster.Namespace}, bmh); err == nil {
+		if err := r.patchBareMetalHostLabels(ctx, bmh, labels, configContextAnnotations); err != nil {
+			return fmt.Errorf("unable to patch BareMetalHost labels: %w", err)
+		}
+
+		if err := r.reconcileConfigContextConfigMap(ctx, configContextExport, bmh, device, labels, configContext); err != nil {
+			return err
+		}
+
+		logger.Info("BareMetalHost custom resource already exists, will skip", "host", bmh.Name)
+		recordDeviceResult(controllerNameMetal3, cluster, nbCluster.Name, deviceResultImported)
+		return nil
+	}
+
+	redfishURL, err := createRedFishURL(device)
+	if err != nil {
+		return errors.New("unable to create redfish url")
+	}
+
+	rootHint, err := createRootHint(device)
+	if err != nil {
+		return fmt.Errorf("unable to create root hint: %w", err)
+	}
+
+	mac, err := getMacForIP(r.netBox, device.PrimaryIP4.Address)
+	if err != nil {
+		logger.Info("unable to lookup mac for ip", "error", err)
+		mac = ""
+	}
+
 	ndSecretName := "networkdata-" + device.Name
 	bareMetalHost := &bmov1alpha1.BareMetalHost{
 		ObjectMeta: ctrl.ObjectMeta{
-			Name:      device.Name,
-			Namespace: cluster.Namespace,
-			Labels: map[string]string{
-				"kubernetes.metal.cloud.sap/cluster": cluster.Name,
-				"kubernetes.metal.cloud.sap/name":    device.Name,
-				"kubernetes.metal.cloud.sap/bb":      deviceNameParts[1],
-				"kubernetes.metal.cloud.sap/role":    role,
-				"topology.kubernetes.io/region":      region,
-				"topology.kubernetes.io/zone":        device.Site.Slug,
-			},
+			Name:        device.Name,
+			Namespace:   cluster.Namespace,
+			Annotations: configContextAnnotations,
 		},
 
 		Spec: bmov1alpha1.BareMetalHostSpec{
@@ -259,24 +424,180 @@ func (r *Metal3Reconciler) reconcileDevice(ctx context.Context, cluster *cluster
 			RootDeviceHints: rootHint,
 		},
 	}
+	setManagedLabels(bareMetalHost, labels)
 
 	if err = r.k8sClient.Create(ctx, bareMetalHost); err != nil {
 		return fmt.Errorf("unable to create baremetal host: %w", err)
 	}
 
 	logger.Info("created BareMetalHost CR", "name", bareMetalHost.Name)
+	bmcOperationsTotal.WithLabelValues(bmcKindBareMetalHost, operationCreated).Inc()
 
-	if err = r.createNetworkDataSecret(ctx, bareMetalHost, cluster, device, role, ndSecretName); err != nil {
+	if err = r.createNetworkDataSecret(ctx, bareMetalHost, cluster, clusterSelector, device, role, ndSecretName); err != nil {
 		return fmt.Errorf("unable to create network data: %w", err)
 	}
 
 	logger.Info("created NetworkData Secret", "name", ndSecretName)
 
+	if err = r.reconcileConfigContextConfigMap(ctx, configContextExport, bareMetalHost, device, labels, configContext); err != nil {
+		return err
+	}
+
+	recordDeviceResult(controllerNameMetal3, cluster, nbCluster.Name, deviceResultImported)
+	return nil
+}
+
+func (r *Metal3Reconciler) generateLabels(cluster *clusterv1.Cluster, clusterSelector *argorav1alpha1.ClusterSelector, nbCluster *models.Cluster, device *models.Device, region, role string, nameParts map[string]string) (map[string]string, error) {
+	topology, err := newDeviceTopology(r.netBox, device, region, cluster.Name, nbCluster.Type.Slug, role, nameParts)
+	if err != nil {
+		return nil, err
+	}
+
+	labels, err := topology.labels(clusterSelector.LabelTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate labels: %w", err)
+	}
+
+	return labels, nil
+}
+
+// renderConfigContext renders the config context of the device if its export is configured on the cluster selector.
+func (r *Metal3Reconciler) renderConfigContext(clusterSelector *argorav1alpha1.ClusterSelector, device *models.Device) (*argorav1alpha1.ConfigContextExport, map[string]string, error) {
+	export := clusterSelector.ConfigContext
+	if export == nil {
+		return nil, nil, nil
+	}
+
+	configContext, err := r.netBox.DCIM().GetConfigContextForDevice(device)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := renderConfigContext(configContext, export)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return export, data, nil
+}
+
+func (r *Metal3Reconciler) reconcileConfigContextConfigMap(ctx context.Context, export *argorav1alpha1.ConfigContextExport, bmh *bmov1alpha1.BareMetalHost, device *models.Device, labels, data map[string]string) error {
+	if export == nil || export.Target == argorav1alpha1.ConfigContextTargetAnnotations {
+		return deleteConfigContextConfigMap(ctx, r.k8sClient, r.apiReader, bmh, bmh.Namespace, device.Name)
+	}
+
+	return reconcileConfigContextConfigMap(ctx, r.k8sClient, r.apiReader, r.scheme, bmh, bmh.Namespace, device.Name, labels, data)
+}
+
+// requestMaintenance detaches the BareMetalHost of the device, so it is no longer managed by the baremetal operator
+// until the device is active again.
+func (r *Metal3Reconciler) requestMaintenance(ctx context.Context, cluster *clusterv1.Cluster, device *models.Device) error {
+	logger := log.FromContext(ctx)
+
+	bmh := &bmov1alpha1.BareMetalHost{}
+	if err := r.k8sClient.Get(ctx, client.ObjectKey{Name: device.Name, Namespace: cluster.Namespace}, bmh); err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.Info("no BareMetalHost found for device, will skip maintenance", "status", device.Status.Value)
+			return nil
+		}
+		return fmt.Errorf("unable to get BareMetalHost: %w", err)
+	}
+
+	if bmh.Annotations[argorav1alpha1.AnnotationIgnore] == annotationValueTrue {
+		logger.Info("BareMetalHost has ignore annotation, will skip maintenance", "host", bmh.Name)
+		return nil
+	}
+
+	reason := "netbox device status is " + device.Status.Value
+	if _, detached := bmh.Annotations[bmov1alpha1.DetachedAnnotation]; detached && bmh.Annotations[annotationMaintenanceReasonKey] == reason {
+		return nil
+	}
+
+	bmhBase := bmh.DeepCopy()
+	if bmh.Annotations == nil {
+		bmh.Annotations = make(map[string]string)
+	}
+	bmh.Annotations[bmov1alpha1.DetachedAnnotation] = ""
+	bmh.Annotations[annotationMaintenanceReasonKey] = reason
+
+	if err := r.k8sClient.Patch(ctx, bmh, client.MergeFrom(bmhBase)); err != nil {
+		return fmt.Errorf("unable to detach BareMetalHost: %w", err)
+	}
+
+	logger.Info("detached BareMetalHost for maintenance", "host", bmh.Name, "reason", reason)
+	return nil
+}
+
+// clearMaintenance attaches the BareMetalHost of the device again, if it was detached by requestMaintenance.
+func (r *Metal3Reconciler) clearMaintenance(ctx context.Context, cluster *clusterv1.Cluster, device *models.Device) error {
+	logger := log.FromContext(ctx)
+
+	bmh := &bmov1alpha1.BareMetalHost{}
+	if err := r.k8sClient.Get(ctx, client.ObjectKey{Name: device.Name, Namespace: cluster.Namespace}, bmh); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	if _, ok := bmh.Annotations[annotationMaintenanceReasonKey]; !ok {
+		return nil
+	}
+
+	bmhBase := bmh.DeepCopy()
+	delete(bmh.Annotations, bmov1alpha1.DetachedAnnotation)
+	delete(bmh.Annotations, annotationMaintenanceReasonKey)
+
+	if err := r.k8sClient.Patch(ctx, bmh, client.MergeFrom(bmhBase)); err != nil {
+		return fmt.Errorf("unable to attach BareMetalHost: %w", err)
+	}
+
+	logger.Info("attached BareMetalHost after maintenance", "host", bmh.Name)
+	return nil
+}
+
+// decommissionDevice deletes the BareMetalHost of the device, its network data Secret is garbage collected.
+func (r *Metal3Reconciler) decommissionDevice(ctx context.Context, cluster *clusterv1.Cluster, device *models.Device) error {
+	logger := log.FromContext(ctx)
+
+	bmh := &bmov1alpha1.BareMetalHost{}
+	if err := r.k8sClient.Get(ctx, client.ObjectKey{Name: device.Name, Namespace: cluster.Namespace}, bmh); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("unable to get BareMetalHost: %w", err)
+	}
+
+	if bmh.Annotations[argorav1alpha1.AnnotationIgnore] == annotationValueTrue {
+		logger.Info("BareMetalHost has ignore annotation, will skip decommissioning", "host", bmh.Name)
+		return nil
+	}
+
+	if err := r.k8sClient.Delete(ctx, bmh); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("unable to delete BareMetalHost: %w", err)
+	}
+
+	logger.Info("deleted BareMetalHost of decommissioned device", "host", bmh.Name)
+	return nil
+}
+
+// patchBareMetalHostLabels sets the labels and config context annotations on the BareMetalHost, removing the labels
+// no longer rendered. The BareMetalHost is only patched if they change.
+func (r *Metal3Reconciler) patchBareMetalHostLabels(ctx context.Context, bmh *bmov1alpha1.BareMetalHost, labels, configContextAnnotations map[string]string) error {
+	bmhBase := bmh.DeepCopy()
+	setManagedLabels(bmh, labels)
+	setConfigContextAnnotations(bmh, configContextAnnotations)
+	if maps.Equal(bmh.Labels, bmhBase.Labels) && maps.Equal(bmh.Annotations, bmhBase.Annotations) {
+		return nil
+	}
+
+	if err := r.k8sClient.Patch(ctx, bmh, client.MergeFrom(bmhBase)); err != nil {
+		return err
+	}
+
+	bmcOperationsTotal.WithLabelValues(bmcKindBareMetalHost, operationPatched).Inc()
 	return nil
 }
 
 // CreateNetworkDataForDevice uses the device to get to the netbox interfaces and creates a secret containing the network data for this device
-func (r *Metal3Reconciler) createNetworkDataSecret(ctx context.Context, bareMetalHost *bmov1alpha1.BareMetalHost, cluster *clusterv1.Cluster, device *models.Device, role, secretName string) error {
+func (r *Metal3Reconciler) createNetworkDataSecret(ctx context.Context, bareMetalHost *bmov1alpha1.BareMetalHost, cluster *clusterv1.Cluster, clusterSelector *argorav1alpha1.ClusterSelector, device *models.Device, role, secretName string) error {
 	iface, err := r.netBox.DCIM().GetInterfaceForDevice(device, "LAG1")
 	if err != nil {
 		return fmt.Errorf("unable to find interface LAG1 for device %s: %w", device.Name, err)
@@ -305,6 +626,17 @@ func (r *Metal3Reconciler) createNetworkDataSecret(ctx context.Context, bareMeta
 		return fmt.Errorf("unable to parse IP address %s: %w", ip.Address, err)
 	}
 
+	gateway, dnsServers, err := r.resolvePrefixServices(ctx, ip.Address, prefixes, clusterSelector.GatewayRoles)
+	if err != nil {
+		return err
+	}
+	if !gateway.IsValid() {
+		gateway, err = netip.ParseAddr(netw.Nth(1).String())
+		if err != nil {
+			return fmt.Errorf("unable to parse gateway of IP address %s: %w", ip.Address, err)
+		}
+	}
+
 	linkHint, err := createLinkHint(device, role)
 	if err != nil {
 		return fmt.Errorf("unable to create link hint for device %s and role %s: %w", device.Name, role, err)
@@ -322,7 +654,7 @@ func (r *Metal3Reconciler) createNetworkDataSecret(ctx context.Context, bareMeta
 				NetworkID: "",
 				Routes: []networkdata.L3IPVRoutingConfigurationItem{
 					{
-						Gateway: netw.Nth(1).String(),
+						Gateway: gateway.String(),
 						Netmask: "0.0.0.0",
 						Network: "0.0.0.0",
 					},
@@ -330,6 +662,12 @@ func (r *Metal3Reconciler) createNetworkDataSecret(ctx context.Context, bareMeta
 			},
 		},
 	}
+	for _, server := range dnsServers {
+		nwData.Services = append(nwData.Services, networkdata.NetworkService{
+			Address: server.String(),
+			Type:    networkdata.DNS,
+		})
+	}
 
 	nwDataYaml, err := yaml.Marshal(nwData)
 	if err != nil {
@@ -354,6 +692,41 @@ func (r *Metal3Reconciler) createNetworkDataSecret(ctx context.Context, bareMeta
 	return r.k8sClient.Create(ctx, nwDataSecret)
 }
 
+// resolvePrefixServices resolves the gateway and DNS servers documented in NetBox for the most specific prefix
+// containing the IP address. The gateway is invalid if NetBox documents none.
+func (r *Metal3Reconciler) resolvePrefixServices(ctx context.Context, address string, prefixes []models.Prefix, gatewayRoles []string) (netip.Addr, []netip.Addr, error) {
+	addr, err := parseNetboxAddress(address)
+	if err != nil {
+		return netip.Addr{}, nil, fmt.Errorf("unable to parse IP address %s: %w", address, err)
+	}
+
+	var prefix *models.Prefix
+	bits := -1
+	for i := range prefixes {
+		parsed, err := netip.ParsePrefix(prefixes[i].Prefix)
+		if err != nil || !parsed.Contains(addr) || parsed.Bits() <= bits {
+			continue
+		}
+		prefix = &prefixes[i]
+		bits = parsed.Bits()
+	}
+	if prefix == nil {
+		return netip.Addr{}, nil, nil
+	}
+
+	gateway, err := resolveGateway(ctx, r.netBox.IPAM(), prefix, gatewayRoles)
+	if err != nil {
+		return netip.Addr{}, nil, fmt.Errorf("unable to resolve gateway of prefix %s: %w", prefix.Prefix, err)
+	}
+
+	dnsServers, err := resolveDNSServers(ctx, r.netBox.IPAM(), prefix)
+	if err != nil {
+		return netip.Addr{}, nil, fmt.Errorf("unable to resolve DNS servers of prefix %s: %w", prefix.Prefix, err)
+	}
+
+	return gateway, dnsServers, nil
+}
+
 func (r *Metal3Reconciler) reconcileBmcSecret(ctx context.Context, cluster *clusterv1.Cluster, device *models.Device) (*corev1.Secret, bool, error) {
 	logger := log.FromContext(ctx)
 
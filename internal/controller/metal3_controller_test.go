@@ -6,27 +6,32 @@ package controller
 import (
 	"context"
 	"errors"
+	"slices"
 	"time"
 
 	"github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/sapcc/go-netbox-go/models"
 	"gopkg.in/yaml.v3"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
 	"k8s.io/utils/ptr"
 	clusterv1 "sigs.k8s.io/cluster-api/api/core/v1beta2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	argorav1alpha1 "github.com/sapcc/argora/api/v1alpha1"
 	"github.com/sapcc/argora/internal/controller/mock"
 	"github.com/sapcc/argora/internal/credentials"
+	"github.com/sapcc/argora/internal/netbox/ipam"
 	"github.com/sapcc/argora/internal/networkdata"
 )
 
@@ -138,6 +143,10 @@ var _ = Describe("Metal3 Controller", func() {
 			Expect(device.Name).To(Equal(deviceName))
 			return "region1", nil
 		}
+		netBoxMock.DCIMMock.(*mock.DCIMMock).GetSiteGroupForDeviceFunc = func(device *models.Device) (string, error) {
+			Expect(device.Name).To(Equal(deviceName))
+			return "group1", nil
+		}
 		netBoxMock.IPAMMock.(*mock.IPAMMock).GetIPAddressByAddressFunc = func(ipAddress string) (*models.IPAddress, error) {
 			Expect(ipAddress).To(Equal("192.168.1.1"))
 			return &models.IPAddress{
@@ -208,12 +217,15 @@ var _ = Describe("Metal3 Controller", func() {
 		Expect(bmh.Name).To(Equal(deviceName))
 		Expect(bmh.Namespace).To(Equal(clusterNamespace))
 		Expect(bmh.Labels).To(Equal(map[string]string{
-			"topology.kubernetes.io/region":      "region1",
-			"topology.kubernetes.io/zone":        "site1",
-			"kubernetes.metal.cloud.sap/cluster": clusterName,
-			"kubernetes.metal.cloud.sap/name":    deviceName,
-			"kubernetes.metal.cloud.sap/bb":      "name1",
-			"kubernetes.metal.cloud.sap/role":    "kvm",
+			"topology.kubernetes.io/region":         "region1",
+			"topology.kubernetes.io/zone":           "site1",
+			"kubernetes.metal.cloud.sap/cluster":    clusterName,
+			"kubernetes.metal.cloud.sap/name":       deviceName,
+			"kubernetes.metal.cloud.sap/bb":         "name1",
+			"kubernetes.metal.cloud.sap/nodename":   "device",
+			"kubernetes.metal.cloud.sap/type":       "poweredge-r640",
+			"kubernetes.metal.cloud.sap/role":       "kvm",
+			"kubernetes.metal.cloud.sap/site-group": "group1",
 		}))
 		Expect(bmh.Spec.Architecture).To(Equal("x86_64"))
 		Expect(bmh.Spec.AutomatedCleaningMode).To(Equal(v1alpha1.AutomatedCleaningMode("disabled")))
@@ -338,6 +350,98 @@ var _ = Describe("Metal3 Controller", func() {
 			Expect(netBoxMock.DCIMMock.(*mock.DCIMMock).GetRegionForDeviceCalls).To(Equal(1))
 		})
 
+		It("should use the gateway and DNS servers documented in NetBox", func() {
+			// given
+			netBoxMock := prepareNetboxMock()
+			netBoxMock.IPAMMock.(*mock.IPAMMock).GetPrefixesContainingFunc = func(ipAddress string) ([]models.Prefix, error) {
+				return []models.Prefix{
+					{ID: 1, Prefix: "192.168.0.0/16"},
+					{ID: 2, Prefix: "192.168.1.0/24", Vrf: models.NestedVRF{ID: 3}, CustomFields: map[string]any{"dns_servers": "10.0.0.53"}},
+				}, nil
+			}
+			netBoxMock.IPAMMock.(*mock.IPAMMock).GetIPAddressesFunc = func(_ context.Context, opts ...ipam.ListIPAddressesRequestOption) ([]models.IPAddress, error) {
+				query := ipam.NewListIPAddressesRequest(opts...).BuildQuery()
+				Expect(query.Get("parent")).To(Equal("192.168.1.0/24"))
+				Expect(query.Get("vrf_id")).To(Equal("3"))
+				if !slices.Equal(query["role"], []string{"anycast", "vip"}) {
+					return nil, nil
+				}
+				return []models.IPAddress{
+					{NestedIPAddress: models.NestedIPAddress{Address: "192.168.1.254/24"}, Role: models.IpamRole{Value: "anycast"}},
+				}, nil
+			}
+			controllerReconciler := createMetal3Reconciler(k8sClient, netBoxMock, fileReaderMock)
+
+			clusterImport := &argorav1alpha1.ClusterImport{
+				ObjectMeta: metav1.ObjectMeta{Name: "gateway-roles", Namespace: clusterNamespace},
+				Spec: argorav1alpha1.ClusterImportSpec{
+					Clusters: []*argorav1alpha1.ClusterSelector{
+						{Name: clusterName, GatewayRoles: []string{"anycast", "vip"}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, clusterImport)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, clusterImport)
+
+			// when
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedClusterName,
+			})
+
+			// then
+			Expect(err).ToNot(HaveOccurred())
+
+			ndSecret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, typeNamespacedNDSecretName, ndSecret)).To(Succeed())
+
+			var networkData networkdata.NetworkData
+			Expect(yaml.Unmarshal(ndSecret.Data["networkData"], &networkData)).To(Succeed())
+			Expect(networkData.Networks).To(HaveLen(1))
+			Expect(networkData.Networks[0].Routes).To(HaveLen(1))
+			Expect(networkData.Networks[0].Routes[0].Gateway).To(Equal("192.168.1.254"))
+			Expect(networkData.Services).To(Equal([]networkdata.NetworkService{{Address: "10.0.0.53", Type: networkdata.DNS}}))
+			By("querying NetBox for the tagged and the anycast or vip IP addresses only")
+			Expect(netBoxMock.IPAMMock.(*mock.IPAMMock).GetIPAddressesCalls).To(Equal(2))
+		})
+
+		It("should annotate the BareMetalHost with the config context", func() {
+			// given
+			netBoxMock := prepareNetboxMock()
+			netBoxMock.DCIMMock.(*mock.DCIMMock).GetConfigContextForDeviceFunc = func(device *models.Device) (map[string]any, error) {
+				return map[string]any{"kernel": map[string]any{"args": "quiet"}}, nil
+			}
+			controllerReconciler := createMetal3Reconciler(k8sClient, netBoxMock, fileReaderMock)
+
+			clusterImport := &argorav1alpha1.ClusterImport{
+				ObjectMeta: metav1.ObjectMeta{Name: "config-context", Namespace: clusterNamespace},
+				Spec: argorav1alpha1.ClusterImportSpec{
+					Clusters: []*argorav1alpha1.ClusterSelector{
+						{
+							Name: clusterName,
+							ConfigContext: &argorav1alpha1.ConfigContextExport{
+								Target: argorav1alpha1.ConfigContextTargetAnnotations,
+								Keys:   map[string]string{"kernel-args": "{.kernel.args}"},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, clusterImport)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, clusterImport)
+
+			// when
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedClusterName,
+			})
+
+			// then
+			Expect(err).ToNot(HaveOccurred())
+
+			bmh := &v1alpha1.BareMetalHost{}
+			Expect(k8sClient.Get(ctx, typeNamespacedBareMetalHostName, bmh)).To(Succeed())
+			Expect(bmh.Annotations).To(HaveKeyWithValue("config-context.argora.cloud.sap/kernel-args", "quiet"))
+		})
+
 		It("should return an error if credentials reload fails", func() {
 			// given
 			netBoxMock := prepareNetboxMock()
@@ -649,9 +753,328 @@ var _ = Describe("Metal3 Controller", func() {
 func createMetal3Reconciler(k8sClient client.Client, netBoxMock *mock.NetBoxMock, fileReaderMock credentials.FileReader) *Metal3Reconciler {
 	return &Metal3Reconciler{
 		k8sClient:         k8sClient,
+		apiReader:         k8sClient,
 		scheme:            k8sClient.Scheme(),
 		credentials:       credentials.NewDefaultCredentials(fileReaderMock),
 		netBox:            netBoxMock,
+		recorder:          events.NewFakeRecorder(100),
 		reconcileInterval: time.Minute,
 	}
 }
+
+var _ = Describe("Metal3 device lifecycle", func() {
+	ctx := context.Background()
+
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Namespace: "default"},
+	}
+	nbCluster := &models.Cluster{ID: 1, Name: "cluster1"}
+
+	newDevice := func(status string) *models.Device {
+		return &models.Device{ID: 1, Name: "device1-bb1", Status: models.DeviceStatus{Value: status}}
+	}
+
+	newBareMetalHost := func(annotations map[string]string) *v1alpha1.BareMetalHost {
+		return &v1alpha1.BareMetalHost{
+			ObjectMeta: metav1.ObjectMeta{Name: "device1-bb1", Namespace: "default", Annotations: annotations},
+		}
+	}
+
+	newReconciler := func(objects ...client.Object) *Metal3Reconciler {
+		k8sClient := createFakeClient(objects...)
+		return &Metal3Reconciler{
+			k8sClient:         k8sClient,
+			apiReader:         k8sClient,
+			scheme:            k8sClient.Scheme(),
+			netBox:            &mock.NetBoxMock{},
+			recorder:          events.NewFakeRecorder(10),
+			deviceNamePattern: defaultDeviceNamePattern,
+		}
+	}
+
+	getBareMetalHost := func(r *Metal3Reconciler) (*v1alpha1.BareMetalHost, error) {
+		bmh := &v1alpha1.BareMetalHost{}
+		return bmh, r.k8sClient.Get(ctx, client.ObjectKey{Name: "device1-bb1", Namespace: "default"}, bmh)
+	}
+
+	It("should use the cluster selector of a ClusterImport selecting the cluster by name", func() {
+		clusterImport := &argorav1alpha1.ClusterImport{
+			ObjectMeta: metav1.ObjectMeta{Name: "import", Namespace: "default"},
+			Spec: argorav1alpha1.ClusterImportSpec{
+				Clusters: []*argorav1alpha1.ClusterSelector{
+					{Name: "cluster2"},
+					{Name: "cluster1", Type: "other"},
+					{Name: "cluster1", Type: "kvm", NamePattern: "^(?P<nodename>.+)$"},
+				},
+			},
+		}
+		r := newReconciler(clusterImport)
+
+		selectingClusterImport, clusterSelector, err := r.clusterSelector(ctx, cluster, "kvm")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(selectingClusterImport.Name).To(Equal("import"))
+		Expect(clusterSelector.NamePattern).To(Equal("^(?P<nodename>.+)$"))
+
+		selectingClusterImport, clusterSelector, err = r.clusterSelector(ctx, cluster, "ceph")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(selectingClusterImport).To(BeNil())
+		Expect(clusterSelector).To(Equal(&argorav1alpha1.ClusterSelector{Name: "cluster1", Type: "ceph"}))
+	})
+
+	It("should map a ClusterImport to the clusters named by its cluster selectors", func() {
+		clusterImport := &argorav1alpha1.ClusterImport{
+			ObjectMeta: metav1.ObjectMeta{Name: "import", Namespace: "default"},
+			Spec: argorav1alpha1.ClusterImportSpec{
+				Clusters: []*argorav1alpha1.ClusterSelector{{Name: "cluster1"}, {Region: "qa-de-1"}},
+			},
+		}
+
+		Expect(selectedClusters(ctx, clusterImport)).To(Equal([]reconcile.Request{
+			{NamespacedName: types.NamespacedName{Namespace: "default", Name: "cluster1"}},
+		}))
+	})
+
+	It("should detach the BareMetalHost of an offline device for maintenance", func() {
+		r := newReconciler(newBareMetalHost(nil))
+
+		Expect(r.reconcileDevice(ctx, cluster, nil, &argorav1alpha1.ClusterSelector{}, nbCluster, newDevice("offline"))).To(Succeed())
+
+		bmh, err := getBareMetalHost(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bmh.Annotations).To(HaveKey(v1alpha1.DetachedAnnotation))
+		Expect(bmh.Annotations).To(HaveKeyWithValue("argora.cloud.sap/maintenance-reason", "netbox device status is offline"))
+	})
+
+	It("should record the lifecycle of a device in the ClusterImport selecting the cluster", func() {
+		clusterImport := &argorav1alpha1.ClusterImport{
+			ObjectMeta: metav1.ObjectMeta{Name: "import", Namespace: "default"},
+			Spec: argorav1alpha1.ClusterImportSpec{
+				Clusters: []*argorav1alpha1.ClusterSelector{{Name: "cluster1"}},
+			},
+		}
+		r := newReconciler(clusterImport, newBareMetalHost(nil))
+		devicesResult := func(result string) float64 {
+			return testutil.ToFloat64(devicesTotal.WithLabelValues(controllerNameMetal3, "default", "cluster1", "cluster1", result))
+		}
+		maintenance := devicesResult(deviceResultMaintenance)
+		decommissioned := devicesResult(deviceResultDecommissioned)
+
+		selectingClusterImport, clusterSelector, err := r.clusterSelector(ctx, cluster, "")
+		Expect(err).ToNot(HaveOccurred())
+
+		By("putting the device into maintenance")
+		Expect(r.reconcileDevice(ctx, cluster, selectingClusterImport, clusterSelector, nbCluster, newDevice("offline"))).To(Succeed())
+		Expect(r.updateDeviceLifecycles(ctx, selectingClusterImport, nil)).To(Succeed())
+
+		updatedClusterImport := &argorav1alpha1.ClusterImport{}
+		Expect(r.k8sClient.Get(ctx, client.ObjectKeyFromObject(clusterImport), updatedClusterImport)).To(Succeed())
+		Expect(updatedClusterImport.Status.Devices).To(HaveLen(1))
+		Expect(updatedClusterImport.Status.Devices[0].Name).To(Equal("device1-bb1"))
+		Expect(updatedClusterImport.Status.Devices[0].NetboxStatus).To(Equal("offline"))
+		Expect(updatedClusterImport.Status.Devices[0].Action).To(Equal(argorav1alpha1.DeviceStatusActionMaintenance))
+		Expect(devicesResult(deviceResultMaintenance)).To(Equal(maintenance + 1))
+
+		By("decommissioning the device")
+		devicesBase := slices.Clone(selectingClusterImport.Status.Devices)
+		Expect(r.reconcileDevice(ctx, cluster, selectingClusterImport, clusterSelector, nbCluster, newDevice("decommissioning"))).To(Succeed())
+		Expect(r.updateDeviceLifecycles(ctx, selectingClusterImport, devicesBase)).To(Succeed())
+
+		Expect(r.k8sClient.Get(ctx, client.ObjectKeyFromObject(clusterImport), updatedClusterImport)).To(Succeed())
+		Expect(updatedClusterImport.Status.Devices).To(HaveLen(1))
+		Expect(updatedClusterImport.Status.Devices[0].Action).To(Equal(argorav1alpha1.DeviceStatusActionDecommission))
+		Expect(devicesResult(deviceResultDecommissioned)).To(Equal(decommissioned + 1))
+	})
+
+	It("should use the status mapping of the cluster selector", func() {
+		r := newReconciler(newBareMetalHost(nil))
+		clusterSelector := &argorav1alpha1.ClusterSelector{
+			StatusMapping: []argorav1alpha1.DeviceStatusMapping{
+				{Status: "planned", Action: argorav1alpha1.DeviceStatusActionMaintenance},
+			},
+		}
+
+		Expect(r.reconcileDevice(ctx, cluster, nil, clusterSelector, nbCluster, newDevice("planned"))).To(Succeed())
+
+		bmh, err := getBareMetalHost(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bmh.Annotations).To(HaveKey(v1alpha1.DetachedAnnotation))
+	})
+
+	It("should only attach BareMetalHosts detached for maintenance", func() {
+		r := newReconciler(newBareMetalHost(map[string]string{
+			v1alpha1.DetachedAnnotation:           "",
+			"argora.cloud.sap/maintenance-reason": "netbox device status is offline",
+		}))
+
+		Expect(r.clearMaintenance(ctx, cluster, newDevice("active"))).To(Succeed())
+
+		bmh, err := getBareMetalHost(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bmh.Annotations).To(BeEmpty())
+
+		r = newReconciler(newBareMetalHost(map[string]string{v1alpha1.DetachedAnnotation: ""}))
+
+		Expect(r.clearMaintenance(ctx, cluster, newDevice("active"))).To(Succeed())
+
+		bmh, err = getBareMetalHost(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bmh.Annotations).To(HaveKey(v1alpha1.DetachedAnnotation))
+	})
+
+	It("should delete the BareMetalHost of a decommissioning device", func() {
+		r := newReconciler(newBareMetalHost(nil))
+
+		Expect(r.reconcileDevice(ctx, cluster, nil, &argorav1alpha1.ClusterSelector{}, nbCluster, newDevice("decommissioning"))).To(Succeed())
+
+		_, err := getBareMetalHost(r)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should keep the BareMetalHost of a decommissioning device with ignore annotation", func() {
+		r := newReconciler(newBareMetalHost(map[string]string{argorav1alpha1.AnnotationIgnore: "true"}))
+
+		Expect(r.reconcileDevice(ctx, cluster, nil, &argorav1alpha1.ClusterSelector{}, nbCluster, newDevice("decommissioning"))).To(Succeed())
+
+		_, err := getBareMetalHost(r)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should only patch BareMetalHosts whose labels or annotations change", func() {
+		r := newReconciler(newBareMetalHost(nil))
+		patches := 0
+		r.k8sClient = interceptor.NewClient(r.k8sClient.(client.WithWatch), interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				patches++
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+		})
+		patched := testutil.ToFloat64(bmcOperationsTotal.WithLabelValues(bmcKindBareMetalHost, operationPatched))
+		labels := map[string]string{"topology.kubernetes.io/region": "region1"}
+		annotations := map[string]string{configContextAnnotationPrefix + "kernel-args": "quiet"}
+
+		for range 2 {
+			bmh, err := getBareMetalHost(r)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(r.patchBareMetalHostLabels(ctx, bmh, labels, annotations)).To(Succeed())
+		}
+
+		Expect(patches).To(Equal(1))
+		Expect(testutil.ToFloat64(bmcOperationsTotal.WithLabelValues(bmcKindBareMetalHost, operationPatched))).To(Equal(patched + 1))
+		bmh, err := getBareMetalHost(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bmh.Labels).To(Equal(labels))
+		Expect(bmh.Annotations).To(Equal(map[string]string{
+			configContextAnnotationPrefix + "kernel-args": "quiet",
+			annotationManagedLabelsKey:                    "topology.kubernetes.io/region",
+		}))
+	})
+
+	It("should delete the config context ConfigMap no longer exported", func() {
+		bmh := newBareMetalHost(nil)
+		bmh.UID = "bmh-uid"
+		r := newReconciler(bmh)
+		device := newDevice("active")
+		configMapKey := client.ObjectKey{Name: "config-context-" + device.Name, Namespace: "default"}
+		data := map[string]string{"kernel-args": "quiet"}
+
+		Expect(r.reconcileConfigContextConfigMap(ctx, &argorav1alpha1.ConfigContextExport{}, bmh, device, nil, data)).To(Succeed())
+		Expect(r.k8sClient.Get(ctx, configMapKey, &corev1.ConfigMap{})).To(Succeed())
+
+		export := &argorav1alpha1.ConfigContextExport{Target: argorav1alpha1.ConfigContextTargetAnnotations}
+		Expect(r.reconcileConfigContextConfigMap(ctx, export, bmh, device, nil, data)).To(Succeed())
+		err := r.k8sClient.Get(ctx, configMapKey, &corev1.ConfigMap{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		Expect(r.reconcileConfigContextConfigMap(ctx, nil, bmh, device, nil, nil)).To(Succeed())
+	})
+
+	It("should remove the labels of BareMetalHosts no longer rendered", func() {
+		bmh := newBareMetalHost(nil)
+		bmh.Labels = map[string]string{"example.com/other": "value"}
+		r := newReconciler(bmh)
+
+		for _, labels := range []map[string]string{
+			{"topology.kubernetes.io/region": "region1", "example.com/rack": "rack-1"},
+			{"topology.kubernetes.io/region": "region1"},
+		} {
+			bmh, err := getBareMetalHost(r)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(r.patchBareMetalHostLabels(ctx, bmh, labels, nil)).To(Succeed())
+		}
+
+		bmh, err := getBareMetalHost(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bmh.Labels).To(Equal(map[string]string{
+			"topology.kubernetes.io/region": "region1",
+			"example.com/other":             "value",
+		}))
+		Expect(bmh.Annotations).To(HaveKeyWithValue(annotationManagedLabelsKey, "topology.kubernetes.io/region"))
+	})
+})
+
+var _ = Describe("Metal3 cluster selector", func() {
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Namespace: "default"},
+	}
+	nbCluster := &models.Cluster{ID: 1, Name: "cluster1"}
+	device := &models.Device{ID: 1, Name: "device1-bb1", Rack: models.NestedRack{Name: "rack-1"}}
+
+	newReconciler := func() *Metal3Reconciler {
+		return &Metal3Reconciler{
+			netBox: &mock.NetBoxMock{
+				DCIMMock: &mock.DCIMMock{
+					GetSiteGroupForDeviceFunc: func(_ *models.Device) (string, error) {
+						return "", nil
+					},
+				},
+			},
+			recorder:          events.NewFakeRecorder(10),
+			deviceNamePattern: defaultDeviceNamePattern,
+		}
+	}
+
+	It("should render the label template of the cluster selector", func() {
+		clusterSelector := &argorav1alpha1.ClusterSelector{
+			LabelTemplate: &argorav1alpha1.LabelTemplate{
+				Labels: map[string]string{"example.com/rack": "{{ .Rack }}"},
+			},
+		}
+
+		labels, err := newReconciler().generateLabels(cluster, clusterSelector, nbCluster, device, "qa-de-1", "compute", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(labels).To(HaveKeyWithValue("example.com/rack", "rack-1"))
+	})
+
+	It("should render the config context export of the cluster selector", func() {
+		r := newReconciler()
+		r.netBox.DCIM().(*mock.DCIMMock).GetConfigContextForDeviceFunc = func(_ *models.Device) (map[string]any, error) {
+			return map[string]any{"kernel": map[string]any{"args": "quiet"}}, nil
+		}
+
+		export, data, err := r.renderConfigContext(&argorav1alpha1.ClusterSelector{}, device)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(export).To(BeNil())
+		Expect(data).To(BeNil())
+
+		clusterSelector := &argorav1alpha1.ClusterSelector{
+			ConfigContext: &argorav1alpha1.ConfigContextExport{Keys: map[string]string{"kernel-args": "{.kernel.args}"}},
+		}
+		export, data, err = r.renderConfigContext(clusterSelector, device)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(export).To(BeIdenticalTo(clusterSelector.ConfigContext))
+		Expect(data).To(Equal(map[string]string{"kernel-args": "quiet"}))
+	})
+
+	It("should report devices not matching the name pattern of the cluster selector", func() {
+		r := newReconciler()
+		clusterSelector := &argorav1alpha1.ClusterSelector{NamePattern: `^(?P<nodename>node[0-9]+)-(?P<bb>bb[0-9]+)$`}
+		activeDevice := *device
+		activeDevice.Status.Value = "active"
+
+		Expect(r.reconcileDevice(context.Background(), cluster, nil, clusterSelector, nbCluster, &activeDevice)).To(Succeed())
+		Expect(r.recorder.(*events.FakeRecorder).Events).To(Receive(SatisfyAll(
+			ContainSubstring("DeviceNameMismatch"),
+			ContainSubstring("device1-bb1"),
+		)))
+	})
+})
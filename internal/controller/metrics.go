@@ -0,0 +1,187 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// controller names used as label of the metrics, matching the names of the controllers
+const (
+	controllerNameIronCore     = "ironcore"
+	controllerNameMetal3       = "metal3"
+	controllerNameUpdate       = "update"
+	controllerNameIPPoolImport = "ippoolimport"
+	controllerNameIPUpdate     = "ipupdate"
+)
+
+// results of the devices processed by the import and update controllers
+const (
+	deviceResultImported       = "imported"
+	deviceResultUpdated        = "updated"
+	deviceResultMaintenance    = "maintenance"
+	deviceResultDecommissioned = "decommissioned"
+	deviceResultSkipped        = "skipped"
+	deviceResultFailed         = "failed"
+)
+
+// operations on BMCs and NetBox IP addresses
+const (
+	operationCreated    = "created"
+	operationPatched    = "patched"
+	operationDeleted    = "deleted"
+	operationDeprecated = "deprecated"
+	operationUnassigned = "unassigned"
+	operationConflicted = "conflicted"
+
+	bmcKindBMC           = "BMC"
+	bmcKindBareMetalHost = "BareMetalHost"
+)
+
+// phases of the reconciliations
+const (
+	phaseNetboxReload = "netbox_reload"
+	phaseClusters     = "clusters"
+	phaseIPPools      = "ippools"
+	phasePrune        = "prune"
+	phaseTarget       = "target"
+	phaseNetbox       = "netbox"
+	phaseDeletion     = "deletion"
+)
+
+var (
+	// ipDriftTotal counts the drifts of IP addresses in NetBox detected by the IPUpdate controller.
+	ipDriftTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "argora_ipupdate_drift_total",
+			Help: "Number of drifts of IP addresses in NetBox detected by the IPUpdate controller, by kind of drift and policy applied.",
+		},
+		[]string{"kind", "policy"},
+	)
+
+	// devicesProcessedTotal counts the NetBox devices processed per custom resource and NetBox cluster.
+	devicesProcessedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "argora_devices_processed_total",
+			Help: "Number of NetBox devices processed, by controller, custom resource and NetBox cluster.",
+		},
+		[]string{"controller", "namespace", "name", "cluster"},
+	)
+
+	// devicesTotal counts the NetBox devices imported, updated, put into maintenance, decommissioned, skipped or failed
+	// per custom resource and NetBox cluster.
+	devicesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "argora_devices_total",
+			Help: "Number of NetBox devices by result (imported, updated, maintenance, decommissioned, skipped or failed), controller, custom resource and NetBox cluster.",
+		},
+		[]string{"controller", "namespace", "name", "cluster", "result"},
+	)
+
+	// bmcOperationsTotal counts the BMCs and BareMetalHosts created or patched for NetBox devices.
+	bmcOperationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "argora_bmc_operations_total",
+			Help: "Number of BMCs and BareMetalHosts created or patched, by kind and operation.",
+		},
+		[]string{"kind", "operation"},
+	)
+
+	// ipPoolsCreatedTotal counts the IP pools created per IPPoolImport.
+	ipPoolsCreatedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "argora_ippools_created_total",
+			Help: "Number of IP pools created, by IPPoolImport and kind of IP pool.",
+		},
+		[]string{"namespace", "name", "kind"},
+	)
+
+	// netboxIPAddressesTotal counts the operations of the IPUpdate controller on NetBox IP addresses.
+	netboxIPAddressesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "argora_ipupdate_netbox_ip_addresses_total",
+			Help: "Number of NetBox IP addresses created, deleted, deprecated, unassigned or found conflicting by the IPUpdate controller.",
+		},
+		[]string{"operation"},
+	)
+
+	// lastSuccessfulReconcile is the time of the last successful reconciliation per custom resource.
+	lastSuccessfulReconcile = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "argora_last_successful_reconcile_timestamp_seconds",
+			Help: "Unix time of the last successful reconciliation, by controller and custom resource.",
+		},
+		[]string{"controller", "namespace", "name"},
+	)
+
+	// reconcilePhaseDuration observes the duration of the phases of a reconciliation.
+	reconcilePhaseDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "argora_reconcile_phase_duration_seconds",
+			Help:    "Duration of the phases of a reconciliation, by controller and phase.",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+		},
+		[]string{"controller", "phase"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		ipDriftTotal,
+		devicesProcessedTotal,
+		devicesTotal,
+		bmcOperationsTotal,
+		ipPoolsCreatedTotal,
+		netboxIPAddressesTotal,
+		lastSuccessfulReconcile,
+		reconcilePhaseDuration,
+	)
+}
+
+// recordDeviceProcessed counts a device processed for the custom resource.
+func recordDeviceProcessed(controllerName string, obj client.Object, cluster string) {
+	devicesProcessedTotal.WithLabelValues(controllerName, obj.GetNamespace(), obj.GetName(), cluster).Inc()
+}
+
+// recordDeviceResult counts the result of a device processed for the custom resource.
+func recordDeviceResult(controllerName string, obj client.Object, cluster, result string) {
+	devicesTotal.WithLabelValues(controllerName, obj.GetNamespace(), obj.GetName(), cluster, result).Inc()
+}
+
+// recordReconcileStarted initializes the time of the last successful reconciliation of a custom resource without one
+// to its creation time, so a custom resource never reconciled successfully is reported as stale as well.
+func recordReconcileStarted(controllerName string, obj client.Object) {
+	gauge := lastSuccessfulReconcile.WithLabelValues(controllerName, obj.GetNamespace(), obj.GetName())
+	metric := &dto.Metric{}
+	if err := gauge.Write(metric); err == nil && metric.GetGauge().GetValue() == 0 {
+		gauge.Set(float64(obj.GetCreationTimestamp().Unix()))
+	}
+}
+
+// recordReconcileSuccess sets the time of the last successful reconciliation of the custom resource to now.
+func recordReconcileSuccess(controllerName string, obj client.Object) {
+	lastSuccessfulReconcile.WithLabelValues(controllerName, obj.GetNamespace(), obj.GetName()).SetToCurrentTime()
+}
+
+// deleteReconcileMetrics removes the metrics of a deleted custom resource, so it is not reported as stale.
+func deleteReconcileMetrics(controllerName string, key types.NamespacedName) {
+	labels := prometheus.Labels{"controller": controllerName, "namespace": key.Namespace, "name": key.Name}
+	lastSuccessfulReconcile.Delete(labels)
+	devicesProcessedTotal.DeletePartialMatch(labels)
+	devicesTotal.DeletePartialMatch(labels)
+}
+
+// observePhase starts timing a phase of a reconciliation, the returned function observes its duration.
+func observePhase(controllerName, phase string) func() {
+	start := time.Now()
+	return func() {
+		reconcilePhaseDuration.WithLabelValues(controllerName, phase).Observe(time.Since(start).Seconds())
+	}
+}
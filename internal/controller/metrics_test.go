@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	argorav1alpha1 "github.com/sapcc/argora/api/v1alpha1"
+)
+
+var _ = Describe("Metrics", func() {
+	clusterImport := &argorav1alpha1.ClusterImport{
+		ObjectMeta: metav1.ObjectMeta{Name: "metrics-import", Namespace: "metrics"},
+	}
+
+	It("should count processed devices by result", func() {
+		recordDeviceProcessed(controllerNameIronCore, clusterImport, "cluster1")
+		recordDeviceProcessed(controllerNameIronCore, clusterImport, "cluster1")
+		recordDeviceResult(controllerNameIronCore, clusterImport, "cluster1", deviceResultImported)
+		recordDeviceResult(controllerNameIronCore, clusterImport, "cluster1", deviceResultFailed)
+
+		Expect(testutil.ToFloat64(devicesProcessedTotal.WithLabelValues(controllerNameIronCore, "metrics", "metrics-import", "cluster1"))).To(Equal(2.0))
+		Expect(testutil.ToFloat64(devicesTotal.WithLabelValues(controllerNameIronCore, "metrics", "metrics-import", "cluster1", deviceResultImported))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(devicesTotal.WithLabelValues(controllerNameIronCore, "metrics", "metrics-import", "cluster1", deviceResultFailed))).To(Equal(1.0))
+	})
+
+	It("should delete the metrics of deleted custom resources", func() {
+		recordDeviceProcessed(controllerNameIronCore, clusterImport, "cluster2")
+		recordDeviceResult(controllerNameIronCore, clusterImport, "cluster2", deviceResultSkipped)
+		recordReconcileSuccess(controllerNameIronCore, clusterImport)
+		Expect(testutil.ToFloat64(lastSuccessfulReconcile.WithLabelValues(controllerNameIronCore, "metrics", "metrics-import"))).To(BeNumerically(">", 0))
+
+		deleteReconcileMetrics(controllerNameIronCore, types.NamespacedName{Namespace: "metrics", Name: "metrics-import"})
+
+		Expect(lastSuccessfulReconcile.DeleteLabelValues(controllerNameIronCore, "metrics", "metrics-import")).To(BeFalse())
+		Expect(testutil.ToFloat64(devicesProcessedTotal.WithLabelValues(controllerNameIronCore, "metrics", "metrics-import", "cluster2"))).To(BeZero())
+		Expect(testutil.ToFloat64(devicesTotal.WithLabelValues(controllerNameIronCore, "metrics", "metrics-import", "cluster2", deviceResultSkipped))).To(BeZero())
+	})
+
+	It("should initialize the last successful reconciliation to the creation time", func() {
+		created := &argorav1alpha1.ClusterImport{
+			ObjectMeta: metav1.ObjectMeta{Name: "metrics-created", Namespace: "metrics", CreationTimestamp: metav1.Unix(1000, 0)},
+		}
+		gauge := lastSuccessfulReconcile.WithLabelValues(controllerNameIronCore, "metrics", "metrics-created")
+
+		recordReconcileStarted(controllerNameIronCore, created)
+		Expect(testutil.ToFloat64(gauge)).To(Equal(1000.0))
+
+		recordReconcileSuccess(controllerNameIronCore, created)
+		recordReconcileStarted(controllerNameIronCore, created)
+		Expect(testutil.ToFloat64(gauge)).To(BeNumerically(">", 1000))
+	})
+
+	It("should observe the duration of reconcile phases", func() {
+		count := testutil.CollectAndCount(reconcilePhaseDuration, "argora_reconcile_phase_duration_seconds")
+
+		observePhase("metrics", phasePrune)()
+
+		Expect(testutil.CollectAndCount(reconcilePhaseDuration, "argora_reconcile_phase_duration_seconds")).To(Equal(count + 1))
+	})
+})
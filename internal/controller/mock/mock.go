@@ -5,6 +5,7 @@
 package mock
 
 import (
+	"context"
 	"errors"
 
 	"github.com/go-logr/logr"
@@ -82,31 +83,37 @@ func (v *VirtualizationMock) GetClustersByNameRegionType(name, region, clusterTy
 }
 
 type DCIMMock struct {
-	GetDeviceByNameFunc         func(deviceName string) (*models.Device, error)
-	GetDeviceByNameCalls        int
-	GetDeviceByIDFunc           func(id int) (*models.Device, error)
-	GetDeviceByIDCalls          int
-	GetDevicesByClusterIDFunc   func(clusterID int) ([]models.Device, error)
-	GetDevicesByClusterIDCalls  int
-	GetRoleByNameFunc           func(roleName string) (*models.DeviceRole, error)
-	GetRoleByNameCalls          int
-	GetRegionForDeviceFunc      func(device *models.Device) (string, error)
-	GetRegionForDeviceCalls     int
-	GetInterfaceByIDFunc        func(id int) (*models.Interface, error)
-	GetInterfaceByIDCalls       int
-	GetInterfacesForDeviceFunc  func(device *models.Device) ([]models.Interface, error)
-	GetInterfacesForDeviceCalls int
-	GetInterfaceForDeviceFunc   func(device *models.Device, ifaceName string) (*models.Interface, error)
-	GetInterfaceForDeviceCalls  int
-	GetInterfacesByLagIDFunc    func(lagID int) ([]models.Interface, error)
-	GetInterfacesByLagIDCalls   int
-	GetPlatformByNameFunc       func(platformName string) (*models.Platform, error)
-	GetPlatformByNameCalls      int
-
-	UpdateDeviceFunc     func(device models.WritableDeviceWithConfigContext) (*models.Device, error)
-	UpdateDeviceCalls    int
-	UpdateInterfaceFunc  func(iface models.WritableInterface, id int) (*models.Interface, error)
-	UpdateInterfaceCalls int
+	GetDeviceByNameFunc            func(deviceName string) (*models.Device, error)
+	GetDeviceByNameCalls           int
+	GetDeviceByIDFunc              func(id int) (*models.Device, error)
+	GetDeviceByIDCalls             int
+	GetDevicesByClusterIDFunc      func(clusterID int) ([]models.Device, error)
+	GetDevicesByClusterIDCalls     int
+	GetRoleByNameFunc              func(roleName string) (*models.DeviceRole, error)
+	GetRoleByNameCalls             int
+	GetRegionForDeviceFunc         func(device *models.Device) (string, error)
+	GetRegionForDeviceCalls        int
+	GetSiteGroupForDeviceFunc      func(device *models.Device) (string, error)
+	GetSiteGroupForDeviceCalls     int
+	GetConfigContextForDeviceFunc  func(device *models.Device) (map[string]any, error)
+	GetConfigContextForDeviceCalls int
+	GetInterfaceByIDFunc           func(id int) (*models.Interface, error)
+	GetInterfaceByIDCalls          int
+	GetInterfacesForDeviceFunc     func(device *models.Device) ([]models.Interface, error)
+	GetInterfacesForDeviceCalls    int
+	GetInterfaceForDeviceFunc      func(device *models.Device, ifaceName string) (*models.Interface, error)
+	GetInterfaceForDeviceCalls     int
+	GetInterfacesByLagIDFunc       func(lagID int) ([]models.Interface, error)
+	GetInterfacesByLagIDCalls      int
+	GetPlatformByNameFunc          func(platformName string) (*models.Platform, error)
+	GetPlatformByNameCalls         int
+
+	UpdateDeviceFunc          func(device models.WritableDeviceWithConfigContext) (*models.Device, error)
+	UpdateDeviceCalls         int
+	UpdateInterfaceFunc       func(iface models.WritableInterface, id int) (*models.Interface, error)
+	UpdateInterfaceCalls      int
+	ClearDevicePrimaryIPFunc  func(ctx context.Context, deviceID int, ipv6 bool) error
+	ClearDevicePrimaryIPCalls int
 
 	DeleteInterfaceFunc  func(id int) error
 	DeleteInterfaceCalls int
@@ -137,6 +144,16 @@ func (d *DCIMMock) GetRegionForDevice(device *models.Device) (string, error) {
 	return d.GetRegionForDeviceFunc(device)
 }
 
+func (d *DCIMMock) GetSiteGroupForDevice(device *models.Device) (string, error) {
+	d.GetSiteGroupForDeviceCalls++
+	return d.GetSiteGroupForDeviceFunc(device)
+}
+
+func (d *DCIMMock) GetConfigContextForDevice(device *models.Device) (map[string]any, error) {
+	d.GetConfigContextForDeviceCalls++
+	return d.GetConfigContextForDeviceFunc(device)
+}
+
 func (d *DCIMMock) GetInterfaceByID(id int) (*models.Interface, error) {
 	d.GetInterfaceByIDCalls++
 	return d.GetInterfaceByIDFunc(id)
@@ -172,6 +189,11 @@ func (d *DCIMMock) UpdateInterface(iface models.WritableInterface, id int) (*mod
 	return d.UpdateInterfaceFunc(iface, id)
 }
 
+func (d *DCIMMock) ClearDevicePrimaryIP(ctx context.Context, deviceID int, ipv6 bool) error {
+	d.ClearDevicePrimaryIPCalls++
+	return d.ClearDevicePrimaryIPFunc(ctx, deviceID, ipv6)
+}
+
 func (d *DCIMMock) DeleteInterface(id int) error {
 	d.DeleteInterfaceCalls++
 	return d.DeleteInterfaceFunc(id)
@@ -188,13 +210,27 @@ type IPAMMock struct {
 	GetIPAddressForInterfaceCalls   int
 	GetPrefixesContainingFunc       func(contains string) ([]models.Prefix, error)
 	GetPrefixesContainingCalls      int
-	GetPrefixesByRegionRoleFunc     func(region, role string) ([]models.Prefix, error)
-	GetPrefixesByRegionRoleCalls    int
+	GetPrefixesFunc                 func(ctx context.Context, opts ...ipam.ListPrefixesRequestOption) ([]models.Prefix, error)
+	GetPrefixesCalls                int
+	GetVlansByGroupFunc             func(group string) ([]models.Vlan, error)
+	GetVlansByGroupCalls            int
+	GetVrfsByNameFunc               func(name string) ([]models.VRF, error)
+	GetVrfsByNameCalls              int
 	GetPrefixesByPrefixesFunc       func(prefix string) ([]models.Prefix, error)
 	GetPrefixesByPrefixesCalls      int
-
-	DeleteIPAddressFunc  func(id int) error
-	DeleteIPAddressCalls int
+	GetIPAddressesFunc              func(ctx context.Context, opts ...ipam.ListIPAddressesRequestOption) ([]models.IPAddress, error)
+	GetIPAddressesCalls             int
+	GetIPRangesInPrefixFunc         func(ctx context.Context, prefix string, vrfID int) ([]ipam.IPRange, error)
+	GetIPRangesInPrefixCalls        int
+	CreateAvailablePrefixFunc       func(ctx context.Context, containerID int, params ipam.CreateAvailablePrefixParams) (*models.Prefix, error)
+	CreateAvailablePrefixCalls      int
+
+	UnassignIPAddressFunc  func(ctx context.Context, id int) error
+	UnassignIPAddressCalls int
+	DeleteIPAddressFunc    func(id int) error
+	DeleteIPAddressCalls   int
+	DeletePrefixFunc       func(id int) error
+	DeletePrefixCalls      int
 
 	CreateIPAddressFunc  func(params ipam.CreateIPAddressParams) (*models.IPAddress, error)
 	CreateIPAddressCalls int
@@ -227,9 +263,19 @@ func (i *IPAMMock) GetPrefixesContaining(contains string) ([]models.Prefix, erro
 	return i.GetPrefixesContainingFunc(contains)
 }
 
-func (i *IPAMMock) GetPrefixesByRegionRole(region, role string) ([]models.Prefix, error) {
-	i.GetPrefixesByRegionRoleCalls++
-	return i.GetPrefixesByRegionRoleFunc(region, role)
+func (i *IPAMMock) GetPrefixes(ctx context.Context, opts ...ipam.ListPrefixesRequestOption) ([]models.Prefix, error) {
+	i.GetPrefixesCalls++
+	return i.GetPrefixesFunc(ctx, opts...)
+}
+
+func (i *IPAMMock) GetVlansByGroup(group string) ([]models.Vlan, error) {
+	i.GetVlansByGroupCalls++
+	return i.GetVlansByGroupFunc(group)
+}
+
+func (i *IPAMMock) GetVrfsByName(name string) ([]models.VRF, error) {
+	i.GetVrfsByNameCalls++
+	return i.GetVrfsByNameFunc(name)
 }
 
 func (i *IPAMMock) GetPrefixesByPrefix(prefix string) ([]models.Prefix, error) {
@@ -237,11 +283,36 @@ func (i *IPAMMock) GetPrefixesByPrefix(prefix string) ([]models.Prefix, error) {
 	return i.GetPrefixesByPrefixesFunc(prefix)
 }
 
+func (i *IPAMMock) GetIPAddresses(ctx context.Context, opts ...ipam.ListIPAddressesRequestOption) ([]models.IPAddress, error) {
+	i.GetIPAddressesCalls++
+	return i.GetIPAddressesFunc(ctx, opts...)
+}
+
+func (i *IPAMMock) GetIPRangesInPrefix(ctx context.Context, prefix string, vrfID int) ([]ipam.IPRange, error) {
+	i.GetIPRangesInPrefixCalls++
+	return i.GetIPRangesInPrefixFunc(ctx, prefix, vrfID)
+}
+
 func (i *IPAMMock) UpdateIPAddress(addr models.WriteableIPAddress) (*models.IPAddress, error) {
 	i.UpdateIPAddressCalls++
 	return i.UpdateIPAddressFunc(addr)
 }
 
+func (i *IPAMMock) CreateAvailablePrefix(ctx context.Context, containerID int, params ipam.CreateAvailablePrefixParams) (*models.Prefix, error) {
+	i.CreateAvailablePrefixCalls++
+	return i.CreateAvailablePrefixFunc(ctx, containerID, params)
+}
+
+func (i *IPAMMock) DeletePrefix(id int) error {
+	i.DeletePrefixCalls++
+	return i.DeletePrefixFunc(id)
+}
+
+func (i *IPAMMock) UnassignIPAddress(ctx context.Context, id int) error {
+	i.UnassignIPAddressCalls++
+	return i.UnassignIPAddressFunc(ctx, id)
+}
+
 func (i *IPAMMock) DeleteIPAddress(id int) error {
 	i.DeleteIPAddressCalls++
 	return i.DeleteIPAddressFunc(id)
@@ -0,0 +1,310 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	metalv1alpha1 "github.com/ironcore-dev/metal-operator/api/v1alpha1"
+	bmov1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/sapcc/go-netbox-go/models"
+
+	"golang.org/x/time/rate"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/sapcc/argora/internal/credentials"
+	"github.com/sapcc/argora/internal/netbox"
+)
+
+const (
+	statusSyncKindBMC           = "BMC"
+	statusSyncKindServer        = "Server"
+	statusSyncKindBareMetalHost = "BareMetalHost"
+)
+
+// StatusSyncRule describes a NetBox device change that is allowed once a Kubernetes object reached a given state.
+type StatusSyncRule struct {
+	// Kind of the observed object: BMC, Server or BareMetalHost.
+	Kind string `json:"kind"`
+	// State the observed object has to be in, e.g. Enabled for a BMC or provisioned for a BareMetalHost.
+	State string `json:"state"`
+	// FromStatuses restricts the rule to devices currently in one of these NetBox statuses. Required if ToStatus is set.
+	FromStatuses []string `json:"fromStatuses,omitempty"`
+	// ToStatus is the NetBox device status to set.
+	ToStatus string `json:"toStatus,omitempty"`
+	// CustomField is the NetBox device custom field to set to CustomFieldValue.
+	CustomField      string `json:"customField,omitempty"`
+	CustomFieldValue string `json:"customFieldValue,omitempty"`
+	// Tag is the name of the NetBox tag to add to the device.
+	Tag string `json:"tag,omitempty"`
+}
+
+func (s StatusSyncRule) validate() error {
+	if !slices.Contains([]string{statusSyncKindBMC, statusSyncKindServer, statusSyncKindBareMetalHost}, s.Kind) {
+		return fmt.Errorf("unsupported kind %q", s.Kind)
+	}
+	if s.State == "" {
+		return errors.New("state must be set")
+	}
+	if s.ToStatus == "" && s.CustomField == "" && s.Tag == "" {
+		return errors.New("one of toStatus, customField or tag must be set")
+	}
+	if s.ToStatus != "" && len(s.FromStatuses) == 0 {
+		return errors.New("fromStatuses must be set if toStatus is set")
+	}
+	return nil
+}
+
+func (s StatusSyncRule) matches(observed observedState, deviceStatus string) bool {
+	if s.Kind != observed.kind || s.State != observed.state {
+		return false
+	}
+	return len(s.FromStatuses) == 0 || slices.Contains(s.FromStatuses, deviceStatus)
+}
+
+// LoadStatusSyncRules reads the status sync rules from a JSON file containing a list of rules.
+func LoadStatusSyncRules(fileReader credentials.FileReader, fileName string) ([]StatusSyncRule, error) {
+	data, err := fileReader.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", fileName, err)
+	}
+
+	var rules []StatusSyncRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal %s: %w", fileName, err)
+	}
+
+	for i, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("invalid status sync rule %d: %w", i, err)
+		}
+	}
+
+	return rules, nil
+}
+
+type observedState struct {
+	kind  string
+	state string
+}
+
+// StatusSyncReconciler reflects the state of argora-created BMC/Server or BareMetalHost objects into NetBox.
+// Only the changes allowed by the configured rules are written, NetBox remains authoritative for everything else.
+type StatusSyncReconciler struct {
+	k8sClient      client.Client
+	credentials    *credentials.Credentials
+	netBox         netbox.Netbox
+	rules          []StatusSyncRule
+	enableIronCore bool
+}
+
+func NewStatusSyncReconciler(mgr ctrl.Manager, creds *credentials.Credentials, netBox netbox.Netbox, rules []StatusSyncRule, enableIronCore bool) *StatusSyncReconciler {
+	return &StatusSyncReconciler{
+		k8sClient:      mgr.GetClient(),
+		credentials:    creds,
+		netBox:         netBox,
+		rules:          rules,
+		enableIronCore: enableIronCore,
+	}
+}
+
+func (r *StatusSyncReconciler) SetupWithManager(mgr ctrl.Manager, rateLimiter RateLimiter) error {
+	// the device name label may be dropped by the label template, the managed labels annotation is set regardless
+	importedByArgora := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		_, labeled := obj.GetLabels()[labelDeviceNameKey]
+		_, managed := obj.GetAnnotations()[annotationManagedLabelsKey]
+		return labeled || managed
+	})
+
+	b := ctrl.NewControllerManagedBy(mgr)
+	if r.enableIronCore {
+		// the Servers are listed by the serverBMCRefField index of the IronCore controller, which is always set up
+		// along with this controller
+		b = b.For(&metalv1alpha1.BMC{}, builder.WithPredicates(importedByArgora)).
+			Watches(&metalv1alpha1.Server{}, handler.EnqueueRequestsFromMapFunc(func(_ context.Context, obj client.Object) []reconcile.Request {
+				server, ok := obj.(*metalv1alpha1.Server)
+				if !ok || server.Spec.BMCRef == nil {
+					return nil
+				}
+				return []reconcile.Request{{NamespacedName: client.ObjectKey{Name: server.Spec.BMCRef.Name}}}
+			}))
+	} else {
+		b = b.For(&bmov1alpha1.BareMetalHost{}, builder.WithPredicates(importedByArgora))
+	}
+
+	return b.
+		WithOptions(controller.Options{
+			RateLimiter: workqueue.NewTypedMaxOfRateLimiter(
+				workqueue.NewTypedItemExponentialFailureRateLimiter[ctrl.Request](rateLimiter.BaseDelay,
+					rateLimiter.FailureMaxDelay),
+				&workqueue.TypedBucketRateLimiter[ctrl.Request]{
+					Limiter: rate.NewLimiter(rate.Limit(rateLimiter.Frequency), rateLimiter.Burst),
+				},
+			),
+		}).
+		Named("statussync").
+		Complete(r)
+}
+
+// +kubebuilder:rbac:groups=metal.ironcore.dev,resources=bmcs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=metal.ironcore.dev,resources=servers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=metal3.io,resources=baremetalhosts,verbs=get;list;watch
+
+func (r *StatusSyncReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger.Info("reconciling status sync")
+
+	deviceName, observedStates, err := r.observe(ctx, req)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	if !r.hasRulesFor(observedStates) {
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.credentials.Reload(); err != nil {
+		logger.Error(err, "unable to reload credentials")
+		return ctrl.Result{}, err
+	}
+
+	if err := r.netBox.Reload(r.credentials.NetboxToken, logger); err != nil {
+		logger.Error(err, "unable to reload netbox")
+		return ctrl.Result{}, err
+	}
+
+	device, err := r.netBox.DCIM().GetDeviceByName(deviceName)
+	if err != nil {
+		logger.Error(err, "unable to get device", "device", deviceName)
+		return ctrl.Result{}, err
+	}
+
+	if err := r.syncDevice(ctx, device, observedStates); err != nil {
+		logger.Error(err, "unable to sync device", "device", device.Name, "ID", device.ID)
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// observe returns the name of the device of the observed objects and their states. BMCs and BareMetalHosts are named
+// after their device.
+func (r *StatusSyncReconciler) observe(ctx context.Context, req ctrl.Request) (string, []observedState, error) {
+	if !r.enableIronCore {
+		bmh := &bmov1alpha1.BareMetalHost{}
+		if err := r.k8sClient.Get(ctx, req.NamespacedName, bmh); err != nil {
+			return "", nil, err
+		}
+		return bmh.Name, []observedState{
+			{kind: statusSyncKindBareMetalHost, state: string(bmh.Status.Provisioning.State)},
+		}, nil
+	}
+
+	bmc := &metalv1alpha1.BMC{}
+	if err := r.k8sClient.Get(ctx, client.ObjectKey{Name: req.Name}, bmc); err != nil {
+		return "", nil, err
+	}
+
+	observedStates := []observedState{
+		{kind: statusSyncKindBMC, state: string(bmc.Status.State)},
+	}
+
+	serverList := &metalv1alpha1.ServerList{}
+	if err := r.k8sClient.List(ctx, serverList, client.MatchingFields{serverBMCRefField: bmc.Name}); err != nil {
+		return "", nil, fmt.Errorf("unable to list servers: %w", err)
+	}
+
+	for _, server := range serverList.Items {
+		observedStates = append(observedStates, observedState{kind: statusSyncKindServer, state: string(server.Status.State)})
+	}
+
+	return bmc.Name, observedStates, nil
+}
+
+func (r *StatusSyncReconciler) hasRulesFor(observedStates []observedState) bool {
+	for _, rule := range r.rules {
+		for _, observed := range observedStates {
+			if rule.Kind == observed.kind && rule.State == observed.state {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *StatusSyncReconciler) syncDevice(ctx context.Context, device *models.Device, observedStates []observedState) error {
+	logger := log.FromContext(ctx)
+
+	wDevice := device.Writeable()
+	changed := false
+
+	for _, rule := range r.rules {
+		if !slices.ContainsFunc(observedStates, func(observed observedState) bool { return rule.matches(observed, device.Status.Value) }) {
+			continue
+		}
+
+		if rule.ToStatus != "" && wDevice.Status != rule.ToStatus && device.Status.Value != rule.ToStatus {
+			logger.Info("setting device status", "device", device.Name, "from", device.Status.Value, "to", rule.ToStatus)
+			wDevice.Status = rule.ToStatus
+			changed = true
+		}
+
+		if rule.CustomField != "" && !hasCustomFieldValue(device, rule.CustomField, rule.CustomFieldValue) {
+			logger.Info("setting device custom field", "device", device.Name, "field", rule.CustomField, "value", rule.CustomFieldValue)
+			customFields, _ := wDevice.CustomFields.(map[string]any)
+			if customFields == nil {
+				customFields = make(map[string]any)
+			}
+			customFields[rule.CustomField] = rule.CustomFieldValue
+			wDevice.CustomFields = customFields
+			changed = true
+		}
+
+		if rule.Tag != "" && !slices.ContainsFunc(wDevice.Tags, func(tag models.NestedTag) bool { return tag.Name == rule.Tag }) {
+			tag, err := r.netBox.Extras().GetTagByName(rule.Tag)
+			if err != nil {
+				return fmt.Errorf("unable to get tag %s: %w", rule.Tag, err)
+			}
+			logger.Info("adding device tag", "device", device.Name, "tag", tag.Name)
+			wDevice.Tags = append(wDevice.Tags, tag.NestedTag)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	if _, err := r.netBox.DCIM().UpdateDevice(wDevice); err != nil {
+		return err
+	}
+
+	logger.Info("device synced to netbox", "device", device.Name, "ID", device.ID)
+	return nil
+}
+
+func hasCustomFieldValue(device *models.Device, field, value string) bool {
+	customFields, ok := device.CustomFields.(map[string]any)
+	if !ok {
+		return false
+	}
+	current, ok := customFields[field]
+	return ok && fmt.Sprint(current) == value
+}
@@ -0,0 +1,310 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+
+	metalv1alpha1 "github.com/ironcore-dev/metal-operator/api/v1alpha1"
+	bmov1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/sapcc/go-netbox-go/models"
+
+	"github.com/sapcc/argora/internal/controller/mock"
+	"github.com/sapcc/argora/internal/credentials"
+)
+
+var _ = Describe("StatusSync Controller", func() {
+	const (
+		deviceName = "node001-bb01"
+	)
+
+	fileReaderMock := &mock.FileReaderMock{
+		FileContent: make(map[string]string),
+		ReturnError: false,
+	}
+	fileReaderMock.FileContent["/etc/credentials/credentials.json"] = `{
+		"bmcUser": "user",
+		"bmcPassword": "password",
+		"netboxToken": "token"
+	}`
+
+	Context("LoadStatusSyncRules", func() {
+		It("should load valid rules", func() {
+			// given
+			rulesReaderMock := &mock.FileReaderMock{FileContent: map[string]string{
+				"rules.json": `[{"kind": "BMC", "state": "Enabled", "fromStatuses": ["staged"], "toStatus": "active"}]`,
+			}}
+
+			// when
+			rules, err := LoadStatusSyncRules(rulesReaderMock, "rules.json")
+
+			// then
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rules).To(Equal([]StatusSyncRule{
+				{Kind: "BMC", State: "Enabled", FromStatuses: []string{"staged"}, ToStatus: "active"},
+			}))
+		})
+
+		It("should refuse a status transition without allowed source statuses", func() {
+			// given
+			rulesReaderMock := &mock.FileReaderMock{FileContent: map[string]string{
+				"rules.json": `[{"kind": "BMC", "state": "Enabled", "toStatus": "active"}]`,
+			}}
+
+			// when
+			_, err := LoadStatusSyncRules(rulesReaderMock, "rules.json")
+
+			// then
+			Expect(err).To(MatchError("invalid status sync rule 0: fromStatuses must be set if toStatus is set"))
+		})
+
+		It("should refuse an unsupported kind", func() {
+			// given
+			rulesReaderMock := &mock.FileReaderMock{FileContent: map[string]string{
+				"rules.json": `[{"kind": "Machine", "state": "Running", "tag": "onboarded"}]`,
+			}}
+
+			// when
+			_, err := LoadStatusSyncRules(rulesReaderMock, "rules.json")
+
+			// then
+			Expect(err).To(MatchError(`invalid status sync rule 0: unsupported kind "Machine"`))
+		})
+	})
+
+	Context("Reconcile", func() {
+		ctx := context.Background()
+
+		prepareNetboxMock := func(deviceStatus string) *mock.NetBoxMock {
+			netBoxMock := &mock.NetBoxMock{
+				ReturnError:        false,
+				VirtualizationMock: &mock.VirtualizationMock{},
+				DCIMMock:           &mock.DCIMMock{},
+				IPAMMock:           &mock.IPAMMock{},
+				ExtrasMock:         &mock.ExtrasMock{},
+			}
+
+			netBoxMock.DCIMMock.(*mock.DCIMMock).GetDeviceByNameFunc = func(name string) (*models.Device, error) {
+				Expect(name).To(Equal(deviceName))
+				return &models.Device{
+					ID:           1,
+					Name:         deviceName,
+					Status:       models.DeviceStatus{Value: deviceStatus},
+					CustomFields: map[string]any{"provisioning": "pending"},
+				}, nil
+			}
+
+			return netBoxMock
+		}
+
+		bmc := &metalv1alpha1.BMC{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   deviceName,
+				Labels: map[string]string{"kubernetes.metal.cloud.sap/name": deviceName},
+			},
+			Status: metalv1alpha1.BMCStatus{
+				State: metalv1alpha1.BMCStateEnabled,
+			},
+		}
+
+		It("should set the device status once the BMC is enabled", func() {
+			// given
+			netBoxMock := prepareNetboxMock("staged")
+			netBoxMock.DCIMMock.(*mock.DCIMMock).UpdateDeviceFunc = func(device models.WritableDeviceWithConfigContext) (*models.Device, error) {
+				Expect(device.ID).To(Equal(1))
+				Expect(device.Status).To(Equal("active"))
+				return &models.Device{}, nil
+			}
+
+			rules := []StatusSyncRule{
+				{Kind: "BMC", State: "Enabled", FromStatuses: []string{"staged"}, ToStatus: "active"},
+			}
+			controllerReconciler := createStatusSyncReconciler(createFakeClient(bmc), netBoxMock, fileReaderMock, rules, true)
+
+			// when
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: deviceName}})
+
+			// then
+			Expect(err).ToNot(HaveOccurred())
+			Expect(netBoxMock.DCIMMock.(*mock.DCIMMock).UpdateDeviceCalls).To(Equal(1))
+		})
+
+		It("should not touch a device whose status is not allowed by the rule", func() {
+			// given
+			netBoxMock := prepareNetboxMock("offline")
+
+			rules := []StatusSyncRule{
+				{Kind: "BMC", State: "Enabled", FromStatuses: []string{"staged"}, ToStatus: "active"},
+			}
+			controllerReconciler := createStatusSyncReconciler(createFakeClient(bmc), netBoxMock, fileReaderMock, rules, true)
+
+			// when
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: deviceName}})
+
+			// then
+			Expect(err).ToNot(HaveOccurred())
+			Expect(netBoxMock.DCIMMock.(*mock.DCIMMock).UpdateDeviceCalls).To(Equal(0))
+		})
+
+		It("should not query netbox if no rule matches the observed state", func() {
+			// given
+			netBoxMock := prepareNetboxMock("staged")
+
+			rules := []StatusSyncRule{
+				{Kind: "Server", State: "Available", Tag: "discovered"},
+			}
+			controllerReconciler := createStatusSyncReconciler(createFakeClient(bmc), netBoxMock, fileReaderMock, rules, true)
+
+			// when
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: deviceName}})
+
+			// then
+			Expect(err).ToNot(HaveOccurred())
+			Expect(netBoxMock.DCIMMock.(*mock.DCIMMock).GetDeviceByNameCalls).To(Equal(0))
+		})
+
+		It("should tag the device once its server is available", func() {
+			// given
+			netBoxMock := prepareNetboxMock("active")
+			netBoxMock.ExtrasMock.(*mock.ExtrasMock).GetTagByNameFunc = func(tagName string) (*models.Tag, error) {
+				Expect(tagName).To(Equal("discovered"))
+				return &models.Tag{NestedTag: models.NestedTag{ID: 7, Name: "discovered", Slug: "discovered"}}, nil
+			}
+			netBoxMock.DCIMMock.(*mock.DCIMMock).UpdateDeviceFunc = func(device models.WritableDeviceWithConfigContext) (*models.Device, error) {
+				Expect(device.Status).To(BeEmpty())
+				Expect(device.Tags).To(Equal([]models.NestedTag{{ID: 7, Name: "discovered", Slug: "discovered"}}))
+				return &models.Device{}, nil
+			}
+
+			server := &metalv1alpha1.Server{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: deviceName + "-system-0",
+				},
+				Spec: metalv1alpha1.ServerSpec{
+					BMCRef: &corev1.LocalObjectReference{Name: deviceName},
+				},
+				Status: metalv1alpha1.ServerStatus{
+					State: metalv1alpha1.ServerStateAvailable,
+				},
+			}
+
+			rules := []StatusSyncRule{
+				{Kind: "Server", State: "Available", Tag: "discovered"},
+			}
+			controllerReconciler := createStatusSyncReconciler(createFakeClient(bmc, server), netBoxMock, fileReaderMock, rules, true)
+
+			// when
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: deviceName}})
+
+			// then
+			Expect(err).ToNot(HaveOccurred())
+			Expect(netBoxMock.DCIMMock.(*mock.DCIMMock).UpdateDeviceCalls).To(Equal(1))
+		})
+
+		It("should use the name of the BMC as device name regardless of its labels", func() {
+			// given
+			netBoxMock := prepareNetboxMock("staged")
+			netBoxMock.DCIMMock.(*mock.DCIMMock).UpdateDeviceFunc = func(device models.WritableDeviceWithConfigContext) (*models.Device, error) {
+				return &models.Device{}, nil
+			}
+
+			relabeledBMC := bmc.DeepCopy()
+			relabeledBMC.Labels = map[string]string{"kubernetes.metal.cloud.sap/name": "custom-name"}
+
+			rules := []StatusSyncRule{
+				{Kind: "BMC", State: "Enabled", FromStatuses: []string{"staged"}, ToStatus: "active"},
+			}
+			controllerReconciler := createStatusSyncReconciler(createFakeClient(relabeledBMC), netBoxMock, fileReaderMock, rules, true)
+
+			// when
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: deviceName}})
+
+			// then
+			Expect(err).ToNot(HaveOccurred())
+			Expect(netBoxMock.DCIMMock.(*mock.DCIMMock).GetDeviceByNameCalls).To(Equal(1))
+			Expect(netBoxMock.DCIMMock.(*mock.DCIMMock).UpdateDeviceCalls).To(Equal(1))
+		})
+
+		It("should only observe the servers of the BMC", func() {
+			// given
+			netBoxMock := prepareNetboxMock("active")
+
+			server := &metalv1alpha1.Server{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "other-device-system-0",
+				},
+				Spec: metalv1alpha1.ServerSpec{
+					BMCRef: &corev1.LocalObjectReference{Name: "other-device"},
+				},
+				Status: metalv1alpha1.ServerStatus{
+					State: metalv1alpha1.ServerStateAvailable,
+				},
+			}
+
+			rules := []StatusSyncRule{
+				{Kind: "Server", State: "Available", Tag: "discovered"},
+			}
+			controllerReconciler := createStatusSyncReconciler(createFakeClient(bmc, server), netBoxMock, fileReaderMock, rules, true)
+
+			// when
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: deviceName}})
+
+			// then
+			Expect(err).ToNot(HaveOccurred())
+			Expect(netBoxMock.DCIMMock.(*mock.DCIMMock).GetDeviceByNameCalls).To(Equal(0))
+		})
+
+		It("should set a custom field once the BareMetalHost is provisioned", func() {
+			// given
+			netBoxMock := prepareNetboxMock("active")
+			netBoxMock.DCIMMock.(*mock.DCIMMock).UpdateDeviceFunc = func(device models.WritableDeviceWithConfigContext) (*models.Device, error) {
+				Expect(device.CustomFields).To(Equal(map[string]any{"provisioning": "done"}))
+				return &models.Device{}, nil
+			}
+
+			bmh := &bmov1alpha1.BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      deviceName,
+					Namespace: "default",
+					Labels:    map[string]string{"kubernetes.metal.cloud.sap/name": deviceName},
+				},
+				Status: bmov1alpha1.BareMetalHostStatus{
+					Provisioning: bmov1alpha1.ProvisionStatus{
+						State: bmov1alpha1.StateProvisioned,
+					},
+				},
+			}
+
+			rules := []StatusSyncRule{
+				{Kind: "BareMetalHost", State: "provisioned", CustomField: "provisioning", CustomFieldValue: "done"},
+			}
+			controllerReconciler := createStatusSyncReconciler(createFakeClient(bmh), netBoxMock, fileReaderMock, rules, false)
+
+			// when
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(bmh)})
+
+			// then
+			Expect(err).ToNot(HaveOccurred())
+			Expect(netBoxMock.DCIMMock.(*mock.DCIMMock).UpdateDeviceCalls).To(Equal(1))
+		})
+	})
+})
+
+func createStatusSyncReconciler(k8sClient client.Client, netBoxMock *mock.NetBoxMock, fileReaderMock credentials.FileReader, rules []StatusSyncRule, enableIronCore bool) *StatusSyncReconciler {
+	return &StatusSyncReconciler{
+		k8sClient:      k8sClient,
+		credentials:    credentials.NewDefaultCredentials(fileReaderMock),
+		netBox:         netBoxMock,
+		rules:          rules,
+		enableIronCore: enableIronCore,
+	}
+}
@@ -170,8 +170,9 @@ func SetupTest(fileReaderMock *mock.FileReaderMock, netBoxMock *mock.NetBoxMock)
 
 		// Create credentials and register reconciler
 		creds := credentials.NewDefaultCredentials(fileReaderMock)
-		r := NewIPUpdateReconciler(mgr, creds, netBoxMock)
-		if err := r.SetupWithManager(mgr); err != nil {
+		r := NewIPUpdateReconciler(mgr, creds, netBoxMock, IPConflictPolicyReport, DefaultInterfaceRules(), nil, 0, IPDriftPolicyRepair, IPDeletionPolicyDelete)
+		rateLimiter := RateLimiter{Burst: 200, Frequency: 30, BaseDelay: time.Second, FailureMaxDelay: time.Minute}
+		if err := r.SetupWithManager(mgr, rateLimiter, 1, IPUpdateFilter{}); err != nil {
 			Expect(err).ToNot(HaveOccurred())
 		}
 
@@ -241,5 +242,7 @@ func createFakeClient(objects ...client.Object) client.Client {
 	Expect(bmov1alpha1.AddToScheme(scheme)).Should(Succeed())
 	Expect(ipamv1.AddToScheme(scheme)).Should(Succeed())
 
-	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).WithStatusSubresource(objects...).Build()
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).WithStatusSubresource(objects...).
+		WithIndex(&metalv1alpha1.Server{}, serverBMCRefField, serverBMCRefIndex).
+		Build()
 }
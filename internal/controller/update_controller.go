@@ -87,10 +87,15 @@ func (r *UpdateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 	updateCR := &argorav1alpha1.Update{}
 	err := r.k8sClient.Get(ctx, req.NamespacedName, updateCR)
 	if err != nil {
+		if apierrors.IsNotFound(err) {
+			deleteReconcileMetrics(controllerNameUpdate, req.NamespacedName)
+		}
 		logger.Error(err, "unable to get Update CR")
 		return ctrl.Result{}, err
 	}
+	recordReconcileStarted(controllerNameUpdate, updateCR)
 
+	observeReload := observePhase(controllerNameUpdate, phaseNetboxReload)
 	err = r.credentials.Reload()
 	if err != nil {
 		logger.Error(err, "unable to reload credentials")
@@ -116,19 +121,24 @@ func (r *UpdateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 
 		return ctrl.Result{}, err
 	}
+	observeReload()
 
+	observeClusters := observePhase(controllerNameUpdate, phaseClusters)
 	for _, clusterSelector := range updateCR.Spec.Clusters {
 		err = r.reconcileClusterSelection(ctx, updateCR, clusterSelector)
 		if err != nil {
 			return ctrl.Result{}, err
 		}
 	}
+	observeClusters()
 
 	r.statusHandler.SetCondition(updateCR, argorav1alpha1.NewReasonWithMessage(argorav1alpha1.ConditionReasonUpdateSucceeded))
 	if errUpdateStatus := r.statusHandler.UpdateToReady(ctx, updateCR); errUpdateStatus != nil {
 		return ctrl.Result{}, errUpdateStatus
 	}
 
+	recordReconcileSuccess(controllerNameUpdate, updateCR)
+
 	return ctrl.Result{RequeueAfter: r.reconcileInterval}, nil
 }
 
@@ -164,8 +174,10 @@ func (r *UpdateReconciler) reconcileClusterSelection(ctx context.Context, update
 		}
 
 		for _, device := range devices {
-			err = r.reconcileDevice(ctx, r.netBox, &device)
+			recordDeviceProcessed(controllerNameUpdate, updateCR, cluster.Name)
+			err = r.reconcileDevice(ctx, updateCR, r.netBox, &cluster, &device)
 			if err != nil {
+				recordDeviceResult(controllerNameUpdate, updateCR, cluster.Name, deviceResultFailed)
 				logger.Error(err, "unable to reconcile device", "cluster", cluster.Name, "clusterID", cluster.ID, "device", device.Name, "deviceID", device.ID)
 
 				r.statusHandler.SetCondition(updateCR, argorav1alpha1.NewReasonWithMessage(argorav1alpha1.ConditionReasonUpdateFailed))
@@ -180,12 +192,13 @@ func (r *UpdateReconciler) reconcileClusterSelection(ctx context.Context, update
 	return nil
 }
 
-func (r *UpdateReconciler) reconcileDevice(ctx context.Context, netBox netbox.Netbox, device *models.Device) error {
+func (r *UpdateReconciler) reconcileDevice(ctx context.Context, updateCR *argorav1alpha1.Update, netBox netbox.Netbox, cluster *models.Cluster, device *models.Device) error {
 	logger := log.FromContext(ctx)
 	logger.Info("reconciling device", "device", device.Name, "ID", device.ID)
 
 	if !slices.Contains([]string{deviceStatusActive, deviceStatusStaged}, device.Status.Value) {
 		logger.Info("device is neither active or staged, will skip", "status", device.Status.Value)
+		recordDeviceResult(controllerNameUpdate, updateCR, cluster.Name, deviceResultSkipped)
 		return nil
 	}
 
@@ -205,6 +218,7 @@ func (r *UpdateReconciler) reconcileDevice(ctx context.Context, netBox netbox.Ne
 		return fmt.Errorf("unable to update BMC hostname for device %s: %w", device.Name, err)
 	}
 
+	recordDeviceResult(controllerNameUpdate, updateCR, cluster.Name, deviceResultUpdated)
 	return nil
 }
 
@@ -11,6 +11,7 @@ import (
 	metalv1alpha1 "github.com/ironcore-dev/metal-operator/api/v1alpha1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -55,6 +56,10 @@ var _ = Describe("Update Controller", func() {
 			},
 		}
 
+		devicesResult := func(result string) float64 {
+			return testutil.ToFloat64(devicesTotal.WithLabelValues(controllerNameUpdate, resourceNamespace, resourceName, "cluster1", result))
+		}
+
 		expectStatus := func(state argorav1alpha1.State, description string) {
 			err := k8sClient.Get(ctx, typeNamespacedUpdateName, update)
 			Expect(err).ToNot(HaveOccurred())
@@ -182,6 +187,7 @@ var _ = Describe("Update Controller", func() {
 			// given
 			netBoxMock := prepareNetboxMock()
 			controllerReconciler := createUpdateReconciler(netBoxMock, fileReaderMock)
+			updated := devicesResult(deviceResultUpdated)
 
 			// when
 			By("reconciling Update CR")
@@ -191,6 +197,9 @@ var _ = Describe("Update Controller", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(res.RequeueAfter).To(Equal(reconcileInterval))
 
+			Expect(devicesResult(deviceResultUpdated)).To(Equal(updated + 1))
+			Expect(testutil.ToFloat64(lastSuccessfulReconcile.WithLabelValues(controllerNameUpdate, resourceNamespace, resourceName))).To(BeNumerically(">", 0))
+
 			Expect(netBoxMock.VirtualizationMock.(*mock.VirtualizationMock).GetClustersByNameRegionTypeCalls).To(Equal(1))
 			Expect(netBoxMock.DCIMMock.(*mock.DCIMMock).GetDevicesByClusterIDCalls).To(Equal(1))
 			Expect(netBoxMock.DCIMMock.(*mock.DCIMMock).GetInterfacesForDeviceCalls).To(Equal(2))   // called twice: once for the device and once for the remoteboard interface
@@ -443,6 +452,7 @@ var _ = Describe("Update Controller", func() {
 			}
 
 			controllerReconciler := createUpdateReconciler(netBoxMock, fileReaderMock)
+			skipped := devicesResult(deviceResultSkipped)
 
 			// when
 			By("reconciling Update CR")
@@ -451,6 +461,7 @@ var _ = Describe("Update Controller", func() {
 			// then
 			Expect(err).ToNot(HaveOccurred())
 			Expect(res.RequeueAfter).To(Equal(reconcileInterval))
+			Expect(devicesResult(deviceResultSkipped)).To(Equal(skipped + 1))
 
 			expectStatus(argorav1alpha1.Ready, "")
 		})
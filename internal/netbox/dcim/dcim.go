@@ -5,6 +5,7 @@
 package dcim
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/go-logr/logr"
@@ -18,6 +19,8 @@ type DCIM interface {
 	GetDevicesByClusterID(clusterID int) ([]models.Device, error)
 	GetRoleByName(roleName string) (*models.DeviceRole, error)
 	GetRegionForDevice(device *models.Device) (string, error)
+	GetSiteGroupForDevice(device *models.Device) (string, error)
+	GetConfigContextForDevice(device *models.Device) (map[string]any, error)
 	GetInterfaceByID(id int) (*models.Interface, error)
 	GetInterfacesForDevice(device *models.Device) ([]models.Interface, error)
 	GetInterfaceForDevice(device *models.Device, ifaceName string) (*models.Interface, error)
@@ -26,6 +29,7 @@ type DCIM interface {
 
 	UpdateDevice(device models.WritableDeviceWithConfigContext) (*models.Device, error)
 	UpdateInterface(iface models.WritableInterface, id int) (*models.Interface, error)
+	ClearDevicePrimaryIP(ctx context.Context, deviceID int, ipv6 bool) error
 
 	DeleteInterface(id int) error
 }
@@ -110,6 +114,31 @@ func (d *DCIMService) GetRegionForDevice(device *models.Device) (string, error)
 	return region.Slug, nil
 }
 
+func (d *DCIMService) GetSiteGroupForDevice(device *models.Device) (string, error) {
+	d.logger.V(1).Info("get site", "ID", device.Site.ID)
+	site, err := d.netboxAPI.GetSite(device.Site.ID)
+	if err != nil {
+		return "", fmt.Errorf("unable to get site for ID %d: %w", device.Site.ID, err)
+	}
+	return site.Group.Slug, nil
+}
+
+func (d *DCIMService) GetConfigContextForDevice(device *models.Device) (map[string]any, error) {
+	d.logger.V(1).Info("get device with config context", "ID", device.ID)
+	res, err := d.netboxAPI.GetDeviceWithContext(device.ID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get device with config context for ID %d: %w", device.ID, err)
+	}
+	if res.ConfigContext == nil {
+		return map[string]any{}, nil
+	}
+	configContext, ok := res.ConfigContext.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected config context type %T for device ID %d", res.ConfigContext, device.ID)
+	}
+	return configContext, nil
+}
+
 func (d *DCIMService) GetInterfaceByID(id int) (*models.Interface, error) {
 	listInterfacesRequest := NewListInterfacesRequest(
 		InterfaceWithID(id),
@@ -0,0 +1,31 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package dcim
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/sapcc/argora/internal/netbox/rest"
+)
+
+// ClearDevicePrimaryIP unsets the primary IPv4 or IPv6 address of the device. go-netbox-go omits unset primary
+// addresses on device updates, hence the device is patched directly.
+func (d *DCIMService) ClearDevicePrimaryIP(ctx context.Context, deviceID int, ipv6 bool) error {
+	field := "primary_ip4"
+	if ipv6 {
+		field = "primary_ip6"
+	}
+	fields := map[string]any{field: nil}
+
+	u := d.netboxAPI.BaseURL().JoinPath("/api/dcim/devices/", strconv.Itoa(deviceID), "/")
+
+	d.logger.V(1).Info("patch device", "url", u.String(), "fields", fields)
+	if err := rest.Do(ctx, d.netboxAPI, http.MethodPatch, u, fields, nil); err != nil {
+		return fmt.Errorf("unable to clear %s of device (%d): %w", field, deviceID, err)
+	}
+	return nil
+}
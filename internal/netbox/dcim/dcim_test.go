@@ -4,8 +4,11 @@
 package dcim_test
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 
@@ -395,6 +398,74 @@ var _ = Describe("DCIM", func() {
 		})
 	})
 
+	Describe("GetSiteGroupForDevice", func() {
+		It("should return the site group slug when found", func() {
+			mockClient.GetSiteFunc = func(id int) (*models.Site, error) {
+				Expect(id).To(Equal(1))
+				return &models.Site{
+					Group: models.NestedSiteGroup{ID: 2, Slug: "group1"},
+				}, nil
+			}
+
+			siteGroup, err := dcimService.GetSiteGroupForDevice(&models.Device{Site: models.NestedSite{ID: 1}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(siteGroup).To(Equal("group1"))
+		})
+
+		It("should return an empty slug when the site has no group", func() {
+			mockClient.GetSiteFunc = func(id int) (*models.Site, error) {
+				return &models.Site{}, nil
+			}
+
+			siteGroup, err := dcimService.GetSiteGroupForDevice(&models.Device{Site: models.NestedSite{ID: 1}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(siteGroup).To(BeEmpty())
+		})
+
+		It("should return an error when site is not found", func() {
+			mockClient.GetSiteFunc = func(id int) (*models.Site, error) {
+				return nil, fmt.Errorf("site not found")
+			}
+
+			_, err := dcimService.GetSiteGroupForDevice(&models.Device{Site: models.NestedSite{ID: 1}})
+			Expect(err).To(MatchError("unable to get site for ID 1: site not found"))
+		})
+	})
+
+	Describe("GetConfigContextForDevice", func() {
+		It("should return the config context of the device", func() {
+			mockClient.GetDeviceWithContextFunc = func(id int) (*models.Device, error) {
+				Expect(id).To(Equal(1))
+				return &models.Device{
+					ConfigContext: map[string]any{"kernel": map[string]any{"args": "quiet"}},
+				}, nil
+			}
+
+			configContext, err := dcimService.GetConfigContextForDevice(&models.Device{ID: 1})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(configContext).To(Equal(map[string]any{"kernel": map[string]any{"args": "quiet"}}))
+		})
+
+		It("should return an empty config context when the device has none", func() {
+			mockClient.GetDeviceWithContextFunc = func(id int) (*models.Device, error) {
+				return &models.Device{}, nil
+			}
+
+			configContext, err := dcimService.GetConfigContextForDevice(&models.Device{ID: 1})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(configContext).To(BeEmpty())
+		})
+
+		It("should return an error when the device is not found", func() {
+			mockClient.GetDeviceWithContextFunc = func(id int) (*models.Device, error) {
+				return nil, fmt.Errorf("device not found")
+			}
+
+			_, err := dcimService.GetConfigContextForDevice(&models.Device{ID: 1})
+			Expect(err).To(MatchError("unable to get device with config context for ID 1: device not found"))
+		})
+	})
+
 	Describe("GetInterfaceByID", func() {
 		It("should return the interface when found", func() {
 			mockClient.ListInterfacesFunc = func(opts models.ListInterfacesRequest) (*models.ListInterfacesResponse, error) {
@@ -650,4 +721,47 @@ var _ = Describe("DCIM", func() {
 			Expect(err).To(MatchError("unable to delete interface (1): delete failed"))
 		})
 	})
+
+	Describe("ClearDevicePrimaryIP", func() {
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			mockClient.AuthTokenFunc = func() string { return "token" }
+			mockClient.HTTPClientFunc = func() *http.Client { return server.Client() }
+			mockClient.BaseURLFunc = func() *url.URL {
+				u, err := url.Parse(server.URL)
+				Expect(err).ToNot(HaveOccurred())
+				return u
+			}
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should clear the primary IPv6 address of the device", func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.Method).To(Equal(http.MethodPatch))
+				Expect(r.URL.Path).To(Equal("/api/dcim/devices/7/"))
+				Expect(r.Header.Get("Authorization")).To(Equal("Token token"))
+				body, err := io.ReadAll(r.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(body).To(MatchJSON(`{"primary_ip6": null}`))
+				fmt.Fprint(w, `{"id": 7}`)
+			}))
+
+			err := dcimService.ClearDevicePrimaryIP(context.Background(), 7, true)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should return an error on unexpected status codes", func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				http.Error(w, "bad request", http.StatusBadRequest)
+			}))
+
+			err := dcimService.ClearDevicePrimaryIP(context.Background(), 7, false)
+			Expect(err).To(MatchError(ContainSubstring("unable to clear primary_ip4 of device (7): unexpected return code of 400")))
+		})
+	})
 })
@@ -5,8 +5,10 @@
 package ipam
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/go-logr/logr"
 	"github.com/sapcc/go-netbox-go/ipam"
@@ -21,12 +23,19 @@ type IPAM interface {
 	GetIPAddressesForInterface(interfaceID int) ([]models.IPAddress, error)
 	GetIPAddressForInterface(interfaceID int) (*models.IPAddress, error)
 	GetPrefixesContaining(contains string) ([]models.Prefix, error)
-	GetPrefixesByRegionRole(region, role string) ([]models.Prefix, error)
+	GetPrefixes(ctx context.Context, opts ...ListPrefixesRequestOption) ([]models.Prefix, error)
+	GetVlansByGroup(group string) ([]models.Vlan, error)
+	GetVrfsByName(name string) ([]models.VRF, error)
 	CreateIPAddress(addr CreateIPAddressParams) (*models.IPAddress, error)
 	UpdateIPAddress(addr models.WriteableIPAddress) (*models.IPAddress, error)
 	GetPrefixesByPrefix(prefix string) ([]models.Prefix, error)
+	GetIPAddresses(ctx context.Context, opts ...ListIPAddressesRequestOption) ([]models.IPAddress, error)
+	GetIPRangesInPrefix(ctx context.Context, prefix string, vrfID int) ([]IPRange, error)
+	CreateAvailablePrefix(ctx context.Context, containerID int, params CreateAvailablePrefixParams) (*models.Prefix, error)
 
+	UnassignIPAddress(ctx context.Context, id int) error
 	DeleteIPAddress(id int) error
+	DeletePrefix(id int) error
 }
 
 type IPAMService struct {
@@ -83,6 +92,24 @@ func (i *IPAMService) GetIPAddressesForInterface(interfaceID int) ([]models.IPAd
 	return res.Results, nil
 }
 
+// GetIPAddresses returns all IP addresses matching the request options, e.g. the IP addresses in a prefix
+// carrying a tag. It returns no error if no IP address matches.
+func (i *IPAMService) GetIPAddresses(ctx context.Context, opts ...ListIPAddressesRequestOption) ([]models.IPAddress, error) {
+	var addresses []models.IPAddress
+	for {
+		res, err := i.listIPAddresses(ctx, NewListIPAddressesRequest(
+			append(slices.Clip(opts), IPAddressesWithOffset(len(addresses)))...,
+		).BuildQuery())
+		if err != nil {
+			return nil, fmt.Errorf("unable to list IP addresses: %w", err)
+		}
+		addresses = append(addresses, res.Results...)
+		if len(res.Results) == 0 || len(addresses) >= res.Count {
+			return addresses, nil
+		}
+	}
+}
+
 func (i *IPAMService) GetIPAddressForInterface(interfaceID int) (*models.IPAddress, error) {
 	i.logger.V(1).Info("get IP addresses for interface", "ID", interfaceID)
 	ifaces, err := i.GetIPAddressesForInterface(interfaceID)
@@ -110,18 +137,49 @@ func (i *IPAMService) GetPrefixesContaining(contains string) ([]models.Prefix, e
 	return res.Results, nil
 }
 
-func (i *IPAMService) GetPrefixesByRegionRole(region, role string) ([]models.Prefix, error) {
-	ListPrefixesRequest := NewListPrefixesRequest(
-		PrefixWithRegion(region),
-		PrefixWithRole(role),
-	).BuildRequest()
-	i.logger.V(1).Info("list prefixes", "request", ListPrefixesRequest)
-	res, err := i.netboxAPI.ListPrefixes(ListPrefixesRequest)
-	if err != nil {
-		return nil, fmt.Errorf("unable to list prefixes in region %s with role %s: %w", region, role, err)
+// GetPrefixes returns all prefixes matching the request options. Unlike the other prefix lookups it returns
+// no error if no prefix matches.
+func (i *IPAMService) GetPrefixes(ctx context.Context, opts ...ListPrefixesRequestOption) ([]models.Prefix, error) {
+	var prefixes []models.Prefix
+	for {
+		res, err := i.listPrefixes(ctx, NewListPrefixesRequest(
+			append(slices.Clip(opts), PrefixWithOffset(len(prefixes)))...,
+		).BuildQuery())
+		if err != nil {
+			return nil, fmt.Errorf("unable to list prefixes: %w", err)
+		}
+		prefixes = append(prefixes, res.Results...)
+		if len(res.Results) == 0 || len(prefixes) >= res.Count {
+			return prefixes, nil
+		}
 	}
-	if len(res.Results) == 0 {
-		return nil, fmt.Errorf("prefixes in region %s with role %s not found", region, role)
+}
+
+func (i *IPAMService) GetVlansByGroup(group string) ([]models.Vlan, error) {
+	var vlans []models.Vlan
+	for {
+		listVlanRequest := NewListVlanRequest(
+			VlanWithGroup(group),
+			VlanWithOffset(len(vlans)),
+		).BuildRequest()
+		i.logger.V(1).Info("list VLANs", "request", listVlanRequest)
+		res, err := i.netboxAPI.ListVlans(listVlanRequest)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list VLANs in group %s: %w", group, err)
+		}
+		vlans = append(vlans, res.Results...)
+		if len(res.Results) == 0 || len(vlans) >= res.Count {
+			return vlans, nil
+		}
+	}
+}
+
+func (i *IPAMService) GetVrfsByName(name string) ([]models.VRF, error) {
+	listVRFsRequest := models.ListVRFsRequest{Name: name}
+	i.logger.V(1).Info("list VRFs", "request", listVRFsRequest)
+	res, err := i.netboxAPI.ListVRFs(listVRFsRequest)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list VRFs by name %s: %w", name, err)
 	}
 	return res.Results, nil
 }
@@ -147,11 +205,27 @@ func (i *IPAMService) DeleteIPAddress(id int) error {
 	return nil
 }
 
+func (i *IPAMService) DeletePrefix(id int) error {
+	i.logger.V(1).Info("delete prefix", "ID", id)
+	err := i.netboxAPI.DeletePrefix(id)
+	if err != nil {
+		return fmt.Errorf("unable to delete prefix (%d): %w", id, err)
+	}
+	return nil
+}
+
 type CreateIPAddressParams struct {
 	Address     string
 	TenantID    int
 	InterfaceID int
 	VrfID       int
+
+	DNSName      string
+	Status       string
+	Role         string
+	Description  string
+	Tags         []models.NestedTag
+	CustomFields map[string]any
 }
 
 func (i *IPAMService) CreateIPAddress(params CreateIPAddressParams) (*models.IPAddress, error) {
@@ -163,6 +237,14 @@ func (i *IPAMService) CreateIPAddress(params CreateIPAddressParams) (*models.IPA
 		Tenant:             params.TenantID,
 		AssignedObjectType: "dcim.interface",
 		AssignedObjectID:   params.InterfaceID,
+		DNSName:            params.DNSName,
+		Status:             params.Status,
+		Role:               params.Role,
+		Description:        params.Description,
+		Tags:               params.Tags,
+	}
+	if len(params.CustomFields) > 0 {
+		addr.CustomFields = params.CustomFields
 	}
 	i.logger.V(1).Info("create ipaddress", "addr", addr)
 	res, err := i.netboxAPI.CreateIPAddress(addr)
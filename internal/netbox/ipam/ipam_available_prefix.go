@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/sapcc/go-netbox-go/models"
+
+	"github.com/sapcc/argora/internal/netbox/rest"
+)
+
+type CreateAvailablePrefixParams struct {
+	PrefixLength int
+	SiteID       int
+	VrfID        int
+	TenantID     int
+
+	Status      string
+	Description string
+	Tags        []models.NestedTag
+}
+
+type createAvailablePrefixRequest struct {
+	PrefixLength int                `json:"prefix_length"`
+	Site         int                `json:"site,omitempty"`
+	Vrf          int                `json:"vrf,omitempty"`
+	Tenant       int                `json:"tenant,omitempty"`
+	Status       string             `json:"status,omitempty"`
+	Description  string             `json:"description,omitempty"`
+	Tags         []models.NestedTag `json:"tags,omitempty"`
+}
+
+// CreateAvailablePrefix allocates the first available child prefix with the given length in the container prefix,
+// setting its attributes in the same request. go-netbox-go only sends the prefix length on allocations, hence the
+// available prefixes are posted directly.
+func (i *IPAMService) CreateAvailablePrefix(ctx context.Context, containerID int, params CreateAvailablePrefixParams) (*models.Prefix, error) {
+	opts := createAvailablePrefixRequest{
+		PrefixLength: params.PrefixLength,
+		Site:         params.SiteID,
+		Vrf:          params.VrfID,
+		Tenant:       params.TenantID,
+		Status:       params.Status,
+		Description:  params.Description,
+		Tags:         params.Tags,
+	}
+
+	u := i.netboxAPI.BaseURL().JoinPath("/api/ipam/prefixes/", strconv.Itoa(containerID), "/available-prefixes/")
+
+	i.logger.V(1).Info("create available prefix", "url", u.String(), "request", opts)
+	prefix := &models.Prefix{}
+	if err := rest.Do(ctx, i.netboxAPI, http.MethodPost, u, opts, prefix); err != nil {
+		return nil, fmt.Errorf("unable to create available prefix in prefix (%d): %w", containerID, err)
+	}
+	return prefix, nil
+}
@@ -0,0 +1,28 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/sapcc/go-netbox-go/models"
+
+	"github.com/sapcc/argora/internal/netbox/rest"
+)
+
+// listIPAddresses lists the IP addresses matching the query. go-netbox-go does not filter IP addresses by tag
+// and several roles, hence the IP addresses are listed directly.
+func (i *IPAMService) listIPAddresses(ctx context.Context, query url.Values) (*models.ListIPAddressesResponse, error) {
+	u := i.netboxAPI.BaseURL().JoinPath("/api/ipam/ip-addresses/")
+	u.RawQuery = query.Encode()
+
+	i.logger.V(1).Info("list IP addresses", "url", u.String())
+	res := &models.ListIPAddressesResponse{}
+	if err := rest.Do(ctx, i.netboxAPI, http.MethodGet, u, nil, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
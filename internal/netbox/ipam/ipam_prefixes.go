@@ -0,0 +1,28 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/sapcc/go-netbox-go/models"
+
+	"github.com/sapcc/argora/internal/netbox/rest"
+)
+
+// listPrefixes lists the prefixes matching the query. go-netbox-go does not filter prefixes by tenant, several VRFs
+// and family, hence the prefixes are listed directly.
+func (i *IPAMService) listPrefixes(ctx context.Context, query url.Values) (*models.ListPrefixesReponse, error) {
+	u := i.netboxAPI.BaseURL().JoinPath("/api/ipam/prefixes/")
+	u.RawQuery = query.Encode()
+
+	i.logger.V(1).Info("list prefixes", "url", u.String())
+	res := &models.ListPrefixesReponse{}
+	if err := rest.Do(ctx, i.netboxAPI, http.MethodGet, u, nil, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/sapcc/go-netbox-go/common"
+	"github.com/sapcc/go-netbox-go/models"
+
+	"github.com/sapcc/argora/internal/netbox/rest"
+)
+
+// IPRange is a NetBox IP range. IP ranges are not supported by go-netbox-go, hence they are listed directly.
+type IPRange struct {
+	ID           int                    `json:"id"`
+	StartAddress string                 `json:"start_address"`
+	EndAddress   string                 `json:"end_address"`
+	Status       models.IPAddressStatus `json:"status"`
+	Description  string                 `json:"description"`
+}
+
+type listIPRangesResponse struct {
+	common.ReturnValues
+	Results []IPRange `json:"results"`
+}
+
+// GetIPRangesInPrefix returns the IP ranges in the prefix of the VRF, or of the global VRF if the ID is 0.
+func (i *IPAMService) GetIPRangesInPrefix(ctx context.Context, prefix string, vrfID int) ([]IPRange, error) {
+	var ranges []IPRange
+	for {
+		res, err := i.listIPRanges(ctx, prefix, vrfID, len(ranges))
+		if err != nil {
+			return nil, fmt.Errorf("unable to list IP ranges in prefix %s: %w", prefix, err)
+		}
+		ranges = append(ranges, res.Results...)
+		if len(res.Results) == 0 || len(ranges) >= res.Count {
+			return ranges, nil
+		}
+	}
+}
+
+func (i *IPAMService) listIPRanges(ctx context.Context, parent string, vrfID, offset int) (*listIPRangesResponse, error) {
+	u := i.netboxAPI.BaseURL().JoinPath("/api/ipam/ip-ranges/")
+	q := u.Query()
+	q.Set("parent", parent)
+	q.Set("limit", strconv.Itoa(listPageSize))
+	q.Set("vrf_id", vrfIDQueryValue(vrfID))
+	if offset != 0 {
+		q.Set("offset", strconv.Itoa(offset))
+	}
+	u.RawQuery = q.Encode()
+
+	i.logger.V(1).Info("list IP ranges", "url", u.String())
+	res := &listIPRangesResponse{}
+	if err := rest.Do(ctx, i.netboxAPI, http.MethodGet, u, nil, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
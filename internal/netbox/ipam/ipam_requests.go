@@ -4,11 +4,20 @@
 package ipam
 
 import (
+	"net/url"
+	"strconv"
+
 	"github.com/sapcc/go-netbox-go/models"
 )
 
+// listPageSize is the page size of list requests which may return more objects than the NetBox default page size.
+const listPageSize = 1000
+
 type ListVlanRequest struct {
-	name string
+	name   string
+	group  string
+	offset int
+	paged  bool
 }
 
 type ListVlanRequestOption func(c *ListVlanRequest)
@@ -30,17 +39,47 @@ func VlanWithName(name string) ListVlanRequestOption {
 	return opt
 }
 
+func VlanWithGroup(group string) ListVlanRequestOption {
+	opt := func(r *ListVlanRequest) {
+		r.group = group
+	}
+
+	return opt
+}
+
+func VlanWithOffset(offset int) ListVlanRequestOption {
+	opt := func(r *ListVlanRequest) {
+		r.offset = offset
+		r.paged = true
+	}
+
+	return opt
+}
+
 func (r *ListVlanRequest) BuildRequest() models.ListVlanRequest {
 	listVlanRequest := models.ListVlanRequest{}
 	if r.name != "" {
 		listVlanRequest.Name = r.name
 	}
+	if r.group != "" {
+		listVlanRequest.Group = r.group
+	}
+	if r.paged {
+		listVlanRequest.Limit = listPageSize
+		listVlanRequest.OffSet = r.offset
+	}
 	return listVlanRequest
 }
 
 type ListIPAddressesRequest struct {
 	interfaceID int
 	address     string
+	parent      string
+	vrfID       *int
+	tag         string
+	roles       []string
+	offset      int
+	paged       bool
 }
 
 type ListIPAddressesRequestOption func(c *ListIPAddressesRequest)
@@ -70,8 +109,59 @@ func IPAddressesWithAddress(address string) ListIPAddressesRequestOption {
 	return opt
 }
 
+func IPAddressesWithParent(parent string) ListIPAddressesRequestOption {
+	opt := func(r *ListIPAddressesRequest) {
+		r.parent = parent
+	}
+
+	return opt
+}
+
+// IPAddressesWithVrfID selects the IP addresses of the VRF, or of the global VRF if the ID is 0.
+func IPAddressesWithVrfID(vrfID int) ListIPAddressesRequestOption {
+	opt := func(r *ListIPAddressesRequest) {
+		r.vrfID = &vrfID
+	}
+
+	return opt
+}
+
+func IPAddressesWithTag(tag string) ListIPAddressesRequestOption {
+	opt := func(r *ListIPAddressesRequest) {
+		r.tag = tag
+	}
+
+	return opt
+}
+
+// IPAddressesWithRole selects the IP addresses with any of the roles.
+func IPAddressesWithRole(roles ...string) ListIPAddressesRequestOption {
+	opt := func(r *ListIPAddressesRequest) {
+		r.roles = roles
+	}
+
+	return opt
+}
+
+func IPAddressesWithOffset(offset int) ListIPAddressesRequestOption {
+	opt := func(r *ListIPAddressesRequest) {
+		r.offset = offset
+		r.paged = true
+	}
+
+	return opt
+}
+
 func (r *ListIPAddressesRequest) BuildRequest() models.ListIPAddressesRequest {
 	listIPAddressesRequest := models.ListIPAddressesRequest{}
+	if r.parent != "" {
+		listIPAddressesRequest.Parent = r.parent
+		listIPAddressesRequest.Limit = listPageSize
+		listIPAddressesRequest.OffSet = r.offset
+	}
+	if r.vrfID != nil {
+		listIPAddressesRequest.VrfID = *r.vrfID
+	}
 	if r.interfaceID != 0 {
 		listIPAddressesRequest.InterfaceID = r.interfaceID
 	}
@@ -81,11 +171,50 @@ func (r *ListIPAddressesRequest) BuildRequest() models.ListIPAddressesRequest {
 	return listIPAddressesRequest
 }
 
+// BuildQuery returns the query parameters of the request. Unlike BuildRequest, it includes the tag and role filters,
+// which go-netbox-go does not support.
+func (r *ListIPAddressesRequest) BuildQuery() url.Values {
+	q := url.Values{}
+	if r.parent != "" {
+		q.Set("parent", r.parent)
+	}
+	if r.vrfID != nil {
+		q.Set("vrf_id", vrfIDQueryValue(*r.vrfID))
+	}
+	if r.interfaceID != 0 {
+		q.Set("interface_id", strconv.Itoa(r.interfaceID))
+	}
+	if r.address != "" {
+		q.Set("address", r.address)
+	}
+	if r.tag != "" {
+		q.Set("tag", r.tag)
+	}
+	for _, role := range r.roles {
+		q.Add("role", role)
+	}
+	if r.paged {
+		q.Set("limit", strconv.Itoa(listPageSize))
+		q.Set("offset", strconv.Itoa(r.offset))
+	}
+	return q
+}
+
 type ListPrefixesRequest struct {
-	contains string
-	region   string
-	role     string
-	prefix   string
+	contains   string
+	region     string
+	role       string
+	prefix     string
+	site       string
+	tag        string
+	tenant     string
+	vrfIDs     []int
+	family     int
+	status     string
+	maskLength int
+	within     string
+	offset     int
+	paged      bool
 }
 
 type ListPrefixesRequestOption func(c *ListPrefixesRequest)
@@ -131,6 +260,80 @@ func PrefixWithPrefix(prefix string) ListPrefixesRequestOption {
 	return opt
 }
 
+func PrefixWithSite(site string) ListPrefixesRequestOption {
+	opt := func(r *ListPrefixesRequest) {
+		r.site = site
+	}
+
+	return opt
+}
+
+func PrefixWithTag(tag string) ListPrefixesRequestOption {
+	opt := func(r *ListPrefixesRequest) {
+		r.tag = tag
+	}
+
+	return opt
+}
+
+func PrefixWithTenant(tenant string) ListPrefixesRequestOption {
+	opt := func(r *ListPrefixesRequest) {
+		r.tenant = tenant
+	}
+
+	return opt
+}
+
+// PrefixWithVrf selects the prefixes in any of the VRFs.
+func PrefixWithVrf(vrfIDs ...int) ListPrefixesRequestOption {
+	opt := func(r *ListPrefixesRequest) {
+		r.vrfIDs = vrfIDs
+	}
+
+	return opt
+}
+
+func PrefixWithFamily(family int) ListPrefixesRequestOption {
+	opt := func(r *ListPrefixesRequest) {
+		r.family = family
+	}
+
+	return opt
+}
+
+func PrefixWithStatus(status string) ListPrefixesRequestOption {
+	opt := func(r *ListPrefixesRequest) {
+		r.status = status
+	}
+
+	return opt
+}
+
+func PrefixWithMaskLength(maskLength int) ListPrefixesRequestOption {
+	opt := func(r *ListPrefixesRequest) {
+		r.maskLength = maskLength
+	}
+
+	return opt
+}
+
+func PrefixWithin(within string) ListPrefixesRequestOption {
+	opt := func(r *ListPrefixesRequest) {
+		r.within = within
+	}
+
+	return opt
+}
+
+func PrefixWithOffset(offset int) ListPrefixesRequestOption {
+	opt := func(r *ListPrefixesRequest) {
+		r.offset = offset
+		r.paged = true
+	}
+
+	return opt
+}
+
 func (r *ListPrefixesRequest) BuildRequest() models.ListPrefixesRequest {
 	listPrefixesRequest := models.ListPrefixesRequest{}
 	if r.contains != "" {
@@ -145,5 +348,79 @@ func (r *ListPrefixesRequest) BuildRequest() models.ListPrefixesRequest {
 	if r.prefix != "" {
 		listPrefixesRequest.Prefix = r.prefix
 	}
+	if r.site != "" {
+		listPrefixesRequest.Site = r.site
+	}
+	if r.tag != "" {
+		listPrefixesRequest.Tag = r.tag
+	}
+	if r.status != "" {
+		listPrefixesRequest.Status = r.status
+	}
+	if r.maskLength != 0 {
+		listPrefixesRequest.MaskLength = r.maskLength
+	}
+	if r.within != "" {
+		listPrefixesRequest.Within = r.within
+	}
+	if r.paged {
+		listPrefixesRequest.Limit = listPageSize
+		listPrefixesRequest.OffSet = r.offset
+	}
 	return listPrefixesRequest
 }
+
+// BuildQuery returns the query parameters of the request. Unlike BuildRequest, it includes the tenant, VRF and family
+// filters, which go-netbox-go does not support.
+func (r *ListPrefixesRequest) BuildQuery() url.Values {
+	q := url.Values{}
+	if r.contains != "" {
+		q.Set("contains", r.contains)
+	}
+	if r.region != "" {
+		q.Set("region", r.region)
+	}
+	if r.role != "" {
+		q.Set("role", r.role)
+	}
+	if r.prefix != "" {
+		q.Set("prefix", r.prefix)
+	}
+	if r.site != "" {
+		q.Set("site", r.site)
+	}
+	if r.tag != "" {
+		q.Set("tag", r.tag)
+	}
+	if r.tenant != "" {
+		q.Set("tenant", r.tenant)
+	}
+	for _, vrfID := range r.vrfIDs {
+		q.Add("vrf_id", strconv.Itoa(vrfID))
+	}
+	if r.family != 0 {
+		q.Set("family", strconv.Itoa(r.family))
+	}
+	if r.status != "" {
+		q.Set("status", r.status)
+	}
+	if r.maskLength != 0 {
+		q.Set("mask_length", strconv.Itoa(r.maskLength))
+	}
+	if r.within != "" {
+		q.Set("within", r.within)
+	}
+	if r.paged {
+		q.Set("limit", strconv.Itoa(listPageSize))
+		q.Set("offset", strconv.Itoa(r.offset))
+	}
+	return q
+}
+
+// vrfIDQueryValue returns the vrf_id query value of the VRF, NetBox selects the global VRF with null.
+func vrfIDQueryValue(vrfID int) string {
+	if vrfID == 0 {
+		return "null"
+	}
+	return strconv.Itoa(vrfID)
+}
@@ -4,6 +4,8 @@
 package ipam_test
 
 import (
+	"net/url"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
@@ -25,6 +27,17 @@ var _ = Describe("BuildRequest", func() {
 
 			Expect(req.Name).To(BeEmpty())
 		})
+
+		It("should build a paged request with group", func() {
+			req := ipam.NewListVlanRequest(
+				ipam.VlanWithGroup("transit"),
+				ipam.VlanWithOffset(1000),
+			).BuildRequest()
+
+			Expect(req.Group).To(Equal("transit"))
+			Expect(req.Limit).To(Equal(1000))
+			Expect(req.OffSet).To(Equal(1000))
+		})
 	})
 
 	Context("ListIPAddressesRequest", func() {
@@ -41,6 +54,48 @@ var _ = Describe("BuildRequest", func() {
 
 			Expect(req.InterfaceID).To(BeZero())
 		})
+
+		It("should build a paged request with parent and VRF ID", func() {
+			req := ipam.NewListIPAddressesRequest(
+				ipam.IPAddressesWithParent("10.0.0.0/24"),
+				ipam.IPAddressesWithVrfID(3),
+				ipam.IPAddressesWithOffset(1000),
+			).BuildRequest()
+
+			Expect(req.Parent).To(Equal("10.0.0.0/24"))
+			Expect(req.VrfID).To(Equal(3))
+			Expect(req.Limit).To(Equal(1000))
+			Expect(req.OffSet).To(Equal(1000))
+		})
+
+		It("should build the query with the tag and role filters", func() {
+			query := ipam.NewListIPAddressesRequest(
+				ipam.IPAddressesWithParent("10.0.0.0/24"),
+				ipam.IPAddressesWithVrfID(3),
+				ipam.IPAddressesWithTag("gateway"),
+				ipam.IPAddressesWithRole("anycast", "vip"),
+			).BuildQuery()
+
+			Expect(query).To(Equal(url.Values{
+				"parent": {"10.0.0.0/24"},
+				"vrf_id": {"3"},
+				"tag":    {"gateway"},
+				"role":   {"anycast", "vip"},
+			}))
+		})
+
+		It("should select the global VRF with a null VRF ID", func() {
+			query := ipam.NewListIPAddressesRequest(
+				ipam.IPAddressesWithParent("10.0.0.0/24"),
+				ipam.IPAddressesWithVrfID(0),
+			).BuildQuery()
+
+			Expect(query).To(Equal(url.Values{
+				"parent": {"10.0.0.0/24"},
+				"vrf_id": {"null"},
+			}))
+			Expect(ipam.NewListIPAddressesRequest().BuildQuery()).ToNot(HaveKey("vrf_id"))
+		})
 	})
 
 	Context("ListPrefixesWithPrefix", func() {
@@ -57,5 +112,49 @@ var _ = Describe("BuildRequest", func() {
 
 			Expect(req.Prefix).To(BeZero())
 		})
+
+		It("should build a paged request with filters", func() {
+			req := ipam.NewListPrefixesRequest(
+				ipam.PrefixWithRegion("qa-de-1"),
+				ipam.PrefixWithRole("transit"),
+				ipam.PrefixWithSite("qa-de-1a"),
+				ipam.PrefixWithTag("k8s"),
+				ipam.PrefixWithStatus("active"),
+				ipam.PrefixWithMaskLength(24),
+				ipam.PrefixWithOffset(0),
+			).BuildRequest()
+
+			Expect(req.Region).To(Equal("qa-de-1"))
+			Expect(req.Role).To(Equal("transit"))
+			Expect(req.Site).To(Equal("qa-de-1a"))
+			Expect(req.Tag).To(Equal("k8s"))
+			Expect(req.Status).To(Equal("active"))
+			Expect(req.MaskLength).To(Equal(24))
+			Expect(req.Limit).To(Equal(1000))
+			Expect(req.OffSet).To(BeZero())
+		})
+
+		It("should build the query with the tenant, VRF and family filters", func() {
+			query := ipam.NewListPrefixesRequest(
+				ipam.PrefixWithRole("transit"),
+				ipam.PrefixWithTenant("cc"),
+				ipam.PrefixWithVrf(3, 4),
+				ipam.PrefixWithFamily(6),
+				ipam.PrefixWithOffset(1000),
+			).BuildQuery()
+
+			Expect(query).To(Equal(url.Values{
+				"role":   {"transit"},
+				"tenant": {"cc"},
+				"vrf_id": {"3", "4"},
+				"family": {"6"},
+				"limit":  {"1000"},
+				"offset": {"1000"},
+			}))
+		})
+
+		It("should build an empty query without filters", func() {
+			Expect(ipam.NewListPrefixesRequest().BuildQuery()).To(BeEmpty())
+		})
 	})
 })
@@ -4,8 +4,12 @@
 package ipam_test
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 
@@ -142,6 +146,13 @@ func (m *MockIPAMClient) ListVRFs(opts models.ListVRFsRequest) (*models.ListVRFs
 	return m.ListVRFsFunc(opts)
 }
 
+// ipAddressJSON returns an unassigned IP address like listed by NetBox, as go-netbox-go requires all its fields.
+func ipAddressJSON(id int, address string) string {
+	return fmt.Sprintf(`{"id": %d, "url": "", "address": %q, "assigned_object_type": null, "assigned_object_id": null,
+		"role": null, "tenant": null, "status": {"value": "active"}, "dns_name": "", "description": "",
+		"created": null, "last_updated": null, "tags": []}`, id, address)
+}
+
 var _ = Describe("IPAM", func() {
 	var (
 		mockClient  *MockIPAMClient
@@ -278,6 +289,125 @@ var _ = Describe("IPAM", func() {
 		})
 	})
 
+	Describe("GetIPAddresses", func() {
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			mockClient.AuthTokenFunc = func() string { return "token" }
+			mockClient.HTTPClientFunc = func() *http.Client { return server.Client() }
+			mockClient.BaseURLFunc = func() *url.URL {
+				u, err := url.Parse(server.URL)
+				Expect(err).ToNot(HaveOccurred())
+				return u
+			}
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should return the IP addresses matching the filters of all pages", func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.URL.Path).To(Equal("/api/ipam/ip-addresses/"))
+				Expect(r.Header.Get("Authorization")).To(Equal("Token token"))
+				if r.URL.Query().Get("offset") == "0" {
+					Expect(r.URL.Query()).To(Equal(url.Values{
+						"parent": {"192.168.1.0/24"},
+						"vrf_id": {"3"},
+						"role":   {"anycast", "vip"},
+						"limit":  {"1000"},
+						"offset": {"0"},
+					}))
+					fmt.Fprintf(w, `{"count": 2, "results": [%s]}`, ipAddressJSON(1, "192.168.1.1/24"))
+					return
+				}
+				Expect(r.URL.Query().Get("offset")).To(Equal("1"))
+				fmt.Fprintf(w, `{"count": 2, "results": [%s]}`, ipAddressJSON(2, "192.168.1.2/24"))
+			}))
+
+			ips, err := ipamService.GetIPAddresses(
+				context.Background(),
+				ipam.IPAddressesWithParent("192.168.1.0/24"),
+				ipam.IPAddressesWithVrfID(3),
+				ipam.IPAddressesWithRole("anycast", "vip"),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ips).To(HaveLen(2))
+			Expect(ips[1].Address).To(Equal("192.168.1.2/24"))
+		})
+
+		It("should return an error when unable to list IP addresses", func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				http.Error(w, "forbidden", http.StatusForbidden)
+			}))
+
+			_, err := ipamService.GetIPAddresses(context.Background(), ipam.IPAddressesWithTag("gateway"))
+			Expect(err).To(MatchError(ContainSubstring("unable to list IP addresses: unexpected return code of 403")))
+		})
+	})
+
+	Describe("GetIPRangesInPrefix", func() {
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			mockClient.AuthTokenFunc = func() string { return "token" }
+			mockClient.HTTPClientFunc = func() *http.Client { return server.Client() }
+			mockClient.BaseURLFunc = func() *url.URL {
+				u, err := url.Parse(server.URL)
+				Expect(err).ToNot(HaveOccurred())
+				return u
+			}
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should return the IP ranges in the prefix", func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.URL.Path).To(Equal("/api/ipam/ip-ranges/"))
+				Expect(r.URL.Query().Get("parent")).To(Equal("192.168.1.0/24"))
+				Expect(r.URL.Query().Get("vrf_id")).To(Equal("3"))
+				Expect(r.Header.Get("Authorization")).To(Equal("Token token"))
+				fmt.Fprint(w, `{"count": 1, "results": [{"id": 7, "start_address": "192.168.1.100/24", "end_address": "192.168.1.150/24", "status": {"value": "reserved"}}]}`)
+			}))
+
+			ranges, err := ipamService.GetIPRangesInPrefix(context.Background(), "192.168.1.0/24", 3)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ranges).To(Equal([]ipam.IPRange{
+				{
+					ID:           7,
+					StartAddress: "192.168.1.100/24",
+					EndAddress:   "192.168.1.150/24",
+					Status:       models.IPAddressStatus{Value: "reserved"},
+				},
+			}))
+		})
+
+		It("should select the IP ranges of the global VRF", func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.URL.Query().Get("vrf_id")).To(Equal("null"))
+				fmt.Fprint(w, `{"count": 0, "results": []}`)
+			}))
+
+			ranges, err := ipamService.GetIPRangesInPrefix(context.Background(), "192.168.1.0/24", 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ranges).To(BeEmpty())
+		})
+
+		It("should return an error on unexpected status codes", func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				http.Error(w, "forbidden", http.StatusForbidden)
+			}))
+
+			_, err := ipamService.GetIPRangesInPrefix(context.Background(), "192.168.1.0/24", 0)
+			Expect(err).To(MatchError(ContainSubstring("unable to list IP ranges in prefix 192.168.1.0/24: unexpected return code of 403")))
+		})
+	})
+
 	Describe("GetIPAddressForInterface", func() {
 		It("should return the IP address for the interface", func() {
 			mockClient.ListIPAddressesFunc = func(opts models.ListIPAddressesRequest) (*models.ListIPAddressesResponse, error) {
@@ -349,45 +479,142 @@ var _ = Describe("IPAM", func() {
 		})
 	})
 
-	Describe("GetPrefixesByRegionRole", func() {
-		It("should return the prefixes for the specified region and role", func() {
-			mockClient.ListPrefixesFunc = func(opts models.ListPrefixesRequest) (*models.ListPrefixesReponse, error) {
-				Expect(opts.Region).To(Equal("eu-central"))
-				Expect(opts.Role).To(Equal("compute"))
-				return &models.ListPrefixesReponse{
-					Results: []models.Prefix{
-						{Prefix: "10.0.0.0/16"},
-						{Prefix: "10.1.0.0/16"},
-					},
-				}, nil
+	Describe("GetPrefixes", func() {
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			mockClient.AuthTokenFunc = func() string { return "token" }
+			mockClient.HTTPClientFunc = func() *http.Client { return server.Client() }
+			mockClient.BaseURLFunc = func() *url.URL {
+				u, err := url.Parse(server.URL)
+				Expect(err).ToNot(HaveOccurred())
+				return u
 			}
+		})
 
-			prefixes, err := ipamService.GetPrefixesByRegionRole("eu-central", "compute")
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should return the prefixes matching the filters", func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.URL.Path).To(Equal("/api/ipam/prefixes/"))
+				Expect(r.URL.Query()).To(Equal(url.Values{
+					"region": {"eu-central"},
+					"role":   {"compute"},
+					"tag":    {"k8s"},
+					"tenant": {"cc"},
+					"vrf_id": {"3"},
+					"family": {"4"},
+					"limit":  {"1000"},
+					"offset": {"0"},
+				}))
+				Expect(r.Header.Get("Authorization")).To(Equal("Token token"))
+				fmt.Fprint(w, `{"count": 2, "results": [{"prefix": "10.0.0.0/16"}, {"prefix": "10.1.0.0/16"}]}`)
+			}))
+
+			prefixes, err := ipamService.GetPrefixes(
+				context.Background(),
+				ipam.PrefixWithRegion("eu-central"),
+				ipam.PrefixWithRole("compute"),
+				ipam.PrefixWithTag("k8s"),
+				ipam.PrefixWithTenant("cc"),
+				ipam.PrefixWithVrf(3),
+				ipam.PrefixWithFamily(4),
+			)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(prefixes).To(HaveLen(2))
 			Expect(prefixes[0].Prefix).To(Equal("10.0.0.0/16"))
 		})
 
-		It("should return an error when no prefixes are found", func() {
-			mockClient.ListPrefixesFunc = func(opts models.ListPrefixesRequest) (*models.ListPrefixesReponse, error) {
-				Expect(opts.Region).To(Equal("eu-central"))
-				Expect(opts.Role).To(Equal("storage"))
-				return &models.ListPrefixesReponse{Results: []models.Prefix{}}, nil
-			}
+		It("should page through all prefixes", func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				if r.URL.Query().Get("offset") == "0" {
+					fmt.Fprint(w, `{"count": 2, "results": [{"prefix": "10.0.0.0/16"}]}`)
+					return
+				}
+				Expect(r.URL.Query().Get("offset")).To(Equal("1"))
+				fmt.Fprint(w, `{"count": 2, "results": [{"prefix": "10.1.0.0/16"}]}`)
+			}))
+
+			prefixes, err := ipamService.GetPrefixes(context.Background(), ipam.PrefixWithRole("compute"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(prefixes).To(HaveLen(2))
+			Expect(prefixes[1].Prefix).To(Equal("10.1.0.0/16"))
+		})
 
-			_, err := ipamService.GetPrefixesByRegionRole("eu-central", "storage")
-			Expect(err).To(HaveOccurred())
-			Expect(err).To(MatchError("prefixes in region eu-central with role storage not found"))
+		It("should return no error when no prefixes are found", func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				fmt.Fprint(w, `{"count": 0, "results": []}`)
+			}))
+
+			prefixes, err := ipamService.GetPrefixes(context.Background(), ipam.PrefixWithRegion("eu-central"), ipam.PrefixWithRole("storage"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(prefixes).To(BeEmpty())
 		})
 
 		It("should return an error when unable to list prefixes", func() {
-			mockClient.ListPrefixesFunc = func(opts models.ListPrefixesRequest) (*models.ListPrefixesReponse, error) {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				http.Error(w, "forbidden", http.StatusForbidden)
+			}))
+
+			_, err := ipamService.GetPrefixes(context.Background(), ipam.PrefixWithRegion("eu-central"), ipam.PrefixWithRole("network"))
+			Expect(err).To(MatchError(ContainSubstring("unable to list prefixes: unexpected return code of 403")))
+		})
+	})
+
+	Describe("GetVlansByGroup", func() {
+		It("should return the VLANs of the group", func() {
+			mockClient.ListVlansFunc = func(opts models.ListVlanRequest) (*models.ListVlanResponse, error) {
+				Expect(opts.Group).To(Equal("transit"))
+				return &models.ListVlanResponse{
+					ReturnValues: common.ReturnValues{Count: 2},
+					Results: []models.Vlan{
+						{NestedVLAN: models.NestedVLAN{ID: 1, VID: 100}},
+						{NestedVLAN: models.NestedVLAN{ID: 2, VID: 200}},
+					},
+				}, nil
+			}
+
+			vlans, err := ipamService.GetVlansByGroup("transit")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vlans).To(HaveLen(2))
+		})
+
+		It("should return an error when unable to list VLANs", func() {
+			mockClient.ListVlansFunc = func(opts models.ListVlanRequest) (*models.ListVlanResponse, error) {
 				return nil, errors.New("API error")
 			}
 
-			_, err := ipamService.GetPrefixesByRegionRole("eu-central", "network")
-			Expect(err).To(HaveOccurred())
-			Expect(err).To(MatchError("unable to list prefixes in region eu-central with role network: API error"))
+			_, err := ipamService.GetVlansByGroup("transit")
+			Expect(err).To(MatchError("unable to list VLANs in group transit: API error"))
+		})
+	})
+
+	Describe("GetVrfsByName", func() {
+		It("should return the VRFs with the name", func() {
+			mockClient.ListVRFsFunc = func(opts models.ListVRFsRequest) (*models.ListVRFsResponse, error) {
+				Expect(opts.Name).To(Equal("CC-CLOUD01"))
+				return &models.ListVRFsResponse{
+					Results: []models.VRF{{NestedVRF: models.NestedVRF{ID: 3, Name: "CC-CLOUD01"}}},
+				}, nil
+			}
+
+			vrfs, err := ipamService.GetVrfsByName("CC-CLOUD01")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vrfs).To(HaveLen(1))
+			Expect(vrfs[0].ID).To(Equal(3))
+		})
+
+		It("should return an error when unable to list VRFs", func() {
+			mockClient.ListVRFsFunc = func(_ models.ListVRFsRequest) (*models.ListVRFsResponse, error) {
+				return nil, errors.New("API error")
+			}
+
+			_, err := ipamService.GetVrfsByName("CC-CLOUD01")
+			Expect(err).To(MatchError("unable to list VRFs by name CC-CLOUD01: API error"))
 		})
 	})
 
@@ -409,16 +636,15 @@ var _ = Describe("IPAM", func() {
 			Expect(prefixes[0].Prefix).To(Equal("10.0.0.0/16"))
 		})
 
-		It("should return an error when no prefixes are found", func() {
+		It("should return no error when no prefixes are found", func() {
 			mockClient.ListPrefixesFunc = func(opts models.ListPrefixesRequest) (*models.ListPrefixesReponse, error) {
-				Expect(opts.Region).To(Equal("eu-central"))
-				Expect(opts.Role).To(Equal("storage"))
+				Expect(opts.Prefix).To(Equal("10.2.0.0/16"))
 				return &models.ListPrefixesReponse{Results: []models.Prefix{}}, nil
 			}
 
-			_, err := ipamService.GetPrefixesByRegionRole("eu-central", "storage")
-			Expect(err).To(HaveOccurred())
-			Expect(err).To(MatchError("prefixes in region eu-central with role storage not found"))
+			prefixes, err := ipamService.GetPrefixesByPrefix("10.2.0.0/16")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(prefixes).To(BeEmpty())
 		})
 
 		It("should return an error when unable to list prefixes", func() {
@@ -426,9 +652,9 @@ var _ = Describe("IPAM", func() {
 				return nil, errors.New("API error")
 			}
 
-			_, err := ipamService.GetPrefixesByRegionRole("eu-central", "network")
+			_, err := ipamService.GetPrefixesByPrefix("10.2.0.0/16")
 			Expect(err).To(HaveOccurred())
-			Expect(err).To(MatchError("unable to list prefixes in region eu-central with role network: API error"))
+			Expect(err).To(MatchError("unable to list prefixes with prefix 10.2.0.0/16: API error"))
 		})
 	})
 
@@ -460,6 +686,30 @@ var _ = Describe("IPAM", func() {
 			Expect(gotAddr.AssignedInterface.ID).To(Equal(2))
 			Expect(gotAddr.Vrf.(models.VRF).ID).To(Equal(3))
 		})
+		It("should create IP address with metadata", func() {
+			mockClient.CreateIPAddressFunc = func(ip models.WriteableIPAddress) (*models.IPAddress, error) {
+				Expect(ip.DNSName).To(Equal("node001-bb001.example.com"))
+				Expect(ip.Status).To(Equal("active"))
+				Expect(ip.Role).To(Equal("anycast"))
+				Expect(ip.Description).To(Equal("claim default/claim"))
+				Expect(ip.Tags).To(Equal([]models.NestedTag{{Name: "argora", Slug: "argora"}}))
+				Expect(ip.CustomFields).To(Equal(map[string]any{"cluster": "cluster1"}))
+				return &models.IPAddress{NestedIPAddress: models.NestedIPAddress{Address: ip.Address}}, nil
+			}
+
+			_, err := ipamService.CreateIPAddress(ipam.CreateIPAddressParams{
+				Address:      "123.123.123.123/24",
+				InterfaceID:  2,
+				DNSName:      "node001-bb001.example.com",
+				Status:       "active",
+				Role:         "anycast",
+				Description:  "claim default/claim",
+				Tags:         []models.NestedTag{{Name: "argora", Slug: "argora"}},
+				CustomFields: map[string]any{"cluster": "cluster1"},
+			})
+
+			Expect(err).To(Succeed())
+		})
 		It("should fail on client failure", func() {
 			mockClient.CreateIPAddressFunc = func(ip models.WriteableIPAddress) (*models.IPAddress, error) {
 				return nil, errors.New("API error")
@@ -544,4 +794,128 @@ var _ = Describe("IPAM", func() {
 			Expect(err).To(MatchError("unable to delete IP address (1): error deleting IP address"))
 		})
 	})
+
+	Describe("CreateAvailablePrefix", func() {
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			mockClient.AuthTokenFunc = func() string { return "token" }
+			mockClient.HTTPClientFunc = func() *http.Client { return server.Client() }
+			mockClient.BaseURLFunc = func() *url.URL {
+				u, err := url.Parse(server.URL)
+				Expect(err).ToNot(HaveOccurred())
+				return u
+			}
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should allocate a child prefix with its attributes in one request", func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.Method).To(Equal(http.MethodPost))
+				Expect(r.URL.Path).To(Equal("/api/ipam/prefixes/1/available-prefixes/"))
+				Expect(r.Header.Get("Authorization")).To(Equal("Token token"))
+				body, err := io.ReadAll(r.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(body).To(MatchJSON(`{
+					"prefix_length": 26,
+					"site": 2,
+					"vrf": 3,
+					"tenant": 4,
+					"status": "active",
+					"description": "claimed",
+					"tags": [{"id": 5, "name": "tag", "slug": "tag"}]
+				}`))
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprint(w, `{"id": 6, "prefix": "10.0.0.0/26", "description": "claimed"}`)
+			}))
+
+			prefix, err := ipamService.CreateAvailablePrefix(context.Background(), 1, ipam.CreateAvailablePrefixParams{
+				PrefixLength: 26,
+				SiteID:       2,
+				VrfID:        3,
+				TenantID:     4,
+				Status:       "active",
+				Description:  "claimed",
+				Tags:         []models.NestedTag{{ID: 5, Name: "tag", Slug: "tag"}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(prefix.ID).To(Equal(6))
+			Expect(prefix.Prefix).To(Equal("10.0.0.0/26"))
+			Expect(prefix.Description).To(Equal("claimed"))
+		})
+
+		It("should return an error when no child prefix is available", func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				http.Error(w, "insufficient space", http.StatusConflict)
+			}))
+
+			_, err := ipamService.CreateAvailablePrefix(context.Background(), 1, ipam.CreateAvailablePrefixParams{PrefixLength: 26})
+			Expect(err).To(MatchError(ContainSubstring("unable to create available prefix in prefix (1): unexpected return code of 409: insufficient space")))
+		})
+	})
+
+	Describe("DeletePrefix", func() {
+		It("should delete the prefix successfully", func() {
+			mockClient.DeletePrefixFunc = func(id int) error {
+				Expect(id).To(Equal(2))
+				return nil
+			}
+
+			Expect(ipamService.DeletePrefix(2)).To(Succeed())
+		})
+
+		It("should return an error when unable to delete the prefix", func() {
+			mockClient.DeletePrefixFunc = func(_ int) error {
+				return errors.New("error deleting prefix")
+			}
+
+			Expect(ipamService.DeletePrefix(2)).To(MatchError("unable to delete prefix (2): error deleting prefix"))
+		})
+	})
+
+	Describe("UnassignIPAddress", func() {
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			mockClient.AuthTokenFunc = func() string { return "token" }
+			mockClient.HTTPClientFunc = func() *http.Client { return server.Client() }
+			mockClient.BaseURLFunc = func() *url.URL {
+				u, err := url.Parse(server.URL)
+				Expect(err).ToNot(HaveOccurred())
+				return u
+			}
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should detach the IP address from its interface", func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.Method).To(Equal(http.MethodPatch))
+				Expect(r.URL.Path).To(Equal("/api/ipam/ip-addresses/5/"))
+				Expect(r.Header.Get("Authorization")).To(Equal("Token token"))
+				body, err := io.ReadAll(r.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(body).To(MatchJSON(`{"assigned_object_type": null, "assigned_object_id": null}`))
+				fmt.Fprint(w, `{"id": 5}`)
+			}))
+
+			Expect(ipamService.UnassignIPAddress(context.Background(), 5)).To(Succeed())
+		})
+
+		It("should return an error on unexpected status codes", func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				http.Error(w, "bad request", http.StatusBadRequest)
+			}))
+
+			err := ipamService.UnassignIPAddress(context.Background(), 5)
+			Expect(err).To(MatchError(ContainSubstring("unable to unassign IP address (5): unexpected return code of 400")))
+		})
+	})
 })
@@ -0,0 +1,30 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package ipam
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/sapcc/argora/internal/netbox/rest"
+)
+
+// UnassignIPAddress detaches the IP address from its interface, keeping the IP address. go-netbox-go omits unset
+// assignments on IP address updates, hence the IP address is patched directly.
+func (i *IPAMService) UnassignIPAddress(ctx context.Context, id int) error {
+	fields := map[string]any{
+		"assigned_object_type": nil,
+		"assigned_object_id":   nil,
+	}
+
+	u := i.netboxAPI.BaseURL().JoinPath("/api/ipam/ip-addresses/", strconv.Itoa(id), "/")
+
+	i.logger.V(1).Info("patch IP address", "url", u.String(), "fields", fields)
+	if err := rest.Do(ctx, i.netboxAPI, http.MethodPatch, u, fields, nil); err != nil {
+		return fmt.Errorf("unable to unassign IP address (%d): %w", id, err)
+	}
+	return nil
+}
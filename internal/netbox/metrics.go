@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package netbox
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sapcc/go-netbox-go/common"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// statusClassError is the status class of requests failed without a response.
+	statusClassError = "error"
+	// endpointID replaces the object IDs in the endpoint label, to keep the cardinality of the metrics bounded.
+	endpointID = "{id}"
+)
+
+var (
+	// requestDuration observes the latency of the requests to the NetBox API.
+	requestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "argora_netbox_request_duration_seconds",
+			Help:    "Latency of requests to the NetBox API, by component, endpoint and method.",
+			Buckets: []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"component", "endpoint", "method"},
+	)
+
+	// requestErrorsTotal counts the failed requests to the NetBox API.
+	requestErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "argora_netbox_request_errors_total",
+			Help: "Number of failed requests to the NetBox API, by component, endpoint, method and status class (4xx, 5xx or error if no response was received).",
+		},
+		[]string{"component", "endpoint", "method", "status_class"},
+	)
+
+	// requestsInFlight is the number of requests to the NetBox API waiting for a response.
+	requestsInFlight = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "argora_netbox_requests_in_flight",
+			Help: "Number of requests to the NetBox API waiting for a response, by component.",
+		},
+		[]string{"component"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(requestDuration, requestErrorsTotal, requestsInFlight)
+}
+
+// instrument wraps the transport of the go-netbox-go client, so all its requests are measured and logged.
+func instrument(client common.HTTPConnectable, component string, logger logr.Logger) {
+	httpClient := &http.Client{}
+	if client.HTTPClient() != nil {
+		*httpClient = *client.HTTPClient()
+	}
+
+	next := httpClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	httpClient.Transport = &instrumentedTransport{next: next, component: component, logger: logger}
+	client.SetHTTPClient(httpClient)
+}
+
+// instrumentedTransport observes the latency, errors and in-flight requests of a NetBox API client.
+type instrumentedTransport struct {
+	next      http.RoundTripper
+	component string
+	logger    logr.Logger
+}
+
+func (t *instrumentedTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	endpoint := requestEndpoint(request.URL)
+
+	inFlight := requestsInFlight.WithLabelValues(t.component)
+	inFlight.Inc()
+	defer inFlight.Dec()
+
+	start := time.Now()
+	response, err := t.next.RoundTrip(request)
+	duration := time.Since(start)
+	requestDuration.WithLabelValues(t.component, endpoint, request.Method).Observe(duration.Seconds())
+
+	if err != nil {
+		requestErrorsTotal.WithLabelValues(t.component, endpoint, request.Method, statusClassError).Inc()
+		t.logger.V(1).Info("netbox request failed", "method", request.Method, "endpoint", endpoint, "duration", duration, "error", err.Error())
+		return nil, err
+	}
+
+	if response.StatusCode >= http.StatusBadRequest {
+		requestErrorsTotal.WithLabelValues(t.component, endpoint, request.Method, statusClass(response.StatusCode)).Inc()
+	}
+	t.logger.V(1).Info("netbox request", "method", request.Method, "endpoint", endpoint, "status", response.StatusCode, "duration", duration)
+
+	return response, nil
+}
+
+// requestEndpoint returns the path of the request with object IDs replaced, e.g. /api/dcim/devices/{id}/.
+func requestEndpoint(u *url.URL) string {
+	segments := strings.Split(u.Path, "/")
+	for i, segment := range segments {
+		if _, err := strconv.Atoi(segment); err == nil {
+			segments[i] = endpointID
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// statusClass returns the class of the HTTP status code, e.g. 5xx.
+func statusClass(code int) string {
+	return strconv.Itoa(code/100) + "xx"
+}
@@ -5,6 +5,8 @@
 package netbox
 
 import (
+	"sync"
+
 	"github.com/go-logr/logr"
 	"github.com/sapcc/go-netbox-go/dcim"
 	"github.com/sapcc/go-netbox-go/extras"
@@ -26,8 +28,14 @@ type Netbox interface {
 	Extras() _extras.Extras
 }
 
+// newClientsMu serializes the creation of the go-netbox-go clients, which initializes the shared default transport.
+var newClientsMu sync.Mutex
+
 type NetboxService struct {
-	netboxURL      string
+	netboxURL string
+
+	// mu guards the services, which are replaced on Reload while concurrent reconciles use them
+	mu             sync.RWMutex
 	virtualization _virtualization.Virtualization
 	dcim           _dcim.DCIM
 	ipam           _ipam.IPAM
@@ -45,6 +53,9 @@ func NewNetbox(netboxURL string) Netbox {
 }
 
 func (n *NetboxService) Reload(token string, logger logr.Logger) error {
+	newClientsMu.Lock()
+	defer newClientsMu.Unlock()
+
 	virtClient, err := virtualization.NewClient(n.netboxURL, token, false)
 	if err != nil {
 		return err
@@ -61,25 +72,46 @@ func (n *NetboxService) Reload(token string, logger logr.Logger) error {
 	if err != nil {
 		return err
 	}
-	n.virtualization = _virtualization.NewVirtualization(virtClient, logger.WithValues("nbComponent", "virtualization"))
-	n.dcim = _dcim.NewDCIM(dcimClient, logger.WithValues("nbComponent", "dcim"))
-	n.ipam = _ipam.NewIPAM(ipamClient, logger.WithValues("nbComponent", "ipam"))
-	n.extras = _extras.NewExtras(extrasClient, logger.WithValues("nbComponent", "extras"))
+
+	virtLogger := logger.WithValues("nbComponent", "virtualization")
+	dcimLogger := logger.WithValues("nbComponent", "dcim")
+	ipamLogger := logger.WithValues("nbComponent", "ipam")
+	extrasLogger := logger.WithValues("nbComponent", "extras")
+
+	instrument(virtClient, "virtualization", virtLogger)
+	instrument(dcimClient, "dcim", dcimLogger)
+	instrument(ipamClient, "ipam", ipamLogger)
+	instrument(extrasClient, "extras", extrasLogger)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.virtualization = _virtualization.NewVirtualization(virtClient, virtLogger)
+	n.dcim = _dcim.NewDCIM(dcimClient, dcimLogger)
+	n.ipam = _ipam.NewIPAM(ipamClient, ipamLogger)
+	n.extras = _extras.NewExtras(extrasClient, extrasLogger)
 	return nil
 }
 
 func (n *NetboxService) Virtualization() _virtualization.Virtualization {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
 	return n.virtualization
 }
 
 func (n *NetboxService) DCIM() _dcim.DCIM {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
 	return n.dcim
 }
 
 func (n *NetboxService) IPAM() _ipam.IPAM {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
 	return n.ipam
 }
 
 func (n *NetboxService) Extras() _extras.Extras {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
 	return n.extras
 }
@@ -4,12 +4,19 @@
 package netbox
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
 	"testing"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sapcc/go-netbox-go/common"
 	"github.com/sapcc/go-netbox-go/models"
 
 	"github.com/sapcc/argora/internal/netbox/ipam"
@@ -49,6 +56,14 @@ func (m *MockDCIM) GetRegionForDevice(device *models.Device) (string, error) {
 	return "", nil
 }
 
+func (m *MockDCIM) GetSiteGroupForDevice(device *models.Device) (string, error) {
+	return "", nil
+}
+
+func (m *MockDCIM) GetConfigContextForDevice(device *models.Device) (map[string]any, error) {
+	return nil, nil
+}
+
 func (m *MockDCIM) GetInterfaceByID(id int) (*models.Interface, error) {
 	return nil, nil
 }
@@ -77,6 +92,10 @@ func (m *MockDCIM) UpdateInterface(iface models.WritableInterface, id int) (*mod
 	return nil, nil
 }
 
+func (m *MockDCIM) ClearDevicePrimaryIP(_ context.Context, deviceID int, ipv6 bool) error {
+	return nil
+}
+
 func (m *MockDCIM) DeleteInterface(id int) error {
 	return nil
 }
@@ -101,7 +120,15 @@ func (m *MockIPAM) GetPrefixesContaining(contains string) ([]models.Prefix, erro
 	return nil, nil
 }
 
-func (m *MockIPAM) GetPrefixesByRegionRole(region, role string) ([]models.Prefix, error) {
+func (m *MockIPAM) GetPrefixes(_ context.Context, _ ...ipam.ListPrefixesRequestOption) ([]models.Prefix, error) {
+	return nil, nil
+}
+
+func (m *MockIPAM) GetVlansByGroup(_ string) ([]models.Vlan, error) {
+	return nil, nil
+}
+
+func (m *MockIPAM) GetVrfsByName(_ string) ([]models.VRF, error) {
 	return nil, nil
 }
 
@@ -109,6 +136,14 @@ func (m *MockIPAM) GetPrefixesByPrefix(_ string) ([]models.Prefix, error) {
 	return nil, nil
 }
 
+func (m *MockIPAM) GetIPAddresses(_ context.Context, _ ...ipam.ListIPAddressesRequestOption) ([]models.IPAddress, error) {
+	return nil, nil
+}
+
+func (m *MockIPAM) GetIPRangesInPrefix(_ context.Context, _ string, _ int) ([]ipam.IPRange, error) {
+	return nil, nil
+}
+
 func (m *MockIPAM) CreateIPAddress(addr ipam.CreateIPAddressParams) (*models.IPAddress, error) {
 	return nil, nil
 }
@@ -117,6 +152,18 @@ func (m *MockIPAM) UpdateIPAddress(addr models.WriteableIPAddress) (*models.IPAd
 	return nil, nil
 }
 
+func (m *MockIPAM) CreateAvailablePrefix(_ context.Context, _ int, _ ipam.CreateAvailablePrefixParams) (*models.Prefix, error) {
+	return nil, nil
+}
+
+func (m *MockIPAM) DeletePrefix(_ int) error {
+	return nil
+}
+
+func (m *MockIPAM) UnassignIPAddress(_ context.Context, _ int) error {
+	return nil
+}
+
 func (m *MockIPAM) DeleteIPAddress(id int) error {
 	return nil
 }
@@ -140,7 +187,7 @@ var _ = Describe("NetboxService", func() {
 		mockIPAM = &MockIPAM{}
 		mockExtras = &MockExtras{}
 
-		netboxService = &NetboxService{"", mockVirtualization, mockDCIM, mockIPAM, mockExtras}
+		netboxService = &NetboxService{virtualization: mockVirtualization, dcim: mockDCIM, ipam: mockIPAM, extras: mockExtras}
 	})
 
 	Describe("Virtualization", func() {
@@ -179,5 +226,71 @@ var _ = Describe("NetboxService", func() {
 			Expect(netboxService.IPAM()).ToNot(BeNil())
 			Expect(netboxService.Extras()).ToNot(BeNil())
 		})
+
+		It("should reload the services while they are used concurrently", func() {
+			var wg sync.WaitGroup
+			for range 4 {
+				wg.Go(func() {
+					defer GinkgoRecover()
+					Expect(netboxService.Reload("test-token", logr.Discard())).To(Succeed())
+					Expect(netboxService.IPAM()).ToNot(BeNil())
+				})
+			}
+			wg.Wait()
+		})
+	})
+})
+
+var _ = Describe("Request instrumentation", func() {
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/api/dcim/devices/42/" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	newClient := func() *common.Client {
+		client := &common.Client{}
+		client.SetHTTPClient(server.Client())
+		instrument(client, "test", logr.Discard())
+		return client
+	}
+
+	It("should replace object IDs in endpoints", func() {
+		u, err := url.Parse("https://netbox/api/ipam/ip-addresses/123/?limit=1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requestEndpoint(u)).To(Equal("/api/ipam/ip-addresses/{id}/"))
+	})
+
+	It("should observe the latency of requests", func() {
+		count := testutil.CollectAndCount(requestDuration, "argora_netbox_request_duration_seconds")
+
+		response, err := newClient().HTTPClient().Get(server.URL + "/api/dcim/devices/")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Body.Close()).To(Succeed())
+
+		Expect(testutil.CollectAndCount(requestDuration, "argora_netbox_request_duration_seconds")).To(Equal(count + 1))
+		Expect(testutil.ToFloat64(requestErrorsTotal.WithLabelValues("test", "/api/dcim/devices/", http.MethodGet, "2xx"))).To(BeZero())
+		Expect(testutil.ToFloat64(requestsInFlight.WithLabelValues("test"))).To(BeZero())
+	})
+
+	It("should count failed requests by status class", func() {
+		client := newClient()
+
+		response, err := client.HTTPClient().Get(server.URL + "/api/dcim/devices/42/")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Body.Close()).To(Succeed())
+		Expect(testutil.ToFloat64(requestErrorsTotal.WithLabelValues("test", "/api/dcim/devices/{id}/", http.MethodGet, "4xx"))).To(Equal(1.0))
+
+		server.Close()
+		_, err = client.HTTPClient().Get(server.URL + "/api/dcim/devices/")
+		Expect(err).To(HaveOccurred())
+		Expect(testutil.ToFloat64(requestErrorsTotal.WithLabelValues("test", "/api/dcim/devices/", http.MethodGet, statusClassError))).To(Equal(1.0))
 	})
 })
@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+// Package rest sends the requests to the Netbox API which are not supported by go-netbox-go.
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/sapcc/go-netbox-go/common"
+)
+
+// Do sends the request with the JSON encoded body to the URL and decodes the response into result. The body and
+// the result are skipped if nil. Responses with other than a 2xx status code are returned as error.
+func Do(ctx context.Context, api common.HTTPConnectable, method string, u *url.URL, body, result any) error {
+	var payload io.Reader = http.NoBody
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(encoded)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, u.String(), payload)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Token "+api.AuthToken())
+	request.Header.Set("Accept", "application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := api.HTTPClient().Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected return code of %d: %s", response.StatusCode, responseBody)
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(responseBody, result)
+}
@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: 2025 SAP SE or an SAP affiliate company
+// SPDX-License-Identifier: Apache-2.0
+
+package rest_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sapcc/go-netbox-go/common"
+
+	"github.com/sapcc/argora/internal/netbox/rest"
+)
+
+func TestRest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Rest Suite")
+}
+
+var _ = Describe("Do", func() {
+	var (
+		server *httptest.Server
+		api    *common.Client
+	)
+
+	newAPI := func(handler http.HandlerFunc) *url.URL {
+		server = httptest.NewServer(handler)
+		u, err := url.Parse(server.URL)
+		Expect(err).ToNot(HaveOccurred())
+
+		api = &common.Client{}
+		api.SetBaseURL(u)
+		api.SetAuthToken("token")
+		api.SetHTTPClient(server.Client())
+		return u
+	}
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should send the JSON encoded body and decode the response", func() {
+		u := newAPI(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			Expect(r.Method).To(Equal(http.MethodPatch))
+			Expect(r.URL.Path).To(Equal("/api/dcim/devices/7/"))
+			Expect(r.Header.Get("Authorization")).To(Equal("Token token"))
+			Expect(r.Header.Get("Content-Type")).To(Equal("application/json"))
+			body, err := io.ReadAll(r.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(body).To(MatchJSON(`{"primary_ip4": null}`))
+			fmt.Fprint(w, `{"id": 7}`)
+		})
+
+		var result struct {
+			ID int `json:"id"`
+		}
+		Expect(rest.Do(context.Background(), api, http.MethodPatch, u.JoinPath("/api/dcim/devices/7/"), map[string]any{"primary_ip4": nil}, &result)).To(Succeed())
+		Expect(result.ID).To(Equal(7))
+	})
+
+	It("should send requests without body and skip the result", func() {
+		u := newAPI(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			Expect(r.Method).To(Equal(http.MethodGet))
+			Expect(r.URL.Query().Get("parent")).To(Equal("10.0.0.0/24"))
+			Expect(r.Header.Get("Content-Type")).To(BeEmpty())
+			fmt.Fprint(w, `{"count": 0}`)
+		})
+
+		u = u.JoinPath("/api/ipam/ip-ranges/")
+		u.RawQuery = url.Values{"parent": {"10.0.0.0/24"}}.Encode()
+		Expect(rest.Do(context.Background(), api, http.MethodGet, u, nil, nil)).To(Succeed())
+	})
+
+	It("should return an error on unexpected status codes", func() {
+		u := newAPI(func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, "bad request", http.StatusBadRequest)
+		})
+
+		err := rest.Do(context.Background(), api, http.MethodPatch, u, map[string]any{}, nil)
+		Expect(err).To(MatchError("unexpected return code of 400: bad request\n"))
+	})
+
+	It("should not send requests with a cancelled context", func() {
+		u := newAPI(func(_ http.ResponseWriter, _ *http.Request) {
+			defer GinkgoRecover()
+			Fail("unexpected request")
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		Expect(rest.Do(ctx, api, http.MethodGet, u, nil, nil)).To(MatchError(context.Canceled))
+	})
+})
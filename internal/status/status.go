@@ -36,6 +36,7 @@ type IPPoolImportStatus interface {
 	UpdateToError(ctx context.Context, ipPoolImportCR *argorav1alpha1.IPPoolImport, err error) error
 
 	SetCondition(ipPoolImportCR *argorav1alpha1.IPPoolImport, reason argorav1alpha1.ReasonWithMessage)
+	RemoveCondition(ipPoolImportCR *argorav1alpha1.IPPoolImport, conditionType argorav1alpha1.ConditionType)
 }
 
 func NewUpdateStatusHandler(k8sClient client.Client) UpdateStatus {
@@ -167,6 +168,13 @@ func (d IPPoolImportStatusHandler) SetCondition(ipPoolImportCR *argorav1alpha1.I
 	setCondition(ipPoolImportCR.Status.Conditions, reason)
 }
 
+func (d IPPoolImportStatusHandler) RemoveCondition(ipPoolImportCR *argorav1alpha1.IPPoolImport, conditionType argorav1alpha1.ConditionType) {
+	if ipPoolImportCR.Status.Conditions == nil {
+		return
+	}
+	meta.RemoveStatusCondition(ipPoolImportCR.Status.Conditions, string(conditionType))
+}
+
 func setCondition(conditions *[]metav1.Condition, reason argorav1alpha1.ReasonWithMessage) {
 	condition := argorav1alpha1.ConditionFromReason(reason)
 	if condition != nil {
@@ -215,6 +215,30 @@ var _ = Describe("ClusterImportStatus", func() {
 	})
 })
 
+var _ = Describe("IPPoolImportStatus", func() {
+	Describe("RemoveCondition", func() {
+		It("should remove IPPoolImport CR status conditions of the given type only", func() {
+			// given
+			cr := argorav1alpha1.IPPoolImport{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+			}
+			k8sClient := createFakeClient(&cr)
+			handler := NewIPPoolImportStatusHandler(k8sClient)
+			handler.RemoveCondition(&cr, argorav1alpha1.ConditionTypePrefixesMatched)
+			handler.SetCondition(&cr, argorav1alpha1.NewReasonWithMessage(argorav1alpha1.ConditionReasonIPPoolImportSucceeded))
+			handler.SetCondition(&cr, argorav1alpha1.NewReasonWithMessage(argorav1alpha1.ConditionReasonIPPoolImportNoPrefixesMatched))
+			Expect((*cr.Status.Conditions)).To(HaveLen(2))
+
+			// when
+			handler.RemoveCondition(&cr, argorav1alpha1.ConditionTypePrefixesMatched)
+
+			// then
+			Expect((*cr.Status.Conditions)).To(HaveLen(1))
+			Expect((*cr.Status.Conditions)[0].Type).To(Equal(string(argorav1alpha1.ConditionTypeReady)))
+		})
+	})
+})
+
 func createFakeClient(objects ...client.Object) client.Client {
 	return fake.NewClientBuilder().WithScheme(getTestScheme()).WithObjects(objects...).WithStatusSubresource(objects...).Build()
 }